@@ -0,0 +1,528 @@
+package activity
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"api/internal/configuration"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	chainSequenceField = "chain_sequence"
+	chainPrevHashField = "chain_prev_hash"
+	chainHashField     = "chain_hash"
+)
+
+var ErrVerifyRangeTooLarge = errors.New("VERIFY_RANGE_TOO_LARGE")
+
+// chainRecord is the content covered by the hash of an activity record.
+// It is serialized as JSON, whose map keys are sorted, so that any backend can recompute it.
+type chainRecord struct {
+	Sequence  int64             `json:"sequence"`
+	PrevHash  string            `json:"prev_hash"`
+	Timestamp string            `json:"timestamp"`
+	Message   string            `json:"message"`
+	Fields    map[string]string `json:"fields"`
+	Object    interface{}       `json:"object,omitempty"`
+}
+
+func (r chainRecord) hash() (string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// ChainedActivityLogger appends every activity to a hash chain before handing it to the backend.
+// Each record carries its sequence number, the hash of the previous record and its own hash
+// in its filter fields, so the chain works with any backend able to return the fields it stores.
+// The chained records go through the activity_outbox table, so that a failed delivery is retried
+// by the delivery job instead of leaving a gap in the chain.
+// A record failing the maximum number of attempts is dead-lettered, and kept in the outbox for investigation.
+type ChainedActivityLogger struct {
+	DB          *gorm.DB
+	Backend     IActivityLogger
+	MaxAttempts int
+	delivered   atomic.Int64
+	failed      atomic.Int64
+}
+
+// NewChainedActivityLogger wraps an activity backend with the hash chain.
+func NewChainedActivityLogger(db *gorm.DB, backend IActivityLogger, maxAttempts int) *ChainedActivityLogger {
+	return &ChainedActivityLogger{DB: db, Backend: backend, MaxAttempts: maxAttempts}
+}
+
+// Send appends the activity to the chain, then delivers it to the backend. The chain head is only locked
+// while the record is chained and stored in the outbox, so that audited requests do not wait for the backend.
+// Once stored, the record belongs to the audit log: a failed delivery is retried by the delivery job,
+// and does not fail the caller.
+func (s *ChainedActivityLogger) Send(activity models.Activity) error {
+	entry, err := newActivityOutbox(activity)
+	if err != nil {
+		return err
+	}
+	entry.NextAttemptAt = time.Now().Add(configuration.ActivityDeliveryLeaseSeconds * time.Second)
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.chain(tx, []*models.ActivityOutbox{&entry}); err != nil {
+			return err
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// The failure is recorded on the record, which the delivery job retries
+	_ = s.deliver(entry)
+	return nil
+}
+
+// SendTx stores the activity in the outbox in the transaction of the change it describes, so that it is
+// only recorded when the change is committed. The record is chained and delivered by the delivery job,
+// so that the chain head is not locked while the transaction of the caller is open.
+func (s *ChainedActivityLogger) SendTx(tx *gorm.DB, activity models.Activity) error {
+	entry, err := newActivityOutbox(activity)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Create(&entry).Error; err != nil {
+		zap.L().Error("Failed to queue activity", zap.String("message", activity.Message), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SendSync chains the activity and delivers it to the backend before advancing the chain head,
+// so that an activity refused by the backend is neither recorded nor leaves a gap in the chain.
+// The chain head stays locked during the delivery.
+func (s *ChainedActivityLogger) SendSync(activity models.Activity) error {
+	entry, err := newActivityOutbox(activity)
+	if err != nil {
+		return err
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.chain(tx, []*models.ActivityOutbox{&entry}); err != nil {
+			return err
+		}
+
+		chained, err := outboxActivity(entry)
+		if err != nil {
+			return err
+		}
+		if err = s.Backend.Send(chained); err != nil {
+			s.failed.Add(1)
+			return err
+		}
+		s.delivered.Add(1)
+		return nil
+	})
+}
+
+// Deliver sends a batch of due records of the outbox to the backend, oldest first, and returns the number
+// of delivered records. The batch is chained and leased in a short transaction, then delivered once committed,
+// so that several instances can deliver concurrently without holding locks during the deliveries.
+// When a delivery fails, the rest of the batch waits for the next run.
+func (s *ChainedActivityLogger) Deliver(batchSize int) (int, error) {
+	var entries []models.ActivityOutbox
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("failed_at IS NULL AND next_attempt_at <= ?", time.Now()).
+			Order("id").
+			Limit(batchSize).
+			Find(&entries).Error
+		if err != nil || len(entries) == 0 {
+			return err
+		}
+
+		pending := make([]*models.ActivityOutbox, 0, len(entries))
+		for i := range entries {
+			pending = append(pending, &entries[i])
+		}
+		if err = s.chain(tx, pending); err != nil {
+			return err
+		}
+
+		lease := time.Now().Add(configuration.ActivityDeliveryLeaseSeconds * time.Second)
+		for i := range entries {
+			entries[i].NextAttemptAt = lease
+			err = tx.Model(&entries[i]).
+				Select("fields", "timestamp", "chain_sequence", "next_attempt_at").
+				Updates(&entries[i]).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, entry := range entries {
+		if err = s.deliver(entry); err != nil {
+			if i+1 < len(entries) {
+				s.release(entries[i+1:])
+			}
+			return i, nil
+		}
+	}
+	return len(entries), nil
+}
+
+func (s *ChainedActivityLogger) Search(criteria SearchCriteria) ([]map[string]interface{}, string, error) {
+	return s.Backend.Search(criteria)
+}
+
+// chain links the records which are not chained yet to the chain head, in order, and advances the head.
+func (s *ChainedActivityLogger) chain(tx *gorm.DB, entries []*models.ActivityOutbox) error {
+	unchained := make([]*models.ActivityOutbox, 0, len(entries))
+	for _, entry := range entries {
+		if entry.ChainSequence == nil {
+			unchained = append(unchained, entry)
+		}
+	}
+	if len(unchained) == 0 {
+		return nil
+	}
+
+	var head models.ActivityChainHead
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, 1).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range unchained {
+		activity, err := outboxActivity(*entry)
+		if err != nil {
+			return err
+		}
+
+		chained, hash, err := chainActivity(activity, head)
+		if err != nil {
+			return err
+		}
+
+		if entry.Fields, err = json.Marshal(chained.Filter.Fields); err != nil {
+			return err
+		}
+		if entry.Timestamp, err = strconv.ParseInt(chained.Filter.Timestamp, 10, 64); err != nil {
+			return err
+		}
+
+		head.Sequence++
+		head.Hash = hash
+		sequence := head.Sequence
+		entry.ChainSequence = &sequence
+	}
+
+	return tx.Model(&head).Updates(map[string]interface{}{
+		"sequence": head.Sequence,
+		"hash":     head.Hash,
+	}).Error
+}
+
+// deliver sends a chained record to the backend, and removes it from the outbox once accepted.
+// A failed record is retried later with an exponential backoff.
+func (s *ChainedActivityLogger) deliver(entry models.ActivityOutbox) error {
+	activity, err := outboxActivity(entry)
+	if err == nil {
+		err = s.Backend.Send(activity)
+	}
+	if err != nil {
+		s.failed.Add(1)
+		attempts := entry.Attempts + 1
+		updates := map[string]interface{}{
+			"attempts":        attempts,
+			"last_error":      err.Error(),
+			"next_attempt_at": time.Now().Add(retryDelay(attempts)),
+		}
+
+		if s.MaxAttempts > 0 && attempts >= s.MaxAttempts {
+			updates["failed_at"] = time.Now()
+			zap.L().Error("Failed to deliver activity, giving up",
+				zap.Int64("id", entry.ID),
+				zap.String("message", entry.Message),
+				zap.Int("attempts", attempts),
+				zap.Error(err))
+		} else {
+			zap.L().Warn("Failed to deliver activity, retrying later",
+				zap.Int64("id", entry.ID),
+				zap.String("message", entry.Message),
+				zap.Int("attempts", attempts),
+				zap.Error(err))
+		}
+
+		updateErr := s.DB.Model(&entry).Updates(updates).Error
+		if updateErr != nil {
+			zap.L().Error("Failed to record the activity delivery failure", zap.Error(updateErr))
+		}
+		return err
+	}
+
+	s.delivered.Add(1)
+	// The record is delivered again when it cannot be removed, which the verification reports as a duplicate
+	if err = s.DB.Delete(&entry).Error; err != nil {
+		zap.L().Error("Failed to remove delivered activity from the outbox", zap.Int64("id", entry.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// release gives the lease of records back, so that the next run delivers them.
+func (s *ChainedActivityLogger) release(entries []models.ActivityOutbox) {
+	ids := make([]int64, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	err := s.DB.Model(&models.ActivityOutbox{}).Where("id IN ?", ids).Update("next_attempt_at", time.Now()).Error
+	if err != nil {
+		zap.L().Error("Failed to release the activity delivery lease", zap.Error(err))
+	}
+}
+
+// chainActivity returns a copy of the activity linked to the chain head, and its hash.
+func chainActivity(activity models.Activity, head models.ActivityChainHead) (models.Activity, string, error) {
+	timestamp, err := strconv.ParseInt(activity.Filter.Timestamp, 10, 64)
+	if err != nil {
+		timestamp = time.Now().UnixNano()
+	}
+	// Timestamps are truncated to the microsecond precision of the database backend
+	timestamp = timestamp / int64(time.Microsecond) * int64(time.Microsecond)
+
+	fields := make(map[string]string, len(activity.Filter.Fields)+3)
+	for key, value := range activity.Filter.Fields {
+		fields[key] = value
+	}
+
+	record := chainRecord{
+		Sequence:  head.Sequence + 1,
+		PrevHash:  head.Hash,
+		Timestamp: strconv.FormatInt(timestamp, 10),
+		Message:   activity.Message,
+		Fields:    hashedFields(fields),
+	}
+
+	// The object is hashed as the backends return it: decoded from JSON, and only for the stored object types
+	if isAuthorizedObject(fields["object_type"]) && activity.Object != nil {
+		data, err := json.Marshal(activity.Object)
+		if err != nil {
+			return models.Activity{}, "", err
+		}
+		if err = json.Unmarshal(data, &record.Object); err != nil {
+			return models.Activity{}, "", err
+		}
+	}
+
+	hash, err := record.hash()
+	if err != nil {
+		return models.Activity{}, "", err
+	}
+
+	fields[chainSequenceField] = strconv.FormatInt(record.Sequence, 10)
+	fields[chainPrevHashField] = record.PrevHash
+	fields[chainHashField] = hash
+
+	return models.Activity{
+		Message: activity.Message,
+		Object:  activity.Object,
+		Filter: models.LogFilter{
+			Fields:    fields,
+			Timestamp: record.Timestamp,
+		},
+	}, hash, nil
+}
+
+// hashedFields returns the fields covered by the hash: the chain fields are hashed separately,
+// and empty values are skipped because backends do not store them.
+func hashedFields(fields map[string]string) map[string]string {
+	hashed := make(map[string]string, len(fields))
+	for key, value := range fields {
+		if value == "" || strings.HasPrefix(key, "chain_") {
+			continue
+		}
+		hashed[key] = value
+	}
+	return hashed
+}
+
+// SignCheckpoint signs a chain head with the signing key.
+func SignCheckpoint(signingKey string, sequence int64, hash string) string {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(fmt.Sprintf("%d:%s", sequence, hash)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// chainEntry is a record returned by a backend, with the chain fields parsed.
+type chainEntry struct {
+	record chainRecord
+	hash   string
+}
+
+// VerifyChain walks the activity of the time range and checks that every record matches its hash,
+// links to the previous record without gaps, and matches the signed checkpoints of the range.
+func VerifyChain(
+	db *gorm.DB,
+	logger IActivityLogger,
+	signingKey string,
+	from time.Time,
+	to time.Time,
+) (models.ActivityChainReport, error) {
+	report := models.ActivityChainReport{From: from, To: to, Issues: []models.ActivityChainIssue{}}
+
+	var entries []chainEntry
+	criteria := SearchCriteria{Fields: map[string][]string{}, From: from, To: to, Limit: 100}
+	for {
+		history, nextCursor, err := logger.Search(criteria)
+		if err != nil {
+			return models.ActivityChainReport{}, err
+		}
+
+		for _, log := range history {
+			entry, ok := parseChainEntry(log)
+			if !ok {
+				report.Issues = append(report.Issues, models.ActivityChainIssue{
+					Type:      "unchained",
+					Timestamp: fmt.Sprint(log["timestamp"]),
+					Detail:    "record has no chain fields",
+				})
+				continue
+			}
+			entries = append(entries, entry)
+		}
+
+		if len(entries) > configuration.ActivityVerifyMaxRecords {
+			return models.ActivityChainReport{}, ErrVerifyRangeTooLarge
+		}
+		if nextCursor == "" {
+			break
+		}
+		criteria.Cursor = nextCursor
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].record.Sequence < entries[j].record.Sequence
+	})
+
+	hashes := make(map[int64]string, len(entries))
+	for i, entry := range entries {
+		sequence := entry.record.Sequence
+
+		if expected, err := entry.record.hash(); err != nil || expected != entry.hash {
+			report.Issues = append(report.Issues, chainIssue("hash_mismatch", entry,
+				"record content does not match its hash"))
+		}
+
+		if i > 0 {
+			previous := entries[i-1]
+			switch {
+			case previous.record.Sequence == sequence:
+				report.Issues = append(report.Issues, chainIssue("duplicate", entry, "sequence appears more than once"))
+			case previous.record.Sequence+1 != sequence:
+				report.Issues = append(report.Issues, chainIssue("gap", entry, fmt.Sprintf(
+					"records %d to %d are missing", previous.record.Sequence+1, sequence-1)))
+			case entry.record.PrevHash != previous.hash:
+				report.Issues = append(report.Issues, chainIssue("link_mismatch", entry,
+					"previous hash does not match the previous record"))
+			}
+		}
+
+		hashes[sequence] = entry.hash
+	}
+
+	if len(entries) > 0 {
+		report.FirstSequence = entries[0].record.Sequence
+		report.LastSequence = entries[len(entries)-1].record.Sequence
+
+		var checkpoints []models.ActivityChainCheckpoint
+		err := db.Where("sequence BETWEEN ? AND ?", report.FirstSequence, report.LastSequence).
+			Order("sequence").
+			Find(&checkpoints).Error
+		if err != nil {
+			return models.ActivityChainReport{}, err
+		}
+
+		for _, checkpoint := range checkpoints {
+			issue := models.ActivityChainIssue{Sequence: checkpoint.Sequence}
+			hash, found := hashes[checkpoint.Sequence]
+			switch {
+			case !hmac.Equal(
+				[]byte(checkpoint.Signature),
+				[]byte(SignCheckpoint(signingKey, checkpoint.Sequence, checkpoint.Hash)),
+			):
+				issue.Type, issue.Detail = "invalid_signature", "checkpoint signature is invalid"
+			case !found:
+				issue.Type, issue.Detail = "missing", "signed record is missing"
+			case hash != checkpoint.Hash:
+				issue.Type, issue.Detail = "checkpoint_mismatch", "record hash does not match the signed checkpoint"
+			default:
+				continue
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+		report.Checkpoints = len(checkpoints)
+	}
+
+	report.Records = len(entries)
+	report.Valid = len(report.Issues) == 0
+	return report, nil
+}
+
+// parseChainEntry rebuilds the hashed content of a record returned by a backend.
+func parseChainEntry(log map[string]interface{}) (chainEntry, bool) {
+	sequenceValue, _ := log[chainSequenceField].(string)
+	sequence, err := strconv.ParseInt(sequenceValue, 10, 64)
+	if err != nil {
+		return chainEntry{}, false
+	}
+
+	prevHash, _ := log[chainPrevHashField].(string)
+	hash, _ := log[chainHashField].(string)
+
+	fields := map[string]string{}
+	for key, value := range log {
+		if key == "timestamp" || key == "message" || key == "object" {
+			continue
+		}
+		if str, isString := value.(string); isString {
+			fields[key] = str
+		}
+	}
+
+	return chainEntry{
+		record: chainRecord{
+			Sequence:  sequence,
+			PrevHash:  prevHash,
+			Timestamp: fmt.Sprint(log["timestamp"]),
+			Message:   fmt.Sprint(log["message"]),
+			Fields:    hashedFields(fields),
+			Object:    log["object"],
+		},
+		hash: hash,
+	}, true
+}
+
+func chainIssue(issueType string, entry chainEntry, detail string) models.ActivityChainIssue {
+	return models.ActivityChainIssue{
+		Type:      issueType,
+		Sequence:  entry.record.Sequence,
+		Timestamp: entry.record.Timestamp,
+		Detail:    detail,
+	}
+}
@@ -0,0 +1,278 @@
+package activity
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+
+	"api/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// backendStub records the activity it receives, and fails when an error is set.
+type backendStub struct {
+	sent   []models.Activity
+	err    error
+	onSend func()
+}
+
+func (b *backendStub) Send(activity models.Activity) error {
+	if b.onSend != nil {
+		b.onSend()
+	}
+	if b.err != nil {
+		return b.err
+	}
+	b.sent = append(b.sent, activity)
+	return nil
+}
+
+func (b *backendStub) Search(_ SearchCriteria) ([]map[string]interface{}, string, error) {
+	return nil, "", nil
+}
+
+var outboxColumns = []string{"id", "message", "timestamp", "fields", "object", "chain_sequence", "attempts"}
+
+func TestChainActivityIsVerifiable(t *testing.T) {
+	head := models.ActivityChainHead{ID: 1, Sequence: 41, Hash: "previous"}
+	activity := models.Activity{
+		Message: BucketCreated,
+		Object:  map[string]string{"name": "reports"},
+		Filter: models.LogFilter{
+			Fields: map[string]string{
+				"action":      "create",
+				"object_type": "bucket",
+				"bucket_id":   "8d5c4ec1-4a4c-4a63-8f8c-3a6f5b6f2b11",
+				"user_id":     "",
+			},
+			Timestamp: "1760000000123456789",
+		},
+	}
+
+	chained, hash, err := chainActivity(activity, head)
+	require.NoError(t, err)
+
+	assert.Equal(t, "42", chained.Filter.Fields[chainSequenceField])
+	assert.Equal(t, "previous", chained.Filter.Fields[chainPrevHashField])
+	assert.Equal(t, hash, chained.Filter.Fields[chainHashField])
+	assert.Equal(t, "1760000000123456000", chained.Filter.Timestamp)
+
+	// The backends return the stored fields, without the empty ones, and the object decoded from JSON
+	log := map[string]interface{}{
+		"timestamp": chained.Filter.Timestamp,
+		"message":   chained.Message,
+		"object":    map[string]interface{}{"name": "reports"},
+	}
+	for key, value := range chained.Filter.Fields {
+		if value != "" {
+			log[key] = value
+		}
+	}
+
+	entry, ok := parseChainEntry(log)
+	require.True(t, ok)
+	recomputed, err := entry.record.hash()
+	require.NoError(t, err)
+	assert.Equal(t, hash, recomputed)
+
+	log["action"] = "delete"
+	tampered, _ := parseChainEntry(log)
+	recomputed, err = tampered.record.hash()
+	require.NoError(t, err)
+	assert.NotEqual(t, hash, recomputed)
+}
+
+func TestChainedActivityLoggerDeliverCommitsBeforeDelivery(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+
+	fields, err := json.Marshal(map[string]string{"action": "create", "object_type": "bucket"})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "activity_outbox" WHERE failed_at IS NULL AND next_attempt_at <= $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`)).
+		WillReturnRows(sqlmock.NewRows(outboxColumns).
+			AddRow(7, BucketCreated, int64(1760000000000000000), fields, nil, nil, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "activity_chain_head" WHERE "activity_chain_head"."id" = $1 ORDER BY "activity_chain_head"."id" LIMIT $2 FOR UPDATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sequence", "hash"}).AddRow(1, 5, "previous"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_chain_head" SET "hash"=$1,"sequence"=$2 WHERE "id" = $3`)).
+		WithArgs(sqlmock.AnyArg(), 6, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_outbox" SET "timestamp"=$1,"fields"=$2,"chain_sequence"=$3,"next_attempt_at"=$4 WHERE "id" = $5`)).
+		WithArgs(int64(1760000000000000000), sqlmock.AnyArg(), 6, sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	backend := &backendStub{}
+	backend.onSend = func() {
+		// The batch is chained and leased in a transaction committed before the delivery
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "activity_outbox" WHERE "activity_outbox"."id" = $1`)).
+			WithArgs(7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	chain := NewChainedActivityLogger(gormDB, backend, 3)
+	delivered, err := chain.Deliver(10)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, delivered)
+	require.Len(t, backend.sent, 1)
+	assert.Equal(t, "6", backend.sent[0].Filter.Fields[chainSequenceField])
+	assert.Equal(t, "previous", backend.sent[0].Filter.Fields[chainPrevHashField])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChainedActivityLoggerDeadLettersAfterMaxAttempts(t *testing.T) {
+	testCases := []struct {
+		name         string
+		attempts     int
+		deadLettered bool
+	}{
+		{"Failure before the maximum attempts is retried", 0, false},
+		{"Failure at the maximum attempts is dead-lettered", 2, true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDB, mock := newMockDB(t)
+
+			fields, err := json.Marshal(map[string]string{"chain_sequence": "3"})
+			require.NoError(t, err)
+
+			// Records already chained are delivered as they are, without locking the chain head
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "activity_outbox"`)).
+				WillReturnRows(sqlmock.NewRows(outboxColumns).
+					AddRow(7, BucketCreated, int64(1760000000000000000), fields, nil, 3, tt.attempts).
+					AddRow(8, BucketCreated, int64(1760000000000000000), fields, nil, 4, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_outbox"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_outbox"`)).WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			mock.ExpectBegin()
+			if tt.deadLettered {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_outbox" SET "attempts"=$1,"failed_at"=$2,"last_error"=$3,"next_attempt_at"=$4 WHERE "id" = $5`)).
+					WithArgs(tt.attempts+1, sqlmock.AnyArg(), "backend down", sqlmock.AnyArg(), 7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			} else {
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_outbox" SET "attempts"=$1,"last_error"=$2,"next_attempt_at"=$3 WHERE "id" = $4`)).
+					WithArgs(tt.attempts+1, "backend down", sqlmock.AnyArg(), 7).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			mock.ExpectCommit()
+
+			// The rest of the batch is released for the next run
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_outbox" SET "next_attempt_at"=$1 WHERE id IN ($2)`)).
+				WithArgs(sqlmock.AnyArg(), 8).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+
+			chain := NewChainedActivityLogger(gormDB, &backendStub{err: errors.New("backend down")}, 3)
+			delivered, err := chain.Deliver(10)
+			require.NoError(t, err)
+
+			assert.Equal(t, 0, delivered)
+			assert.Equal(t, int64(1), chain.failed.Load())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestChainedActivityLoggerSendTx(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+	backend := &backendStub{}
+	chain := NewChainedActivityLogger(gormDB, backend, 3)
+
+	// The record is stored in the transaction of the change, without locking the chain head
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "activity_outbox"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := SendTx(chain, tx, models.Activity{Message: BucketDeleted}); err != nil {
+			return err
+		}
+		return errors.New("change failed")
+	})
+	require.EqualError(t, err, "change failed")
+
+	assert.Empty(t, backend.sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChainedActivityLoggerSendStoresBeforeDelivery(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "activity_chain_head"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "sequence", "hash"}).AddRow(1, 5, "previous"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_chain_head"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "activity_outbox"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+	// The failed delivery is left to the delivery job
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "activity_outbox" SET "attempts"=$1`)).
+		WithArgs(1, "backend down", sqlmock.AnyArg(), 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	chain := NewChainedActivityLogger(gormDB, &backendStub{err: errors.New("backend down")}, 3)
+	require.NoError(t, chain.Send(models.Activity{Message: FileDownloaded}))
+
+	assert.Equal(t, int64(1), chain.failed.Load())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChainedActivityLoggerSendSync(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{"Delivered activity advances the chain", nil},
+		{"Refused activity leaves the chain as it was", errors.New("backend down")},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			gormDB, mock := newMockDB(t)
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "activity_chain_head"`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "sequence", "hash"}).AddRow(1, 5, "previous"))
+			query := `UPDATE "activity_chain_head" SET "hash"=$1,"sequence"=$2 WHERE "id" = $3`
+			mock.ExpectExec(regexp.QuoteMeta(query)).
+				WithArgs(sqlmock.AnyArg(), 6, 1).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			if tt.err != nil {
+				mock.ExpectRollback()
+			} else {
+				mock.ExpectCommit()
+			}
+
+			backend := &backendStub{err: tt.err}
+			chain := NewChainedActivityLogger(gormDB, backend, 3)
+			err := chain.SendSync(models.Activity{Message: UserLoggedIn})
+
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+			} else {
+				require.NoError(t, err)
+				require.Len(t, backend.sent, 1)
+				assert.Equal(t, "6", backend.sent[0].Filter.Fields[chainSequenceField])
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -1,21 +1,43 @@
 package activity
 
 const (
-	BucketCreated       string = "BUCKET_CREATED"
-	BucketDeleted       string = "BUCKET_DELETED"
-	FileUploaded        string = "FILE_UPLOADED"
-	FileDownloaded      string = "FILE_DOWNLOADED"
-	FileUpdated         string = "FILE_UPDATED"
-	FileDeleted         string = "FILE_DELETED"
-	FileTrashed         string = "FILE_TRASHED"
-	FileRestored        string = "FILE_RESTORED"
-	FilePurged          string = "FILE_PURGED"
-	FolderCreated       string = "FOLDER_CREATED"
-	FolderUpdated       string = "FOLDER_UPDATED"
-	FolderTrashed       string = "FOLDER_TRASHED"
-	FolderRestored      string = "FOLDER_RESTORED"
-	FolderPurged        string = "FOLDER_PURGED"
-	BucketMemberCreated string = "BUCKET_MEMBER_CREATED"
-	BucketMemberUpdated string = "BUCKET_MEMBER_UPDATED"
-	BucketMemberDeleted string = "BUCKET_MEMBER_DELETED"
+	BucketCreated            string = "BUCKET_CREATED"
+	BucketDeleted            string = "BUCKET_DELETED"
+	BucketOwnerTransferred   string = "BUCKET_OWNER_TRANSFERRED"
+	BucketRetentionUpdated   string = "BUCKET_RETENTION_UPDATED"
+	BucketLockUpdated        string = "BUCKET_LOCK_UPDATED"
+	FileUploaded             string = "FILE_UPLOADED"
+	FileDownloaded           string = "FILE_DOWNLOADED"
+	FileUpdated              string = "FILE_UPDATED"
+	FileDeleted              string = "FILE_DELETED"
+	FileTrashed              string = "FILE_TRASHED"
+	FileRestored             string = "FILE_RESTORED"
+	FilePurged               string = "FILE_PURGED"
+	FileScanned              string = "FILE_SCANNED"
+	FileQuarantined          string = "FILE_QUARANTINED"
+	FileUploadFailed         string = "FILE_UPLOAD_FAILED"
+	FileLockUpdated          string = "FILE_LOCK_UPDATED"
+	StorageReconciled        string = "STORAGE_RECONCILED"
+	FolderCreated            string = "FOLDER_CREATED"
+	FolderUpdated            string = "FOLDER_UPDATED"
+	FolderTrashed            string = "FOLDER_TRASHED"
+	FolderRestored           string = "FOLDER_RESTORED"
+	FolderPurged             string = "FOLDER_PURGED"
+	FolderPermissionsUpdated string = "FOLDER_PERMISSIONS_UPDATED"
+	BucketMemberCreated      string = "BUCKET_MEMBER_CREATED"
+	BucketMemberUpdated      string = "BUCKET_MEMBER_UPDATED"
+	BucketMemberDeleted      string = "BUCKET_MEMBER_DELETED"
+	UserLoggedIn             string = "USER_LOGGED_IN"
+	UserLoginFailed          string = "USER_LOGIN_FAILED"
+	UserSignedUp             string = "USER_SIGNED_UP"
+	UserPasswordReset        string = "USER_PASSWORD_RESET"
+	InviteAccepted           string = "INVITE_ACCEPTED"
+	UserCreated              string = "USER_CREATED"
+	UserDeleted              string = "USER_DELETED"
+	UserRoleUpdated          string = "USER_ROLE_UPDATED"
+	UserDisabled             string = "USER_DISABLED"
+	UserEnabled              string = "USER_ENABLED"
+	WebhookCreated           string = "WEBHOOK_CREATED"
+	WebhookUpdated           string = "WEBHOOK_UPDATED"
+	WebhookDeleted           string = "WEBHOOK_DELETED"
 )
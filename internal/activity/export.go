@@ -0,0 +1,203 @@
+package activity
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/internal/configuration"
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	ExportFormatCSV    = "csv"
+	ExportFormatNDJSON = "ndjson"
+)
+
+// exportColumns are the columns of the CSV export, in order.
+var exportColumns = []string{
+	"timestamp",
+	"message",
+	"action",
+	"object_type",
+	"domain",
+	"user_id",
+	"user_email",
+	"user_name",
+	"bucket_id",
+	"bucket_name",
+	"file_id",
+	"file_name",
+	"folder_id",
+	"folder_name",
+	"target_user_id",
+	"target_user_email",
+	"bucket_member_email",
+}
+
+// ExportContentType returns the content type of an export format.
+func ExportContentType(format string) string {
+	if format == ExportFormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// ExportActivity writes all the activity matching the criteria, most recent first, in the given format.
+// The activity is fetched and enriched page by page, and each page is flushed to the client
+// before the next one is fetched, so that exports of any size use a bounded amount of memory.
+// Entries for which visible returns false are left out, a nil visible exports all of them.
+func ExportActivity(
+	db *gorm.DB,
+	logger IActivityLogger,
+	criteria SearchCriteria,
+	visible func(map[string]interface{}) bool,
+	format string,
+	w io.Writer,
+) error {
+	criteria.Limit = configuration.ActivityExportPageSize
+	criteria.Cursor = ""
+
+	var csvWriter *csv.Writer
+	encoder := json.NewEncoder(w)
+	if format == ExportFormatCSV {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(exportColumns); err != nil {
+			return err
+		}
+	}
+
+	cache := make(map[uuid.UUID]interface{})
+	for {
+		history, nextCursor, err := logger.Search(criteria)
+		if err != nil {
+			return err
+		}
+
+		for _, log := range history {
+			if visible != nil && !visible(log) {
+				continue
+			}
+			if csvWriter != nil {
+				ids := exportIDs(log)
+				err = csvWriter.Write(exportRow(ids, enrichLog(db, log, cache)))
+			} else {
+				err = encoder.Encode(enrichLog(db, log, cache))
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err = csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		if nextCursor == "" {
+			return nil
+		}
+		criteria.Cursor = nextCursor
+	}
+}
+
+// WriteEmptyExport writes an export without any activity, which is only the CSV header.
+func WriteEmptyExport(format string, w io.Writer) error {
+	if format != ExportFormatCSV {
+		return nil
+	}
+	csvWriter := csv.NewWriter(w)
+	if err := csvWriter.Write(exportColumns); err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// exportIDs keeps the identifiers of a log before the enrichment replaces them with the related objects.
+func exportIDs(log map[string]interface{}) map[string]string {
+	ids := make(map[string]string, len(ToEnrich))
+	for fieldName := range ToEnrich {
+		ids[fieldName] = stringField(log, fieldName)
+	}
+	return ids
+}
+
+// exportRow flattens an enriched log into the CSV columns.
+func exportRow(ids map[string]string, log map[string]interface{}) []string {
+	row := map[string]string{
+		"timestamp":           exportTimestamp(stringField(log, "timestamp")),
+		"message":             stringField(log, "message"),
+		"action":              stringField(log, "action"),
+		"object_type":         stringField(log, "object_type"),
+		"domain":              stringField(log, "domain"),
+		"user_id":             ids["user_id"],
+		"bucket_id":           ids["bucket_id"],
+		"file_id":             ids["file_id"],
+		"folder_id":           ids["folder_id"],
+		"target_user_id":      ids["target_user_id"],
+		"bucket_member_email": stringField(log, "bucket_member_email"),
+	}
+
+	if user, ok := log["user"].(*models.User); ok {
+		row["user_email"] = user.Email
+		row["user_name"] = strings.TrimSpace(fmt.Sprintf("%s %s", user.FirstName, user.LastName))
+	}
+	if bucket, ok := log["bucket"].(*models.Bucket); ok {
+		row["bucket_id"] = exportID(row["bucket_id"], bucket.ID)
+		row["bucket_name"] = bucket.Name
+	}
+	if file, ok := log["file"].(*models.File); ok {
+		row["file_id"] = exportID(row["file_id"], file.ID)
+		row["file_name"] = file.Name
+	}
+	if folder, ok := log["folder"].(*models.Folder); ok {
+		row["folder_id"] = exportID(row["folder_id"], folder.ID)
+		row["folder_name"] = folder.Name
+	}
+	if target, ok := log["target_user"].(*models.User); ok {
+		row["target_user_id"] = exportID(row["target_user_id"], target.ID)
+		row["target_user_email"] = target.Email
+	}
+
+	values := make([]string, 0, len(exportColumns))
+	for _, column := range exportColumns {
+		values = append(values, row[column])
+	}
+	return values
+}
+
+// exportID returns the stored identifier, or the identifier of the object when the field was not stored.
+func exportID(stored string, id uuid.UUID) string {
+	if stored != "" || id == uuid.Nil {
+		return stored
+	}
+	return id.String()
+}
+
+// exportTimestamp converts a nanosecond timestamp into an RFC 3339 date readable in spreadsheets.
+func exportTimestamp(value string) string {
+	nanoseconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return value
+	}
+	return time.Unix(0, nanoseconds).UTC().Format(time.RFC3339Nano)
+}
+
+func stringField(log map[string]interface{}, key string) string {
+	value, _ := log[key].(string)
+	return value
+}
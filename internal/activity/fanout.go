@@ -0,0 +1,30 @@
+package activity
+
+import "api/internal/models"
+
+// FanOutActivityLogger stores the activity with the primary logger and forwards a copy to the sinks.
+// The sinks only receive the activity once it is stored, and never affect the result of Send or Search.
+type FanOutActivityLogger struct {
+	Primary IActivityLogger
+	Sinks   []IActivitySink
+}
+
+// NewFanOutActivityLogger wraps an activity backend to forward its activity to the sinks.
+func NewFanOutActivityLogger(primary IActivityLogger, sinks ...IActivitySink) IActivityLogger {
+	return &FanOutActivityLogger{Primary: primary, Sinks: sinks}
+}
+
+func (s *FanOutActivityLogger) Send(activity models.Activity) error {
+	if err := s.Primary.Send(activity); err != nil {
+		return err
+	}
+
+	for _, sink := range s.Sinks {
+		sink.Forward(activity)
+	}
+	return nil
+}
+
+func (s *FanOutActivityLogger) Search(criteria SearchCriteria) ([]map[string]interface{}, string, error) {
+	return s.Primary.Search(criteria)
+}
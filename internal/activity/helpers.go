@@ -23,6 +23,8 @@ var ToEnrich = map[string]ToEnrichValue{
 	"bucket_id": {Name: "bucket", Object: models.Bucket{}},
 	"file_id":   {Name: "file", Object: models.File{}},
 	"folder_id": {Name: "folder", Object: models.Folder{}},
+	// The user targeted by an authentication or administration action, when different from the actor
+	"target_user_id": {Name: "target_user", Object: models.User{}},
 }
 
 // NewLogFilter creates a LogFilter object with the specified criteria and the current timestamp in nanoseconds.
@@ -33,6 +35,15 @@ func NewLogFilter(criteria map[string]string) models.LogFilter {
 	}
 }
 
+// SendTx records the activity in the transaction of the change it describes when the logger supports it,
+// and sends it right away otherwise.
+func SendTx(logger IActivityLogger, tx *gorm.DB, activity models.Activity) error {
+	if transactional, ok := logger.(ITransactionalActivityLogger); ok {
+		return transactional.SendTx(tx, activity)
+	}
+	return logger.Send(activity)
+}
+
 // enrichLogWithMetadata handles Tier 1 enrichment by extracting objects from log metadata.
 func enrichLogWithMetadata(log map[string]interface{}) map[string]interface{} {
 	newLog := log
@@ -61,6 +72,12 @@ func enrichLogWithMetadata(log map[string]interface{}) map[string]interface{} {
 					newLog["folder"] = &folder
 					delete(newLog, "folder_id")
 				}
+			case "user", "auth":
+				var user models.User
+				if json.Unmarshal(jsonBytes, &user) == nil {
+					newLog["target_user"] = &user
+					delete(newLog, "target_user_id")
+				}
 			}
 			delete(newLog, "object")
 		}
@@ -121,16 +138,21 @@ func EnrichActivity(db *gorm.DB, activity []map[string]interface{}) []map[string
 	cache := make(map[uuid.UUID]interface{})
 
 	for _, log := range activity {
-		enrichedLog := enrichLogWithMetadata(log)
-		enrichedLog = enrichLogWithDatabase(db, enrichedLog, cache)
-		enrichedActivity = append(enrichedActivity, enrichedLog)
+		enrichedActivity = append(enrichedActivity, enrichLog(db, log, cache))
 	}
 
 	return sortByTimestamp(enrichedActivity)
 }
 
+// enrichLog enriches a single log, sharing the cache of database lookups with the other logs.
+func enrichLog(db *gorm.DB, log map[string]interface{}, cache map[uuid.UUID]interface{}) map[string]interface{} {
+	enrichedLog := enrichLogWithMetadata(log)
+	return enrichLogWithDatabase(db, enrichedLog, cache)
+}
+
+// sortByTimestamp sorts the activity from the newest, keeping the order of the entries of the same timestamp.
 func sortByTimestamp(activity []map[string]interface{}) []map[string]interface{} {
-	sort.Slice(activity, func(i, j int) bool {
+	sort.SliceStable(activity, func(i, j int) bool {
 		ts1, ok1 := activity[i]["timestamp"].(string)
 		if !ok1 {
 			return false
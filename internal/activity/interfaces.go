@@ -1,9 +1,26 @@
 package activity
 
-import "api/internal/models"
+import (
+	"api/internal/models"
+
+	"gorm.io/gorm"
+)
 
 // IActivityLogger defines a common interface for all logs.
 type IActivityLogger interface {
-	Search(searchCriteria map[string][]string) ([]map[string]interface{}, error)
+	// Search returns a page of activity, most recent first, and the cursor of the next page if there is one.
+	Search(criteria SearchCriteria) ([]map[string]interface{}, string, error)
 	Send(message models.Activity) error
 }
+
+// ITransactionalActivityLogger is implemented by the loggers able to record the activity in the database
+// transaction of the change it describes, so that the activity is only recorded when the change is committed.
+type ITransactionalActivityLogger interface {
+	SendTx(tx *gorm.DB, message models.Activity) error
+}
+
+// IActivitySink receives a copy of the activity stored by the activity logger, such as a SIEM.
+type IActivitySink interface {
+	// Forward queues the activity for delivery without blocking the caller.
+	Forward(activity models.Activity)
+}
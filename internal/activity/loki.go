@@ -3,6 +3,7 @@ package activity
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -17,11 +18,25 @@ import (
 )
 
 var authorizedLabels = [2]string{"object_type", "action"}
-var authorizedObjects = [3]rbac.Resource{rbac.ResourceBucket, rbac.ResourceFile, rbac.ResourceFolder}
+var authorizedObjects = [5]rbac.Resource{
+	rbac.ResourceBucket,
+	rbac.ResourceFile,
+	rbac.ResourceFolder,
+	rbac.ResourceUser,
+	rbac.ResourceAuth,
+}
+
+// lokiInternalLabels are the labels added by the application or by Loki itself rather than filter fields.
+var lokiInternalLabels = [2]string{"service_name", "detected_level"}
 
 const (
 	lokiPushURI   = "/loki/api/v1/push"
 	lokiSearchURI = "/loki/api/v1/query_range"
+	// lokiMaxEntries is the default highest limit of a query accepted by Loki.
+	lokiMaxEntries = 5000
+	// lokiMaxCursorOffset bounds the entries of a timestamp skipped by a cursor, so that the query of a page
+	// of the largest size never exceeds the highest limit accepted by Loki.
+	lokiMaxCursorOffset = lokiMaxEntries - configuration.ActivityExportPageSize - 1
 )
 
 // LokiBody represents the main structure for sending logs to Loki, containing a list of log stream entries.
@@ -83,13 +98,123 @@ func (s *LokiClient) Send(activity models.Activity) error {
 	return nil
 }
 
-func (s *LokiClient) Search(searchCriteria map[string][]string) ([]map[string]interface{}, error) {
-	query := generateSearchQuery(searchCriteria)
+// lokiEntry is an entry of a search, with the timestamp and the key ordering the entries of the same nanosecond.
+type lokiEntry struct {
+	timestamp int64
+	key       string
+	fields    map[string]interface{}
+}
+
+// formatLokiCursor builds the cursor of the last entry of a page: its timestamp, and the number of entries
+// sharing that timestamp returned so far.
+func formatLokiCursor(timestamp int64, offset int) string {
+	return fmt.Sprintf("%d-%d", timestamp, offset)
+}
+
+// parseLokiCursor extracts the timestamp and the offset from a cursor built by formatLokiCursor.
+func parseLokiCursor(cursor string) (int64, int, error) {
+	rawTimestamp, rawOffset, found := strings.Cut(cursor, "-")
+	if !found {
+		return 0, 0, ErrInvalidCursor
+	}
+	timestamp, err := strconv.ParseInt(rawTimestamp, 10, 64)
+	if err != nil || timestamp <= 0 {
+		return 0, 0, ErrInvalidCursor
+	}
+	offset, err := strconv.Atoi(rawOffset)
+	if err != nil || offset <= 0 || offset > lokiMaxCursorOffset {
+		return 0, 0, ErrInvalidCursor
+	}
+	return timestamp, offset, nil
+}
+
+// Search queries Loki backward over the time range. The cursor holds the timestamp of the last entry
+// of the previous page, and the number of entries of that timestamp already returned. As several entries
+// may share a timestamp, the next query includes it and skips these entries, the entries of the same
+// timestamp being ordered by their stream and line.
+func (s *LokiClient) Search(criteria SearchCriteria) ([]map[string]interface{}, string, error) {
+	query := generateSearchQuery(criteria.Fields)
+
+	end := criteria.To.UnixNano()
+	var cursorTimestamp int64
+	var skip int
+	if criteria.Cursor != "" {
+		timestamp, offset, err := parseLokiCursor(criteria.Cursor)
+		if err != nil {
+			return []map[string]interface{}{}, "", err
+		}
+		// The end of the query is exclusive
+		if timestamp < end {
+			end = timestamp + 1
+			cursorTimestamp, skip = timestamp, offset
+		}
+	}
+
+	// One more entry than needed is requested to know if there is a next page
+	limit := criteria.Limit + 1 + skip
+	entries, err := s.query(query, criteria.From.UnixNano(), end, limit)
+	if err != nil {
+		return []map[string]interface{}{}, "", err
+	}
+	sortLokiEntries(entries)
+
+	// When the limit is reached, Loki may only return some of the entries of the oldest timestamp.
+	// They are all queried when the page reaches them, so that the entries of a timestamp are always ordered alike.
+	truncated := len(entries) >= limit
+	if truncated {
+		oldest := entries[len(entries)-1].timestamp
+		complete := 0
+		for complete < len(entries) && entries[complete].timestamp > oldest {
+			complete++
+		}
+
+		if oldest == cursorTimestamp || complete-skip < criteria.Limit {
+			group, groupErr := s.query(query, oldest, oldest+1, lokiMaxEntries)
+			if groupErr != nil {
+				return []map[string]interface{}{}, "", groupErr
+			}
+			entries = append(entries[:complete], group...)
+			sortLokiEntries(entries)
+		}
+	}
+
+	// The entries of the cursor timestamp returned by the previous pages come first
+	skipped := 0
+	for skipped < skip && skipped < len(entries) && entries[skipped].timestamp == cursorTimestamp {
+		skipped++
+	}
+	entries = entries[skipped:]
+
+	var nextCursor string
+	if len(entries) > criteria.Limit || (truncated && len(entries) == criteria.Limit) {
+		entries = entries[:criteria.Limit]
+
+		last := entries[len(entries)-1].timestamp
+		offset := 0
+		if last == cursorTimestamp {
+			offset = skipped
+		}
+		for _, entry := range entries {
+			if entry.timestamp == last {
+				offset++
+			}
+		}
+		nextCursor = formatLokiCursor(last, offset)
+	}
+
+	activity := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		activity = append(activity, entry.fields)
+	}
+	return activity, nextCursor, nil
+}
 
-	thirtyDaysAgo := time.Now().AddDate(0, 0, -30).Unix()
+// query returns the entries of the query between the start and the exclusive end, the newest first, up to the limit.
+func (s *LokiClient) query(query string, start int64, end int64, limit int) ([]lokiEntry, error) {
 	params := map[string]string{
-		"start":     strconv.FormatInt(thirtyDaysAgo, 10),
-		"limit":     "100",
+		"start":     strconv.FormatInt(start, 10),
+		"end":       strconv.FormatInt(end, 10),
+		"limit":     strconv.Itoa(limit),
 		"query":     query,
 		"direction": "backward",
 	}
@@ -100,7 +225,7 @@ func (s *LokiClient) Search(searchCriteria map[string][]string) ([]map[string]in
 		Get(s.searchURL)
 	if err != nil {
 		zap.L().Error("Failed to query Loki", zap.Any("error", err))
-		return []map[string]interface{}{}, err
+		return nil, err
 	}
 
 	if resp.StatusCode() != 200 {
@@ -110,7 +235,7 @@ func (s *LokiClient) Search(searchCriteria map[string][]string) ([]map[string]in
 			zap.String("response_body", string(resp.Body())),
 			zap.Error(err),
 		)
-		return []map[string]interface{}{}, fmt.Errorf(
+		return nil, fmt.Errorf(
 			"unexpected status code: %d",
 			resp.StatusCode(),
 		)
@@ -124,10 +249,10 @@ func (s *LokiClient) Search(searchCriteria map[string][]string) ([]map[string]in
 			zap.String("response_body", string(resp.Body())),
 			zap.Error(err),
 		)
-		return []map[string]interface{}{}, err
+		return nil, err
 	}
 
-	var activity []map[string]interface{}
+	var entries []lokiEntry
 	for _, result := range parsedResp.Data.Result {
 		for _, log := range result.Values {
 			entry := map[string]interface{}{
@@ -139,15 +264,22 @@ func (s *LokiClient) Search(searchCriteria map[string][]string) ([]map[string]in
 				"file_id":             result.Stream["file_id"],
 				"folder_id":           result.Stream["folder_id"],
 				"bucket_member_email": result.Stream["bucket_member_email"],
-				"timestamp":           log[0],
 			}
 
+			// Structured metadata is returned with the stream labels, it holds the other filter fields
+			for key, value := range result.Stream {
+				if _, exists := entry[key]; !exists && !isLokiInternalLabel(key) {
+					entry[key] = value
+				}
+			}
+			entry["timestamp"] = log[0]
+
 			var logLineData map[string]interface{}
 			if err = json.Unmarshal([]byte(log[1]), &logLineData); err != nil {
 				zap.L().Error("Failed to unmarshal log line data from Loki",
 					zap.Error(err),
 					zap.String("log_line_str", log[1]))
-				return activity, err
+				return nil, err
 			}
 
 			if message, ok := logLineData["message"]; ok {
@@ -157,11 +289,26 @@ func (s *LokiClient) Search(searchCriteria map[string][]string) ([]map[string]in
 				entry["object"] = object
 			}
 
-			activity = append(activity, entry)
+			timestamp, _ := strconv.ParseInt(log[0], 10, 64)
+			entries = append(entries, lokiEntry{
+				timestamp: timestamp,
+				key:       fmt.Sprint(result.Stream) + log[1],
+				fields:    entry,
+			})
 		}
 	}
 
-	return activity, nil
+	return entries, nil
+}
+
+// sortLokiEntries sorts the entries spread across streams from the newest, then by their stream and line.
+func sortLokiEntries(entries []lokiEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].timestamp != entries[j].timestamp {
+			return entries[i].timestamp > entries[j].timestamp
+		}
+		return entries[i].key < entries[j].key
+	})
 }
 
 // NewLokiClient initializes and returns a new LokiClient instance based on the provided log configuration.
@@ -214,6 +361,15 @@ func isAuthorized(label string) bool {
 	return false
 }
 
+func isLokiInternalLabel(label string) bool {
+	for _, internal := range lokiInternalLabels {
+		if label == internal {
+			return true
+		}
+	}
+	return false
+}
+
 // isAuthorizedObject checks if the given object type is part of the predefined authorizedObjects array and returns true if matched.
 func isAuthorizedObject(objectType string) bool {
 	for _, item := range authorizedObjects {
@@ -246,6 +402,15 @@ func generateSearchQuery(searchCriteria map[string][]string) string {
 	formattedLabels := generateORCriteria(labels)
 	formattedMetadata := generateORCriteria(metadata)
 
+	// A stream selector needs at least one matcher, the whole activity of the app is selected by default
+	if len(formattedLabels) == 0 {
+		formattedLabels = []string{fmt.Sprintf("service_name=\"%s\"", configuration.AppName)}
+	}
+
+	if len(formattedMetadata) == 0 {
+		return fmt.Sprintf("{%s}", strings.Join(formattedLabels, ", "))
+	}
+
 	return fmt.Sprintf(
 		"{%s} | %s",
 		strings.Join(formattedLabels, ", "),
@@ -0,0 +1,162 @@
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"api/internal/configuration"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type lokiLine struct {
+	action    string
+	timestamp int64
+	message   string
+}
+
+// newLokiServer serves the lines like Loki: backward from the exclusive end, up to the limit, grouped by stream.
+// The lines of the same timestamp are returned in the reverse order of the client, as Loki does not order them.
+func newLokiServer(t *testing.T, lines []lokiLine) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, err := strconv.ParseInt(r.URL.Query().Get("start"), 10, 64)
+		require.NoError(t, err)
+		end, err := strconv.ParseInt(r.URL.Query().Get("end"), 10, 64)
+		require.NoError(t, err)
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		require.NoError(t, err)
+
+		var selected []lokiLine
+		for _, line := range lines {
+			if line.timestamp >= start && line.timestamp < end {
+				selected = append(selected, line)
+			}
+		}
+		sort.Slice(selected, func(i, j int) bool {
+			if selected[i].timestamp != selected[j].timestamp {
+				return selected[i].timestamp > selected[j].timestamp
+			}
+			return selected[i].action > selected[j].action
+		})
+		if len(selected) > limit {
+			selected = selected[:limit]
+		}
+
+		var response LokiQueryResponse
+		streams := map[string]int{}
+		for _, line := range selected {
+			index, ok := streams[line.action]
+			if !ok {
+				index = len(response.Data.Result)
+				streams[line.action] = index
+				response.Data.Result = append(response.Data.Result, LokiResult{
+					Stream: map[string]string{"action": line.action},
+				})
+			}
+			logLine := fmt.Sprintf(`{"message":%q}`, line.message)
+			response.Data.Result[index].Values = append(response.Data.Result[index].Values,
+				[]string{strconv.FormatInt(line.timestamp, 10), logLine})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func TestLokiSearchPagesEntriesSharingATimestamp(t *testing.T) {
+	now := time.Now().UnixNano()
+	lines := []lokiLine{
+		{"create", now - 1, "newest"},
+		{"create", now - 2, "first of the same nanosecond"},
+		{"delete", now - 2, "second of the same nanosecond"},
+		{"restore", now - 2, "third of the same nanosecond"},
+		{"update", now - 2, "fourth of the same nanosecond"},
+		{"create", now - 3, "oldest"},
+	}
+	server := newLokiServer(t, lines)
+	defer server.Close()
+
+	client := &LokiClient{Client: resty.New(), searchURL: server.URL + lokiSearchURI}
+	criteria := SearchCriteria{
+		From:  time.Unix(0, now-time.Hour.Nanoseconds()),
+		To:    time.Unix(0, now),
+		Limit: 2,
+	}
+
+	var messages []string
+	for pages := 0; pages < len(lines); pages++ {
+		activity, cursor, err := client.Search(criteria)
+		require.NoError(t, err)
+		for _, entry := range activity {
+			messages = append(messages, entry["message"].(string))
+		}
+		if cursor == "" {
+			break
+		}
+		criteria.Cursor = cursor
+	}
+
+	assert.Equal(t, []string{
+		"newest",
+		"first of the same nanosecond",
+		"second of the same nanosecond",
+		"third of the same nanosecond",
+		"fourth of the same nanosecond",
+		"oldest",
+	}, messages)
+}
+
+func TestLokiSearchRejectsCursorsSkippingTooManyEntries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Loki must not be queried, got limit %s", r.URL.Query().Get("limit"))
+	}))
+	defer server.Close()
+
+	now := time.Now().UnixNano()
+	client := &LokiClient{Client: resty.New(), searchURL: server.URL + lokiSearchURI}
+	activity, cursor, err := client.Search(SearchCriteria{
+		From:   time.Unix(0, now-time.Hour.Nanoseconds()),
+		To:     time.Unix(0, now),
+		Limit:  configuration.ActivityExportPageSize,
+		Cursor: formatLokiCursor(now-1, 2000000000),
+	})
+
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	assert.Empty(t, activity)
+	assert.Empty(t, cursor)
+}
+
+func TestParseLokiCursor(t *testing.T) {
+	timestamp, offset, err := parseLokiCursor(formatLokiCursor(1700000000000000000, 3))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000000000000), timestamp)
+	assert.Equal(t, 3, offset)
+
+	timestamp, offset, err = parseLokiCursor(formatLokiCursor(1700000000000000000, lokiMaxCursorOffset))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1700000000000000000), timestamp)
+	assert.Equal(t, lokiMaxCursorOffset, offset)
+
+	invalidCursors := []string{
+		"1700000000000000000",
+		"abc-1",
+		"1700000000000000000-0",
+		"-1-1",
+		"1-x",
+		formatLokiCursor(1700000000000000000, lokiMaxCursorOffset+1),
+		"1700000000000000000-2000000000",
+		"1700000000000000000-9223372036854775807",
+	}
+	for _, cursor := range invalidCursors {
+		_, _, err = parseLokiCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
@@ -0,0 +1,235 @@
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"api/internal/helpers"
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// activityLogColumns maps the filter fields stored in dedicated columns of the activity_log table.
+// The boolean tells whether the column holds a UUID. Other fields are stored in the metadata column.
+var activityLogColumns = map[string]bool{
+	"action":              false,
+	"object_type":         false,
+	"domain":              false,
+	"bucket_member_email": false,
+	"user_id":             true,
+	"bucket_id":           true,
+	"file_id":             true,
+	"folder_id":           true,
+}
+
+// PostgresActivityLogger stores the activity in the append-only activity_log table of the application database.
+type PostgresActivityLogger struct {
+	DB *gorm.DB
+}
+
+// NewPostgresActivityLogger initializes an activity logger writing to the application database.
+func NewPostgresActivityLogger(db *gorm.DB) IActivityLogger {
+	return &PostgresActivityLogger{DB: db}
+}
+
+func (s *PostgresActivityLogger) Send(activity models.Activity) error {
+	entry, err := newActivityLog(activity)
+	if err != nil {
+		return err
+	}
+
+	if err = s.DB.Create(&entry).Error; err != nil {
+		zap.L().Error("Failed to insert activity", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Search returns the activity matching the criteria with keyset pagination on the timestamp and the ID.
+// Like the Loki query, each field must match one of its values and all the fields must match.
+func (s *PostgresActivityLogger) Search(criteria SearchCriteria) ([]map[string]interface{}, string, error) {
+	query := s.DB.Where("timestamp >= ? AND timestamp < ?", criteria.From.UTC(), criteria.To.UTC())
+
+	if criteria.Cursor != "" {
+		value, id, err := helpers.DecodeCursor(criteria.Cursor)
+		if err != nil {
+			return []map[string]interface{}{}, "", ErrInvalidCursor
+		}
+		timestamp, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return []map[string]interface{}{}, "", ErrInvalidCursor
+		}
+		query = query.Where("(timestamp, id) < (?, ?)", time.Unix(0, timestamp).UTC(), id)
+	}
+
+	keys := make([]string, 0, len(criteria.Fields))
+	for key := range criteria.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		values := criteria.Fields[key]
+
+		isUUID, isColumn := activityLogColumns[key]
+		switch {
+		case isColumn && isUUID:
+			ids := validUUIDs(values)
+			if len(ids) == 0 {
+				return []map[string]interface{}{}, "", nil
+			}
+			query = query.Where(fmt.Sprintf("%s IN ?", key), ids)
+		case isColumn:
+			query = query.Where(fmt.Sprintf("%s IN ?", key), values)
+		default:
+			query = query.Where("metadata ->> ? IN ?", key, values)
+		}
+	}
+
+	var logs []models.ActivityLog
+	if err := query.Order("timestamp DESC, id DESC").Limit(criteria.Limit + 1).Find(&logs).Error; err != nil {
+		zap.L().Error("Failed to query activity", zap.Error(err))
+		return []map[string]interface{}{}, "", err
+	}
+
+	var nextCursor string
+	if len(logs) > criteria.Limit {
+		logs = logs[:criteria.Limit]
+		last := logs[criteria.Limit-1]
+		nextCursor = helpers.EncodeCursor(strconv.FormatInt(last.Timestamp.UnixNano(), 10), last.ID)
+	}
+
+	activity := make([]map[string]interface{}, 0, len(logs))
+	for _, log := range logs {
+		activity = append(activity, toActivityEntry(log))
+	}
+	return activity, nextCursor, nil
+}
+
+// newActivityLog converts an activity into a row, with the same object filtering as the Loki body.
+func newActivityLog(activity models.Activity) (models.ActivityLog, error) {
+	timestamp, err := strconv.ParseInt(activity.Filter.Timestamp, 10, 64)
+	if err != nil {
+		timestamp = time.Now().UnixNano()
+	}
+
+	entry := models.ActivityLog{
+		Timestamp: time.Unix(0, timestamp).UTC(),
+		Message:   activity.Message,
+	}
+
+	metadata := map[string]string{}
+	for key, value := range activity.Filter.Fields {
+		if !setActivityLogColumn(&entry, key, value) {
+			metadata[key] = value
+		}
+	}
+
+	entry.Metadata, err = json.Marshal(metadata)
+	if err != nil {
+		return models.ActivityLog{}, err
+	}
+
+	if isAuthorizedObject(activity.Filter.Fields["object_type"]) && activity.Object != nil {
+		entry.Object, err = json.Marshal(activity.Object)
+		if err != nil {
+			zap.L().Error("Failed to marshal activity object", zap.Error(err))
+			return models.ActivityLog{}, err
+		}
+	}
+
+	return entry, nil
+}
+
+// setActivityLogColumn stores a field in its dedicated column, and reports whether the field has one.
+// Values that are not valid UUIDs are kept in the metadata instead of being lost.
+func setActivityLogColumn(entry *models.ActivityLog, key string, value string) bool {
+	switch key {
+	case "action":
+		entry.Action = value
+	case "object_type":
+		entry.ObjectType = value
+	case "domain":
+		entry.Domain = value
+	case "bucket_member_email":
+		entry.BucketMemberEmail = value
+	case "user_id", "bucket_id", "file_id", "folder_id":
+		if value == "" {
+			return true
+		}
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return false
+		}
+		switch key {
+		case "user_id":
+			entry.UserID = &id
+		case "bucket_id":
+			entry.BucketID = &id
+		case "file_id":
+			entry.FileID = &id
+		default:
+			entry.FolderID = &id
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// toActivityEntry converts a row into the same entry format as the Loki search results.
+func toActivityEntry(log models.ActivityLog) map[string]interface{} {
+	entry := map[string]interface{}{
+		"domain":              log.Domain,
+		"user_id":             uuidString(log.UserID),
+		"action":              log.Action,
+		"object_type":         log.ObjectType,
+		"bucket_id":           uuidString(log.BucketID),
+		"file_id":             uuidString(log.FileID),
+		"folder_id":           uuidString(log.FolderID),
+		"bucket_member_email": log.BucketMemberEmail,
+		"timestamp":           strconv.FormatInt(log.Timestamp.UnixNano(), 10),
+		"message":             log.Message,
+	}
+
+	var metadata map[string]string
+	if err := json.Unmarshal(log.Metadata, &metadata); err == nil {
+		for key, value := range metadata {
+			if existing, exists := entry[key]; !exists || existing == "" {
+				entry[key] = value
+			}
+		}
+	}
+
+	if len(log.Object) > 0 {
+		var object map[string]interface{}
+		if err := json.Unmarshal(log.Object, &object); err == nil && object != nil {
+			entry["object"] = object
+		}
+	}
+
+	return entry
+}
+
+func uuidString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
+
+func validUUIDs(values []string) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, value := range values {
+		if id, err := uuid.Parse(value); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,227 @@
+package activity
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"api/internal/helpers"
+	"api/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+func TestNewActivityLog(t *testing.T) {
+	userID, bucketID := uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name             string
+		activity         models.Activity
+		expectedMetadata map[string]string
+		expectedObject   bool
+	}{
+		{
+			name: "Known fields stored in their columns",
+			activity: models.Activity{
+				Message: FileUploaded,
+				Object:  map[string]string{"name": "invoice.pdf"},
+				Filter: models.LogFilter{
+					Fields: map[string]string{
+						"action":      "create",
+						"object_type": "file",
+						"user_id":     userID.String(),
+						"bucket_id":   bucketID.String(),
+						"file_id":     "",
+						"reason":      "scan",
+					},
+					Timestamp: "1700000000123456789",
+				},
+			},
+			expectedMetadata: map[string]string{"reason": "scan"},
+			expectedObject:   true,
+		},
+		{
+			name: "Invalid UUID kept in the metadata",
+			activity: models.Activity{
+				Message: "USER_LOGIN_FAILED",
+				Filter: models.LogFilter{
+					Fields:    map[string]string{"object_type": "auth", "user_id": "unknown"},
+					Timestamp: "1700000000123456789",
+				},
+			},
+			expectedMetadata: map[string]string{"user_id": "unknown"},
+		},
+		{
+			name: "Object of an unauthorized type left out",
+			activity: models.Activity{
+				Message: "WEBHOOK_CREATED",
+				Object:  map[string]string{"secret": "s3cr3t"},
+				Filter: models.LogFilter{
+					Fields:    map[string]string{"object_type": "webhook"},
+					Timestamp: "1700000000123456789",
+				},
+			},
+			expectedMetadata: map[string]string{},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, err := newActivityLog(tt.activity)
+			require.NoError(t, err)
+
+			assert.Equal(t, time.Unix(0, 1700000000123456789).UTC(), entry.Timestamp)
+			assert.Equal(t, tt.activity.Message, entry.Message)
+			assert.Equal(t, tt.activity.Filter.Fields["action"], entry.Action)
+			assert.Equal(t, tt.activity.Filter.Fields["object_type"], entry.ObjectType)
+
+			var metadata map[string]string
+			require.NoError(t, json.Unmarshal(entry.Metadata, &metadata))
+			assert.Equal(t, tt.expectedMetadata, metadata)
+			assert.Equal(t, tt.expectedObject, len(entry.Object) > 0)
+		})
+	}
+}
+
+func TestActivityLogEntryMatchesLokiEntry(t *testing.T) {
+	userID := uuid.New()
+	activity := models.Activity{
+		Message: FileUploaded,
+		Object:  map[string]string{"name": "invoice.pdf"},
+		Filter: models.LogFilter{
+			Fields: map[string]string{
+				"action":      "create",
+				"object_type": "file",
+				"user_id":     userID.String(),
+				"reason":      "scan",
+			},
+			Timestamp: "1700000000123456789",
+		},
+	}
+
+	log, err := newActivityLog(activity)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{
+		"domain":              "",
+		"user_id":             userID.String(),
+		"action":              "create",
+		"object_type":         "file",
+		"bucket_id":           "",
+		"file_id":             "",
+		"folder_id":           "",
+		"bucket_member_email": "",
+		"reason":              "scan",
+		"timestamp":           "1700000000123456789",
+		"message":             FileUploaded,
+		"object":              map[string]interface{}{"name": "invoice.pdf"},
+	}, toActivityEntry(log))
+}
+
+func TestPostgresActivityLoggerSearch(t *testing.T) {
+	from := time.Unix(1700000000, 0)
+	to := from.Add(time.Hour)
+	bucketID := uuid.New()
+	columns := []string{"id", "timestamp", "message", "action", "metadata"}
+
+	t.Run("Fields filter their column or the metadata", func(t *testing.T) {
+		db, dbMock := newMockDB(t)
+		first, second := uuid.New(), uuid.New()
+
+		dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "activity_log" WHERE (timestamp >= $1 AND timestamp < $2) `+
+			`AND action IN ($3,$4) AND bucket_id IN ($5) AND metadata ->> $6 IN ($7) `+
+			`ORDER BY timestamp DESC, id DESC LIMIT $8`)).
+			WithArgs(from.UTC(), to.UTC(), "create", "delete", bucketID, "reason", "scan", 2).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(first, to.Add(-time.Minute), FileUploaded, "create", []byte(`{}`)).
+				AddRow(second, to.Add(-2*time.Minute), FileUploaded, "create", []byte(`{}`)))
+
+		logger := PostgresActivityLogger{DB: db}
+		activity, cursor, err := logger.Search(SearchCriteria{
+			Fields: map[string][]string{
+				"action":    {"create", "delete"},
+				"bucket_id": {bucketID.String(), "not-a-uuid"},
+				"reason":    {"scan"},
+			},
+			From:  from,
+			To:    to,
+			Limit: 1,
+		})
+		require.NoError(t, err)
+		require.Len(t, activity, 1)
+
+		// The cursor points after the last entry of the page
+		value, id, err := helpers.DecodeCursor(cursor)
+		require.NoError(t, err)
+		assert.Equal(t, strconv.FormatInt(to.Add(-time.Minute).UnixNano(), 10), value)
+		assert.Equal(t, first, id)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("Cursor continues after the last entry", func(t *testing.T) {
+		db, dbMock := newMockDB(t)
+		lastID := uuid.New()
+		lastTimestamp := to.Add(-time.Minute)
+
+		dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "activity_log" WHERE (timestamp >= $1 AND timestamp < $2) `+
+			`AND (timestamp, id) < ($3, $4) ORDER BY timestamp DESC, id DESC LIMIT $5`)).
+			WithArgs(from.UTC(), to.UTC(), lastTimestamp.UTC(), lastID, 11).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		logger := PostgresActivityLogger{DB: db}
+		activity, cursor, err := logger.Search(SearchCriteria{
+			From:   from,
+			To:     to,
+			Limit:  10,
+			Cursor: helpers.EncodeCursor(strconv.FormatInt(lastTimestamp.UnixNano(), 10), lastID),
+		})
+		require.NoError(t, err)
+		assert.Empty(t, activity)
+		assert.Empty(t, cursor)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("Invalid cursor", func(t *testing.T) {
+		db, dbMock := newMockDB(t)
+
+		logger := PostgresActivityLogger{DB: db}
+		_, _, err := logger.Search(SearchCriteria{From: from, To: to, Limit: 10, Cursor: "1700000000"})
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+
+	t.Run("No valid UUID matches nothing", func(t *testing.T) {
+		db, dbMock := newMockDB(t)
+
+		logger := PostgresActivityLogger{DB: db}
+		activity, cursor, err := logger.Search(SearchCriteria{
+			Fields: map[string][]string{"bucket_id": {"not-a-uuid"}},
+			From:   from,
+			To:     to,
+			Limit:  10,
+		})
+		require.NoError(t, err)
+		assert.Empty(t, activity)
+		assert.Empty(t, cursor)
+		assert.NoError(t, dbMock.ExpectationsWereMet())
+	})
+}
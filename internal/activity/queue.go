@@ -0,0 +1,148 @@
+package activity
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"api/internal/configuration"
+	"api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// QueuedActivityLogger stores the activity in the activity_outbox table of the application database,
+// where the delivery job of the hash chain picks it up, so that requests do not depend on the backend being up.
+// Delivery is at least once: an activity is only removed from the queue once the backend has accepted it.
+// Synchronous messages sent outside of a transaction bypass the queue, and fail the request when the backend is down.
+type QueuedActivityLogger struct {
+	DB          *gorm.DB
+	Chain       *ChainedActivityLogger
+	Synchronous map[string]bool
+}
+
+// NewQueuedActivityLogger wraps the hash chain with a delivery queue.
+func NewQueuedActivityLogger(
+	db *gorm.DB,
+	chain *ChainedActivityLogger,
+	synchronousMessages []string,
+) *QueuedActivityLogger {
+	synchronous := make(map[string]bool, len(synchronousMessages))
+	for _, message := range synchronousMessages {
+		synchronous[message] = true
+	}
+	return &QueuedActivityLogger{DB: db, Chain: chain, Synchronous: synchronous}
+}
+
+// Send queues the activity, or delivers a synchronous message right away as the caller waits for the backend.
+func (s *QueuedActivityLogger) Send(activity models.Activity) error {
+	if s.Synchronous[activity.Message] {
+		return s.Chain.SendSync(activity)
+	}
+	return s.Chain.SendTx(s.DB, activity)
+}
+
+// SendTx queues the activity in the transaction of the change it describes, so that it is delivered
+// if and only if the change is committed. Synchronous messages are queued as well,
+// as the backend cannot wait for the commit of the change.
+func (s *QueuedActivityLogger) SendTx(tx *gorm.DB, activity models.Activity) error {
+	return s.Chain.SendTx(tx, activity)
+}
+
+func (s *QueuedActivityLogger) Search(criteria SearchCriteria) ([]map[string]interface{}, string, error) {
+	return s.Chain.Search(criteria)
+}
+
+// Stats describes the queue backlog and the deliveries of this instance since it started.
+func (s *QueuedActivityLogger) Stats() (models.ActivityQueueStats, error) {
+	stats := models.ActivityQueueStats{
+		Delivered:   s.Chain.delivered.Load(),
+		Failed:      s.Chain.failed.Load(),
+		Synchronous: make([]string, 0, len(s.Synchronous)),
+	}
+	for message := range s.Synchronous {
+		stats.Synchronous = append(stats.Synchronous, message)
+	}
+
+	var backlog struct {
+		Pending      int64
+		Retrying     int64
+		DeadLettered int64
+		MaxAttempts  int
+		OldestAt     *time.Time
+	}
+	err := s.DB.Model(&models.ActivityOutbox{}).
+		Select("COUNT(*) FILTER (WHERE failed_at IS NULL) AS pending, " +
+			"COUNT(*) FILTER (WHERE failed_at IS NULL AND attempts > 0) AS retrying, " +
+			"COUNT(*) FILTER (WHERE failed_at IS NOT NULL) AS dead_lettered, " +
+			"COALESCE(MAX(attempts), 0) AS max_attempts, MIN(created_at) AS oldest_at").
+		Scan(&backlog).Error
+	if err != nil {
+		return models.ActivityQueueStats{}, err
+	}
+	stats.Pending = backlog.Pending
+	stats.Retrying = backlog.Retrying
+	stats.DeadLettered = backlog.DeadLettered
+	stats.MaxAttempts = backlog.MaxAttempts
+	stats.OldestPendingAt = backlog.OldestAt
+
+	if stats.Retrying > 0 || stats.DeadLettered > 0 {
+		var lastFailure models.ActivityOutbox
+		if err = s.DB.Where("attempts > 0").Order("next_attempt_at DESC").First(&lastFailure).Error; err == nil {
+			stats.LastError = lastFailure.LastError
+		}
+	}
+
+	return stats, nil
+}
+
+// newActivityOutbox converts an activity into a queue record, keeping its original timestamp.
+func newActivityOutbox(activity models.Activity) (models.ActivityOutbox, error) {
+	timestamp, err := strconv.ParseInt(activity.Filter.Timestamp, 10, 64)
+	if err != nil {
+		timestamp = time.Now().UnixNano()
+	}
+
+	entry := models.ActivityOutbox{Message: activity.Message, Timestamp: timestamp}
+
+	entry.Fields, err = json.Marshal(activity.Filter.Fields)
+	if err != nil {
+		return models.ActivityOutbox{}, err
+	}
+
+	if activity.Object != nil {
+		entry.Object, err = json.Marshal(activity.Object)
+		if err != nil {
+			return models.ActivityOutbox{}, err
+		}
+	}
+
+	return entry, nil
+}
+
+// outboxActivity rebuilds a queued activity. The object is kept as JSON, which the backends marshal as is.
+func outboxActivity(entry models.ActivityOutbox) (models.Activity, error) {
+	var fields map[string]string
+	if err := json.Unmarshal(entry.Fields, &fields); err != nil {
+		return models.Activity{}, err
+	}
+
+	activity := models.Activity{
+		Message: entry.Message,
+		Filter: models.LogFilter{
+			Fields:    fields,
+			Timestamp: strconv.FormatInt(entry.Timestamp, 10),
+		},
+	}
+	if len(entry.Object) > 0 && string(entry.Object) != "null" {
+		activity.Object = entry.Object
+	}
+	return activity, nil
+}
+
+// retryDelay doubles the delay after each failed attempt, up to the configured maximum.
+func retryDelay(attempts int) time.Duration {
+	maxDelay := float64(configuration.ActivityQueueRetryMaxDelaySeconds)
+	return time.Duration(math.Min(math.Pow(2, float64(attempts)), maxDelay)) * time.Second
+}
@@ -0,0 +1,67 @@
+package activity
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"api/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestQueuedActivityLoggerSendTx(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+
+	backend := &backendStub{}
+	queue := NewQueuedActivityLogger(gormDB, NewChainedActivityLogger(gormDB, backend, 3), nil)
+
+	activity := models.Activity{
+		Message: BucketCreated,
+		Filter: models.LogFilter{
+			Fields:    map[string]string{"action": "create", "object_type": "bucket"},
+			Timestamp: "1760000000000000000",
+		},
+	}
+
+	// The activity is queued in the transaction of the change, and discarded with it
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "activity_outbox"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectRollback()
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		if err := SendTx(queue, tx, activity); err != nil {
+			return err
+		}
+		return errors.New("change failed")
+	})
+	require.EqualError(t, err, "change failed")
+
+	assert.Empty(t, backend.sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestQueuedActivityLoggerQueuesSynchronousMessagesOfTransactions(t *testing.T) {
+	gormDB, mock := newMockDB(t)
+
+	backend := &backendStub{}
+	queue := NewQueuedActivityLogger(gormDB, NewChainedActivityLogger(gormDB, backend, 3), []string{BucketDeleted})
+
+	// The backend cannot wait for the commit of the change
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "activity_outbox"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := gormDB.Transaction(func(tx *gorm.DB) error {
+		return SendTx(queue, tx, models.Activity{Message: BucketDeleted})
+	})
+	require.NoError(t, err)
+
+	assert.Empty(t, backend.sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,97 @@
+package activity
+
+import (
+	"errors"
+	"time"
+
+	"api/internal/configuration"
+	"api/internal/models"
+)
+
+var (
+	ErrInvalidCursor    = errors.New("INVALID_CURSOR")
+	ErrInvalidTimeRange = errors.New("INVALID_TIME_RANGE")
+)
+
+// SearchCriteria defines the activity to search for, from the most recent to the oldest.
+// Each field of Fields matches any of its values, and all the fields must match.
+// The cursor is an opaque value built by the backend that returned the previous page.
+type SearchCriteria struct {
+	Fields map[string][]string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Cursor string
+}
+
+// NewSearchCriteria restricts the base fields with the query filters and applies the default time range and limit.
+// It returns false when a filter excludes every value allowed by the base fields, so that no search is needed.
+func NewSearchCriteria(fields map[string][]string, query models.ActivityQueryParams) (SearchCriteria, bool, error) {
+	criteria := SearchCriteria{
+		Fields: make(map[string][]string, len(fields)),
+		To:     time.Now(),
+		Limit:  query.Limit,
+		Cursor: query.Cursor,
+	}
+	for key, values := range fields {
+		criteria.Fields[key] = values
+	}
+
+	if query.To != "" {
+		to, err := time.Parse(time.RFC3339, query.To)
+		if err != nil {
+			return SearchCriteria{}, false, ErrInvalidTimeRange
+		}
+		criteria.To = to
+	}
+
+	criteria.From = criteria.To.AddDate(0, 0, -configuration.ActivitySearchDefaultDays)
+	if query.From != "" {
+		from, err := time.Parse(time.RFC3339, query.From)
+		if err != nil {
+			return SearchCriteria{}, false, ErrInvalidTimeRange
+		}
+		criteria.From = from
+	}
+
+	if !criteria.From.Before(criteria.To) {
+		return SearchCriteria{}, false, ErrInvalidTimeRange
+	}
+
+	if criteria.Limit == 0 {
+		criteria.Limit = configuration.ActivitySearchDefaultLimit
+	}
+
+	filters := map[string]string{
+		"action":      query.Action,
+		"object_type": query.ObjectType,
+		"user_id":     query.UserID,
+		"bucket_id":   query.BucketID,
+		"file_id":     query.FileID,
+		"folder_id":   query.FolderID,
+	}
+	for key, value := range filters {
+		if value != "" && !criteria.restrict(key, value) {
+			return criteria, false, nil
+		}
+	}
+
+	return criteria, true, nil
+}
+
+// restrict narrows a field down to a single value, and reports whether the value is allowed by the field.
+func (c *SearchCriteria) restrict(key string, value string) bool {
+	allowed, exists := c.Fields[key]
+	if !exists {
+		c.Fields[key] = []string{value}
+		return true
+	}
+
+	for _, allowedValue := range allowed {
+		if allowedValue == value {
+			c.Fields[key] = []string{value}
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package activity
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/internal/configuration"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	SyslogFormatRFC5424 = "rfc5424"
+	SyslogFormatCEF     = "cef"
+	SyslogFormatLEEF    = "leef"
+)
+
+const (
+	// syslogFacility is the "log audit" facility of RFC 5424
+	syslogFacility      = 13
+	syslogSeverityInfo  = 6
+	syslogSeverityWarn  = 4
+	syslogStructuredID  = "safebucket@32473"
+	siemVendor          = "Safebucket"
+	siemProduct         = "Safebucket"
+	siemProductVersion  = "1.0"
+	syslogTimestampForm = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+// cefExtensionKeys maps the activity fields to the standard CEF extension keys.
+var cefExtensionKeys = map[string]string{
+	"action":              "act",
+	"object_type":         "cat",
+	"user_id":             "suid",
+	"domain":              "dvchost",
+	"bucket_member_email": "duser",
+}
+
+// cefCustomStrings maps the activity fields to the labelled CEF custom strings.
+var cefCustomStrings = [4]string{"bucket_id", "file_id", "folder_id", "target_user_id"}
+
+// SyslogForwarder forwards the activity to a syslog server, such as a SIEM, as RFC 5424 messages.
+// The activity is queued in a buffer drained by a background goroutine, which retries failed deliveries
+// with an exponential backoff. When the buffer is full, new activity is dropped rather than blocking requests.
+type SyslogForwarder struct {
+	network   string
+	address   string
+	format    string
+	appName   string
+	hostname  string
+	tlsConfig *tls.Config
+	queue     chan models.Activity
+	conn      net.Conn
+}
+
+// NewSyslogForwarder initializes a syslog forwarder and starts its delivery goroutine.
+func NewSyslogForwarder(config models.SyslogConfiguration) *SyslogForwarder {
+	forwarder := &SyslogForwarder{
+		network: config.Network,
+		address: config.Address,
+		format:  config.Format,
+		appName: config.AppName,
+	}
+
+	if forwarder.format == "" {
+		forwarder.format = SyslogFormatRFC5424
+	}
+	if forwarder.appName == "" {
+		forwarder.appName = configuration.SyslogDefaultAppName
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	forwarder.hostname = hostname
+
+	if config.Network == "tls" {
+		forwarder.tlsConfig = &tls.Config{
+			ServerName:         config.TLSServerName,
+			InsecureSkipVerify: config.SkipVerifyTLS, //nolint:gosec // opt-in for self-signed SIEM certificates
+			MinVersion:         tls.VersionTLS12,
+		}
+	}
+
+	bufferSize := config.BufferSize
+	if bufferSize == 0 {
+		bufferSize = configuration.SyslogDefaultBufferSize
+	}
+	forwarder.queue = make(chan models.Activity, bufferSize)
+
+	go forwarder.run()
+	return forwarder
+}
+
+func (f *SyslogForwarder) Forward(activity models.Activity) {
+	select {
+	case f.queue <- activity:
+	default:
+		zap.L().Warn("Syslog buffer is full, dropping activity", zap.String("message", activity.Message))
+	}
+}
+
+// run delivers the queued activity in order, retrying each message until it is delivered.
+func (f *SyslogForwarder) run() {
+	for activity := range f.queue {
+		message := formatSyslogMessage(f.format, f.hostname, f.appName, activity)
+
+		delay := time.Second
+		for {
+			err := f.write(message)
+			if err == nil {
+				break
+			}
+
+			zap.L().Warn("Failed to forward activity to syslog, retrying",
+				zap.String("address", f.address),
+				zap.Duration("delay", delay),
+				zap.Error(err))
+			f.close()
+
+			time.Sleep(delay)
+			delay = min(delay*2, configuration.SyslogRetryMaxDelaySeconds*time.Second)
+		}
+	}
+}
+
+// write sends a message, connecting first if needed. Stream transports use the octet counting
+// framing of RFC 6587, while each UDP datagram holds a single message.
+func (f *SyslogForwarder) write(message string) error {
+	if f.conn == nil {
+		conn, err := f.dial()
+		if err != nil {
+			return err
+		}
+		f.conn = conn
+	}
+
+	if f.network != "udp" {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+
+	deadline := time.Now().Add(configuration.SyslogWriteTimeoutSeconds * time.Second)
+	if err := f.conn.SetWriteDeadline(deadline); err != nil {
+		return err
+	}
+	_, err := f.conn.Write([]byte(message))
+	return err
+}
+
+func (f *SyslogForwarder) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: configuration.SyslogDialTimeoutSeconds * time.Second}
+	if f.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, "tcp", f.address, f.tlsConfig)
+	}
+	return dialer.Dial(f.network, f.address)
+}
+
+func (f *SyslogForwarder) close() {
+	if f.conn != nil {
+		_ = f.conn.Close()
+		f.conn = nil
+	}
+}
+
+// formatSyslogMessage builds the RFC 5424 message of an activity. In the CEF and LEEF formats,
+// the activity is carried in the message body and the structured data is left empty.
+func formatSyslogMessage(format string, hostname string, appName string, activity models.Activity) string {
+	timestamp := activityTime(activity)
+	severity := syslogSeverityInfo
+	if activity.Message == UserLoginFailed {
+		severity = syslogSeverityWarn
+	}
+
+	structuredData := "-"
+	var body string
+	switch format {
+	case SyslogFormatCEF:
+		body = formatCEF(activity, timestamp, severity)
+	case SyslogFormatLEEF:
+		body = formatLEEF(activity, timestamp, severity)
+	default:
+		structuredData = formatStructuredData(activity.Filter.Fields)
+		body = activity.Message
+	}
+
+	msgID := activity.Message
+	if msgID == "" || len(msgID) > 32 {
+		msgID = "-"
+	}
+
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s %s",
+		syslogFacility*8+severity,
+		timestamp.UTC().Format(syslogTimestampForm),
+		hostname,
+		appName,
+		os.Getpid(),
+		msgID,
+		structuredData,
+		body,
+	)
+}
+
+// formatStructuredData writes the activity fields as an RFC 5424 structured data element.
+func formatStructuredData(fields map[string]string) string {
+	var builder strings.Builder
+	builder.WriteString("[" + syslogStructuredID)
+	for _, key := range sortedFieldKeys(fields) {
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(fields[key])
+		builder.WriteString(fmt.Sprintf(` %s="%s"`, key, value))
+	}
+	builder.WriteString("]")
+	return builder.String()
+}
+
+// formatCEF writes the activity as an ArcSight Common Event Format event.
+// Fields without a standard key are listed in the msg extension.
+func formatCEF(activity models.Activity, timestamp time.Time, severity int) string {
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	value := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+	extensions := []string{"rt=" + strconv.FormatInt(timestamp.UnixMilli(), 10)}
+	custom := map[string]bool{}
+	for i, key := range cefCustomStrings {
+		custom[key] = true
+		if fieldValue := activity.Filter.Fields[key]; fieldValue != "" {
+			extensions = append(extensions,
+				fmt.Sprintf("cs%dLabel=%s", i+1, key),
+				fmt.Sprintf("cs%d=%s", i+1, value.Replace(fieldValue)))
+		}
+	}
+
+	var details []string
+	for _, key := range sortedFieldKeys(activity.Filter.Fields) {
+		fieldValue := activity.Filter.Fields[key]
+		if cefKey, ok := cefExtensionKeys[key]; ok {
+			extensions = append(extensions, fmt.Sprintf("%s=%s", cefKey, value.Replace(fieldValue)))
+		} else if !custom[key] {
+			details = append(details, fmt.Sprintf("%s=%s", key, fieldValue))
+		}
+	}
+	if len(details) > 0 {
+		extensions = append(extensions, "msg="+value.Replace(strings.Join(details, " ")))
+	}
+
+	// CEF severities range from 0 to 10, where 3 is low and 6 is medium
+	cefSeverity := 3
+	if severity == syslogSeverityWarn {
+		cefSeverity = 6
+	}
+
+	return fmt.Sprintf("CEF:0|%s|%s|%s|%s|%s|%d|%s",
+		header.Replace(siemVendor),
+		header.Replace(siemProduct),
+		header.Replace(siemProductVersion),
+		header.Replace(activity.Message),
+		header.Replace(strings.ReplaceAll(strings.ToLower(activity.Message), "_", " ")),
+		cefSeverity,
+		strings.Join(extensions, " "),
+	)
+}
+
+// formatLEEF writes the activity as an IBM QRadar Log Event Extended Format 2.0 event.
+// The attributes are separated by tabs, as declared in the header, and the time is in epoch milliseconds.
+func formatLEEF(activity models.Activity, timestamp time.Time, severity int) string {
+	header := strings.NewReplacer(`|`, ` `)
+	value := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+	leefSeverity := 3
+	if severity == syslogSeverityWarn {
+		leefSeverity = 6
+	}
+
+	attributes := []string{
+		"devTime=" + strconv.FormatInt(timestamp.UnixMilli(), 10),
+		"sev=" + strconv.Itoa(leefSeverity),
+	}
+	for _, key := range sortedFieldKeys(activity.Filter.Fields) {
+		fieldKey := key
+		if key == "object_type" {
+			fieldKey = "cat"
+		}
+		attributes = append(attributes, fmt.Sprintf("%s=%s", fieldKey, value.Replace(activity.Filter.Fields[key])))
+	}
+
+	return fmt.Sprintf("LEEF:2.0|%s|%s|%s|%s|x09|%s",
+		header.Replace(siemVendor),
+		header.Replace(siemProduct),
+		header.Replace(siemProductVersion),
+		header.Replace(activity.Message),
+		strings.Join(attributes, "\t"),
+	)
+}
+
+// activityTime returns the time of an activity from its nanosecond timestamp.
+func activityTime(activity models.Activity) time.Time {
+	timestamp, err := strconv.ParseInt(activity.Filter.Timestamp, 10, 64)
+	if err != nil {
+		return time.Now()
+	}
+	return time.Unix(0, timestamp)
+}
+
+// sortedFieldKeys returns the keys of the non-empty fields, sorted so that messages are stable.
+func sortedFieldKeys(fields map[string]string) []string {
+	keys := make([]string, 0, len(fields))
+	for key, value := range fields {
+		if value != "" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,231 @@
+package activity
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// syslogTimestamp is 2023-11-14T22:13:20.123456Z in nanoseconds.
+const syslogTimestamp = "1700000000123456789"
+
+func syslogActivity(message string, fields map[string]string) models.Activity {
+	return models.Activity{
+		Message: message,
+		Filter:  models.LogFilter{Fields: fields, Timestamp: syslogTimestamp},
+	}
+}
+
+func TestFormatStructuredData(t *testing.T) {
+	testCases := []struct {
+		name     string
+		fields   map[string]string
+		expected string
+	}{
+		{
+			name:     "No field",
+			expected: `[safebucket@32473]`,
+		},
+		{
+			name:     "Fields sorted and empty fields left out",
+			fields:   map[string]string{"user_id": "42", "action": "create", "file_id": ""},
+			expected: `[safebucket@32473 action="create" user_id="42"]`,
+		},
+		{
+			name:     "Backslashes, quotes and closing brackets escaped",
+			fields:   map[string]string{"name": `a\b"c]d`},
+			expected: `[safebucket@32473 name="a\\b\"c\]d"]`,
+		},
+		{
+			name:     "Equal signs, pipes and newlines kept",
+			fields:   map[string]string{"name": "a=b|c\nd"},
+			expected: "[safebucket@32473 name=\"a=b|c\nd\"]",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatStructuredData(tt.fields))
+		})
+	}
+}
+
+func TestFormatCEF(t *testing.T) {
+	timestamp := activityTime(syslogActivity("", nil))
+
+	testCases := []struct {
+		name     string
+		activity models.Activity
+		severity int
+		expected string
+	}{
+		{
+			name: "Standard, custom and other fields",
+			activity: syslogActivity(FileUploaded, map[string]string{
+				"action":      "create",
+				"object_type": "file",
+				"bucket_id":   "b1",
+				"file_id":     "f1",
+				"user_id":     "u1",
+				"reason":      "scan",
+			}),
+			severity: syslogSeverityInfo,
+			expected: "CEF:0|Safebucket|Safebucket|1.0|FILE_UPLOADED|file uploaded|3|rt=1700000000123 " +
+				"cs1Label=bucket_id cs1=b1 cs2Label=file_id cs2=f1 act=create cat=file suid=u1 msg=reason\\=scan",
+		},
+		{
+			name: "Warning severity",
+			activity: syslogActivity(UserLoginFailed, map[string]string{
+				"domain": "example.com",
+			}),
+			severity: syslogSeverityWarn,
+			expected: "CEF:0|Safebucket|Safebucket|1.0|USER_LOGIN_FAILED|user login failed|6|rt=1700000000123 " +
+				"dvchost=example.com",
+		},
+		{
+			name:     "Pipes and backslashes escaped in the header",
+			activity: syslogActivity(`A|B\C`, nil),
+			severity: syslogSeverityInfo,
+			expected: `CEF:0|Safebucket|Safebucket|1.0|A\|B\\C|a\|b\\c|3|rt=1700000000123`,
+		},
+		{
+			name: "Equal signs, backslashes and newlines escaped in the extensions",
+			activity: syslogActivity(FileUploaded, map[string]string{
+				"bucket_member_email": "a=b\\c\nd\re",
+				"file_id":             "x=y",
+			}),
+			severity: syslogSeverityInfo,
+			expected: "CEF:0|Safebucket|Safebucket|1.0|FILE_UPLOADED|file uploaded|3|rt=1700000000123 " +
+				`cs2Label=file_id cs2=x\=y duser=a\=b\\c\nd\re`,
+		},
+		{
+			name:     "Pipes and closing brackets kept in the extensions",
+			activity: syslogActivity(FileUploaded, map[string]string{"name": "a|b]c"}),
+			severity: syslogSeverityInfo,
+			expected: "CEF:0|Safebucket|Safebucket|1.0|FILE_UPLOADED|file uploaded|3|rt=1700000000123 " +
+				`msg=name\=a|b]c`,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatCEF(tt.activity, timestamp, tt.severity))
+		})
+	}
+}
+
+func TestFormatLEEF(t *testing.T) {
+	timestamp := activityTime(syslogActivity("", nil))
+
+	testCases := []struct {
+		name     string
+		activity models.Activity
+		severity int
+		expected string
+	}{
+		{
+			name: "Fields as tab separated attributes",
+			activity: syslogActivity(FileUploaded, map[string]string{
+				"object_type": "file",
+				"action":      "create",
+				"user_id":     "",
+			}),
+			severity: syslogSeverityInfo,
+			expected: "LEEF:2.0|Safebucket|Safebucket|1.0|FILE_UPLOADED|x09|" +
+				"devTime=1700000000123\tsev=3\taction=create\tcat=file",
+		},
+		{
+			name:     "Warning severity",
+			activity: syslogActivity(UserLoginFailed, nil),
+			severity: syslogSeverityWarn,
+			expected: "LEEF:2.0|Safebucket|Safebucket|1.0|USER_LOGIN_FAILED|x09|devTime=1700000000123\tsev=6",
+		},
+		{
+			name:     "Pipes replaced in the header",
+			activity: syslogActivity("A|B", nil),
+			severity: syslogSeverityInfo,
+			expected: "LEEF:2.0|Safebucket|Safebucket|1.0|A B|x09|devTime=1700000000123\tsev=3",
+		},
+		{
+			name:     "Tabs and newlines replaced in the attributes",
+			activity: syslogActivity(FileUploaded, map[string]string{"name": "a\tb\nc\rd"}),
+			severity: syslogSeverityInfo,
+			expected: "LEEF:2.0|Safebucket|Safebucket|1.0|FILE_UPLOADED|x09|" +
+				"devTime=1700000000123\tsev=3\tname=a b c d",
+		},
+		{
+			name:     "Equal signs, pipes, backslashes and brackets kept in the attributes",
+			activity: syslogActivity(FileUploaded, map[string]string{"name": `a=b|c\d]`}),
+			severity: syslogSeverityInfo,
+			expected: "LEEF:2.0|Safebucket|Safebucket|1.0|FILE_UPLOADED|x09|" +
+				"devTime=1700000000123\tsev=3\tname=a=b|c\\d]",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatLEEF(tt.activity, timestamp, tt.severity))
+		})
+	}
+}
+
+func TestFormatSyslogMessage(t *testing.T) {
+	header := func(priority int, msgID string) string {
+		return fmt.Sprintf("<%d>1 2023-11-14T22:13:20.123456Z host safebucket %d %s", priority, os.Getpid(), msgID)
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		activity models.Activity
+		expected string
+	}{
+		{
+			name:     "RFC 5424 with structured data",
+			format:   SyslogFormatRFC5424,
+			activity: syslogActivity(FileUploaded, map[string]string{"action": "create", "name": `a"]`}),
+			expected: header(110, FileUploaded) + ` [safebucket@32473 action="create" name="a\"\]"] FILE_UPLOADED`,
+		},
+		{
+			name:     "Failed login as a warning",
+			format:   SyslogFormatRFC5424,
+			activity: syslogActivity(UserLoginFailed, nil),
+			expected: header(108, UserLoginFailed) + " [safebucket@32473] USER_LOGIN_FAILED",
+		},
+		{
+			name:     "Message ID left out when too long",
+			format:   SyslogFormatRFC5424,
+			activity: syslogActivity("A_MESSAGE_LONGER_THAN_THIRTY_TWO_CHARACTERS", nil),
+			expected: header(110, "-") + " [safebucket@32473] A_MESSAGE_LONGER_THAN_THIRTY_TWO_CHARACTERS",
+		},
+		{
+			name:     "CEF without structured data",
+			format:   SyslogFormatCEF,
+			activity: syslogActivity(FileUploaded, map[string]string{"action": "a=b"}),
+			expected: header(110, FileUploaded) +
+				` - CEF:0|Safebucket|Safebucket|1.0|FILE_UPLOADED|file uploaded|3|rt=1700000000123 act=a\=b`,
+		},
+		{
+			name:     "LEEF without structured data",
+			format:   SyslogFormatLEEF,
+			activity: syslogActivity(FileUploaded, map[string]string{"action": "a\nb"}),
+			expected: header(110, FileUploaded) +
+				" - LEEF:2.0|Safebucket|Safebucket|1.0|FILE_UPLOADED|x09|devTime=1700000000123\tsev=3\taction=a b",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, formatSyslogMessage(tt.format, "host", "safebucket", tt.activity))
+		})
+	}
+}
+
+func TestActivityTime(t *testing.T) {
+	assert.Equal(t, time.Unix(0, 1700000000123456789), activityTime(syslogActivity("", nil)))
+}
@@ -0,0 +1,174 @@
+package antivirus
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+)
+
+// ErrFileTooLarge is returned for the content larger than the maximum size, or than the limit of clamd.
+// Scanning it again fails the same way.
+var ErrFileTooLarge = errors.New("file too large to scan")
+
+// ClamAVScanner implements IScanner by streaming the content to a clamd daemon with the INSTREAM command.
+type ClamAVScanner struct {
+	network        string
+	address        string
+	timeout        time.Duration
+	maxFileSize    int64
+	skipLargeFiles bool
+}
+
+// NewClamAVScanner initializes the scanner and checks clamd is reachable.
+func NewClamAVScanner(config models.AntivirusConfiguration) *ClamAVScanner {
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = c.AntivirusDefaultTimeoutSeconds * time.Second
+	}
+
+	scanner := &ClamAVScanner{
+		network:        config.Network,
+		address:        config.Address,
+		timeout:        timeout,
+		maxFileSize:    config.MaxFileSize,
+		skipLargeFiles: config.SkipLargeFiles,
+	}
+
+	if err := scanner.Ping(); err != nil {
+		zap.L().Error("Failed to connect to clamd", zap.String("address", config.Address), zap.Error(err))
+	}
+
+	return scanner
+}
+
+// Ping checks clamd answers.
+func (s *ClamAVScanner) Ping() error {
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if _, err = conn.Write([]byte("zPING\x00")); err != nil {
+		return err
+	}
+
+	reply, err := s.readReply(conn)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+	return nil
+}
+
+// Scan streams the content to clamd in chunks, and returns whether it is infected.
+// Content larger than the configured maximum size is not scanned, and fails unless skipping it is allowed.
+func (s *ClamAVScanner) Scan(content io.Reader, size int64) (models.FileScanResult, string, error) {
+	if s.maxFileSize > 0 && size > s.maxFileSize {
+		if s.skipLargeFiles {
+			return models.FileScanSkipped, "", nil
+		}
+		return "", "", ErrFileTooLarge
+	}
+
+	conn, err := s.dial()
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err = s.stream(conn, content); err != nil {
+		// clamd replies and closes the connection when the stream exceeds its size limit
+		if reply, replyErr := s.readReply(conn); replyErr == nil && reply != "" {
+			return parseReply(reply)
+		}
+		return "", "", err
+	}
+
+	reply, err := s.readReply(conn)
+	if err != nil {
+		return "", "", err
+	}
+	return parseReply(reply)
+}
+
+func (s *ClamAVScanner) dial() (net.Conn, error) {
+	return net.DialTimeout(s.network, s.address, s.timeout)
+}
+
+// stream sends the INSTREAM command, followed by the content in chunks prefixed with their length,
+// and a zero-length chunk marking the end of the stream.
+func (s *ClamAVScanner) stream(conn net.Conn, content io.Reader) error {
+	if err := s.write(conn, []byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+
+	chunk := make([]byte, 4+c.AntivirusChunkSizeBytes)
+	for {
+		n, readErr := content.Read(chunk[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(chunk[:4], uint32(n)) // #nosec G115 -- n is at most the chunk size
+			if err := s.write(conn, chunk[:4+n]); err != nil {
+				return err
+			}
+		}
+		if errors.Is(readErr, io.EOF) {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	return s.write(conn, []byte{0, 0, 0, 0})
+}
+
+// write sends data with a deadline for each write, so that the timeout does not limit the size of the files.
+func (s *ClamAVScanner) write(conn net.Conn, data []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return err
+	}
+	_, err := conn.Write(data)
+	return err
+}
+
+// readReply reads a reply of clamd, which ends with a null character with the z prefixed commands.
+func (s *ClamAVScanner) readReply(conn net.Conn) (string, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(s.timeout)); err != nil {
+		return "", err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimSuffix(reply, "\x00")), nil
+}
+
+// parseReply parses the reply to a scan: "stream: OK", "stream: <signature> FOUND" or "<message> ERROR".
+// The size limit of clamd is reported as ErrFileTooLarge, the other errors may succeed when retried.
+func parseReply(reply string) (models.FileScanResult, string, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return models.FileScanClean, "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return models.FileScanInfected, strings.TrimSuffix(result, " FOUND"), nil
+	case strings.Contains(result, "size limit exceeded"):
+		return "", "", fmt.Errorf("clamd: %s: %w", reply, ErrFileTooLarge)
+	default:
+		return "", "", fmt.Errorf("clamd: %s", reply)
+	}
+}
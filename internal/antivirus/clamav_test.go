@@ -0,0 +1,179 @@
+package antivirus
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// startClamd starts a stub of clamd, reporting the EICAR test file as infected,
+// and rejecting the streams larger than the size limit like clamd does.
+func startClamd(t *testing.T, streamMaxLength int) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamd(conn, streamMaxLength)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func serveClamd(conn net.Conn, streamMaxLength int) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+
+	command, err := reader.ReadString(0)
+	if err != nil {
+		return
+	}
+
+	switch command {
+	case "zPING\x00":
+		_, _ = conn.Write([]byte("PONG\x00"))
+	case "zINSTREAM\x00":
+		var content bytes.Buffer
+		for {
+			var size uint32
+			if err = binary.Read(reader, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if content.Len()+int(size) > streamMaxLength {
+				_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+				return
+			}
+			if _, err = io.CopyN(&content, reader, int64(size)); err != nil {
+				return
+			}
+		}
+
+		if strings.Contains(content.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+			_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		} else {
+			_, _ = conn.Write([]byte("stream: OK\x00"))
+		}
+	default:
+		_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+	}
+}
+
+func TestClamAVScanner(t *testing.T) {
+	address := startClamd(t, 1024*1024)
+	scanner := NewClamAVScanner(models.AntivirusConfiguration{Network: "tcp", Address: address})
+
+	t.Run("Ping", func(t *testing.T) {
+		require.NoError(t, scanner.Ping())
+	})
+
+	t.Run("Clean content", func(t *testing.T) {
+		content := strings.Repeat("safebucket", 20000)
+		result, signature, err := scanner.Scan(strings.NewReader(content), int64(len(content)))
+		require.NoError(t, err)
+		assert.Equal(t, models.FileScanClean, result)
+		assert.Empty(t, signature)
+	})
+
+	t.Run("Infected content", func(t *testing.T) {
+		result, signature, err := scanner.Scan(strings.NewReader(eicar), int64(len(eicar)))
+		require.NoError(t, err)
+		assert.Equal(t, models.FileScanInfected, result)
+		assert.Equal(t, "Eicar-Test-Signature", signature)
+	})
+
+	t.Run("Empty content", func(t *testing.T) {
+		result, _, err := scanner.Scan(strings.NewReader(""), 0)
+		require.NoError(t, err)
+		assert.Equal(t, models.FileScanClean, result)
+	})
+}
+
+func TestClamAVScannerLimits(t *testing.T) {
+	address := startClamd(t, 1024)
+
+	t.Run("Content larger than the maximum size fails", func(t *testing.T) {
+		scanner := NewClamAVScanner(models.AntivirusConfiguration{Network: "tcp", Address: address, MaxFileSize: 10})
+		_, _, err := scanner.Scan(strings.NewReader(eicar), int64(len(eicar)))
+		require.ErrorIs(t, err, ErrFileTooLarge)
+	})
+
+	t.Run("Content larger than the maximum size is skipped when allowed", func(t *testing.T) {
+		scanner := NewClamAVScanner(models.AntivirusConfiguration{
+			Network:        "tcp",
+			Address:        address,
+			MaxFileSize:    10,
+			SkipLargeFiles: true,
+		})
+		result, _, err := scanner.Scan(strings.NewReader(eicar), int64(len(eicar)))
+		require.NoError(t, err)
+		assert.Equal(t, models.FileScanSkipped, result)
+	})
+
+	t.Run("Content larger than the clamd limit fails", func(t *testing.T) {
+		scanner := NewClamAVScanner(models.AntivirusConfiguration{Network: "tcp", Address: address})
+		content := strings.Repeat("a", 1024*1024)
+		_, _, err := scanner.Scan(strings.NewReader(content), int64(len(content)))
+		require.ErrorIs(t, err, ErrFileTooLarge)
+	})
+
+	t.Run("Unreachable daemon", func(t *testing.T) {
+		scanner := NewClamAVScanner(models.AntivirusConfiguration{Network: "tcp", Address: "127.0.0.1:1"})
+		_, _, err := scanner.Scan(strings.NewReader("content"), 7)
+		require.Error(t, err)
+	})
+}
+
+func TestParseReply(t *testing.T) {
+	tests := []struct {
+		reply     string
+		result    models.FileScanResult
+		signature string
+		wantErr   bool
+		tooLarge  bool
+	}{
+		{reply: "stream: OK", result: models.FileScanClean},
+		{
+			reply:     "stream: Win.Test.EICAR_HDB-1 FOUND",
+			result:    models.FileScanInfected,
+			signature: "Win.Test.EICAR_HDB-1",
+		},
+		{reply: "INSTREAM size limit exceeded. ERROR", wantErr: true, tooLarge: true},
+		{reply: "Can't allocate memory ERROR", wantErr: true},
+		{reply: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			result, signature, err := parseReply(tt.reply)
+			if tt.wantErr {
+				require.Error(t, err)
+				assert.Equal(t, tt.tooLarge, errors.Is(err, ErrFileTooLarge))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.result, result)
+			assert.Equal(t, tt.signature, signature)
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package antivirus
+
+import (
+	"io"
+
+	"api/internal/models"
+)
+
+// IScanner defines the interface for scanning the content of the files for malware.
+// The signature is the name of the malware found, when the content is infected.
+type IScanner interface {
+	Scan(content io.Reader, size int64) (result models.FileScanResult, signature string, err error)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"fmt"
+	"strconv"
 	"time"
 
 	"api/internal/configuration"
@@ -12,6 +13,18 @@ import (
 	"go.uber.org/zap"
 )
 
+// acquireLockScript takes the lock when it is free, or extends it when the owner already holds it.
+var acquireLockScript = rueidis.NewLuaScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 1
+end
+return 0
+`)
+
 type RueidisCache struct {
 	client rueidis.Client
 }
@@ -105,6 +118,36 @@ func (r *RueidisCache) GetRateLimit(userIdentifier string, requestsPerMinute int
 	return 0, nil
 }
 
+// AcquireLock reports whether the owner holds the lock, taking or extending it for the ttl.
+// The lock is released when its owner stops extending it.
+func (r *RueidisCache) AcquireLock(name string, owner string, ttl time.Duration) (bool, error) {
+	ctx := context.Background()
+	key := fmt.Sprintf(configuration.CacheLockKey, name)
+	acquired, err := acquireLockScript.Exec(
+		ctx,
+		r.client,
+		[]string{key},
+		[]string{owner, strconv.FormatInt(ttl.Milliseconds(), 10)},
+	).AsInt64()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+func (r *RueidisCache) Publish(channel string, message string) error {
+	ctx := context.Background()
+	return r.client.Do(ctx, r.client.B().Publish().Channel(channel).Message(message).Build()).Error()
+}
+
+// Subscribe calls the handler for each message published on the channel, until the context is canceled
+// or the connection is lost.
+func (r *RueidisCache) Subscribe(ctx context.Context, channel string, handler func(message string)) error {
+	return r.client.Receive(ctx, r.client.B().Subscribe().Channel(channel).Build(), func(msg rueidis.PubSubMessage) {
+		handler(msg.Message)
+	})
+}
+
 func (r *RueidisCache) Close() error {
 	r.client.Close()
 	return nil
@@ -1,5 +1,10 @@
 package cache
 
+import (
+	"context"
+	"time"
+)
+
 type ICache interface {
 	RegisterPlatform(id string) error
 	DeleteInactivePlatform() error
@@ -7,5 +12,10 @@ type ICache interface {
 
 	GetRateLimit(userIdentifier string, requestsPerMinute int) (int, error)
 
+	AcquireLock(name string, owner string, ttl time.Duration) (bool, error)
+
+	Publish(channel string, message string) error
+	Subscribe(ctx context.Context, channel string, handler func(message string)) error
+
 	Close() error
 }
@@ -7,10 +7,15 @@ type AuthRule struct {
 }
 
 var AuthRulePrefixMatchPath = []AuthRule{
-	{Path: "/api/v1/auth", Method: "*", RequireAuth: false},    // All /auth excluded
-	{Path: "/api/v1/invites", Method: "*", RequireAuth: false}, // All /invites require auth
-	{Path: "/api/v1/buckets", Method: "*", RequireAuth: true},  // All /buckets require auth
-	{Path: "/api/v1/users", Method: "*", RequireAuth: true},    // All /users require auth
+	{Path: "/api/v1/auth", Method: "*", RequireAuth: false},                         // All /auth excluded
+	{Path: "/api/v1/invites", Method: "*", RequireAuth: false},                      // All /invites require auth
+	{Path: "/api/v1/buckets", Method: "*", RequireAuth: true},                       // All /buckets require auth
+	{Path: "/api/v1/users", Method: "*", RequireAuth: true},                         // All /users require auth
+	{Path: "/api/v1/policies", Method: "*", RequireAuth: true},                      // All /policies require auth
+	{Path: "/api/v1/activity", Method: "*", RequireAuth: true},                      // All /activity require auth
+	{Path: "/api/v1/notifications/unsubscribe", Method: "GET", RequireAuth: false},  // Unsubscribe links of the emails
+	{Path: "/api/v1/notifications/unsubscribe", Method: "POST", RequireAuth: false}, // One-click unsubscription
+	{Path: "/api/v1/notifications", Method: "*", RequireAuth: true},                 // Other /notifications require auth
 }
 
 var AuthRuleExactMatchPath = map[string][]AuthRule{
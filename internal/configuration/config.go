@@ -124,6 +124,14 @@ func Read() models.Configuration {
 		zap.L().Fatal("Unable to decode config into struct", zap.Error(err))
 	}
 
+	// The bounds of the retention of the buckets are optional
+	if config.App.TrashRetentionMinDays == 0 {
+		config.App.TrashRetentionMinDays = DefaultTrashRetentionMinDays
+	}
+	if config.App.TrashRetentionMaxDays == 0 {
+		config.App.TrashRetentionMaxDays = DefaultTrashRetentionMaxDays
+	}
+
 	validate := validator.New()
 	if err = validate.Struct(config); err != nil {
 		zap.L().Fatal("Invalid configuration", zap.Error(err))
@@ -6,6 +6,8 @@ const (
 	CacheMaxAppIdentityLifetime = 60
 	CacheAppIdentityKey         = "app:identity"
 	CacheAppRateLimitKey        = "app:ratelimit:%s"
+	CacheBucketEventsChannel    = "app:bucket_events"
+	CacheLockKey                = "app:lock:%s"
 )
 
 const (
@@ -16,6 +18,34 @@ const (
 
 const UploadPolicyExpirationInMinutes = 15
 
+// UploadChecksumComputeMaxBytes is the size up to which the checksums the storage does not report are computed
+// from the content. Larger uploads declaring such a checksum fail.
+const UploadChecksumComputeMaxBytes = 1024 * 1024 * 1024
+
+const (
+	UploadReaperIntervalMinutes = 10
+	UploadReaperGraceMinutes    = 15
+	UploadReaperBatchSize       = 100
+)
+
+const (
+	DefaultTrashRetentionMinDays = 1
+	DefaultTrashRetentionMaxDays = 365
+)
+
+const (
+	TrashExpirationIntervalMinutes = 60
+	TrashExpirationGraceHours      = 24
+	TrashExpirationBatchSize       = 100
+)
+
+const (
+	ReconciliationBatchSize      = 1000
+	ReconciliationMaxIssues      = 1000
+	ReconciliationListLimit      = 50
+	ReconciliationTimeoutMinutes = 360
+)
+
 const (
 	SecurityChallengeExpirationMinutes = 30
 	SecurityChallengeMaxFailedAttempts = 3
@@ -23,11 +53,62 @@ const (
 
 const BulkActionsLimit = 1000
 
+const UserListDefaultLimit = 50
+
+const (
+	ActivitySearchDefaultLimit = 50
+	ActivitySearchDefaultDays  = 30
+	ActivityExportPageSize     = 500
+)
+
+const (
+	ActivityChainCheckpointIntervalMinutes = 60
+	ActivityVerifyMaxRecords               = 100000
+)
+
+const (
+	SyslogDefaultAppName       = "safebucket"
+	SyslogDefaultBufferSize    = 1000
+	SyslogDialTimeoutSeconds   = 5
+	SyslogWriteTimeoutSeconds  = 5
+	SyslogRetryMaxDelaySeconds = 60
+)
+
+const (
+	ActivityQueueDefaultBatchSize       = 100
+	ActivityQueueDefaultIntervalSeconds = 5
+	ActivityQueueRetryMaxDelaySeconds   = 300
+	ActivityDeliveryLeaseSeconds        = 60
+	ActivityDeliveryMaxAttempts         = 100
+)
+
+const MembershipExpirationIntervalMinutes = 15
+
+const (
+	WebhookSecretBytes           = 32
+	WebhookTimeoutSeconds        = 10
+	WebhookMaxAttempts           = 8
+	WebhookRetryIntervalSeconds  = 30
+	WebhookRetryBatchSize        = 100
+	WebhookRetryBaseDelaySeconds = 30
+	WebhookRetryMaxDelaySeconds  = 3600
+	WebhookDeliveryLeaseSeconds  = 300
+	WebhookDeliveryLogLimit      = 100
+	WebhookResponseDrainMaxBytes = 4096
+)
+
+const (
+	BucketEventsBufferSize              = 64
+	BucketEventsHeartbeatSeconds        = 15
+	BucketEventsResubscribeDelaySeconds = 5
+)
+
 var ArrayConfigFields = []string{
 	"app.trusted_proxies",
 	"cors.allowed_origins",
 	"cache.redis.hosts",
 	"cache.valkey.hosts",
+	"activity.queue.synchronous_messages",
 }
 
 var ConfigFileSearchPaths = []string{
@@ -41,3 +122,38 @@ var AuthProviderKeys = []string{
 	"client_secret",
 	"issuer",
 }
+
+const DefaultLanguage = "en"
+
+const (
+	NotificationUnsubscribeTokenBytes = 32
+	NotificationDigestIntervalMinutes = 15
+	NotificationDigestBatchSize       = 100
+	NotificationDigestMaxEntries      = 200
+)
+
+const (
+	MailerDefaultPoolSize          = 2
+	MailerDefaultRatePerSecond     = 5
+	MailerPoolIdleSeconds          = 30
+	MailQueueIntervalSeconds       = 5
+	MailQueueBatchSize             = 50
+	MailQueueDefaultMaxAttempts    = 8
+	MailQueueLeaseSeconds          = 300
+	MailQueueRetryBaseDelaySeconds = 60
+	MailQueueRetryMaxDelaySeconds  = 3600
+	MailDeliveryLogDefaultLimit    = 50
+	MailDeliveryRetentionDays      = 30
+)
+
+const (
+	AntivirusDefaultTimeoutSeconds = 60
+	AntivirusChunkSizeBytes        = 64 * 1024
+	AntivirusMaxScanAttempts       = 5
+)
+
+const (
+	NotifierTimeoutSeconds        = 10
+	NotifierResponseErrorMaxBytes = 512
+	NotifierChannelDedupSeconds   = 600
+)
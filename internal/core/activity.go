@@ -1,15 +1,68 @@
 package core
 
 import (
+	"time"
+
 	"api/internal/activity"
+	"api/internal/configuration"
+	"api/internal/jobs"
 	"api/internal/models"
+
+	"gorm.io/gorm"
 )
 
-func NewActivityLogger(config models.ActivityConfiguration) activity.IActivityLogger {
+// NewActivityLogger creates the configured activity backend, wrapped with the audit hash chain.
+// When a syslog server is configured, the stored activity is also forwarded to it.
+// When the queue is enabled, the activity is delivered to the backend in the background.
+func NewActivityLogger(config models.ActivityConfiguration, db *gorm.DB) activity.IActivityLogger {
+	var backend activity.IActivityLogger
 	switch config.Type {
 	case "loki":
-		return activity.NewLokiClient(config)
+		backend = activity.NewLokiClient(config)
+	case "postgres":
+		backend = activity.NewPostgresActivityLogger(db)
 	default:
 		return nil
 	}
+
+	if config.Syslog != nil {
+		backend = activity.NewFanOutActivityLogger(backend, activity.NewSyslogForwarder(*config.Syslog))
+	}
+
+	maxAttempts := configuration.ActivityDeliveryMaxAttempts
+	if config.Queue != nil && config.Queue.MaxAttempts > 0 {
+		maxAttempts = config.Queue.MaxAttempts
+	}
+	chain := activity.NewChainedActivityLogger(db, backend, maxAttempts)
+
+	if config.Queue != nil {
+		return activity.NewQueuedActivityLogger(db, chain, config.Queue.SynchronousMessages)
+	}
+	return chain
+}
+
+// StartActivityDelivery starts delivering the queued activity, and retrying the failed deliveries, in the background.
+func StartActivityDelivery(config models.ActivityConfiguration, logger activity.IActivityLogger) {
+	var chain *activity.ChainedActivityLogger
+	switch l := logger.(type) {
+	case *activity.QueuedActivityLogger:
+		chain = l.Chain
+	case *activity.ChainedActivityLogger:
+		chain = l
+	default:
+		return
+	}
+
+	delivery := jobs.ActivityDelivery{Chain: chain, BatchSize: configuration.ActivityQueueDefaultBatchSize}
+	interval := configuration.ActivityQueueDefaultIntervalSeconds * time.Second
+	if config.Queue != nil {
+		if config.Queue.BatchSize > 0 {
+			delivery.BatchSize = config.Queue.BatchSize
+		}
+		if config.Queue.IntervalSeconds > 0 {
+			interval = time.Duration(config.Queue.IntervalSeconds) * time.Second
+		}
+	}
+
+	go delivery.Start(interval)
 }
@@ -0,0 +1,14 @@
+package core
+
+import (
+	"api/internal/antivirus"
+	"api/internal/models"
+)
+
+// NewScanner creates the antivirus scanner, or returns nil when the uploaded files are not scanned.
+func NewScanner(config *models.AntivirusConfiguration) antivirus.IScanner {
+	if config == nil {
+		return nil
+	}
+	return antivirus.NewClamAVScanner(*config)
+}
@@ -54,13 +54,19 @@ func (er *EventRouter) getTopicKeyForEvent(eventType string) string {
 		events.ChallengeUserInviteName,
 		events.PasswordResetChallengeName,
 		events.PasswordResetSuccessName,
-		events.UserWelcomeName:
+		events.UserWelcomeName,
+		events.MembershipExpiryName,
+		events.WebhookDispatchName,
+		events.WebhookDeliveryName,
+		events.BucketActivityNotificationName,
+		events.NotificationDigestName:
 		return configuration.EventsNotifications
 	case events.BucketPurgeName,
 		events.FolderTrashName,
 		events.FolderPurgeName,
 		events.FolderRestoreName,
-		events.TrashExpirationName:
+		events.TrashExpirationName,
+		events.FileScanName:
 		return configuration.EventsObjectDeletion
 	default:
 		return ""
@@ -1,15 +1,55 @@
 package core
 
 import (
+	"time"
+
+	"api/internal/cache"
+	"api/internal/configuration"
+	"api/internal/jobs"
 	"api/internal/models"
 	"api/internal/notifier"
+
+	"gorm.io/gorm"
 )
 
-func NewNotifier(config models.NotifierConfiguration) notifier.INotifier {
-	switch config.Type {
-	case "smtp":
-		return notifier.NewSMTPNotifier(*config.SMTP)
-	default:
+// NewNotifier creates the configured notifier. The emails are queued and sent in the background.
+// The chat channels use the cache to post each notification once.
+func NewNotifier(config models.NotifierConfiguration, db *gorm.DB, cache cache.ICache) notifier.INotifier {
+	if config.Type != "smtp" {
 		return nil
 	}
+
+	primary := notifier.NewMailQueue(db, notifier.NewSMTPNotifier(*config.SMTP), config.SMTP.MaxAttempts)
+
+	if len(config.Channels) == 0 {
+		return primary
+	}
+
+	channels := make([]notifier.INotifier, 0, len(config.Channels))
+	for _, channel := range config.Channels {
+		switch channel.Type {
+		case "slack":
+			channels = append(channels, notifier.NewSlackNotifier(channel.NotifierEndpointConfiguration, cache))
+		case "teams":
+			channels = append(channels, notifier.NewTeamsNotifier(channel.NotifierEndpointConfiguration, cache))
+		case "webhook":
+			channels = append(channels, notifier.NewWebhookNotifier(channel.NotifierEndpointConfiguration))
+		}
+	}
+	return notifier.NewFanOutNotifier(primary, channels...)
+}
+
+// StartMailDelivery starts sending the queued emails in the background, when the notifier sends emails.
+func StartMailDelivery(n notifier.INotifier) {
+	if fanOut, ok := n.(*notifier.FanOutNotifier); ok {
+		n = fanOut.Primary
+	}
+
+	queue, ok := n.(*notifier.MailQueue)
+	if !ok {
+		return
+	}
+
+	delivery := jobs.MailDelivery{Queue: queue, BatchSize: configuration.MailQueueBatchSize}
+	go delivery.Start(configuration.MailQueueIntervalSeconds * time.Second)
 }
@@ -2,12 +2,17 @@ package core
 
 import (
 	"api/internal/models"
+	"api/internal/sql"
 	"api/internal/storage"
 
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-func NewStorage(config models.StorageConfiguration, trashRetentionDays int) storage.IStorage {
+// NewStorage creates the storage of the provider. When a retention of the trash is given, it configures
+// the expiration of the trash markers for each retention in use, under the lock of the retentions
+// as the owners may change them concurrently.
+func NewStorage(config models.StorageConfiguration, db *gorm.DB, trashRetentionDays int) storage.IStorage {
 	var store storage.IStorage
 
 	switch config.Type {
@@ -24,11 +29,23 @@ func NewStorage(config models.StorageConfiguration, trashRetentionDays int) stor
 	}
 
 	if store != nil && trashRetentionDays > 0 {
-		err := store.EnsureTrashLifecyclePolicy(trashRetentionDays)
+		var retentions []int
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := sql.LockTrashRetentions(tx); err != nil {
+				return err
+			}
+
+			var err error
+			retentions, err = sql.GetTrashRetentions(tx, trashRetentionDays)
+			if err != nil {
+				return err
+			}
+			return store.EnsureTrashLifecyclePolicy(retentions)
+		})
 		if err != nil {
 			zap.L().Fatal("Failed to configure trash lifecycle policy",
 				zap.String("provider", config.Type),
-				zap.Int("retentionDays", trashRetentionDays),
+				zap.Ints("retentionDays", retentions),
 				zap.Error(err))
 		}
 	}
@@ -0,0 +1,53 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE folder_group_type AS ENUM ('none', 'viewer', 'contributor', 'owner');
+
+-- Folder permissions table (per-folder ACL entries, inherited down the folder tree)
+CREATE TABLE folder_permissions
+    (
+        id uuid
+            PRIMARY KEY DEFAULT gen_random_uuid(),
+        folder_id uuid NOT NULL,
+        bucket_id uuid NOT NULL,
+        user_id uuid NOT NULL,
+        "group" folder_group_type NOT NULL,
+        created_by uuid NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+        -- Foreign Keys
+        CONSTRAINT fk_folder_permissions_folder_id
+            FOREIGN KEY (folder_id) REFERENCES folders (id) ON UPDATE CASCADE ON DELETE CASCADE,
+        CONSTRAINT fk_folder_permissions_bucket_id
+            FOREIGN KEY (bucket_id) REFERENCES buckets (id) ON UPDATE CASCADE ON DELETE CASCADE,
+        CONSTRAINT fk_folder_permissions_user_id
+            FOREIGN KEY (user_id) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+        CONSTRAINT fk_folder_permissions_created_by
+            FOREIGN KEY (created_by) REFERENCES users (id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+        -- Constraints
+        CONSTRAINT idx_folder_permissions_unique
+            UNIQUE (folder_id, user_id)
+    );
+
+-- Indexes for Folder permissions
+-- Effective permission lookups load every entry of a user in a bucket at once
+CREATE INDEX idx_folder_permissions_bucket_user ON folder_permissions (bucket_id, user_id);
+
+CREATE TRIGGER update_folder_permissions_updated_at
+    BEFORE UPDATE
+    ON folder_permissions
+    FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS update_folder_permissions_updated_at ON folder_permissions;
+DROP TABLE IF EXISTS folder_permissions;
+DROP TYPE IF EXISTS folder_group_type;
+
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Policies table (casbin-style rules evaluated by the policy engine)
+CREATE TABLE policies
+    (
+        id uuid
+            PRIMARY KEY DEFAULT gen_random_uuid(),
+        ptype VARCHAR(512) NOT NULL,
+        v0 VARCHAR(512) NOT NULL DEFAULT '',
+        v1 VARCHAR(512) NOT NULL DEFAULT '',
+        v2 VARCHAR(512) NOT NULL DEFAULT '',
+        v3 VARCHAR(512) NOT NULL DEFAULT '',
+        v4 VARCHAR(512) NOT NULL DEFAULT '',
+        v5 VARCHAR(512) NOT NULL DEFAULT '',
+
+        -- Constraints
+        CONSTRAINT unique_index
+            UNIQUE (ptype, v0, v1, v2, v3, v4, v5)
+    );
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS policies;
+
+-- +goose StatementEnd
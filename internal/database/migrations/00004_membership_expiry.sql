@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Time-limited memberships and invites (NULL means the grant never expires)
+ALTER TABLE memberships
+    ADD COLUMN expires_at TIMESTAMP,
+    ADD COLUMN expiry_notified_at TIMESTAMP;
+
+ALTER TABLE invites
+    ADD COLUMN expires_at TIMESTAMP;
+
+-- The expiration job only scans active grants with an expiry
+CREATE INDEX idx_memberships_expires_at ON memberships (expires_at) WHERE expires_at IS NOT NULL AND deleted_at IS NULL;
+CREATE INDEX idx_invites_expires_at ON invites (expires_at) WHERE expires_at IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_invites_expires_at;
+DROP INDEX IF EXISTS idx_memberships_expires_at;
+
+ALTER TABLE invites
+    DROP COLUMN IF EXISTS expires_at;
+
+ALTER TABLE memberships
+    DROP COLUMN IF EXISTS expiry_notified_at,
+    DROP COLUMN IF EXISTS expires_at;
+
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Suspended accounts keep their data but can no longer sign in (NULL means the account is active)
+ALTER TABLE users
+    ADD COLUMN disabled_at TIMESTAMP;
+
+-- The admin user list is paginated with keyset cursors
+CREATE INDEX idx_users_created_at_id ON users (created_at, id) WHERE deleted_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_users_created_at_id;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS disabled_at;
+
+-- +goose StatementEnd
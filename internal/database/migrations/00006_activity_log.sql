@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Activity storage for deployments that do not run Loki
+CREATE TABLE activity_log
+    (
+        id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        timestamp           TIMESTAMP NOT NULL,
+        message             VARCHAR(255) NOT NULL,
+        action              VARCHAR(255),
+        object_type         VARCHAR(255),
+        domain              VARCHAR(255),
+        user_id             UUID,
+        bucket_id           UUID,
+        file_id             UUID,
+        folder_id           UUID,
+        bucket_member_email VARCHAR(254),
+        object              JSONB,
+        metadata            JSONB NOT NULL DEFAULT '{}'
+    );
+
+CREATE INDEX idx_activity_log_timestamp ON activity_log (timestamp);
+CREATE INDEX idx_activity_log_bucket_id ON activity_log (bucket_id, timestamp);
+CREATE INDEX idx_activity_log_user_id ON activity_log (user_id, timestamp);
+CREATE INDEX idx_activity_log_object_type ON activity_log (object_type);
+CREATE INDEX idx_activity_log_action ON activity_log (action);
+
+-- The activity log is an audit trail: rows can only be appended
+CREATE FUNCTION activity_log_append_only() RETURNS TRIGGER AS
+$$
+BEGIN
+    RAISE EXCEPTION 'activity_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_activity_log_append_only
+    BEFORE UPDATE OR DELETE ON activity_log
+    FOR EACH ROW
+EXECUTE FUNCTION activity_log_append_only();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TRIGGER IF EXISTS trg_activity_log_append_only ON activity_log;
+DROP FUNCTION IF EXISTS activity_log_append_only();
+DROP TABLE IF EXISTS activity_log;
+
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Head of the activity hash chain, locked while a record is appended so that sequences have no gaps
+CREATE TABLE activity_chain_head
+    (
+        id       SMALLINT PRIMARY KEY CHECK (id = 1),
+        sequence BIGINT NOT NULL DEFAULT 0,
+        hash     VARCHAR(64) NOT NULL DEFAULT ''
+    );
+
+INSERT INTO activity_chain_head (id) VALUES (1);
+
+-- Signed chain heads, used to prove the chain was not rewritten afterwards
+CREATE TABLE activity_chain_checkpoints
+    (
+        id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        sequence   BIGINT NOT NULL UNIQUE,
+        hash       VARCHAR(64) NOT NULL,
+        signature  VARCHAR(64) NOT NULL,
+        created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX idx_activity_chain_checkpoints_created_at ON activity_chain_checkpoints (created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS activity_chain_checkpoints;
+DROP TABLE IF EXISTS activity_chain_head;
+
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Activity waiting to be delivered to the activity backend, so that requests do not depend on its availability
+CREATE TABLE activity_outbox
+    (
+        id              BIGSERIAL PRIMARY KEY,
+        message         VARCHAR(255) NOT NULL,
+        timestamp       BIGINT NOT NULL,
+        fields          JSONB NOT NULL DEFAULT '{}',
+        object          JSONB,
+        attempts        INTEGER NOT NULL DEFAULT 0,
+        last_error      TEXT,
+        next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX idx_activity_outbox_next_attempt_at ON activity_outbox (next_attempt_at, id);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS activity_outbox;
+
+-- +goose StatementEnd
@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE webhook_delivery_status AS ENUM ('pending', 'succeeded', 'failed');
+
+-- Endpoints of a bucket receiving signed notifications of the subscribed events
+CREATE TABLE webhooks
+    (
+        id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        bucket_id   UUID NOT NULL REFERENCES buckets (id) ON DELETE CASCADE,
+        url         TEXT NOT NULL,
+        secret      VARCHAR(255) NOT NULL,
+        event_types JSONB NOT NULL DEFAULT '[]',
+        enabled     BOOLEAN NOT NULL DEFAULT TRUE,
+        created_by  UUID NOT NULL REFERENCES users (id),
+        created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE INDEX idx_webhooks_bucket_id ON webhooks (bucket_id);
+
+-- Delivery log of the webhooks, also used to retry the failed deliveries
+CREATE TABLE webhook_deliveries
+    (
+        id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        webhook_id      UUID NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+        event_type      VARCHAR(50) NOT NULL,
+        payload         JSONB NOT NULL,
+        status          webhook_delivery_status NOT NULL DEFAULT 'pending',
+        attempts        INTEGER NOT NULL DEFAULT 0,
+        response_status INTEGER,
+        last_error      TEXT,
+        next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        delivered_at    TIMESTAMP
+    );
+
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id, created_at);
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TYPE IF EXISTS webhook_delivery_status;
+
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Language of the emails sent to the user (NULL means the default language)
+ALTER TABLE users
+    ADD COLUMN language VARCHAR(10);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS language;
+
+-- +goose StatementEnd
@@ -0,0 +1,39 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE notification_frequency AS ENUM ('immediate', 'daily', 'weekly');
+
+-- Opt-in of the users to be notified of the activity on their buckets, either for all of them or for one bucket
+CREATE TABLE notification_preferences
+    (
+        id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        user_id           UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+        bucket_id         UUID REFERENCES buckets (id) ON DELETE CASCADE,
+        event_types       JSONB NOT NULL DEFAULT '[]',
+        frequency         notification_frequency NOT NULL,
+        enabled           BOOLEAN NOT NULL DEFAULT TRUE,
+        unsubscribe_token VARCHAR(255) NOT NULL,
+        last_digest_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        created_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        updated_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+    );
+
+CREATE UNIQUE INDEX idx_notification_preferences_user ON notification_preferences (user_id) WHERE bucket_id IS NULL;
+CREATE UNIQUE INDEX idx_notification_preferences_user_bucket ON notification_preferences (user_id, bucket_id)
+    WHERE bucket_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_notification_preferences_unsubscribe_token ON notification_preferences (unsubscribe_token);
+
+-- The digest job looks up the enabled digests whose period has elapsed
+CREATE INDEX idx_notification_preferences_digest ON notification_preferences (last_digest_at)
+    WHERE enabled AND frequency <> 'immediate';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS notification_preferences;
+
+DROP TYPE IF EXISTS notification_frequency;
+
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE mail_delivery_status AS ENUM ('pending', 'sent', 'failed', 'bounced');
+
+-- Queue of the emails sent in the background, kept as the mail log once sent or failed
+CREATE TABLE mail_deliveries
+    (
+        id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        recipient       VARCHAR(255) NOT NULL,
+        template        VARCHAR(100) NOT NULL,
+        language        VARCHAR(10) NOT NULL,
+        subject         TEXT NOT NULL,
+        text_body       TEXT NOT NULL,
+        html_body       TEXT NOT NULL,
+        unsubscribe_url TEXT,
+        status          mail_delivery_status NOT NULL DEFAULT 'pending',
+        attempts        INTEGER NOT NULL DEFAULT 0,
+        response_code   INTEGER,
+        last_error      TEXT,
+        next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        created_at      TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        sent_at         TIMESTAMP
+    );
+
+CREATE INDEX idx_mail_deliveries_pending ON mail_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_mail_deliveries_status ON mail_deliveries (status, created_at);
+CREATE INDEX idx_mail_deliveries_recipient ON mail_deliveries (recipient, created_at);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS mail_deliveries;
+DROP TYPE IF EXISTS mail_delivery_status;
+
+-- +goose StatementEnd
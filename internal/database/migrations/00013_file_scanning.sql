@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Uploaded files wait in the scanning status for the antivirus, and are quarantined when found infected
+ALTER TYPE file_status ADD VALUE IF NOT EXISTS 'scanning';
+ALTER TYPE file_status ADD VALUE IF NOT EXISTS 'quarantined';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Enum values cannot be removed: the files waiting for a scan are made available,
+-- and the quarantined files are removed so that they cannot be downloaded
+UPDATE files SET status = 'uploaded' WHERE status = 'scanning';
+DELETE FROM files WHERE status = 'quarantined';
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE checksum_algorithm AS ENUM ('sha256', 'crc32c', 'md5');
+
+-- Checksum of the content of the files, declared by the client or reported by the storage, encoded in base64
+ALTER TABLE files
+    ADD COLUMN checksum_algorithm checksum_algorithm,
+    ADD COLUMN checksum           VARCHAR(64);
+
+-- Uploads whose content does not match the declared size or checksum
+ALTER TYPE file_status ADD VALUE IF NOT EXISTS 'failed';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+-- Enum values cannot be removed: the failed uploads are removed
+DELETE FROM files WHERE status = 'failed';
+
+ALTER TABLE files
+    DROP COLUMN checksum,
+    DROP COLUMN checksum_algorithm;
+
+DROP TYPE checksum_algorithm;
+
+-- +goose StatementEnd
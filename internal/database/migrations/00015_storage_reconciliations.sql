@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+CREATE TYPE reconciliation_status AS ENUM ('running', 'completed', 'failed');
+
+-- Runs of the reconciliation of the storage with the database, with their report
+CREATE TABLE storage_reconciliations
+    (
+        id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+        repair       BOOLEAN NOT NULL DEFAULT FALSE,
+        status       reconciliation_status NOT NULL DEFAULT 'running',
+        report       JSONB,
+        error        TEXT,
+        started_by   UUID REFERENCES users (id) ON DELETE SET NULL,
+        created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+        completed_at TIMESTAMP
+    );
+
+-- A single reconciliation runs at a time
+CREATE UNIQUE INDEX idx_storage_reconciliations_running ON storage_reconciliations (status) WHERE status = 'running';
+CREATE INDEX idx_storage_reconciliations_created_at ON storage_reconciliations (created_at DESC);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP TABLE IF EXISTS storage_reconciliations;
+DROP TYPE IF EXISTS reconciliation_status;
+
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Number of days the trashed files and folders of the bucket are kept, the retention of the platform when null
+ALTER TABLE buckets
+    ADD COLUMN trash_retention_days INTEGER CHECK (trash_retention_days > 0);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE buckets
+    DROP COLUMN trash_retention_days;
+
+-- +goose StatementEnd
@@ -0,0 +1,31 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- A legal hold, or a retention date not yet passed, prevents a file or the files of a bucket
+-- from being trashed or purged
+ALTER TABLE files
+    ADD COLUMN legal_hold   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN retain_until TIMESTAMP;
+
+ALTER TABLE buckets
+    ADD COLUMN legal_hold   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN retain_until TIMESTAMP;
+
+CREATE INDEX idx_files_retention_lock ON files (bucket_id) WHERE legal_hold OR retain_until IS NOT NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX idx_files_retention_lock;
+
+ALTER TABLE buckets
+    DROP COLUMN retain_until,
+    DROP COLUMN legal_hold;
+
+ALTER TABLE files
+    DROP COLUMN retain_until,
+    DROP COLUMN legal_hold;
+
+-- +goose StatementEnd
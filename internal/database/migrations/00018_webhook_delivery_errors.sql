@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- The response bodies of the endpoints are no longer kept in the delivery log, only their status
+UPDATE webhook_deliveries
+SET last_error = substring(last_error FROM '^unexpected status [0-9]+')
+WHERE last_error LIKE 'unexpected status %';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+SELECT 1;
+
+-- +goose StatementEnd
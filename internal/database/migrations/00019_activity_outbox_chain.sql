@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Sequence of the record in the activity hash chain, once chained, so that a retried delivery is not chained again
+ALTER TABLE activity_outbox
+    ADD COLUMN chain_sequence BIGINT;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE activity_outbox
+    DROP COLUMN chain_sequence;
+
+-- +goose StatementEnd
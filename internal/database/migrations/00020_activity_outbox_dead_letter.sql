@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Date the activity was given up after the maximum number of attempts, kept for investigation
+ALTER TABLE activity_outbox
+    ADD COLUMN failed_at TIMESTAMP;
+
+DROP INDEX IF EXISTS idx_activity_outbox_next_attempt_at;
+CREATE INDEX idx_activity_outbox_next_attempt_at ON activity_outbox (next_attempt_at, id) WHERE failed_at IS NULL;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+DROP INDEX IF EXISTS idx_activity_outbox_next_attempt_at;
+CREATE INDEX idx_activity_outbox_next_attempt_at ON activity_outbox (next_attempt_at, id);
+
+ALTER TABLE activity_outbox
+    DROP COLUMN failed_at;
+
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+
+-- Number of failed scans of a file, which is quarantined once the maximum is reached
+ALTER TABLE files
+    ADD COLUMN scan_attempts INTEGER NOT NULL DEFAULT 0;
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+ALTER TABLE files
+    DROP COLUMN scan_attempts;
+
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+package apierrors
+
+// Antivirus error codes.
+const (
+	ErrFileQuarantined = "FILE_QUARANTINED"
+	ErrFileScanning    = "FILE_SCANNING"
+)
+
+// Upload integrity error codes.
+const (
+	ErrInvalidChecksum  = "INVALID_CHECKSUM"
+	ErrFileUploadFailed = "FILE_UPLOAD_FAILED"
+)
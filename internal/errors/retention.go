@@ -0,0 +1,11 @@
+package apierrors
+
+// Retention lock error codes.
+const (
+	ErrFileLocked                 = "FILE_LOCKED"
+	ErrFolderLocked               = "FOLDER_LOCKED"
+	ErrBucketLocked               = "BUCKET_LOCKED"
+	ErrInvalidRetainUntil         = "INVALID_RETAIN_UNTIL"
+	ErrRetentionCannotBeShortened = "RETENTION_CANNOT_BE_SHORTENED"
+	ErrFileNotLockable            = "FILE_NOT_LOCKABLE"
+)
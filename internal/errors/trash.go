@@ -17,3 +17,8 @@ const (
 	ErrFileTrashExpired   = "FILE_TRASH_EXPIRED"
 	ErrFolderTrashExpired = "FOLDER_TRASH_EXPIRED"
 )
+
+// Trash retention error codes - HTTP 400 Bad Request.
+const (
+	ErrTrashRetentionOutOfBounds = "TRASH_RETENTION_OUT_OF_BOUNDS"
+)
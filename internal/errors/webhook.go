@@ -0,0 +1,6 @@
+package apierrors
+
+// Webhook error codes - HTTP 400 Bad Request.
+const (
+	ErrWebhookURLNotAllowed = "WEBHOOK_URL_NOT_ALLOWED"
+)
@@ -9,6 +9,7 @@ import (
 	c "api/internal/configuration"
 	"api/internal/messaging"
 	"api/internal/models"
+	"api/internal/sql"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -68,6 +69,18 @@ func (e *BucketPurge) callback(params *EventParams) error {
 		zap.String("bucket_id", e.Payload.BucketID.String()),
 	)
 
+	locked, err := sql.IsRetentionLocked(params.DB, e.Payload.BucketID, nil)
+	if err != nil {
+		zap.L().Error("Failed to check the retention locks of the bucket", zap.Error(err))
+		return err
+	}
+	if locked {
+		// The bucket is kept until its locks are released or expire
+		zap.L().Warn("Bucket under a retention lock, skipping purge",
+			zap.String("bucket_id", e.Payload.BucketID.String()))
+		return nil
+	}
+
 	if !e.deleteRootFiles(params) {
 		return errors.New("remaining files to delete")
 	}
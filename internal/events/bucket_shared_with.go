@@ -6,6 +6,7 @@ import (
 
 	"api/internal/messaging"
 	"api/internal/models"
+	"api/internal/sql"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -67,6 +68,7 @@ func (e *BucketSharedWith) callback(params *EventParams) error {
 	subject := fmt.Sprintf("%s has shared a bucket with you", e.Payload.From)
 	err := params.Notifier.NotifyFromTemplate(
 		e.Payload.To,
+		sql.GetUserLanguage(params.DB, e.Payload.To),
 		subject,
 		"bucket_shared_with",
 		e.Payload,
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"api/internal/messaging"
+	"api/internal/sql"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -71,7 +72,8 @@ func (e *ChallengeUserInvite) Trigger() {
 func (e *ChallengeUserInvite) callback(params *EventParams) error {
 	e.Payload.WebURL = params.WebURL
 	subject := fmt.Sprintf("%s has invited you", e.Payload.From)
-	err := params.Notifier.NotifyFromTemplate(e.Payload.To, subject, "user_invited", e.Payload)
+	language := sql.GetUserLanguage(params.DB, e.Payload.To)
+	err := params.Notifier.NotifyFromTemplate(e.Payload.To, language, subject, "user_invited", e.Payload)
 	if err != nil {
 		zap.L().Error("failed to notify", zap.Any("event", e), zap.Error(err))
 		return err
@@ -135,7 +137,8 @@ func (e *PasswordResetChallengeEvent) Trigger() {
 func (e *PasswordResetChallengeEvent) callback(params *EventParams) error {
 	e.Payload.WebURL = params.WebURL
 	subject := "Password Reset Request"
-	err := params.Notifier.NotifyFromTemplate(e.Payload.To, subject, "password_reset", e.Payload)
+	language := sql.GetUserLanguage(params.DB, e.Payload.To)
+	err := params.Notifier.NotifyFromTemplate(e.Payload.To, language, subject, "password_reset", e.Payload)
 	if err != nil {
 		zap.L().Error("failed to notify", zap.Any("event", e), zap.Error(err))
 		return err
@@ -197,6 +200,7 @@ func (e *PasswordResetSuccessEvent) callback(params *EventParams) error {
 	subject := "Password Reset Successful"
 	err := params.Notifier.NotifyFromTemplate(
 		e.Payload.Email,
+		sql.GetUserLanguage(params.DB, e.Payload.Email),
 		subject,
 		"password_reset_success",
 		e.Payload,
@@ -257,7 +261,8 @@ func (e *UserWelcomeEvent) Trigger() {
 func (e *UserWelcomeEvent) callback(params *EventParams) error {
 	e.Payload.WebURL = params.WebURL
 	subject := "Welcome to Safebucket!"
-	err := params.Notifier.NotifyFromTemplate(e.Payload.Email, subject, "user_welcome", e.Payload)
+	language := sql.GetUserLanguage(params.DB, e.Payload.Email)
+	err := params.Notifier.NotifyFromTemplate(e.Payload.Email, language, subject, "user_welcome", e.Payload)
 	if err != nil {
 		zap.L().Error("failed to notify", zap.Any("event", e), zap.Error(err))
 		return err
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"path"
+
+	apierrors "api/internal/errors"
+	"api/internal/messaging"
+	"api/internal/models"
+	"api/internal/sql"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	FileChecksumVerificationName        = "FileChecksumVerification"
+	FileChecksumVerificationPayloadName = "FileChecksumVerificationPayload"
+)
+
+type FileChecksumVerificationPayload struct {
+	Type     string
+	BucketID uuid.UUID
+	FileID   uuid.UUID
+	UserID   string
+}
+
+// FileChecksumVerification computes the checksum of an uploaded file the storage does not report,
+// out of the handling of the bucket events, then completes the upload. A failed computation is retried.
+type FileChecksumVerification struct {
+	Publisher messaging.IPublisher
+	Payload   FileChecksumVerificationPayload
+}
+
+func NewFileChecksumVerification(
+	publisher messaging.IPublisher,
+	bucketID uuid.UUID,
+	fileID uuid.UUID,
+	userID string,
+) FileChecksumVerification {
+	return FileChecksumVerification{
+		Publisher: publisher,
+		Payload: FileChecksumVerificationPayload{
+			Type:     FileChecksumVerificationName,
+			BucketID: bucketID,
+			FileID:   fileID,
+			UserID:   userID,
+		},
+	}
+}
+
+func (e *FileChecksumVerification) Trigger() {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		zap.L().Error("Error marshalling file checksum verification event payload", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("type", e.Payload.Type)
+	err = e.Publisher.Publish(msg)
+	if err != nil {
+		zap.L().Error("failed to trigger file checksum verification event", zap.Error(err))
+	}
+}
+
+func (e *FileChecksumVerification) callback(params *EventParams) error {
+	file, err := sql.GetFileByID(params.DB, e.Payload.BucketID, e.Payload.FileID)
+	if err != nil {
+		var apiErr *apierrors.APIError
+		if errors.As(err, &apiErr) {
+			// The file has been deleted in the meantime
+			return nil
+		}
+		zap.L().Error("Failed to fetch file to verify", zap.Error(err))
+		return err
+	}
+
+	declared := file.GetChecksum()
+	if file.Status != models.FileStatusUploading || declared == nil {
+		return nil
+	}
+
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+	actual, err := computeChecksum(params.Storage, objectPath, declared.Algorithm)
+	if err != nil {
+		zap.L().Error("Failed to compute the checksum of the file",
+			zap.String("file_id", file.ID.String()),
+			zap.Error(err))
+		return err
+	}
+
+	checksum, reason, err := compareChecksum(declared, actual)
+	if err != nil {
+		return err
+	}
+	return completeVerifiedUpload(params, file, checksum, reason, e.Payload.UserID)
+}
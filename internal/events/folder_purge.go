@@ -10,6 +10,7 @@ import (
 	"api/internal/messaging"
 	"api/internal/models"
 	"api/internal/rbac"
+	"api/internal/sql"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -87,7 +88,19 @@ func (e *FolderPurge) callback(params *EventParams) error {
 		return errors.New("folder not in trash")
 	}
 
-	err := params.DB.Transaction(func(tx *gorm.DB) error {
+	locked, err := sql.IsRetentionLocked(params.DB, e.Payload.BucketID, &folder.ID)
+	if err != nil {
+		zap.L().Error("Failed to check the retention locks of the folder", zap.Error(err))
+		return err
+	}
+	if locked {
+		// The folder stays in the trash until its locks are released or expire
+		zap.L().Warn("Folder holds a file under a retention lock, skipping purge",
+			zap.String("folder_id", folder.ID.String()))
+		return nil
+	}
+
+	err = params.DB.Transaction(func(tx *gorm.DB) error {
 		var childFolders []models.Folder
 		if err := tx.Unscoped().Where(
 			"bucket_id = ? AND folder_id = ? AND deleted_at IS NOT NULL",
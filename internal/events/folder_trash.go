@@ -10,6 +10,7 @@ import (
 	"api/internal/messaging"
 	"api/internal/models"
 	"api/internal/rbac"
+	"api/internal/sql"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -94,6 +95,12 @@ func (e *FolderTrash) callback(params *EventParams) error {
 			return errors.New("folder not trashed")
 		}
 
+		retentionDays, err := sql.GetBucketTrashRetention(tx, e.Payload.BucketID, params.TrashRetentionDays)
+		if err != nil {
+			zap.L().Error("Failed to get the trash retention of the bucket", zap.Error(err))
+			return err
+		}
+
 		var childFolders []models.Folder
 		if err := tx.Where(
 			"bucket_id = ? AND folder_id = ?",
@@ -120,7 +127,7 @@ func (e *FolderTrash) callback(params *EventParams) error {
 				childFolderIDs = append(childFolderIDs, child.ID)
 
 				folderPath := path.Join("buckets", e.Payload.BucketID.String(), child.ID.String())
-				if err := params.Storage.MarkAsTrashed(folderPath, child); err != nil {
+				if err := params.Storage.MarkAsTrashed(folderPath, child, retentionDays); err != nil {
 					zap.L().Warn("Failed to mark child folder as trashed in storage",
 						zap.Error(err),
 						zap.String("folder_id", child.ID.String()))
@@ -168,7 +175,7 @@ func (e *FolderTrash) callback(params *EventParams) error {
 				fileIDs = append(fileIDs, child.ID)
 
 				filePath := path.Join("buckets", e.Payload.BucketID.String(), child.ID.String())
-				if err := params.Storage.MarkAsTrashed(filePath, child); err != nil {
+				if err := params.Storage.MarkAsTrashed(filePath, child, retentionDays); err != nil {
 					zap.L().Warn("Failed to mark file as trashed in storage",
 						zap.Error(err),
 						zap.String("file_id", child.ID.String()))
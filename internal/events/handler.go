@@ -2,14 +2,18 @@ package events
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 
 	"api/internal/activity"
+	"api/internal/antivirus"
+	c "api/internal/configuration"
 	"api/internal/messaging"
 	"api/internal/models"
 	"api/internal/notifier"
 	"api/internal/rbac"
+	"api/internal/realtime"
 	"api/internal/sql"
 	"api/internal/storage"
 
@@ -21,11 +25,15 @@ import (
 
 type EventParams struct {
 	WebURL             string
+	APIURL             string
 	Notifier           notifier.INotifier
 	Publisher          messaging.IPublisher
 	DB                 *gorm.DB
 	Storage            storage.IStorage
 	ActivityLogger     activity.IActivityLogger
+	Scanner            antivirus.IScanner
+	BucketHub          realtime.IBucketHub
+	WebhookClient      *WebhookClient
 	TrashRetentionDays int
 }
 
@@ -91,12 +99,24 @@ func HandleEvents(params *EventParams, messages <-chan *message.Message) {
 
 func HandleBucketEvents(
 	subscriber messaging.ISubscriber,
+	publisher messaging.IPublisher,
+	bucketHub realtime.IBucketHub,
 	db *gorm.DB,
 	activityLogger activity.IActivityLogger,
 	storage storage.IStorage,
+	scanner antivirus.IScanner,
 	trashRetentionDays int,
 	messages <-chan *message.Message,
 ) {
+	uploadParams := &EventParams{
+		Publisher:      publisher,
+		DB:             db,
+		Storage:        storage,
+		ActivityLogger: activityLogger,
+		Scanner:        scanner,
+		BucketHub:      bucketHub,
+	}
+
 	for msg := range messages {
 		zap.L().
 			Debug("message received", zap.Any("raw_payload", string(msg.Payload)), zap.Any("metadata", msg.Metadata))
@@ -127,23 +147,10 @@ func HandleBucketEvents(
 					continue
 				}
 
-				db.Model(&file).Update("status", models.FileStatusUploaded)
-
-				action := models.Activity{
-					Message: activity.FileUploaded,
-					Object:  file.ToActivity(),
-					Filter: activity.NewLogFilter(map[string]string{
-						"action":      rbac.ActionCreate.String(),
-						"object_type": rbac.ResourceFile.String(),
-						"file_id":     event.FileID,
-						"bucket_id":   event.BucketID,
-						"user_id":     event.UserID,
-					}),
-				}
-
-				err = activityLogger.Send(action)
-				if err != nil {
-					zap.L().Error("failed to send activity", zap.Error(err))
+				if err = CompleteUpload(uploadParams, file, event.UserID); err != nil {
+					zap.L().Error("Failed to complete upload",
+						zap.String("file_id", file.ID.String()),
+						zap.Error(err))
 				}
 			}
 
@@ -181,3 +188,134 @@ func HandleBucketEvents(
 		msg.Ack()
 	}
 }
+
+// CompleteUpload makes an uploaded file available: its content is verified against the declared size
+// and checksum, then scanned when an antivirus is configured. A file completed twice is ignored.
+// When the storage does not report the declared checksum, the file is completed once the checksum
+// is computed by the FileChecksumVerification event, up to a maximum size.
+func CompleteUpload(params *EventParams, file models.File, uploaderID string) error {
+	if file.Status != models.FileStatusUploading {
+		return nil
+	}
+
+	checksum, reason, err := verifyUpload(params.Storage, file)
+	if errors.Is(err, errChecksumNotReported) {
+		if file.Size > c.UploadChecksumComputeMaxBytes {
+			reason = fmt.Sprintf("%s checksum cannot be verified above %d bytes",
+				*file.ChecksumAlgorithm, c.UploadChecksumComputeMaxBytes)
+			failUpload(params.DB, params.Storage, params.BucketHub, params.ActivityLogger, file, uploaderID, reason)
+			return nil
+		}
+
+		verification := NewFileChecksumVerification(params.Publisher, file.BucketID, file.ID, uploaderID)
+		verification.Trigger()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return completeVerifiedUpload(params, file, checksum, reason, uploaderID)
+}
+
+// completeVerifiedUpload fails an upload whose content does not match, or makes it available, or scans it first.
+func completeVerifiedUpload(
+	params *EventParams,
+	file models.File,
+	checksum *models.FileChecksum,
+	reason string,
+	uploaderID string,
+) error {
+	if reason != "" {
+		failUpload(params.DB, params.Storage, params.BucketHub, params.ActivityLogger, file, uploaderID, reason)
+		return nil
+	}
+
+	status := models.FileStatusUploaded
+	if params.Scanner != nil {
+		// The file is only available once scanned
+		status = models.FileStatusScanning
+	}
+	updates := map[string]interface{}{"status": status}
+	if checksum != nil {
+		updates["checksum_algorithm"] = checksum.Algorithm
+		updates["checksum"] = checksum.Value
+	}
+
+	result := params.DB.Model(&models.File{}).
+		Where("id = ? AND status = ?", file.ID, models.FileStatusUploading).
+		Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	if params.Scanner != nil {
+		scanEvent := NewFileScan(params.Publisher, file.BucketID, file.ID, uploaderID)
+		scanEvent.Trigger()
+		return nil
+	}
+
+	file.Status = status
+	if checksum != nil {
+		file.ChecksumAlgorithm = &checksum.Algorithm
+		file.Checksum = &checksum.Value
+	}
+	publishFileUploaded(params.Publisher, params.BucketHub, params.ActivityLogger, file, uploaderID)
+	return nil
+}
+
+// publishFileUploaded records the upload of a file once it is available, and notifies the webhooks,
+// the members and the users viewing the bucket.
+func publishFileUploaded(
+	publisher messaging.IPublisher,
+	bucketHub realtime.IBucketHub,
+	activityLogger activity.IActivityLogger,
+	file models.File,
+	uploaderID string,
+) {
+	action := models.Activity{
+		Message: activity.FileUploaded,
+		Object:  file.ToActivity(),
+		Filter: activity.NewLogFilter(map[string]string{
+			"action":      rbac.ActionCreate.String(),
+			"object_type": rbac.ResourceFile.String(),
+			"file_id":     file.ID.String(),
+			"bucket_id":   file.BucketID.String(),
+			"user_id":     uploaderID,
+		}),
+	}
+
+	if err := activityLogger.Send(action); err != nil {
+		zap.L().Error("failed to send activity", zap.Error(err))
+	}
+
+	var userID *uuid.UUID
+	if parsedUserID, parseErr := uuid.Parse(uploaderID); parseErr == nil {
+		userID = &parsedUserID
+	}
+	dispatchEvent := NewWebhookDispatch(
+		publisher,
+		file.BucketID,
+		userID,
+		models.WebhookEventFileUploaded,
+		file.ToActivity(),
+	)
+	dispatchEvent.Trigger()
+
+	if userID != nil {
+		notificationEvent := NewBucketActivityNotification(
+			publisher,
+			file.BucketID,
+			*userID,
+			models.NotificationEventFileUploaded,
+			file.FolderID,
+			file.Name,
+		)
+		notificationEvent.Trigger()
+	}
+
+	bucketHub.Publish(models.NewFileEvent(models.BucketEventFileUploaded, file, userID))
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"bytes"
+	"crypto/md5" // #nosec G501 -- MD5 is only used to verify the checksum declared by the client
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"path"
+
+	"api/internal/activity"
+	"api/internal/models"
+	"api/internal/rbac"
+	"api/internal/realtime"
+	"api/internal/storage"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// checksumPreference is the order in which the checksums reported by the storage are stored,
+// when the client did not declare one.
+var checksumPreference = []models.ChecksumAlgorithm{
+	models.ChecksumAlgorithmSHA256,
+	models.ChecksumAlgorithmCRC32C,
+	models.ChecksumAlgorithmMD5,
+}
+
+// errChecksumNotReported is returned by verifyUpload when the storage does not report the declared checksum,
+// which is then computed from the content by the FileChecksumVerification event.
+var errChecksumNotReported = errors.New("checksum not reported by the storage")
+
+// verifyUpload checks the uploaded object matches the size and the checksum declared for the file.
+// It returns the checksum of the content, and the reason of the mismatch when the upload is not intact.
+func verifyUpload(store storage.IStorage, file models.File) (*models.FileChecksum, string, error) {
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+	object, err := store.StatObject(objectPath)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if object.Size != int64(file.Size) {
+		return nil, fmt.Sprintf("%d bytes uploaded instead of %d", object.Size, file.Size), nil
+	}
+
+	declared := file.GetChecksum()
+	if declared == nil {
+		for _, algorithm := range checksumPreference {
+			if value, ok := object.Checksums[algorithm]; ok {
+				return &models.FileChecksum{Algorithm: algorithm, Value: value}, "", nil
+			}
+		}
+		return nil, "", nil
+	}
+
+	actual, ok := object.Checksums[declared.Algorithm]
+	if !ok {
+		return nil, "", errChecksumNotReported
+	}
+	return compareChecksum(declared, actual)
+}
+
+// compareChecksum checks the checksum of the content matches the declared one.
+func compareChecksum(declared *models.FileChecksum, actual string) (*models.FileChecksum, string, error) {
+	if !sameChecksum(declared.Value, actual) {
+		return nil, fmt.Sprintf("%s checksum mismatch", declared.Algorithm), nil
+	}
+	return declared, "", nil
+}
+
+// computeChecksum streams the content of an object to compute its checksum, encoded in base64.
+func computeChecksum(store storage.IStorage, objectPath string, algorithm models.ChecksumAlgorithm) (string, error) {
+	var digest hash.Hash
+	switch algorithm {
+	case models.ChecksumAlgorithmSHA256:
+		digest = sha256.New()
+	case models.ChecksumAlgorithmCRC32C:
+		digest = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case models.ChecksumAlgorithmMD5:
+		digest = md5.New() // #nosec G401
+	default:
+		return "", fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+	}
+
+	content, err := store.GetObject(objectPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = content.Close() }()
+
+	if _, err = io.Copy(digest, content); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(digest.Sum(nil)), nil
+}
+
+// sameChecksum compares the decoded checksums, as the padding of the encoding may differ.
+func sameChecksum(expected string, actual string) bool {
+	expectedDigest, err := base64.StdEncoding.DecodeString(expected)
+	if err != nil {
+		return false
+	}
+	actualDigest, err := base64.StdEncoding.DecodeString(actual)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(expectedDigest, actualDigest)
+}
+
+// failUpload marks a file whose content does not match the declared size or checksum as failed,
+// and removes the uploaded object. The file can then be purged and uploaded again.
+func failUpload(
+	db *gorm.DB,
+	store storage.IStorage,
+	bucketHub realtime.IBucketHub,
+	activityLogger activity.IActivityLogger,
+	file models.File,
+	uploaderID string,
+	reason string,
+) {
+	zap.L().Warn("Uploaded file does not match the declared content",
+		zap.String("file_id", file.ID.String()),
+		zap.String("bucket_id", file.BucketID.String()),
+		zap.String("reason", reason))
+
+	// An upload event delivered twice is ignored
+	result := db.Model(&models.File{}).
+		Where("id = ? AND status = ?", file.ID, models.FileStatusUploading).
+		Update("status", models.FileStatusFailed)
+	if result.Error != nil {
+		zap.L().Error("Failed to update file status", zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+	file.Status = models.FileStatusFailed
+
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+	if err := store.RemoveObject(objectPath); err != nil {
+		zap.L().Warn("Failed to remove the object of a failed upload",
+			zap.String("path", objectPath),
+			zap.Error(err))
+	}
+
+	action := models.Activity{
+		Message: activity.FileUploadFailed,
+		Object: models.FileUploadFailedActivity{
+			ID:     file.ID,
+			Name:   file.Name,
+			Reason: reason,
+		},
+		Filter: activity.NewLogFilter(map[string]string{
+			"action":      rbac.ActionCreate.String(),
+			"object_type": rbac.ResourceFile.String(),
+			"file_id":     file.ID.String(),
+			"bucket_id":   file.BucketID.String(),
+			"user_id":     uploaderID,
+		}),
+	}
+	if err := activityLogger.Send(action); err != nil {
+		zap.L().Error("failed to send activity", zap.Error(err))
+	}
+
+	var userID *uuid.UUID
+	if parsedUserID, parseErr := uuid.Parse(uploaderID); parseErr == nil {
+		userID = &parsedUserID
+	}
+	bucketHub.Publish(models.NewFileEvent(models.BucketEventFileFailed, file, userID))
+}
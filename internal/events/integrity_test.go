@@ -0,0 +1,193 @@
+package events
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"api/internal/models"
+	"api/internal/storage"
+	"api/internal/tests"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const uploadedContent = "safebucket"
+
+func sha256Checksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func uploadedFile(algorithm models.ChecksumAlgorithm, checksum string) models.File {
+	return models.File{
+		ID:                uuid.New(),
+		BucketID:          uuid.New(),
+		Name:              "invoice.pdf",
+		Status:            models.FileStatusUploading,
+		Size:              len(uploadedContent),
+		ChecksumAlgorithm: &algorithm,
+		Checksum:          &checksum,
+	}
+}
+
+func objectPath(file models.File) string {
+	return "buckets/" + file.BucketID.String() + "/" + file.ID.String()
+}
+
+func TestVerifyUpload(t *testing.T) {
+	matching := sha256Checksum(uploadedContent)
+	other := sha256Checksum("other content")
+
+	testCases := []struct {
+		name           string
+		declared       string
+		object         storage.ObjectInfo
+		expectedReason string
+		expectedErr    error
+	}{
+		{
+			name:     "Reported checksum matches",
+			declared: matching,
+			object: storage.ObjectInfo{
+				Size:      int64(len(uploadedContent)),
+				Checksums: map[models.ChecksumAlgorithm]string{models.ChecksumAlgorithmSHA256: matching},
+			},
+		},
+		{
+			name:     "Reported checksum does not match",
+			declared: other,
+			object: storage.ObjectInfo{
+				Size:      int64(len(uploadedContent)),
+				Checksums: map[models.ChecksumAlgorithm]string{models.ChecksumAlgorithmSHA256: matching},
+			},
+			expectedReason: "sha256 checksum mismatch",
+		},
+		{
+			name:     "Checksum not reported by the storage",
+			declared: matching,
+			object: storage.ObjectInfo{
+				Size:      int64(len(uploadedContent)),
+				Checksums: map[models.ChecksumAlgorithm]string{models.ChecksumAlgorithmMD5: "1B2M2Y8AsgTpgAmY7PhCfg=="},
+			},
+			expectedErr: errChecksumNotReported,
+		},
+		{
+			name:           "Size does not match",
+			declared:       matching,
+			object:         storage.ObjectInfo{Size: 3},
+			expectedReason: "3 bytes uploaded instead of 10",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			file := uploadedFile(models.ChecksumAlgorithmSHA256, tt.declared)
+			store := &tests.MockStorage{}
+			store.On("StatObject", objectPath(file)).Return(tt.object, nil)
+
+			checksum, reason, err := verifyUpload(store, file)
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedReason, reason)
+			if tt.expectedReason == "" {
+				expected := &models.FileChecksum{Algorithm: models.ChecksumAlgorithmSHA256, Value: tt.declared}
+				assert.Equal(t, expected, checksum)
+			}
+		})
+	}
+}
+
+func TestCompleteUploadWithoutReportedChecksum(t *testing.T) {
+	file := uploadedFile(models.ChecksumAlgorithmSHA256, sha256Checksum(uploadedContent))
+
+	store := &tests.MockStorage{}
+	store.On("StatObject", objectPath(file)).Return(storage.ObjectInfo{Size: int64(len(uploadedContent))}, nil)
+
+	// The checksum is computed by its own event, instead of in the handling of the bucket events
+	publisher := &tests.MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+
+	require.NoError(t, CompleteUpload(&EventParams{Storage: store, Publisher: publisher}, file, ""))
+
+	publisher.AssertNumberOfCalls(t, "Publish", 1)
+	store.AssertNotCalled(t, "GetObject", mock.Anything)
+}
+
+func TestFileChecksumVerification(t *testing.T) {
+	testCases := []struct {
+		name     string
+		declared string
+		failed   bool
+	}{
+		{"Computed checksum matches", sha256Checksum(uploadedContent), false},
+		{"Computed checksum does not match", sha256Checksum("other content"), true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			file := uploadedFile(models.ChecksumAlgorithmSHA256, tt.declared)
+			db, dbMock := newMockDB(t)
+
+			rows := sqlmock.NewRows([]string{
+				"id", "bucket_id", "name", "status", "size", "checksum_algorithm", "checksum",
+			}).AddRow(file.ID, file.BucketID, file.Name, file.Status, file.Size, *file.ChecksumAlgorithm, tt.declared)
+			dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE (id = $1 AND bucket_id = $2)`)).
+				WillReturnRows(rows)
+			dbMock.ExpectBegin()
+			if tt.failed {
+				dbMock.ExpectExec(regexp.QuoteMeta(`UPDATE "files" SET "status"=$1`)).
+					WithArgs(models.FileStatusFailed, sqlmock.AnyArg(), file.ID, models.FileStatusUploading).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			} else {
+				query := `UPDATE "files" SET "checksum"=$1,"checksum_algorithm"=$2,"status"=$3`
+				dbMock.ExpectExec(regexp.QuoteMeta(query)).
+					WithArgs(tt.declared, models.ChecksumAlgorithmSHA256, models.FileStatusScanning, sqlmock.AnyArg(),
+						file.ID, models.FileStatusUploading).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			dbMock.ExpectCommit()
+
+			store := &tests.MockStorage{}
+			store.On("GetObject", objectPath(file)).Return(io.NopCloser(strings.NewReader(uploadedContent)), nil)
+			publisher := &tests.MockPublisher{}
+			activityLogger := &activityLoggerMock{}
+			bucketHub := &tests.MockBucketHub{}
+			if tt.failed {
+				store.On("RemoveObject", objectPath(file)).Return(nil)
+				activityLogger.On("Send", mock.Anything).Return(nil)
+				bucketHub.On("Publish", mock.Anything).Return()
+			} else {
+				// The file is then scanned
+				publisher.On("Publish", mock.Anything).Return(nil)
+			}
+
+			params := &EventParams{
+				DB:             db,
+				Storage:        store,
+				Publisher:      publisher,
+				Scanner:        &tests.MockScanner{},
+				ActivityLogger: activityLogger,
+				BucketHub:      bucketHub,
+			}
+
+			event := NewFileChecksumVerification(publisher, file.BucketID, file.ID, "")
+			require.NoError(t, event.callback(params))
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+			store.AssertExpectations(t)
+			publisher.AssertExpectations(t)
+			activityLogger.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"api/internal/messaging"
+	"api/internal/models"
+	"api/internal/sql"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"go.uber.org/zap"
+)
+
+const (
+	MembershipExpiryName        = "MembershipExpiry"
+	MembershipExpiryPayloadName = "MembershipExpiryPayload"
+)
+
+type MembershipExpiryPayload struct {
+	Type      string
+	To        string
+	Member    string
+	Group     models.Group
+	Bucket    models.Bucket
+	ExpiresAt time.Time
+	Expired   bool
+	WebURL    string
+}
+
+// MembershipExpiry notifies a bucket owner that a time-limited membership
+// is about to expire, or has expired and been removed.
+type MembershipExpiry struct {
+	Publisher messaging.IPublisher
+	Payload   MembershipExpiryPayload
+}
+
+func NewMembershipExpiry(
+	publisher messaging.IPublisher,
+	to string,
+	member string,
+	group models.Group,
+	bucket models.Bucket,
+	expiresAt time.Time,
+	expired bool,
+) MembershipExpiry {
+	return MembershipExpiry{
+		Publisher: publisher,
+		Payload: MembershipExpiryPayload{
+			Type:      MembershipExpiryName,
+			To:        to,
+			Member:    member,
+			Group:     group,
+			Bucket:    bucket,
+			ExpiresAt: expiresAt,
+			Expired:   expired,
+		},
+	}
+}
+
+func (e *MembershipExpiry) Trigger() {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		zap.L().Error("Error marshalling event payload", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("type", e.Payload.Type)
+	err = e.Publisher.Publish(msg)
+	if err != nil {
+		zap.L().Error("failed to trigger event", zap.Error(err))
+	}
+}
+
+func (e *MembershipExpiry) callback(params *EventParams) error {
+	e.Payload.WebURL = params.WebURL
+
+	subject := fmt.Sprintf("Access of %s to %s expires soon", e.Payload.Member, e.Payload.Bucket.Name)
+	template := "membership_expiring"
+	if e.Payload.Expired {
+		subject = fmt.Sprintf("Access of %s to %s has expired", e.Payload.Member, e.Payload.Bucket.Name)
+		template = "membership_expired"
+	}
+
+	language := sql.GetUserLanguage(params.DB, e.Payload.To)
+	err := params.Notifier.NotifyFromTemplate(e.Payload.To, language, subject, template, e.Payload)
+	if err != nil {
+		zap.L().Error("failed to notify", zap.Any("event", e), zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,483 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"api/internal/activity"
+	c "api/internal/configuration"
+	"api/internal/messaging"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	BucketActivityNotificationName        = "BucketActivityNotification"
+	BucketActivityNotificationPayloadName = "BucketActivityNotificationPayload"
+	NotificationDigestName                = "NotificationDigest"
+	NotificationDigestPayloadName         = "NotificationDigestPayload"
+)
+
+// notificationMessages maps the activity messages to the notification events they belong to.
+var notificationMessages = map[string]string{
+	activity.FileUploaded:        models.NotificationEventFileUploaded,
+	activity.FileTrashed:         models.NotificationEventFileDeleted,
+	activity.FilePurged:          models.NotificationEventFileDeleted,
+	activity.BucketMemberCreated: models.NotificationEventMemberCreated,
+}
+
+// BucketActivityItem is an activity of a bucket, as shown in the notifications.
+type BucketActivityItem struct {
+	Event      string
+	Actor      string
+	ObjectName string
+	OccurredAt time.Time
+}
+
+// BucketActivityData is the data of the notification of an activity, and of each bucket of a digest.
+type BucketActivityData struct {
+	Bucket         models.Bucket
+	BucketURL      string
+	Activity       []BucketActivityItem
+	WebURL         string
+	UnsubscribeURL string
+}
+
+// ListUnsubscribeURL returns the unsubscribe link of the notification, set in the headers of the email.
+func (d BucketActivityData) ListUnsubscribeURL() string {
+	return d.UnsubscribeURL
+}
+
+type BucketActivityNotificationPayload struct {
+	Type       string
+	BucketID   uuid.UUID
+	UserID     uuid.UUID
+	EventType  string
+	FolderID   *uuid.UUID
+	ObjectName string
+	OccurredAt time.Time
+}
+
+// BucketActivityNotification emails the members of a bucket who opted in to be notified immediately of an activity,
+// except the member who performed it.
+type BucketActivityNotification struct {
+	Publisher messaging.IPublisher
+	Payload   BucketActivityNotificationPayload
+}
+
+func NewBucketActivityNotification(
+	publisher messaging.IPublisher,
+	bucketID uuid.UUID,
+	userID uuid.UUID,
+	eventType string,
+	folderID *uuid.UUID,
+	objectName string,
+) BucketActivityNotification {
+	return BucketActivityNotification{
+		Publisher: publisher,
+		Payload: BucketActivityNotificationPayload{
+			Type:       BucketActivityNotificationName,
+			BucketID:   bucketID,
+			UserID:     userID,
+			EventType:  eventType,
+			FolderID:   folderID,
+			ObjectName: objectName,
+			OccurredAt: time.Now().UTC(),
+		},
+	}
+}
+
+func (e *BucketActivityNotification) Trigger() {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		zap.L().Error("Error marshalling bucket activity notification payload", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("type", e.Payload.Type)
+	err = e.Publisher.Publish(msg)
+	if err != nil {
+		zap.L().Error("failed to trigger bucket activity notification event", zap.Error(err))
+	}
+}
+
+func (e *BucketActivityNotification) callback(params *EventParams) error {
+	var bucket models.Bucket
+	if err := params.DB.Where("id = ?", e.Payload.BucketID).First(&bucket).Error; err != nil {
+		// The bucket has been deleted in the meantime
+		return nil
+	}
+
+	// Expired memberships are removed by a job, they are skipped until then
+	var memberIDs []uuid.UUID
+	err := params.DB.Model(&models.Membership{}).
+		Where("bucket_id = ? AND user_id <> ?", e.Payload.BucketID, e.Payload.UserID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Pluck("user_id", &memberIDs).Error
+	if err != nil {
+		zap.L().Error("Failed to fetch bucket members", zap.Error(err))
+		return err
+	}
+
+	preferences, err := bucketPreferences(params.DB, e.Payload.BucketID, memberIDs)
+	if err != nil {
+		zap.L().Error("Failed to fetch notification preferences", zap.Error(err))
+		return err
+	}
+
+	item := BucketActivityItem{
+		Event:      e.Payload.EventType,
+		Actor:      userDisplayName(params.DB, map[string]string{}, e.Payload.UserID.String()),
+		ObjectName: e.Payload.ObjectName,
+		OccurredAt: e.Payload.OccurredAt,
+	}
+	subject := fmt.Sprintf("New activity on %s", bucket.Name)
+
+	// A failed recipient is not retried, so that the other recipients are not notified twice
+	for _, preference := range preferences {
+		if preference.Frequency != models.NotificationFrequencyImmediate ||
+			!slices.Contains(preference.EventTypes, e.Payload.EventType) {
+			continue
+		}
+
+		// The new member is already told by the invitation or the share email
+		if e.Payload.EventType == models.NotificationEventMemberCreated &&
+			preference.User.Email == e.Payload.ObjectName {
+			continue
+		}
+
+		access, err := rbac.LoadFolderAccess(params.DB, preference.UserID, e.Payload.BucketID)
+		if err != nil || !access.Has(e.Payload.FolderID, models.GroupViewer) {
+			continue
+		}
+
+		data := BucketActivityData{
+			Bucket:         bucket,
+			BucketURL:      fmt.Sprintf("%s/buckets/%s", params.WebURL, bucket.ID),
+			Activity:       []BucketActivityItem{item},
+			WebURL:         params.WebURL,
+			UnsubscribeURL: unsubscribeURL(params.APIURL, preference),
+		}
+		err = params.Notifier.NotifyFromTemplate(
+			preference.User.Email,
+			preference.User.Language,
+			subject,
+			"bucket_activity",
+			data,
+		)
+		if err != nil {
+			zap.L().Error("failed to notify bucket activity",
+				zap.String("user_id", preference.UserID.String()),
+				zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// NotificationDigestData is the data of a digest. Truncated tells the digest only holds the latest
+// MaxEntries activities of the period.
+type NotificationDigestData struct {
+	Frequency      models.NotificationFrequency
+	From           time.Time
+	To             time.Time
+	Buckets        []BucketActivityData
+	Truncated      bool
+	MaxEntries     int
+	WebURL         string
+	UnsubscribeURL string
+}
+
+// ListUnsubscribeURL returns the unsubscribe link of the digest, set in the headers of the email.
+func (d NotificationDigestData) ListUnsubscribeURL() string {
+	return d.UnsubscribeURL
+}
+
+type NotificationDigestPayload struct {
+	Type         string
+	PreferenceID uuid.UUID
+	From         time.Time
+	To           time.Time
+}
+
+// NotificationDigest emails a user the activity of a period on the buckets covered by a digest preference.
+// Nothing is sent when there was no activity.
+type NotificationDigest struct {
+	Publisher messaging.IPublisher
+	Payload   NotificationDigestPayload
+}
+
+func NewNotificationDigest(
+	publisher messaging.IPublisher,
+	preferenceID uuid.UUID,
+	from time.Time,
+	to time.Time,
+) NotificationDigest {
+	return NotificationDigest{
+		Publisher: publisher,
+		Payload: NotificationDigestPayload{
+			Type:         NotificationDigestName,
+			PreferenceID: preferenceID,
+			From:         from,
+			To:           to,
+		},
+	}
+}
+
+func (e *NotificationDigest) Trigger() {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		zap.L().Error("Error marshalling notification digest payload", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("type", e.Payload.Type)
+	err = e.Publisher.Publish(msg)
+	if err != nil {
+		zap.L().Error("failed to trigger notification digest event", zap.Error(err))
+	}
+}
+
+func (e *NotificationDigest) callback(params *EventParams) error {
+	var preference models.NotificationPreference
+	err := params.DB.Preload("User").Where("id = ?", e.Payload.PreferenceID).First(&preference).Error
+	if err != nil || !preference.Enabled {
+		// The preference has been deleted or disabled in the meantime
+		return nil
+	}
+
+	buckets, err := digestBuckets(params.DB, preference)
+	if err != nil {
+		zap.L().Error("Failed to fetch the buckets of the digest", zap.Error(err))
+		return err
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	bucketIDs := make([]string, 0, len(buckets))
+	for _, bucket := range buckets {
+		bucketIDs = append(bucketIDs, bucket.ID.String())
+	}
+
+	history, nextCursor, err := params.ActivityLogger.Search(activity.SearchCriteria{
+		Fields: map[string][]string{
+			"bucket_id": bucketIDs,
+			"action": {
+				rbac.ActionCreate.String(),
+				rbac.ActionErase.String(),
+				rbac.ActionPurge.String(),
+				rbac.ActionGrant.String(),
+			},
+		},
+		From:  e.Payload.From,
+		To:    e.Payload.To,
+		Limit: c.NotificationDigestMaxEntries,
+	})
+	if err != nil {
+		zap.L().Error("Failed to search the activity of the digest", zap.Error(err))
+		return err
+	}
+
+	digest := digestActivity(params, preference, buckets, history)
+	if len(digest) == 0 {
+		return nil
+	}
+
+	data := NotificationDigestData{
+		Frequency:      preference.Frequency,
+		From:           e.Payload.From,
+		To:             e.Payload.To,
+		Buckets:        digest,
+		Truncated:      nextCursor != "",
+		MaxEntries:     c.NotificationDigestMaxEntries,
+		WebURL:         params.WebURL,
+		UnsubscribeURL: unsubscribeURL(params.APIURL, preference),
+	}
+
+	subject := "Your Safebucket activity digest"
+	err = params.Notifier.NotifyFromTemplate(
+		preference.User.Email,
+		preference.User.Language,
+		subject,
+		"notification_digest",
+		data,
+	)
+	if err != nil {
+		zap.L().Error("failed to notify", zap.Any("event", e), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// digestActivity keeps the activity the user subscribed to, performed by others and visible to the user,
+// grouped by bucket.
+func digestActivity(
+	params *EventParams,
+	preference models.NotificationPreference,
+	buckets []models.Bucket,
+	history []map[string]interface{},
+) []BucketActivityData {
+	items := make(map[string][]BucketActivityItem, len(buckets))
+	accesses := make(map[string]*rbac.FolderAccess, len(buckets))
+	names := make(map[string]string)
+
+	for _, entry := range history {
+		message, _ := entry["message"].(string)
+		eventType, exists := notificationMessages[message]
+		if !exists || !slices.Contains(preference.EventTypes, eventType) {
+			continue
+		}
+
+		actorID, _ := entry["user_id"].(string)
+		if actorID == preference.UserID.String() {
+			continue
+		}
+
+		bucketID, _ := entry["bucket_id"].(string)
+		access, loaded := accesses[bucketID]
+		if !loaded {
+			if parsedBucketID, err := uuid.Parse(bucketID); err == nil {
+				access, _ = rbac.LoadFolderAccess(params.DB, preference.UserID, parsedBucketID)
+			}
+			accesses[bucketID] = access
+		}
+		if access == nil {
+			continue
+		}
+
+		item := BucketActivityItem{Event: eventType, Actor: userDisplayName(params.DB, names, actorID)}
+		if timestamp, ok := entry["timestamp"].(string); ok {
+			if nanoseconds, err := strconv.ParseInt(timestamp, 10, 64); err == nil {
+				item.OccurredAt = time.Unix(0, nanoseconds).UTC()
+			}
+		}
+
+		if eventType == models.NotificationEventMemberCreated {
+			item.ObjectName, _ = entry["bucket_member_email"].(string)
+			if item.ObjectName == preference.User.Email {
+				continue
+			}
+		} else {
+			// Purged files no longer exist, they are only shown to the users who can view the whole bucket
+			var file models.File
+			fileID, _ := entry["file_id"].(string)
+			params.DB.Unscoped().Select("id", "folder_id").Where("id = ?", fileID).Limit(1).Find(&file)
+			if !access.Has(file.FolderID, models.GroupViewer) {
+				continue
+			}
+			if object, ok := entry["object"].(map[string]interface{}); ok {
+				item.ObjectName, _ = object["name"].(string)
+			}
+		}
+
+		items[bucketID] = append(items[bucketID], item)
+	}
+
+	digest := make([]BucketActivityData, 0, len(items))
+	for _, bucket := range buckets {
+		bucketItems := items[bucket.ID.String()]
+		if len(bucketItems) == 0 {
+			continue
+		}
+		slices.Reverse(bucketItems)
+		digest = append(digest, BucketActivityData{
+			Bucket:    bucket,
+			BucketURL: fmt.Sprintf("%s/buckets/%s", params.WebURL, bucket.ID),
+			Activity:  bucketItems,
+		})
+	}
+	return digest
+}
+
+// bucketPreferences returns the preference applying to each user for a bucket: the preference of the bucket,
+// otherwise the preference for all buckets. Users without an enabled preference are left out.
+func bucketPreferences(
+	db *gorm.DB,
+	bucketID uuid.UUID,
+	userIDs []uuid.UUID,
+) ([]models.NotificationPreference, error) {
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	var preferences []models.NotificationPreference
+	err := db.Preload("User").
+		Where("user_id IN ? AND (bucket_id = ? OR bucket_id IS NULL)", userIDs, bucketID).
+		Find(&preferences).Error
+	if err != nil {
+		return nil, err
+	}
+
+	effective := make(map[uuid.UUID]models.NotificationPreference, len(preferences))
+	for _, preference := range preferences {
+		if _, exists := effective[preference.UserID]; !exists || preference.BucketID != nil {
+			effective[preference.UserID] = preference
+		}
+	}
+
+	enabled := make([]models.NotificationPreference, 0, len(effective))
+	for _, preference := range effective {
+		if preference.Enabled {
+			enabled = append(enabled, preference)
+		}
+	}
+	return enabled, nil
+}
+
+// digestBuckets returns the buckets of the user covered by a preference. A preference for all buckets
+// does not cover the buckets having a preference of their own.
+func digestBuckets(db *gorm.DB, preference models.NotificationPreference) ([]models.Bucket, error) {
+	query := db.Model(&models.Bucket{}).
+		Joins("JOIN memberships ON memberships.bucket_id = buckets.id AND memberships.deleted_at IS NULL").
+		Where("memberships.user_id = ?", preference.UserID).
+		Where("memberships.expires_at IS NULL OR memberships.expires_at > ?", time.Now())
+
+	if preference.BucketID != nil {
+		query = query.Where("buckets.id = ?", *preference.BucketID)
+	} else {
+		query = query.Where(
+			"buckets.id NOT IN (?)",
+			db.Model(&models.NotificationPreference{}).
+				Select("bucket_id").
+				Where("user_id = ? AND bucket_id IS NOT NULL", preference.UserID),
+		)
+	}
+
+	var buckets []models.Bucket
+	err := query.Order("buckets.name").Find(&buckets).Error
+	return buckets, err
+}
+
+// userDisplayName returns the name of a user, or their email when they have no name.
+func userDisplayName(db *gorm.DB, names map[string]string, userID string) string {
+	if name, exists := names[userID]; exists {
+		return name
+	}
+
+	var user models.User
+	db.Unscoped().Select("first_name", "last_name", "email").Where("id = ?", userID).Limit(1).Find(&user)
+
+	name := user.Email
+	if user.FirstName != "" || user.LastName != "" {
+		name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	names[userID] = name
+	return name
+}
+
+func unsubscribeURL(apiURL string, preference models.NotificationPreference) string {
+	return fmt.Sprintf("%s/api/v1/notifications/unsubscribe?token=%s", apiURL, preference.UnsubscribeToken)
+}
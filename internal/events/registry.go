@@ -5,26 +5,40 @@ import "reflect"
 // eventRegistry keeps a registry of all the events
 // including their payload so they can be instantiated in the handler.
 var eventRegistry = map[string]reflect.Type{
-	BucketSharedWithName:              reflect.TypeOf(BucketSharedWith{}),
-	BucketSharedWithPayloadName:       reflect.TypeOf(BucketSharedWithPayload{}),
-	ChallengeUserInviteName:           reflect.TypeOf(ChallengeUserInvite{}),
-	ChallengeUserInvitePayloadName:    reflect.TypeOf(ChallengeUserInvitePayload{}),
-	PasswordResetChallengeName:        reflect.TypeOf(PasswordResetChallengeEvent{}),
-	PasswordResetChallengePayloadName: reflect.TypeOf(PasswordResetChallengePayload{}),
-	PasswordResetSuccessName:          reflect.TypeOf(PasswordResetSuccessEvent{}),
-	PasswordResetSuccessPayloadName:   reflect.TypeOf(PasswordResetSuccessPayload{}),
-	UserWelcomeName:                   reflect.TypeOf(UserWelcomeEvent{}),
-	UserWelcomePayloadName:            reflect.TypeOf(UserWelcomePayload{}),
-	UserInvitationName:                reflect.TypeOf(UserInvitation{}),
-	UserInvitationPayloadName:         reflect.TypeOf(UserInvitationPayload{}),
-	BucketPurgeName:                   reflect.TypeOf(BucketPurge{}),
-	BucketPurgePayloadName:            reflect.TypeOf(BucketPurgePayload{}),
-	TrashExpirationName:               reflect.TypeOf(TrashExpiration{}),
-	TrashExpirationPayloadName:        reflect.TypeOf(TrashExpirationPayload{}),
-	FolderRestoreName:                 reflect.TypeOf(FolderRestore{}),
-	FolderRestorePayloadName:          reflect.TypeOf(FolderRestorePayload{}),
-	FolderTrashName:                   reflect.TypeOf(FolderTrash{}),
-	FolderTrashPayloadName:            reflect.TypeOf(FolderTrashPayload{}),
-	FolderPurgeName:                   reflect.TypeOf(FolderPurge{}),
-	FolderPurgePayloadName:            reflect.TypeOf(FolderPurgePayload{}),
+	BucketSharedWithName:                  reflect.TypeOf(BucketSharedWith{}),
+	BucketSharedWithPayloadName:           reflect.TypeOf(BucketSharedWithPayload{}),
+	ChallengeUserInviteName:               reflect.TypeOf(ChallengeUserInvite{}),
+	ChallengeUserInvitePayloadName:        reflect.TypeOf(ChallengeUserInvitePayload{}),
+	PasswordResetChallengeName:            reflect.TypeOf(PasswordResetChallengeEvent{}),
+	PasswordResetChallengePayloadName:     reflect.TypeOf(PasswordResetChallengePayload{}),
+	PasswordResetSuccessName:              reflect.TypeOf(PasswordResetSuccessEvent{}),
+	PasswordResetSuccessPayloadName:       reflect.TypeOf(PasswordResetSuccessPayload{}),
+	UserWelcomeName:                       reflect.TypeOf(UserWelcomeEvent{}),
+	UserWelcomePayloadName:                reflect.TypeOf(UserWelcomePayload{}),
+	UserInvitationName:                    reflect.TypeOf(UserInvitation{}),
+	UserInvitationPayloadName:             reflect.TypeOf(UserInvitationPayload{}),
+	BucketPurgeName:                       reflect.TypeOf(BucketPurge{}),
+	BucketPurgePayloadName:                reflect.TypeOf(BucketPurgePayload{}),
+	TrashExpirationName:                   reflect.TypeOf(TrashExpiration{}),
+	TrashExpirationPayloadName:            reflect.TypeOf(TrashExpirationPayload{}),
+	FileScanName:                          reflect.TypeOf(FileScan{}),
+	FileScanPayloadName:                   reflect.TypeOf(FileScanPayload{}),
+	FileChecksumVerificationName:          reflect.TypeOf(FileChecksumVerification{}),
+	FileChecksumVerificationPayloadName:   reflect.TypeOf(FileChecksumVerificationPayload{}),
+	FolderRestoreName:                     reflect.TypeOf(FolderRestore{}),
+	FolderRestorePayloadName:              reflect.TypeOf(FolderRestorePayload{}),
+	FolderTrashName:                       reflect.TypeOf(FolderTrash{}),
+	FolderTrashPayloadName:                reflect.TypeOf(FolderTrashPayload{}),
+	FolderPurgeName:                       reflect.TypeOf(FolderPurge{}),
+	FolderPurgePayloadName:                reflect.TypeOf(FolderPurgePayload{}),
+	MembershipExpiryName:                  reflect.TypeOf(MembershipExpiry{}),
+	MembershipExpiryPayloadName:           reflect.TypeOf(MembershipExpiryPayload{}),
+	WebhookDispatchName:                   reflect.TypeOf(WebhookDispatch{}),
+	WebhookDispatchPayloadName:            reflect.TypeOf(WebhookDispatchPayload{}),
+	WebhookDeliveryName:                   reflect.TypeOf(WebhookDelivery{}),
+	WebhookDeliveryPayloadName:            reflect.TypeOf(WebhookDeliveryPayload{}),
+	BucketActivityNotificationName:        reflect.TypeOf(BucketActivityNotification{}),
+	BucketActivityNotificationPayloadName: reflect.TypeOf(BucketActivityNotificationPayload{}),
+	NotificationDigestName:                reflect.TypeOf(NotificationDigest{}),
+	NotificationDigestPayloadName:         reflect.TypeOf(NotificationDigestPayload{}),
 }
@@ -0,0 +1,240 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+
+	"api/internal/activity"
+	"api/internal/antivirus"
+	c "api/internal/configuration"
+	apierrors "api/internal/errors"
+	"api/internal/messaging"
+	"api/internal/models"
+	"api/internal/rbac"
+	"api/internal/sql"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	FileScanName        = "FileScan"
+	FileScanPayloadName = "FileScanPayload"
+)
+
+type FileScanPayload struct {
+	Type     string
+	BucketID uuid.UUID
+	FileID   uuid.UUID
+	UserID   string
+}
+
+// FileScan scans an uploaded file with the antivirus. A clean file becomes available,
+// while an infected file is quarantined and the owners of the bucket are notified.
+// A failed scan is retried by the upload reaper, and the file stays unavailable in the meantime.
+// A file which cannot be scanned, because it is too large or the scans keep failing, is quarantined.
+type FileScan struct {
+	Publisher messaging.IPublisher
+	Payload   FileScanPayload
+}
+
+type FileQuarantinedData struct {
+	Bucket    models.Bucket
+	BucketURL string
+	FileName  string
+	Signature string
+	Uploader  string
+	WebURL    string
+}
+
+func NewFileScan(publisher messaging.IPublisher, bucketID uuid.UUID, fileID uuid.UUID, userID string) FileScan {
+	return FileScan{
+		Publisher: publisher,
+		Payload: FileScanPayload{
+			Type:     FileScanName,
+			BucketID: bucketID,
+			FileID:   fileID,
+			UserID:   userID,
+		},
+	}
+}
+
+func (e *FileScan) Trigger() {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		zap.L().Error("Error marshalling file scan event payload", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("type", e.Payload.Type)
+	err = e.Publisher.Publish(msg)
+	if err != nil {
+		zap.L().Error("failed to trigger file scan event", zap.Error(err))
+	}
+}
+
+func (e *FileScan) callback(params *EventParams) error {
+	file, err := sql.GetFileByID(params.DB, e.Payload.BucketID, e.Payload.FileID)
+	if err != nil {
+		var apiErr *apierrors.APIError
+		if errors.As(err, &apiErr) {
+			// The file has been deleted in the meantime
+			return nil
+		}
+		zap.L().Error("Failed to fetch file to scan", zap.Error(err))
+		return err
+	}
+
+	if file.Status != models.FileStatusScanning {
+		return nil
+	}
+
+	result, signature, reason := models.FileScanSkipped, "", ""
+	if params.Scanner != nil {
+		result, signature, err = e.scan(params, file)
+		if err != nil {
+			zap.L().Error("Failed to scan file",
+				zap.String("file_id", file.ID.String()),
+				zap.String("bucket_id", file.BucketID.String()),
+				zap.Int("attempts", file.ScanAttempts+1),
+				zap.Error(err))
+
+			if !errors.Is(err, antivirus.ErrFileTooLarge) && file.ScanAttempts+1 < c.AntivirusMaxScanAttempts {
+				return e.postpone(params, file)
+			}
+			result, reason = models.FileScanFailed, err.Error()
+		}
+	}
+
+	status := models.FileStatusUploaded
+	activityMessage := activity.FileScanned
+	if result == models.FileScanInfected || result == models.FileScanFailed {
+		status = models.FileStatusQuarantined
+		activityMessage = activity.FileQuarantined
+	}
+
+	// Only the first scan of a file published twice is recorded
+	update := params.DB.Model(&models.File{}).
+		Where("id = ? AND status = ?", file.ID, models.FileStatusScanning).
+		Update("status", status)
+	if update.Error != nil {
+		zap.L().Error("Failed to update file status", zap.Error(update.Error))
+		return update.Error
+	}
+	if update.RowsAffected == 0 {
+		return nil
+	}
+	file.Status = status
+
+	action := models.Activity{
+		Message: activityMessage,
+		Object: models.FileScanActivity{
+			ID:        file.ID,
+			Name:      file.Name,
+			Result:    result,
+			Signature: signature,
+			Reason:    reason,
+		},
+		Filter: activity.NewLogFilter(map[string]string{
+			"action":      rbac.ActionScan.String(),
+			"object_type": rbac.ResourceFile.String(),
+			"file_id":     file.ID.String(),
+			"bucket_id":   file.BucketID.String(),
+			"user_id":     e.Payload.UserID,
+		}),
+	}
+	if err = params.ActivityLogger.Send(action); err != nil {
+		zap.L().Error("failed to send activity", zap.Error(err))
+	}
+
+	if status == models.FileStatusUploaded {
+		publishFileUploaded(params.Publisher, params.BucketHub, params.ActivityLogger, file, e.Payload.UserID)
+		return nil
+	}
+
+	zap.L().Warn("File quarantined",
+		zap.String("file_id", file.ID.String()),
+		zap.String("bucket_id", file.BucketID.String()),
+		zap.String("result", string(result)),
+		zap.String("signature", signature),
+		zap.String("reason", reason))
+
+	var userID *uuid.UUID
+	if parsedUserID, parseErr := uuid.Parse(e.Payload.UserID); parseErr == nil {
+		userID = &parsedUserID
+	}
+	params.BucketHub.Publish(models.NewFileEvent(models.BucketEventFileQuarantined, file, userID))
+
+	if result == models.FileScanInfected {
+		e.notifyOwners(params, file, signature)
+	}
+	return nil
+}
+
+// postpone records a failed scan. The file stays in the scanning status, and the upload reaper
+// publishes the scan again later.
+func (e *FileScan) postpone(params *EventParams, file models.File) error {
+	err := params.DB.Model(&models.File{}).
+		Where("id = ? AND status = ?", file.ID, models.FileStatusScanning).
+		Update("scan_attempts", gorm.Expr("scan_attempts + 1")).Error
+	if err != nil {
+		zap.L().Error("Failed to record the failed scan", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// scan streams the content of the file from the storage to the antivirus.
+func (e *FileScan) scan(params *EventParams, file models.File) (models.FileScanResult, string, error) {
+	content, err := params.Storage.GetObject(path.Join("buckets", file.BucketID.String(), file.ID.String()))
+	if err != nil {
+		return "", "", err
+	}
+	defer func() { _ = content.Close() }()
+
+	return params.Scanner.Scan(content, int64(file.Size))
+}
+
+// notifyOwners tells the owners of the bucket a file has been quarantined.
+// A failed recipient is not retried, as the file is already quarantined.
+func (e *FileScan) notifyOwners(params *EventParams, file models.File, signature string) {
+	var bucket models.Bucket
+	if err := params.DB.Where("id = ?", file.BucketID).First(&bucket).Error; err != nil {
+		return
+	}
+
+	var owners []models.Membership
+	err := params.DB.Preload("User").
+		Where(&models.Membership{BucketID: file.BucketID, Group: models.GroupOwner}).
+		Find(&owners).Error
+	if err != nil {
+		zap.L().Error("Failed to fetch bucket owners", zap.Error(err))
+		return
+	}
+
+	data := FileQuarantinedData{
+		Bucket:    bucket,
+		BucketURL: fmt.Sprintf("%s/buckets/%s", params.WebURL, bucket.ID),
+		FileName:  file.Name,
+		Signature: signature,
+		Uploader:  userDisplayName(params.DB, map[string]string{}, e.Payload.UserID),
+		WebURL:    params.WebURL,
+	}
+	subject := fmt.Sprintf("A file has been quarantined in %s", bucket.Name)
+
+	for _, owner := range owners {
+		language := owner.User.Language
+		err = params.Notifier.NotifyFromTemplate(owner.User.Email, language, subject, "file_quarantined", data)
+		if err != nil {
+			zap.L().Error("failed to notify file quarantine",
+				zap.String("user_id", owner.UserID.String()),
+				zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+
+	"api/internal/activity"
+	"api/internal/antivirus"
+	c "api/internal/configuration"
+	"api/internal/models"
+	"api/internal/tests"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+type activityLoggerMock struct {
+	mock.Mock
+}
+
+func (m *activityLoggerMock) Search(criteria activity.SearchCriteria) ([]map[string]interface{}, string, error) {
+	args := m.Called(criteria)
+	history, _ := args.Get(0).([]map[string]interface{})
+	return history, args.String(1), args.Error(2)
+}
+
+func (m *activityLoggerMock) Send(message models.Activity) error {
+	return m.Called(message).Error(0)
+}
+
+func TestFileScanFailures(t *testing.T) {
+	bucketID := uuid.New()
+	fileID := uuid.New()
+
+	testCases := []struct {
+		name        string
+		attempts    int
+		scanErr     error
+		quarantined bool
+	}{
+		{"Failed scan is retried later", 0, errors.New("connection refused"), false},
+		{"Failed scan at the maximum attempts quarantines the file", c.AntivirusMaxScanAttempts - 1,
+			errors.New("connection refused"), true},
+		{"File too large to scan is quarantined", 0, antivirus.ErrFileTooLarge, true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+
+			dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE (id = $1 AND bucket_id = $2)`)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "bucket_id", "name", "status", "size", "scan_attempts"}).
+					AddRow(fileID, bucketID, "invoice.pdf", models.FileStatusScanning, 7, tt.attempts))
+			dbMock.ExpectBegin()
+			if tt.quarantined {
+				dbMock.ExpectExec(regexp.QuoteMeta(`UPDATE "files" SET "status"=$1`)).
+					WithArgs(models.FileStatusQuarantined, sqlmock.AnyArg(), fileID, models.FileStatusScanning).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			} else {
+				dbMock.ExpectExec(regexp.QuoteMeta(`UPDATE "files" SET "scan_attempts"=scan_attempts + 1`)).
+					WithArgs(sqlmock.AnyArg(), fileID, models.FileStatusScanning).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			dbMock.ExpectCommit()
+
+			store := &tests.MockStorage{}
+			store.On("GetObject", "buckets/"+bucketID.String()+"/"+fileID.String()).
+				Return(io.NopCloser(strings.NewReader("content")), nil)
+
+			scanner := &tests.MockScanner{}
+			scanner.On("Scan", mock.Anything, int64(7)).Return(models.FileScanResult(""), "", tt.scanErr)
+
+			activityLogger := &activityLoggerMock{}
+			bucketHub := &tests.MockBucketHub{}
+			if tt.quarantined {
+				activityLogger.On("Send", mock.MatchedBy(func(action models.Activity) bool {
+					object, ok := action.Object.(models.FileScanActivity)
+					return ok && action.Message == activity.FileQuarantined &&
+						object.Result == models.FileScanFailed && object.Reason != ""
+				})).Return(nil)
+				bucketHub.On("Publish", mock.MatchedBy(func(event models.BucketEvent) bool {
+					return event.Type == models.BucketEventFileQuarantined
+				})).Return()
+			}
+
+			params := &EventParams{
+				DB:             db,
+				Storage:        store,
+				Scanner:        scanner,
+				ActivityLogger: activityLogger,
+				BucketHub:      bucketHub,
+			}
+
+			event := NewFileScan(nil, bucketID, fileID, "")
+			require.NoError(t, event.callback(params))
+
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+			activityLogger.AssertExpectations(t)
+			bucketHub.AssertExpectations(t)
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"api/internal/models"
+	"api/internal/sql"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -39,6 +40,36 @@ func NewTrashExpirationFromBucketEvent(bucketID uuid.UUID, objectKey string) *Tr
 	}
 }
 
+// ExpireTrashedFile purges a file whose retention period in the trash is over, for the storages which
+// do not report the expiration of the trash markers. It is idempotent with the expiration reported by the storage.
+func ExpireTrashedFile(params *EventParams, file models.File) error {
+	markerKey := path.Join("trash", file.BucketID.String(), "files", file.ID.String())
+	if err := NewTrashExpirationFromBucketEvent(file.BucketID, markerKey).callback(params); err != nil {
+		return err
+	}
+
+	// The marker has not expired, it is removed so that its later expiration has nothing to purge
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+	if err := params.Storage.UnmarkAsTrashed(objectPath, file); err != nil {
+		zap.L().Warn("Failed to delete trash marker of an expired file",
+			zap.String("file_id", file.ID.String()),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// ExpireTrashedFolder purges a folder whose retention period in the trash is over, with the files and folders
+// it still contains.
+func ExpireTrashedFolder(params *EventParams, folder models.Folder) error {
+	userID := uuid.Nil
+	if folder.DeletedBy != nil {
+		userID = *folder.DeletedBy
+	}
+
+	event := NewFolderPurge(params.Publisher, folder.BucketID, folder.ID, userID)
+	return event.callback(params)
+}
+
 // Trigger publishes the trash expiration event (if needed for manual triggering).
 func (e *TrashExpiration) Trigger(publisher message.Publisher) {
 	payload, err := json.Marshal(e.Payload)
@@ -221,6 +252,21 @@ func (e *TrashExpiration) callback(params *EventParams) error {
 		return nil
 	}
 
+	locked := currentFile.IsLocked()
+	if !locked {
+		locked, err = sql.IsBucketLocked(params.DB, currentFile.BucketID)
+		if err != nil {
+			zap.L().Error("Failed to check the retention lock of the bucket", zap.Error(err))
+			return err
+		}
+	}
+	if locked {
+		// The file stays in the trash until its lock is released or expires
+		zap.L().Warn("File under a retention lock, skipping permanent deletion",
+			zap.String("file_id", file.ID.String()))
+		return nil
+	}
+
 	zap.L().Info("Processing file deletion from trash",
 		zap.String("file_id", file.ID.String()),
 		zap.String("file_name", file.Name),
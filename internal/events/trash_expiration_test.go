@@ -0,0 +1,92 @@
+package events
+
+import (
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	"api/internal/models"
+	"api/internal/tests"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpireTrashedFileThenReportedByStorage(t *testing.T) {
+	file := models.File{ID: uuid.New(), BucketID: uuid.New(), Name: "report.pdf", Status: models.FileStatusDeleted}
+	deletedAt := time.Now().Add(-31 * 24 * time.Hour)
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+	markerKey := path.Join("trash", file.BucketID.String(), "files", file.ID.String())
+	trashedRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "bucket_id", "name", "status", "deleted_at"}).
+			AddRow(file.ID, file.BucketID, file.Name, file.Status, deletedAt)
+	}
+
+	db, dbMock := newMockDB(t)
+	store := &tests.MockStorage{}
+	store.On("IsTrashMarkerPath", markerKey).Return(true, objectPath)
+	store.On("RemoveObject", objectPath).Return(nil)
+	store.On("UnmarkAsTrashed", objectPath, mock.Anything).Return(nil)
+	params := &EventParams{DB: db, Storage: store}
+
+	// The sweeper purges the file, then removes its marker
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE bucket_id = $1 AND id = $2`)).
+		WithArgs(file.BucketID, file.ID).
+		WillReturnRows(trashedRows())
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE id = $1 ORDER BY "files"."id" LIMIT $2`)).
+		WithArgs(file.ID, 1).
+		WillReturnRows(trashedRows())
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "buckets" WHERE id = $1`)).
+		WithArgs(file.BucketID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "files" WHERE "files"."id" = $1`)).
+		WithArgs(file.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	require.NoError(t, ExpireTrashedFile(params, file))
+
+	// The storage reports the removal of the marker afterwards, for a file which does not exist anymore
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE bucket_id = $1 AND id = $2`)).
+		WithArgs(file.BucketID, file.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	require.NoError(t, NewTrashExpirationFromBucketEvent(file.BucketID, markerKey).callback(params))
+
+	store.AssertNumberOfCalls(t, "RemoveObject", 1)
+	store.AssertNumberOfCalls(t, "UnmarkAsTrashed", 1)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestExpireTrashedFileUnderRetentionLock(t *testing.T) {
+	file := models.File{ID: uuid.New(), BucketID: uuid.New(), Name: "report.pdf", Status: models.FileStatusDeleted}
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+	markerKey := path.Join("trash", file.BucketID.String(), "files", file.ID.String())
+	trashedRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "bucket_id", "name", "status", "deleted_at"}).
+			AddRow(file.ID, file.BucketID, file.Name, file.Status, time.Now().Add(-time.Hour))
+	}
+
+	db, dbMock := newMockDB(t)
+	store := &tests.MockStorage{}
+	store.On("IsTrashMarkerPath", markerKey).Return(true, objectPath)
+	store.On("UnmarkAsTrashed", objectPath, mock.Anything).Return(nil)
+
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE bucket_id = $1 AND id = $2`)).
+		WillReturnRows(trashedRows())
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE id = $1`)).
+		WillReturnRows(trashedRows())
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "buckets" WHERE id = $1`)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	require.NoError(t, ExpireTrashedFile(&EventParams{DB: db, Storage: store}, file))
+
+	// The locked file stays in the trash, with its content
+	store.AssertNotCalled(t, "RemoveObject", mock.Anything)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
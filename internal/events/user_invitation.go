@@ -6,6 +6,7 @@ import (
 
 	"api/internal/messaging"
 	"api/internal/models"
+	"api/internal/sql"
 
 	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
@@ -94,7 +95,8 @@ func (e *UserInvitation) Trigger() {
 func (e *UserInvitation) callback(params *EventParams) error {
 	e.Payload.WebURL = params.WebURL
 	subject := fmt.Sprintf("%s has invited you to SafeBucket", e.Payload.From)
-	err := params.Notifier.NotifyFromTemplate(e.Payload.To, subject, "user_invitation", e.Payload)
+	language := sql.GetUserLanguage(params.DB, e.Payload.To)
+	err := params.Notifier.NotifyFromTemplate(e.Payload.To, language, subject, "user_invitation", e.Payload)
 	if err != nil {
 		zap.L().Error("failed to notify", zap.Any("event", e), zap.Error(err))
 		return err
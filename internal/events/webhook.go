@@ -0,0 +1,308 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/messaging"
+	"api/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	WebhookDispatchName        = "WebhookDispatch"
+	WebhookDispatchPayloadName = "WebhookDispatchPayload"
+	WebhookDeliveryName        = "WebhookDelivery"
+	WebhookDeliveryPayloadName = "WebhookDeliveryPayload"
+)
+
+type WebhookDispatchPayload struct {
+	Type       string
+	EventID    uuid.UUID
+	BucketID   uuid.UUID
+	UserID     *uuid.UUID
+	EventType  string
+	Data       interface{}
+	OccurredAt time.Time
+}
+
+// WebhookDispatch creates a delivery for each enabled webhook of a bucket subscribed to an event,
+// and triggers their delivery.
+type WebhookDispatch struct {
+	Publisher messaging.IPublisher
+	Payload   WebhookDispatchPayload
+}
+
+func NewWebhookDispatch(
+	publisher messaging.IPublisher,
+	bucketID uuid.UUID,
+	userID *uuid.UUID,
+	eventType string,
+	data interface{},
+) WebhookDispatch {
+	return WebhookDispatch{
+		Publisher: publisher,
+		Payload: WebhookDispatchPayload{
+			Type:       WebhookDispatchName,
+			EventID:    uuid.New(),
+			BucketID:   bucketID,
+			UserID:     userID,
+			EventType:  eventType,
+			Data:       data,
+			OccurredAt: time.Now().UTC(),
+		},
+	}
+}
+
+func (e *WebhookDispatch) Trigger() {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		zap.L().Error("Error marshalling webhook dispatch event payload", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("type", e.Payload.Type)
+	err = e.Publisher.Publish(msg)
+	if err != nil {
+		zap.L().Error("failed to trigger webhook dispatch event", zap.Error(err))
+	}
+}
+
+func (e *WebhookDispatch) callback(params *EventParams) error {
+	subscribed, err := json.Marshal([]string{e.Payload.EventType})
+	if err != nil {
+		return err
+	}
+
+	var webhooks []models.Webhook
+	err = params.DB.
+		Where("bucket_id = ? AND enabled = ? AND event_types @> ?", e.Payload.BucketID, true, string(subscribed)).
+		Find(&webhooks).Error
+	if err != nil {
+		zap.L().Error("Failed to fetch webhooks", zap.Error(err))
+		return err
+	}
+
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(models.WebhookPayload{
+		ID:         e.Payload.EventID,
+		Event:      e.Payload.EventType,
+		OccurredAt: e.Payload.OccurredAt,
+		BucketID:   e.Payload.BucketID,
+		UserID:     e.Payload.UserID,
+		Data:       e.Payload.Data,
+	})
+	if err != nil {
+		zap.L().Error("Failed to marshal webhook payload", zap.Error(err))
+		return nil
+	}
+
+	// The deliveries are leased until the delivery events are processed, so that the retry job leaves them alone
+	deliveries := make([]models.WebhookDelivery, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventType:     e.Payload.EventType,
+			Payload:       body,
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: time.Now().Add(c.WebhookDeliveryLeaseSeconds * time.Second),
+		})
+	}
+
+	if err = params.DB.Create(&deliveries).Error; err != nil {
+		zap.L().Error("Failed to create webhook deliveries", zap.Error(err))
+		return err
+	}
+
+	for _, delivery := range deliveries {
+		deliveryEvent := NewWebhookDelivery(params.Publisher, delivery.ID)
+		deliveryEvent.Trigger()
+	}
+
+	return nil
+}
+
+type WebhookDeliveryPayload struct {
+	Type       string
+	DeliveryID uuid.UUID
+}
+
+// WebhookDelivery posts a pending delivery to its webhook. Failed deliveries are scheduled
+// for a retry with an exponential backoff, and marked as failed after the maximum number of attempts.
+type WebhookDelivery struct {
+	Publisher messaging.IPublisher
+	Payload   WebhookDeliveryPayload
+}
+
+func NewWebhookDelivery(publisher messaging.IPublisher, deliveryID uuid.UUID) WebhookDelivery {
+	return WebhookDelivery{
+		Publisher: publisher,
+		Payload: WebhookDeliveryPayload{
+			Type:       WebhookDeliveryName,
+			DeliveryID: deliveryID,
+		},
+	}
+}
+
+func (e *WebhookDelivery) Trigger() {
+	payload, err := json.Marshal(e.Payload)
+	if err != nil {
+		zap.L().Error("Error marshalling webhook delivery event payload", zap.Error(err))
+		return
+	}
+
+	msg := message.NewMessage(watermill.NewUUID(), payload)
+	msg.Metadata.Set("type", e.Payload.Type)
+	err = e.Publisher.Publish(msg)
+	if err != nil {
+		zap.L().Error("failed to trigger webhook delivery event", zap.Error(err))
+	}
+}
+
+func (e *WebhookDelivery) callback(params *EventParams) error {
+	var delivery models.WebhookDelivery
+	err := params.DB.Preload("Webhook").Where("id = ?", e.Payload.DeliveryID).First(&delivery).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// The webhook has been deleted along with its deliveries
+			return nil
+		}
+		zap.L().Error("Failed to fetch webhook delivery", zap.Error(err))
+		return err
+	}
+
+	if delivery.Status != models.WebhookDeliveryPending {
+		return nil
+	}
+
+	if !delivery.Webhook.Enabled {
+		return params.DB.Model(&delivery).Updates(map[string]interface{}{
+			"status":     models.WebhookDeliveryFailed,
+			"last_error": "webhook disabled",
+		}).Error
+	}
+
+	// Claim the attempt, so that a delivery published twice is only sent once at a time
+	result := params.DB.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.WebhookDeliveryPending, delivery.Attempts).
+		Updates(map[string]interface{}{
+			"attempts":        delivery.Attempts + 1,
+			"next_attempt_at": time.Now().Add(c.WebhookDeliveryLeaseSeconds * time.Second),
+		})
+	if result.Error != nil {
+		zap.L().Error("Failed to claim webhook delivery", zap.Error(result.Error))
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+	delivery.Attempts++
+
+	client := params.WebhookClient
+	if client == nil {
+		client = defaultWebhookClient
+	}
+
+	responseStatus, sendErr := sendWebhook(client, delivery)
+
+	updates := map[string]interface{}{"response_status": nil}
+	if responseStatus != 0 {
+		updates["response_status"] = responseStatus
+	}
+
+	if sendErr == nil {
+		updates["status"] = models.WebhookDeliverySucceeded
+		updates["last_error"] = nil
+		updates["delivered_at"] = time.Now()
+	} else {
+		zap.L().Warn("Failed to deliver webhook",
+			zap.String("delivery_id", delivery.ID.String()),
+			zap.String("webhook_id", delivery.WebhookID.String()),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Error(sendErr))
+
+		updates["last_error"] = sendErr.Error()
+		if delivery.Attempts >= c.WebhookMaxAttempts {
+			updates["status"] = models.WebhookDeliveryFailed
+		} else {
+			updates["next_attempt_at"] = time.Now().Add(webhookRetryDelay(delivery.Attempts))
+		}
+	}
+
+	if err = params.DB.Model(&delivery).Updates(updates).Error; err != nil {
+		// The lease expires and the retry job delivers it again
+		zap.L().Error("Failed to record webhook delivery", zap.Error(err))
+	}
+	return nil
+}
+
+// sendWebhook posts the payload of a delivery and returns the response status.
+// The response body is discarded, so that the delivery log does not disclose what the endpoint returned.
+// The signature is the hex-encoded HMAC-SHA256 of the timestamp and the body joined by a dot,
+// keyed with the webhook secret, so that receivers can authenticate the payload and reject replays.
+func sendWebhook(client *WebhookClient, delivery models.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.WebhookTimeoutSeconds*time.Second)
+	defer cancel()
+
+	body := bytes.NewReader(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Webhook.URL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Safebucket-Webhook")
+	req.Header.Set("X-Safebucket-Event", delivery.EventType)
+	req.Header.Set("X-Safebucket-Delivery", delivery.ID.String())
+	req.Header.Set("X-Safebucket-Timestamp", timestamp)
+	signature := signWebhook(delivery.Webhook.Secret, timestamp, delivery.Payload)
+	req.Header.Set("X-Safebucket-Signature", "sha256="+signature)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// The connection is only reused once the body is read
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, c.WebhookResponseDrainMaxBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func signWebhook(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookRetryDelay doubles the delay after each failed attempt, up to the configured maximum.
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := c.WebhookRetryBaseDelaySeconds * math.Pow(2, float64(attempts-1))
+	return time.Duration(math.Min(delay, c.WebhookRetryMaxDelaySeconds)) * time.Second
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	c "api/internal/configuration"
+)
+
+var (
+	// ErrWebhookURLNotAllowed is returned for the endpoints which are not served over https.
+	ErrWebhookURLNotAllowed = errors.New("webhook endpoint must use https")
+
+	errWebhookAddressNotAllowed = errors.New("webhook endpoint does not resolve to a public address")
+)
+
+// defaultWebhookClient is used when no client is configured, and allows no internal host.
+var defaultWebhookClient = NewWebhookClient(nil)
+
+// WebhookClient posts the webhook deliveries. It refuses the endpoints which are not served over https,
+// or which resolve to a loopback, private, link-local or unspecified address, unless their host is allowed
+// by the administrator, so that the bucket owners cannot reach the internal network of the platform.
+// It does not follow redirects, so that an endpoint cannot forward the signed payloads elsewhere.
+type WebhookClient struct {
+	allowedHosts []string
+	client       *http.Client
+}
+
+func NewWebhookClient(allowedHosts []string) *WebhookClient {
+	dialer := &net.Dialer{Timeout: c.WebhookTimeoutSeconds * time.Second}
+	guardedDialer := &net.Dialer{
+		Timeout: c.WebhookTimeoutSeconds * time.Second,
+		// The address is checked once resolved, so that a host cannot resolve to another address afterwards
+		Control: func(_ string, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicAddress(ip) {
+				return errWebhookAddressNotAllowed
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(address)
+			if err == nil && isAllowedWebhookHost(host, allowedHosts) {
+				return dialer.DialContext(ctx, network, address)
+			}
+			return guardedDialer.DialContext(ctx, network, address)
+		},
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: c.WebhookTimeoutSeconds * time.Second,
+	}
+
+	return &WebhookClient{
+		allowedHosts: allowedHosts,
+		client: &http.Client{
+			Timeout:   c.WebhookTimeoutSeconds * time.Second,
+			Transport: transport,
+			CheckRedirect: func(_ *http.Request, _ []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Do sends the request once its endpoint is checked.
+func (w *WebhookClient) Do(req *http.Request) (*http.Response, error) {
+	if err := CheckWebhookURL(req.URL.String(), w.allowedHosts); err != nil {
+		return nil, err
+	}
+	return w.client.Do(req)
+}
+
+// CheckWebhookURL checks that a webhook endpoint is served over https, unless its host is allowed.
+func CheckWebhookURL(rawURL string, allowedHosts []string) error {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if endpoint.Scheme == "https" || isAllowedWebhookHost(endpoint.Hostname(), allowedHosts) {
+		return nil
+	}
+	return ErrWebhookURLNotAllowed
+}
+
+func isAllowedWebhookHost(host string, allowedHosts []string) bool {
+	for _, allowed := range allowedHosts {
+		if strings.EqualFold(host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPublicAddress checks that an address is not a loopback, private, link-local, multicast or unspecified one.
+func isPublicAddress(ip net.IP) bool {
+	return !ip.IsLoopback() &&
+		!ip.IsPrivate() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsUnspecified()
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublicAddress(t *testing.T) {
+	testCases := []struct {
+		address  string
+		expected bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"::ffff:10.1.2.3", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.address, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isPublicAddress(net.ParseIP(tt.address)))
+		})
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	allowedHosts := []string{"hooks.internal"}
+
+	testCases := []struct {
+		name     string
+		url      string
+		expected error
+	}{
+		{"Https endpoint", "https://example.com/hook", nil},
+		{"Http endpoint", "http://example.com/hook", ErrWebhookURLNotAllowed},
+		{"Http endpoint on an allowed host", "http://hooks.internal:8080/hook", nil},
+		{"Allowed host is case insensitive", "http://HOOKS.internal/hook", nil},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, CheckWebhookURL(tt.url, allowedHosts), tt.expected)
+		})
+	}
+}
+
+func TestWebhookClientRefusesInternalAddresses(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = NewWebhookClient(nil).Do(req)
+	assert.ErrorIs(t, err, errWebhookAddressNotAllowed)
+
+	endpoint, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	client := NewWebhookClient([]string{endpoint.Hostname()})
+	serverTransport := server.Client().Transport.(*http.Transport)
+	client.client.Transport.(*http.Transport).TLSClientConfig = serverTransport.TLSClientConfig
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+}
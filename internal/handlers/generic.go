@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
+	"api/internal/configuration"
 	apierrors "api/internal/errors"
 	h "api/internal/helpers"
 	m "api/internal/middlewares"
@@ -21,8 +26,13 @@ type (
 	GetOneListTargetFunc[Out any]             func(*zap.Logger, models.UserClaims, uuid.UUIDs) []Out
 	UpdateTargetFunc[In any]                  func(*zap.Logger, models.UserClaims, uuid.UUIDs, In) error
 	DeleteTargetFunc                          func(*zap.Logger, models.UserClaims, uuid.UUIDs) error
+	DeleteWithQueryTargetFunc[Q any]          func(*zap.Logger, models.UserClaims, uuid.UUIDs, Q) error
+	StreamWithQueryTargetFunc[Q any]          func(*zap.Logger, models.UserClaims, uuid.UUIDs, Q) (models.Stream, error)
 )
 
+// EventStreamTargetFunc returns the events to stream, and the function ending the subscription.
+type EventStreamTargetFunc[Out any] func(*zap.Logger, models.UserClaims, uuid.UUIDs) (<-chan Out, func(), error)
+
 func CreateHandler[In any, Out any](create CreateTargetFunc[In, Out]) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ids, ok := h.ParseUUIDs(w, r)
@@ -103,7 +113,13 @@ func GetOneWithQueryHandler[Q any, Out any](getOne GetOneWithQueryTargetFunc[Q,
 		record, err := getOne(logger, claims, ids, query)
 		if err != nil {
 			strErrors := []string{err.Error()}
-			h.RespondWithError(w, http.StatusNotFound, strErrors)
+
+			var apiErr *apierrors.APIError
+			if errors.As(err, &apiErr) {
+				h.RespondWithError(w, apiErr.Code, strErrors)
+			} else {
+				h.RespondWithError(w, http.StatusNotFound, strErrors)
+			}
 		} else {
 			h.RespondWithJSON(w, http.StatusOK, record)
 		}
@@ -155,9 +171,165 @@ func DeleteHandler(del DeleteTargetFunc) http.HandlerFunc {
 		err := del(logger, claims, ids)
 		if err != nil {
 			strErrors := []string{err.Error()}
-			h.RespondWithError(w, http.StatusNotFound, strErrors)
+
+			var apiErr *apierrors.APIError
+			if errors.As(err, &apiErr) {
+				h.RespondWithError(w, apiErr.Code, strErrors)
+			} else {
+				h.RespondWithError(w, http.StatusNotFound, strErrors)
+			}
+		} else {
+			h.RespondWithJSON(w, http.StatusNoContent, nil)
+		}
+	}
+}
+
+func DeleteWithQueryHandler[Q any](del DeleteWithQueryTargetFunc[Q]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids, ok := h.ParseUUIDs(w, r)
+		if !ok {
+			return
+		}
+
+		claims, _ := h.GetUserClaims(r.Context())
+		logger := m.GetLogger(r)
+
+		query, ok := r.Context().Value(models.QueryKey{}).(Q)
+		if !ok {
+			logger.Error("Failed to extract query params from context")
+			h.RespondWithError(w, http.StatusInternalServerError, []string{"INTERNAL_SERVER_ERROR"})
+			return
+		}
+
+		err := del(logger, claims, ids, query)
+		if err != nil {
+			strErrors := []string{err.Error()}
+
+			var apiErr *apierrors.APIError
+			if errors.As(err, &apiErr) {
+				h.RespondWithError(w, apiErr.Code, strErrors)
+			} else {
+				h.RespondWithError(w, http.StatusNotFound, strErrors)
+			}
 		} else {
 			h.RespondWithJSON(w, http.StatusNoContent, nil)
 		}
 	}
 }
+
+// StreamWithQueryHandler writes a stream as an attachment. Errors returned before the stream starts
+// are sent as usual, while errors occurring once the response has started can only be logged.
+func StreamWithQueryHandler[Q any](stream StreamWithQueryTargetFunc[Q]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids, ok := h.ParseUUIDs(w, r)
+		if !ok {
+			return
+		}
+
+		claims, _ := h.GetUserClaims(r.Context())
+		logger := m.GetLogger(r)
+
+		query, ok := r.Context().Value(models.QueryKey{}).(Q)
+		if !ok {
+			logger.Error("Failed to extract query params from context")
+			h.RespondWithError(w, http.StatusInternalServerError, []string{"INTERNAL_SERVER_ERROR"})
+			return
+		}
+
+		response, err := stream(logger, claims, ids, query)
+		if err != nil {
+			strErrors := []string{err.Error()}
+
+			var apiErr *apierrors.APIError
+			if errors.As(err, &apiErr) {
+				h.RespondWithError(w, apiErr.Code, strErrors)
+			} else {
+				h.RespondWithError(w, http.StatusInternalServerError, strErrors)
+			}
+			return
+		}
+
+		// Streams can outlast the write timeout of the server, which is meant for regular responses
+		if err = http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			logger.Debug("Failed to clear the write deadline", zap.Error(err))
+		}
+
+		w.Header().Set("Content-Type", response.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", response.Filename))
+		w.WriteHeader(http.StatusOK)
+
+		if err = response.Write(w); err != nil {
+			logger.Error("Failed to write stream", zap.Error(err))
+		}
+	}
+}
+
+// EventStreamHandler sends events as server-sent events, each one as a JSON data line, until the client
+// disconnects or the events channel is closed. Comments are sent in between to keep the connection alive.
+func EventStreamHandler[Out any](subscribe EventStreamTargetFunc[Out]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ids, ok := h.ParseUUIDs(w, r)
+		if !ok {
+			return
+		}
+
+		claims, _ := h.GetUserClaims(r.Context())
+		logger := m.GetLogger(r)
+
+		events, unsubscribe, err := subscribe(logger, claims, ids)
+		if err != nil {
+			strErrors := []string{err.Error()}
+
+			var apiErr *apierrors.APIError
+			if errors.As(err, &apiErr) {
+				h.RespondWithError(w, apiErr.Code, strErrors)
+			} else {
+				h.RespondWithError(w, http.StatusInternalServerError, strErrors)
+			}
+			return
+		}
+		defer unsubscribe()
+
+		controller := http.NewResponseController(w)
+		if err = controller.SetWriteDeadline(time.Time{}); err != nil {
+			logger.Debug("Failed to clear the write deadline", zap.Error(err))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		heartbeat := time.NewTicker(configuration.BucketEventsHeartbeatSeconds * time.Second)
+		defer heartbeat.Stop()
+
+		for {
+			var message string
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				message = ": heartbeat\n\n"
+			case event, open := <-events:
+				if !open {
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					logger.Error("Failed to marshal event", zap.Error(err))
+					continue
+				}
+				message = fmt.Sprintf("data: %s\n\n", data)
+			}
+
+			if _, err = io.WriteString(w, message); err != nil {
+				return
+			}
+			if err = controller.Flush(); err != nil {
+				logger.Debug("Failed to flush event stream", zap.Error(err))
+				return
+			}
+		}
+	}
+}
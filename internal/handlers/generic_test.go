@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -273,6 +274,37 @@ func TestGetOneHandler_NotFound(t *testing.T) {
 	tests.AssertJSONResponse(t, recorder, http.StatusNotFound, expected)
 }
 
+// TestGetOneWithQueryHandler_APIError tests that API errors keep their status code.
+func TestGetOneWithQueryHandler_APIError(t *testing.T) {
+	query := models.UserListQueryParams{Cursor: "invalid"}
+
+	mockGetOne := new(tests.MockGetOneWithQueryFunc[models.UserListQueryParams, models.Page[models.User]])
+	mockGetOne.On(
+		"GetOne",
+		mock.AnythingOfType("*zap.Logger"),
+		mock.Anything,
+		uuid.UUIDs(nil),
+		query,
+	).Return(models.Page[models.User]{}, apierrors.NewAPIError(http.StatusBadRequest, "INVALID_CURSOR"))
+
+	req := httptest.NewRequest(http.MethodGet, "/users?cursor=invalid", nil)
+	recorder := httptest.NewRecorder()
+
+	logger := zap.NewNop()
+	ctx := context.WithValue(req.Context(), m.LoggerKey, logger)
+	claims := models.UserClaims{UserID: uuid.New()}
+	ctx = context.WithValue(ctx, models.UserClaimKey{}, claims)
+	ctx = context.WithValue(ctx, models.QueryKey{}, query)
+	req = req.WithContext(ctx)
+
+	handler := GetOneWithQueryHandler(mockGetOne.GetOne)
+	handler(recorder, req)
+
+	mockGetOne.AssertExpectations(t)
+	expected := models.Error{Status: http.StatusBadRequest, Error: []string{"INVALID_CURSOR"}}
+	tests.AssertJSONResponse(t, recorder, http.StatusBadRequest, expected)
+}
+
 // TestGetOneHandler_InvalidUUID tests retrieval with invalid UUID.
 func TestGetOneHandler_InvalidUUID(t *testing.T) {
 	invalidUUID := "invalid-uuid"
@@ -543,3 +575,256 @@ func TestDeleteHandler_InvalidUUID(t *testing.T) {
 	expected := models.Error{Status: http.StatusBadRequest, Error: []string{"INVALID_UUID"}}
 	tests.AssertJSONResponse(t, recorder, http.StatusBadRequest, expected)
 }
+
+// TestDeleteHandler_APIError tests deletion refused with an API error code.
+func TestDeleteHandler_APIError(t *testing.T) {
+	testUUID := uuid.New()
+
+	mockDelete := new(tests.MockDeleteFunc)
+	mockDelete.On(
+		"Delete",
+		mock.AnythingOfType("*zap.Logger"),
+		mock.Anything,
+		uuid.UUIDs{testUUID},
+	).Return(apierrors.NewAPIError(http.StatusConflict, "CONFLICT"))
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/buckets/%s", testUUID.String()), nil)
+	recorder := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id0", testUUID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+
+	logger := zap.NewNop()
+	ctx = context.WithValue(ctx, m.LoggerKey, logger)
+	claims := models.UserClaims{UserID: uuid.New()}
+	ctx = context.WithValue(ctx, models.UserClaimKey{}, claims)
+	req = req.WithContext(ctx)
+
+	handler := DeleteHandler(mockDelete.Delete)
+	handler(recorder, req)
+
+	mockDelete.AssertExpectations(t)
+	expected := models.Error{Status: http.StatusConflict, Error: []string{"CONFLICT"}}
+	tests.AssertJSONResponse(t, recorder, http.StatusConflict, expected)
+}
+
+// TestDeleteWithQueryHandler tests successful deletion with query parameters.
+func TestDeleteWithQueryHandler(t *testing.T) {
+	testUUID := uuid.New()
+	query := models.BucketQueryParams{Status: "deleted"}
+
+	mockDelete := new(tests.MockDeleteWithQueryFunc[models.BucketQueryParams])
+	mockDelete.On(
+		"Delete",
+		mock.AnythingOfType("*zap.Logger"),
+		mock.Anything,
+		uuid.UUIDs{testUUID},
+		query,
+	).Return(nil)
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/buckets/%s", testUUID.String()), nil)
+	recorder := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id0", testUUID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+
+	logger := zap.NewNop()
+	ctx = context.WithValue(ctx, m.LoggerKey, logger)
+	claims := models.UserClaims{UserID: uuid.New()}
+	ctx = context.WithValue(ctx, models.UserClaimKey{}, claims)
+	ctx = context.WithValue(ctx, models.QueryKey{}, query)
+	req = req.WithContext(ctx)
+
+	handler := DeleteWithQueryHandler(mockDelete.Delete)
+	handler(recorder, req)
+
+	mockDelete.AssertExpectations(t)
+	tests.AssertJSONResponse(t, recorder, http.StatusNoContent, nil)
+}
+
+// TestDeleteWithQueryHandler_QueryExtractionFailure tests deletion when query params cannot be extracted.
+func TestDeleteWithQueryHandler_QueryExtractionFailure(t *testing.T) {
+	testUUID := uuid.New()
+
+	mockDelete := new(tests.MockDeleteWithQueryFunc[models.BucketQueryParams])
+
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/buckets/%s", testUUID.String()), nil)
+	recorder := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id0", testUUID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+
+	logger := zap.NewNop()
+	ctx = context.WithValue(ctx, m.LoggerKey, logger)
+	// Intentionally not adding query params to context
+	req = req.WithContext(ctx)
+
+	handler := DeleteWithQueryHandler(mockDelete.Delete)
+	handler(recorder, req)
+
+	expected := models.Error{Status: http.StatusInternalServerError, Error: []string{"INTERNAL_SERVER_ERROR"}}
+	tests.AssertJSONResponse(t, recorder, http.StatusInternalServerError, expected)
+}
+
+// TestStreamWithQueryHandler tests that a stream is written as an attachment.
+func TestStreamWithQueryHandler(t *testing.T) {
+	testUUID := uuid.New()
+	query := models.ActivityExportQueryParams{Format: "csv", From: "2025-01-01T00:00:00Z"}
+
+	mockStream := new(tests.MockStreamWithQueryFunc[models.ActivityExportQueryParams])
+	mockStream.On(
+		"Stream",
+		mock.AnythingOfType("*zap.Logger"),
+		mock.Anything,
+		uuid.UUIDs{testUUID},
+		query,
+	).Return(models.Stream{
+		ContentType: "text/csv; charset=utf-8",
+		Filename:    "activity.csv",
+		Write: func(w io.Writer) error {
+			_, err := io.WriteString(w, "timestamp,message\n")
+			return err
+		},
+	}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/buckets/%s/activity/export", testUUID), nil)
+	recorder := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id0", testUUID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+
+	logger := zap.NewNop()
+	ctx = context.WithValue(ctx, m.LoggerKey, logger)
+	claims := models.UserClaims{UserID: uuid.New()}
+	ctx = context.WithValue(ctx, models.UserClaimKey{}, claims)
+	ctx = context.WithValue(ctx, models.QueryKey{}, query)
+	req = req.WithContext(ctx)
+
+	handler := StreamWithQueryHandler(mockStream.Stream)
+	handler(recorder, req)
+
+	mockStream.AssertExpectations(t)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/csv; charset=utf-8" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	disposition := recorder.Header().Get("Content-Disposition")
+	if disposition != `attachment; filename="activity.csv"` {
+		t.Errorf("unexpected content disposition %q", disposition)
+	}
+	if body := recorder.Body.String(); body != "timestamp,message\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+// TestStreamWithQueryHandler_APIError tests that errors raised before streaming keep their status code.
+func TestStreamWithQueryHandler_APIError(t *testing.T) {
+	query := models.ActivityExportQueryParams{Format: "ndjson", From: "2025-01-01T00:00:00Z"}
+
+	mockStream := new(tests.MockStreamWithQueryFunc[models.ActivityExportQueryParams])
+	mockStream.On(
+		"Stream",
+		mock.AnythingOfType("*zap.Logger"),
+		mock.Anything,
+		uuid.UUIDs(nil),
+		query,
+	).Return(models.Stream{}, apierrors.NewAPIError(http.StatusBadRequest, "INVALID_TIME_RANGE"))
+
+	req := httptest.NewRequest(http.MethodGet, "/activity/export", nil)
+	recorder := httptest.NewRecorder()
+
+	logger := zap.NewNop()
+	ctx := context.WithValue(req.Context(), m.LoggerKey, logger)
+	claims := models.UserClaims{UserID: uuid.New()}
+	ctx = context.WithValue(ctx, models.UserClaimKey{}, claims)
+	ctx = context.WithValue(ctx, models.QueryKey{}, query)
+	req = req.WithContext(ctx)
+
+	handler := StreamWithQueryHandler(mockStream.Stream)
+	handler(recorder, req)
+
+	mockStream.AssertExpectations(t)
+	expected := models.Error{Status: http.StatusBadRequest, Error: []string{"INVALID_TIME_RANGE"}}
+	tests.AssertJSONResponse(t, recorder, http.StatusBadRequest, expected)
+}
+
+// TestEventStreamHandler tests that events are sent as server-sent events until the channel is closed.
+func TestEventStreamHandler(t *testing.T) {
+	testUUID := uuid.New()
+
+	events := make(chan models.BucketEvent, 1)
+	events <- models.BucketEvent{Type: models.BucketEventFileCreated, BucketID: testUUID}
+	close(events)
+	unsubscribed := false
+
+	mockSubscribe := new(tests.MockEventStreamFunc[models.BucketEvent])
+	mockSubscribe.On(
+		"Subscribe",
+		mock.AnythingOfType("*zap.Logger"),
+		mock.Anything,
+		uuid.UUIDs{testUUID},
+	).Return((<-chan models.BucketEvent)(events), func() { unsubscribed = true }, nil)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/buckets/%s/events", testUUID), nil)
+	recorder := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id0", testUUID.String())
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = context.WithValue(ctx, m.LoggerKey, zap.NewNop())
+	ctx = context.WithValue(ctx, models.UserClaimKey{}, models.UserClaims{UserID: uuid.New()})
+	req = req.WithContext(ctx)
+
+	handler := EventStreamHandler(mockSubscribe.Subscribe)
+	handler(recorder, req)
+
+	mockSubscribe.AssertExpectations(t)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, recorder.Code)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("unexpected content type %q", contentType)
+	}
+	expected := fmt.Sprintf(
+		"data: {\"type\":\"file.created\",\"bucket_id\":\"%s\","+
+			"\"object\":null,\"occurred_at\":\"0001-01-01T00:00:00Z\"}\n\n",
+		testUUID,
+	)
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("unexpected body %q", body)
+	}
+	if !unsubscribed {
+		t.Error("expected the subscription to be ended")
+	}
+}
+
+// TestEventStreamHandler_APIError tests that subscription errors keep their status code.
+func TestEventStreamHandler_APIError(t *testing.T) {
+	mockSubscribe := new(tests.MockEventStreamFunc[models.BucketEvent])
+	mockSubscribe.On(
+		"Subscribe",
+		mock.AnythingOfType("*zap.Logger"),
+		mock.Anything,
+		uuid.UUIDs(nil),
+	).Return((<-chan models.BucketEvent)(nil), func() {}, apierrors.NewAPIError(http.StatusForbidden, "FORBIDDEN"))
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	recorder := httptest.NewRecorder()
+
+	ctx := context.WithValue(req.Context(), m.LoggerKey, zap.NewNop())
+	ctx = context.WithValue(ctx, models.UserClaimKey{}, models.UserClaims{UserID: uuid.New()})
+	req = req.WithContext(ctx)
+
+	handler := EventStreamHandler(mockSubscribe.Subscribe)
+	handler(recorder, req)
+
+	mockSubscribe.AssertExpectations(t)
+	expected := models.Error{Status: http.StatusForbidden, Error: []string{"FORBIDDEN"}}
+	tests.AssertJSONResponse(t, recorder, http.StatusForbidden, expected)
+}
@@ -0,0 +1,36 @@
+package helpers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// cursor is the position of the last record of a page in a keyset pagination:
+// the value of the sort column, and the ID used as a tie-breaker.
+type cursor struct {
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// EncodeCursor builds the opaque cursor pointing after the given record.
+func EncodeCursor(value string, id uuid.UUID) string {
+	data, _ := json.Marshal(cursor{Value: value, ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor extracts the sort value and the ID from a cursor built by EncodeCursor.
+func DecodeCursor(encoded string) (string, uuid.UUID, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", uuid.Nil, errors.New("invalid cursor")
+	}
+
+	var c cursor
+	if err = json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return "", uuid.Nil, errors.New("invalid cursor")
+	}
+	return c.Value, c.ID, nil
+}
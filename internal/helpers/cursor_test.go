@@ -0,0 +1,28 @@
+package helpers
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	t.Run("Round trip", func(t *testing.T) {
+		id := uuid.New()
+
+		value, decodedID, err := DecodeCursor(EncodeCursor("alice@example.com", id))
+
+		require.NoError(t, err)
+		assert.Equal(t, "alice@example.com", value)
+		assert.Equal(t, id, decodedID)
+	})
+
+	t.Run("Invalid cursors", func(t *testing.T) {
+		for _, encoded := range []string{"not base64!", "bm90IGpzb24", "eyJ2IjoiYSJ9"} {
+			_, _, err := DecodeCursor(encoded)
+			assert.Error(t, err, encoded)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"time"
+
+	"api/internal/activity"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ActivityChainCheckpoint periodically signs the head of the activity hash chain.
+// The signed heads let the verification detect a chain that was rewritten from scratch.
+// Running it on several instances is harmless: a sequence is only signed once.
+type ActivityChainCheckpoint struct {
+	DB         *gorm.DB
+	SigningKey string
+}
+
+// Start signs the chain head at every interval.
+func (j ActivityChainCheckpoint) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.Run()
+	}
+}
+
+// Run signs the current chain head, unless nothing was appended since the last checkpoint.
+func (j ActivityChainCheckpoint) Run() {
+	var head models.ActivityChainHead
+	if err := j.DB.First(&head, 1).Error; err != nil {
+		zap.L().Error("Failed to read the activity chain head", zap.Error(err))
+		return
+	}
+
+	if head.Sequence == 0 {
+		return
+	}
+
+	checkpoint := models.ActivityChainCheckpoint{
+		Sequence:  head.Sequence,
+		Hash:      head.Hash,
+		Signature: activity.SignCheckpoint(j.SigningKey, head.Sequence, head.Hash),
+	}
+
+	err := j.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&checkpoint).Error
+	if err != nil {
+		zap.L().Error("Failed to sign the activity chain head", zap.Error(err))
+	}
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"time"
+
+	"api/internal/activity"
+
+	"go.uber.org/zap"
+)
+
+// ActivityDelivery delivers the queued activity, and the activity whose delivery failed, to the activity backend.
+// Running it on several instances is safe: each batch is leased by the instance delivering it.
+type ActivityDelivery struct {
+	Chain     *activity.ChainedActivityLogger
+	BatchSize int
+}
+
+// Start delivers the queued activity at every interval.
+func (j ActivityDelivery) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.Run()
+	}
+}
+
+// Run delivers batches until the due activity is exhausted or a delivery fails.
+func (j ActivityDelivery) Run() {
+	for {
+		delivered, err := j.Chain.Deliver(j.BatchSize)
+		if err != nil {
+			zap.L().Error("Failed to deliver the queued activity", zap.Error(err))
+			return
+		}
+		if delivered < j.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"time"
+
+	"api/internal/cache"
+
+	"go.uber.org/zap"
+)
+
+// runAsLeader runs the job at every interval on the instance holding the lock named after the job.
+// The lock outlives the interval, so that the leader keeps it between two runs,
+// and another instance takes over once the leader stops extending it.
+func runAsLeader(store cache.ICache, name string, identity string, interval time.Duration, run func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		runIfLeader(store, name, identity, 2*interval, run)
+	}
+}
+
+// runIfLeader runs the job when the instance takes the lock of the job, or extends it, for the ttl.
+func runIfLeader(store cache.ICache, name string, identity string, ttl time.Duration, run func()) {
+	leader, err := store.AcquireLock(name, identity, ttl)
+	if err != nil {
+		zap.L().Error("Failed to acquire the job lock", zap.String("job", name), zap.Error(err))
+		return
+	}
+	if leader {
+		run()
+	}
+}
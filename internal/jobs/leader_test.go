@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// expiringLockCache implements the locks of cache.ICache in memory, expiring them on a clock set by the test.
+type expiringLockCache struct {
+	now     time.Time
+	err     error
+	owners  map[string]string
+	expires map[string]time.Time
+}
+
+func newExpiringLockCache() *expiringLockCache {
+	return &expiringLockCache{
+		now:     time.Now(),
+		owners:  map[string]string{},
+		expires: map[string]time.Time{},
+	}
+}
+
+func (c *expiringLockCache) RegisterPlatform(_ string) error { return nil }
+func (c *expiringLockCache) DeleteInactivePlatform() error   { return nil }
+func (c *expiringLockCache) StartIdentityTicker(_ string)    {}
+func (c *expiringLockCache) GetRateLimit(_ string, _ int) (int, error) {
+	return 0, nil
+}
+func (c *expiringLockCache) Publish(_ string, _ string) error { return nil }
+func (c *expiringLockCache) Subscribe(_ context.Context, _ string, _ func(message string)) error {
+	return nil
+}
+func (c *expiringLockCache) Close() error { return nil }
+
+func (c *expiringLockCache) AcquireLock(name string, owner string, ttl time.Duration) (bool, error) {
+	if c.err != nil {
+		return false, c.err
+	}
+	if current, exists := c.owners[name]; exists && current != owner && c.now.Before(c.expires[name]) {
+		return false, nil
+	}
+	c.owners[name] = owner
+	c.expires[name] = c.now.Add(ttl)
+	return true, nil
+}
+
+func TestRunIfLeader(t *testing.T) {
+	store := newExpiringLockCache()
+	interval := time.Minute
+	runs := map[string]int{}
+	tick := func(identity string) {
+		runIfLeader(store, "job", identity, 2*interval, func() { runs[identity]++ })
+	}
+
+	// The first instance takes the lock and keeps it by running at every interval
+	tick("leader")
+	tick("follower")
+	for range 3 {
+		store.now = store.now.Add(interval)
+		tick("leader")
+		tick("follower")
+	}
+	assert.Equal(t, map[string]int{"leader": 4}, runs)
+
+	// A single missed interval does not hand the lock over
+	store.now = store.now.Add(interval)
+	tick("follower")
+	assert.Zero(t, runs["follower"])
+
+	// Once the leader stops, the follower takes over and the former leader no longer runs
+	store.now = store.now.Add(interval + time.Second)
+	tick("follower")
+	tick("leader")
+	assert.Equal(t, map[string]int{"leader": 4, "follower": 1}, runs)
+}
+
+func TestRunIfLeaderWithoutCache(t *testing.T) {
+	store := newExpiringLockCache()
+	store.err = errors.New("connection refused")
+
+	runs := 0
+	runIfLeader(store, "job", "leader", time.Minute, func() { runs++ })
+	assert.Zero(t, runs)
+}
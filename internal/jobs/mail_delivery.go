@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"time"
+
+	"api/internal/notifier"
+
+	"go.uber.org/zap"
+)
+
+// MailDelivery sends the queued emails, and removes the mail log past its retention period.
+// Running it on several instances is safe: each email is leased by the instance sending it.
+// The send rate applies to each instance.
+type MailDelivery struct {
+	Queue     *notifier.MailQueue
+	BatchSize int
+}
+
+// Start sends the queued emails at every interval.
+func (j MailDelivery) Start(interval time.Duration) {
+	if err := j.Queue.Purge(); err != nil {
+		zap.L().Error("Failed to purge the mail log", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	purge := time.NewTicker(24 * time.Hour)
+	defer purge.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			j.Run()
+		case <-purge.C:
+			if err := j.Queue.Purge(); err != nil {
+				zap.L().Error("Failed to purge the mail log", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Run sends batches until the due emails are exhausted.
+func (j MailDelivery) Run() {
+	for {
+		sent, err := j.Queue.Deliver(j.BatchSize)
+		if err != nil {
+			zap.L().Error("Failed to deliver the queued emails", zap.Error(err))
+			return
+		}
+		if sent < j.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package jobs
+
+import (
+	"time"
+
+	"api/internal/activity"
+	"api/internal/events"
+	"api/internal/messaging"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MembershipExpiration removes time-limited memberships and invites once they expire,
+// and warns the bucket owners before and after a membership expires.
+// It is safe to run on several instances: each membership is claimed with a conditional update
+// so that a warning or a removal is only processed once.
+type MembershipExpiration struct {
+	DB             *gorm.DB
+	Publisher      messaging.IPublisher
+	ActivityLogger activity.IActivityLogger
+	WarningPeriod  time.Duration
+}
+
+// Start runs the job immediately, then at every interval.
+func (j MembershipExpiration) Start(interval time.Duration) {
+	j.Run()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.Run()
+	}
+}
+
+// Run processes the memberships and invites expiring at the current time.
+func (j MembershipExpiration) Run() {
+	now := time.Now()
+
+	if err := j.warnExpiringMemberships(now); err != nil {
+		zap.L().Error("Failed to warn about expiring memberships", zap.Error(err))
+	}
+	if err := j.removeExpiredMemberships(now); err != nil {
+		zap.L().Error("Failed to remove expired memberships", zap.Error(err))
+	}
+	if err := j.removeExpiredInvites(now); err != nil {
+		zap.L().Error("Failed to remove expired invites", zap.Error(err))
+	}
+}
+
+func (j MembershipExpiration) warnExpiringMemberships(now time.Time) error {
+	var memberships []models.Membership
+	err := j.DB.Preload("User").Preload("Bucket").
+		Where("expires_at > ? AND expires_at <= ? AND expiry_notified_at IS NULL", now, now.Add(j.WarningPeriod)).
+		Find(&memberships).Error
+	if err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		result := j.DB.Model(&models.Membership{}).
+			Where("id = ? AND expiry_notified_at IS NULL", membership.ID).
+			Update("expiry_notified_at", now)
+		if result.Error != nil {
+			zap.L().Error("Failed to mark membership expiry as notified",
+				zap.String("membership_id", membership.ID.String()),
+				zap.Error(result.Error))
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		j.notifyOwners(membership, false)
+	}
+
+	return nil
+}
+
+func (j MembershipExpiration) removeExpiredMemberships(now time.Time) error {
+	var memberships []models.Membership
+	err := j.DB.Preload("User").Preload("Bucket").
+		Where("expires_at <= ?", now).
+		Find(&memberships).Error
+	if err != nil {
+		return err
+	}
+
+	for _, membership := range memberships {
+		removed := false
+		err = j.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("id = ?", membership.ID).Delete(&models.Membership{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			if err := rbac.DeleteFolderPermissions(tx, membership.UserID, membership.BucketID); err != nil {
+				return err
+			}
+
+			removed = true
+			action := j.memberDeletedActivity(membership.Bucket, membership.User.Email)
+			return activity.SendTx(j.ActivityLogger, tx, action)
+		})
+		if err != nil {
+			zap.L().Error("Failed to remove expired membership",
+				zap.String("membership_id", membership.ID.String()),
+				zap.Error(err))
+			continue
+		}
+
+		if removed {
+			zap.L().Info("Removed expired membership",
+				zap.String("bucket_id", membership.BucketID.String()),
+				zap.String("user_id", membership.UserID.String()))
+			j.notifyOwners(membership, true)
+			j.dispatchMemberDeleted(membership.BucketID, membership.User.Email)
+		}
+	}
+
+	return nil
+}
+
+func (j MembershipExpiration) removeExpiredInvites(now time.Time) error {
+	var invites []models.Invite
+	if err := j.DB.Preload("Bucket").Where("expires_at <= ?", now).Find(&invites).Error; err != nil {
+		return err
+	}
+
+	for _, invite := range invites {
+		removed := false
+		err := j.DB.Transaction(func(tx *gorm.DB) error {
+			result := tx.Where("id = ?", invite.ID).Delete(&models.Invite{})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			removed = true
+			return activity.SendTx(j.ActivityLogger, tx, j.memberDeletedActivity(invite.Bucket, invite.Email))
+		})
+		if err != nil {
+			zap.L().Error("Failed to remove expired invite",
+				zap.String("invite_id", invite.ID.String()),
+				zap.Error(err))
+			continue
+		}
+
+		if removed {
+			j.dispatchMemberDeleted(invite.BucketID, invite.Email)
+		}
+	}
+
+	return nil
+}
+
+func (j MembershipExpiration) memberDeletedActivity(bucket models.Bucket, email string) models.Activity {
+	return models.Activity{
+		Message: activity.BucketMemberDeleted,
+		Object:  bucket.ToActivity(),
+		Filter: activity.NewLogFilter(map[string]string{
+			"action":              rbac.ActionGrant.String(),
+			"object_type":         rbac.ResourceBucket.String(),
+			"bucket_id":           bucket.ID.String(),
+			"bucket_member_email": email,
+		}),
+	}
+}
+
+// dispatchMemberDeleted notifies the webhooks of the bucket that a member has been removed.
+func (j MembershipExpiration) dispatchMemberDeleted(bucketID uuid.UUID, email string) {
+	dispatchEvent := events.NewWebhookDispatch(
+		j.Publisher,
+		bucketID,
+		nil,
+		models.WebhookEventMemberDeleted,
+		models.WebhookMember{Email: email},
+	)
+	dispatchEvent.Trigger()
+}
+
+// notifyOwners emails the owners of the bucket, except the member whose access expires.
+func (j MembershipExpiration) notifyOwners(membership models.Membership, expired bool) {
+	owners, err := rbac.GetBucketMembers(j.DB, membership.BucketID)
+	if err != nil {
+		zap.L().Error("Failed to fetch bucket owners",
+			zap.String("bucket_id", membership.BucketID.String()),
+			zap.Error(err))
+		return
+	}
+
+	now := time.Now()
+	for _, owner := range owners {
+		if owner.Group != models.GroupOwner || owner.UserID == membership.UserID || owner.IsExpired(now) {
+			continue
+		}
+
+		event := events.NewMembershipExpiry(
+			j.Publisher,
+			owner.User.Email,
+			membership.User.Email,
+			membership.Group,
+			membership.Bucket,
+			*membership.ExpiresAt,
+			expired,
+		)
+		event.Trigger()
+	}
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/events"
+	"api/internal/messaging"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// NotificationDigest triggers the daily and weekly digests whose period has elapsed.
+// Running it on several instances is safe: each digest is claimed with a conditional update
+// of the date of the previous digest, so that a period is only sent once.
+type NotificationDigest struct {
+	DB        *gorm.DB
+	Publisher messaging.IPublisher
+}
+
+// Start triggers the due digests at every interval.
+func (j NotificationDigest) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.Run()
+	}
+}
+
+// Run claims a batch of due digests and triggers them, each covering the activity since the previous digest.
+func (j NotificationDigest) Run() {
+	now := time.Now()
+
+	var preferences []models.NotificationPreference
+	err := j.DB.
+		Where("enabled = ? AND ((frequency = ? AND last_digest_at <= ?) OR (frequency = ? AND last_digest_at <= ?))",
+			true,
+			models.NotificationFrequencyDaily, now.Add(-24*time.Hour),
+			models.NotificationFrequencyWeekly, now.Add(-7*24*time.Hour)).
+		Order("last_digest_at").
+		Limit(c.NotificationDigestBatchSize).
+		Find(&preferences).Error
+	if err != nil {
+		zap.L().Error("Failed to fetch the due notification digests", zap.Error(err))
+		return
+	}
+
+	for _, preference := range preferences {
+		result := j.DB.Model(&models.NotificationPreference{}).
+			Where("id = ? AND last_digest_at = ?", preference.ID, preference.LastDigestAt).
+			Update("last_digest_at", now)
+		if result.Error != nil {
+			zap.L().Error("Failed to claim notification digest",
+				zap.String("preference_id", preference.ID.String()),
+				zap.Error(result.Error))
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		digestEvent := events.NewNotificationDigest(j.Publisher, preference.ID, preference.LastDigestAt, now)
+		digestEvent.Trigger()
+	}
+}
@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"time"
+
+	"api/internal/cache"
+	c "api/internal/configuration"
+	"api/internal/events"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const trashExpirationLock = "trash_expiration"
+
+// TrashExpiration purges the files and folders whose retention period in the trash is over,
+// which is the retention of their bucket, or the one of the platform.
+// It is a fallback for the storages which do not report the expiration of the trash markers, or expire them
+// after a rounded up retention, and leaves them a grace period to do so.
+// It only runs on the instance holding the lock of the job.
+type TrashExpiration struct {
+	Params   *events.EventParams
+	Cache    cache.ICache
+	Identity string
+}
+
+// Start runs the job at every interval while the instance is the leader.
+func (j TrashExpiration) Start(interval time.Duration) {
+	runAsLeader(j.Cache, trashExpirationLock, j.Identity, interval, j.Run)
+}
+
+// Run purges the expired files, then the expired folders, which are then empty.
+func (j TrashExpiration) Run() {
+	expiration := time.Now().Add(-c.TrashExpirationGraceHours * time.Hour)
+
+	if err := j.expireFiles(expiration); err != nil {
+		zap.L().Error("Failed to expire the trashed files", zap.Error(err))
+	}
+	if err := j.expireFolders(expiration); err != nil {
+		zap.L().Error("Failed to expire the trashed folders", zap.Error(err))
+	}
+}
+
+func (j TrashExpiration) expireFiles(expiration time.Time) error {
+	var last *models.File
+	for {
+		query := j.expired("files", expiration)
+		if last != nil {
+			query = query.Where("(files.deleted_at, files.id) > (?, ?)", last.DeletedAt, last.ID)
+		}
+
+		var files []models.File
+		if err := query.Find(&files).Error; err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if err := events.ExpireTrashedFile(j.Params, file); err != nil {
+				zap.L().Error("Failed to expire trashed file",
+					zap.String("file_id", file.ID.String()),
+					zap.Error(err))
+			}
+		}
+
+		if len(files) < c.TrashExpirationBatchSize {
+			return nil
+		}
+		last = &files[len(files)-1]
+	}
+}
+
+func (j TrashExpiration) expireFolders(expiration time.Time) error {
+	var last *models.Folder
+	for {
+		query := j.expired("folders", expiration)
+		if last != nil {
+			query = query.Where("(folders.deleted_at, folders.id) > (?, ?)", last.DeletedAt, last.ID)
+		}
+
+		var folders []models.Folder
+		if err := query.Find(&folders).Error; err != nil {
+			return err
+		}
+
+		for _, folder := range folders {
+			if err := events.ExpireTrashedFolder(j.Params, folder); err != nil {
+				// A folder purged with its parent is not found anymore
+				zap.L().Warn("Failed to expire trashed folder",
+					zap.String("folder_id", folder.ID.String()),
+					zap.Error(err))
+			}
+		}
+
+		if len(folders) < c.TrashExpirationBatchSize {
+			return nil
+		}
+		last = &folders[len(folders)-1]
+	}
+}
+
+// expired selects the trashed files or folders of the table whose retention is over at the expiration.
+func (j TrashExpiration) expired(table string, expiration time.Time) *gorm.DB {
+	return j.Params.DB.Unscoped().
+		Table(table).
+		Select(table+".*").
+		Joins("JOIN buckets ON buckets.id = "+table+".bucket_id").
+		Where(table+".status = ?", models.FileStatusDeleted).
+		Where(table+".deleted_at + make_interval(days => COALESCE(buckets.trash_retention_days, ?)) < ?",
+			j.Params.TrashRetentionDays, expiration).
+		Order(table + ".deleted_at, " + table + ".id").
+		Limit(c.TrashExpirationBatchSize)
+}
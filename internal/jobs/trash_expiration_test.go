@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"regexp"
+	"testing"
+
+	c "api/internal/configuration"
+	"api/internal/events"
+	"api/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashExpirationRun(t *testing.T) {
+	db, dbMock := newMockDB(t)
+
+	for _, table := range []string{"files", "folders"} {
+		// The retention of the bucket applies, or the one of the platform
+		dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT `+table+`.* FROM "`+table+`" `+
+			`JOIN buckets ON buckets.id = `+table+`.bucket_id WHERE `+table+`.status = $1 `+
+			`AND `+table+`.deleted_at + make_interval(days => COALESCE(buckets.trash_retention_days, $2)) < $3 `+
+			`ORDER BY `+table+`.deleted_at, `+table+`.id LIMIT $4`)).
+			WithArgs(models.FileStatusDeleted, 30, sqlmock.AnyArg(), c.TrashExpirationBatchSize).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+
+	job := TrashExpiration{Params: &events.EventParams{DB: db, TrashRetentionDays: 30}}
+	job.Run()
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
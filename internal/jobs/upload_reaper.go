@@ -0,0 +1,138 @@
+package jobs
+
+import (
+	"errors"
+	"path"
+	"time"
+
+	"api/internal/cache"
+	c "api/internal/configuration"
+	"api/internal/events"
+	"api/internal/models"
+	"api/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+const uploadReaperLock = "upload_reaper"
+
+// UploadReaper cleans the files left in the uploading status once their upload policy expired.
+// A file whose object exists missed its upload event and is completed, the others are removed
+// so that their names can be used again. It also publishes again the scans of the files left
+// in the scanning status, whose scan failed or whose event was lost.
+// It only runs on the instance holding the lock of the job in the cache.
+type UploadReaper struct {
+	Params   *events.EventParams
+	Cache    cache.ICache
+	Identity string
+}
+
+// Start runs the job at every interval while the instance is the leader.
+func (j UploadReaper) Start(interval time.Duration) {
+	runAsLeader(j.Cache, uploadReaperLock, j.Identity, interval, j.Run)
+}
+
+// Run processes the abandoned uploads and the pending scans.
+func (j UploadReaper) Run() {
+	expiration := time.Now().Add(-(c.UploadPolicyExpirationInMinutes + c.UploadReaperGraceMinutes) * time.Minute)
+	j.forEachFile("created_at", models.FileStatusUploading, expiration, j.reap)
+
+	// A failed scan updates the file, so that the scans are retried at most once per grace period
+	j.forEachFile("updated_at", models.FileStatusScanning, time.Now().Add(-c.UploadReaperGraceMinutes*time.Minute),
+		j.rescan)
+}
+
+// forEachFile processes the files of a status whose date column is before the cutoff in batches, from the oldest.
+func (j UploadReaper) forEachFile(column string, status models.FileStatus, cutoff time.Time, fn func(models.File)) {
+	var last *models.File
+	var lastDate time.Time
+	for {
+		query := j.Params.DB.
+			Where("status = ? AND "+column+" < ?", status, cutoff).
+			Order(column + ", id").
+			Limit(c.UploadReaperBatchSize)
+		if last != nil {
+			query = query.Where("("+column+", id) > (?, ?)", lastDate, last.ID)
+		}
+
+		var files []models.File
+		if err := query.Find(&files).Error; err != nil {
+			zap.L().Error("Failed to fetch the files to reap", zap.String("status", string(status)), zap.Error(err))
+			return
+		}
+
+		for _, file := range files {
+			fn(file)
+		}
+
+		if len(files) < c.UploadReaperBatchSize {
+			return
+		}
+		last = &files[len(files)-1]
+		lastDate = last.CreatedAt
+		if column == "updated_at" {
+			lastDate = last.UpdatedAt
+		}
+	}
+}
+
+// rescan publishes again the scan of a file, on behalf of its uploader when the storage reports it.
+func (j UploadReaper) rescan(file models.File) {
+	uploaderID := ""
+	object, err := j.Params.Storage.StatObject(path.Join("buckets", file.BucketID.String(), file.ID.String()))
+	if err == nil {
+		uploaderID = object.Uploader()
+	}
+
+	zap.L().Info("Publishing again the scan of a file",
+		zap.String("file_id", file.ID.String()),
+		zap.Int("attempts", file.ScanAttempts))
+	scanEvent := events.NewFileScan(j.Params.Publisher, file.BucketID, file.ID, uploaderID)
+	scanEvent.Trigger()
+}
+
+func (j UploadReaper) reap(file models.File) {
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+	object, err := j.Params.Storage.StatObject(objectPath)
+	if err == nil {
+		zap.L().Info("Completing an upload whose event was missed", zap.String("file_id", file.ID.String()))
+		if err = events.CompleteUpload(j.Params, file, object.Uploader()); err != nil {
+			zap.L().Error("Failed to complete the upload",
+				zap.String("file_id", file.ID.String()),
+				zap.Error(err))
+		}
+		return
+	}
+	if !errors.Is(err, storage.ErrObjectNotFound) {
+		zap.L().Error("Failed to stat the object of an abandoned upload",
+			zap.String("path", objectPath),
+			zap.Error(err))
+		return
+	}
+
+	// An upload completing in the meantime is kept
+	result := j.Params.DB.Unscoped().
+		Where("id = ? AND status = ?", file.ID, models.FileStatusUploading).
+		Delete(&models.File{})
+	if result.Error != nil {
+		zap.L().Error("Failed to remove an abandoned upload",
+			zap.String("file_id", file.ID.String()),
+			zap.Error(result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		return
+	}
+
+	// Removes any object left by the upload, which the storage may not report yet
+	if err = j.Params.Storage.RemoveObject(objectPath); err != nil {
+		zap.L().Debug("No object to remove for the abandoned upload",
+			zap.String("path", objectPath),
+			zap.Error(err))
+	}
+
+	zap.L().Info("Removed an abandoned upload",
+		zap.String("file_id", file.ID.String()),
+		zap.String("bucket_id", file.BucketID.String()))
+	j.Params.BucketHub.Publish(models.NewFileEvent(models.BucketEventFilePurged, file, nil))
+}
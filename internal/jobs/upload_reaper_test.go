@@ -0,0 +1,172 @@
+package jobs
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/events"
+	"api/internal/models"
+	"api/internal/storage"
+	"api/internal/tests"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+func abandonedFile(status models.FileStatus) models.File {
+	return models.File{
+		ID:        uuid.New(),
+		BucketID:  uuid.New(),
+		Name:      "report.pdf",
+		Status:    status,
+		Size:      10,
+		CreatedAt: time.Now().Add(-time.Hour),
+	}
+}
+
+func fileObjectPath(file models.File) string {
+	return path.Join("buckets", file.BucketID.String(), file.ID.String())
+}
+
+func TestUploadReaperReap(t *testing.T) {
+	uploaderID := uuid.New().String()
+
+	testCases := []struct {
+		name          string
+		statErr       error
+		deletedRows   int64
+		expectedPurge bool
+	}{
+		{
+			name: "Upload whose event was missed",
+		},
+		{
+			name:          "Abandoned upload",
+			statErr:       storage.ErrObjectNotFound,
+			deletedRows:   1,
+			expectedPurge: true,
+		},
+		{
+			name:        "Upload completed in the meantime",
+			statErr:     storage.ErrObjectNotFound,
+			deletedRows: 0,
+		},
+		{
+			name:    "Storage unavailable",
+			statErr: errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			file := abandonedFile(models.FileStatusUploading)
+			db, dbMock := newMockDB(t)
+
+			store := &tests.MockStorage{}
+			store.On("StatObject", fileObjectPath(file)).Return(storage.ObjectInfo{
+				Size:     int64(file.Size),
+				Metadata: map[string]string{"user_id": uploaderID},
+			}, tt.statErr)
+			publisher := &tests.MockPublisher{}
+			bucketHub := &tests.MockBucketHub{}
+
+			switch {
+			case tt.statErr == nil:
+				// The upload is completed on behalf of its uploader, then scanned
+				dbMock.ExpectBegin()
+				dbMock.ExpectExec(regexp.QuoteMeta(`UPDATE "files" SET "status"=$1`)).
+					WithArgs(models.FileStatusScanning, sqlmock.AnyArg(), file.ID, models.FileStatusUploading).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+				publisher.On("Publish", mock.Anything).Return(nil)
+			case errors.Is(tt.statErr, storage.ErrObjectNotFound):
+				dbMock.ExpectBegin()
+				dbMock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "files" WHERE id = $1 AND status = $2`)).
+					WithArgs(file.ID, models.FileStatusUploading).
+					WillReturnResult(sqlmock.NewResult(0, tt.deletedRows))
+				dbMock.ExpectCommit()
+				store.On("RemoveObject", fileObjectPath(file)).Return(nil)
+				bucketHub.On("Publish", mock.Anything).Return()
+			}
+
+			job := UploadReaper{Params: &events.EventParams{
+				DB:        db,
+				Storage:   store,
+				Publisher: publisher,
+				Scanner:   &tests.MockScanner{},
+				BucketHub: bucketHub,
+			}}
+			job.reap(file)
+
+			if tt.statErr == nil {
+				publisher.AssertNumberOfCalls(t, "Publish", 1)
+			} else {
+				publisher.AssertNotCalled(t, "Publish", mock.Anything)
+			}
+			if tt.expectedPurge {
+				store.AssertCalled(t, "RemoveObject", fileObjectPath(file))
+				bucketHub.AssertCalled(t, "Publish", mock.MatchedBy(func(event models.BucketEvent) bool {
+					return event.Type == models.BucketEventFilePurged && event.BucketID == file.BucketID
+				}))
+			} else {
+				store.AssertNotCalled(t, "RemoveObject", mock.Anything)
+				bucketHub.AssertNotCalled(t, "Publish", mock.Anything)
+			}
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUploadReaperRescan(t *testing.T) {
+	file := abandonedFile(models.FileStatusScanning)
+	store := &tests.MockStorage{}
+	store.On("StatObject", fileObjectPath(file)).Return(storage.ObjectInfo{
+		Metadata: map[string]string{"User-Id": "uploader"},
+	}, nil)
+	publisher := &tests.MockPublisher{}
+	publisher.On("Publish", mock.Anything).Return(nil)
+
+	job := UploadReaper{Params: &events.EventParams{Storage: store, Publisher: publisher}}
+	job.rescan(file)
+
+	publisher.AssertNumberOfCalls(t, "Publish", 1)
+}
+
+func TestUploadReaperRun(t *testing.T) {
+	db, dbMock := newMockDB(t)
+
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE (status = $1 AND created_at < $2) `+
+		`AND "files"."deleted_at" IS NULL ORDER BY created_at, id LIMIT $3`)).
+		WithArgs(models.FileStatusUploading, sqlmock.AnyArg(), c.UploadReaperBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "files" WHERE (status = $1 AND updated_at < $2) `+
+		`AND "files"."deleted_at" IS NULL ORDER BY updated_at, id LIMIT $3`)).
+		WithArgs(models.FileStatusScanning, sqlmock.AnyArg(), c.UploadReaperBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	job := UploadReaper{Params: &events.EventParams{DB: db}}
+	job.Run()
+
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,63 @@
+package jobs
+
+import (
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/events"
+	"api/internal/messaging"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WebhookRetry publishes again the pending webhook deliveries whose retry is due,
+// as well as the deliveries whose lease expired because their delivery event was lost.
+// Running it on several instances is safe: each delivery is leased before being published.
+type WebhookRetry struct {
+	DB        *gorm.DB
+	Publisher messaging.IPublisher
+}
+
+// Start publishes the due deliveries at every interval.
+func (j WebhookRetry) Start(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		j.Run()
+	}
+}
+
+// Run leases a batch of due deliveries and triggers their delivery.
+func (j WebhookRetry) Run() {
+	var deliveries []models.WebhookDelivery
+	err := j.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(c.WebhookRetryBatchSize).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]string, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.ID.String())
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", time.Now().Add(c.WebhookDeliveryLeaseSeconds*time.Second)).Error
+	})
+	if err != nil {
+		zap.L().Error("Failed to fetch the due webhook deliveries", zap.Error(err))
+		return
+	}
+
+	for _, delivery := range deliveries {
+		deliveryEvent := events.NewWebhookDelivery(j.Publisher, delivery.ID)
+		deliveryEvent.Trigger()
+	}
+}
@@ -0,0 +1,8 @@
+// Package mails embeds the email templates, with one directory of templates per language.
+// Each message has an HTML template and a plain-text template, which defines the "subject" template.
+package mails
+
+import "embed"
+
+//go:embed en fr
+var Templates embed.FS
@@ -129,7 +129,7 @@ func (s *AWSSubscriber) ParseBucketUploadEvents(message *message.Message) []Buck
 
 	var uploadEvents []BucketUploadEvent
 	for _, record := range event.Records {
-		metadata, err := s.storage.StatObject(record.S3.Object.Key)
+		object, err := s.storage.StatObject(record.S3.Object.Key)
 		if err != nil {
 			zap.L().Error("failed to stat object",
 				zap.String("object_key", record.S3.Object.Key),
@@ -137,9 +137,9 @@ func (s *AWSSubscriber) ParseBucketUploadEvents(message *message.Message) []Buck
 			continue
 		}
 
-		bucketID := metadata["bucket_id"]
-		fileID := metadata["file_id"]
-		userID := metadata["user_id"]
+		bucketID := object.Metadata["bucket_id"]
+		fileID := object.Metadata["file_id"]
+		userID := object.Metadata["user_id"]
 
 		if bucketID == "" || fileID == "" || userID == "" {
 			zap.L().Warn("incomplete metadata in object",
@@ -8,9 +8,13 @@ import (
 	"api/internal/configuration"
 	"api/internal/helpers"
 	"api/internal/models"
+
+	"gorm.io/gorm"
 )
 
-func Authenticate(jwtSecret string) func(next http.Handler) http.Handler {
+// Authenticate validates the access token and checks that the account still exists and is not disabled.
+// The role is taken from the database so that role changes apply without waiting for the token to expire.
+func Authenticate(jwtSecret string, db *gorm.DB) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			if isExcluded(r.URL.Path, r.Method) {
@@ -23,6 +27,19 @@ func Authenticate(jwtSecret string) func(next http.Handler) http.Handler {
 					helpers.RespondWithError(w, 403, []string{"FORBIDDEN"})
 					return
 				}
+
+				var user models.User
+				result := db.Select("id", "role", "disabled_at").Where("id = ?", userClaims.UserID).Find(&user)
+				if result.Error != nil || result.RowsAffected == 0 {
+					helpers.RespondWithError(w, 403, []string{"FORBIDDEN"})
+					return
+				}
+				if user.IsDisabled() {
+					helpers.RespondWithError(w, 403, []string{"USER_DISABLED"})
+					return
+				}
+				userClaims.Role = user.Role
+
 				ctx := context.WithValue(r.Context(), models.UserClaimKey{}, userClaims)
 				next.ServeHTTP(w, r.WithContext(ctx))
 			}
@@ -2,6 +2,7 @@ package middlewares
 
 import (
 	"context"
+	"database/sql/driver"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,10 +11,13 @@ import (
 	"api/internal/models"
 	"api/internal/tests"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
 )
 
 const testJWTSecret = "test-secret-key-for-testing"
@@ -48,6 +52,31 @@ func generateTestToken(secret string, user *models.User, expiresIn time.Duration
 	return token.SignedString([]byte(secret))
 }
 
+// setupUserDB returns a database where the account lookup of the middleware finds the given user.
+// A nil user simulates a deleted or unknown account.
+func setupUserDB(t *testing.T, user *models.User) *gorm.DB {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+
+	rows := sqlmock.NewRows([]string{"id", "role", "disabled_at"})
+	if user != nil {
+		var disabledAt driver.Value
+		if user.DisabledAt != nil {
+			disabledAt = *user.DisabledAt
+		}
+		rows.AddRow(user.ID, user.Role, disabledAt)
+	}
+	mock.ExpectQuery(`SELECT "id","role","disabled_at" FROM "users"`).WillReturnRows(rows)
+
+	return gormDB
+}
+
 func TestAuthenticate(t *testing.T) {
 	testUser := &models.User{
 		ID:    uuid.New(),
@@ -142,7 +171,8 @@ func TestAuthenticate(t *testing.T) {
 			}
 			recorder := httptest.NewRecorder()
 
-			handler := Authenticate(testJWTSecret)(http.HandlerFunc(mockAuthenticatedNextHandler))
+			db := setupUserDB(t, testUser)
+			handler := Authenticate(testJWTSecret, db)(http.HandlerFunc(mockAuthenticatedNextHandler))
 			handler.ServeHTTP(recorder, req)
 
 			assert.Equal(t, tt.expectedStatus, recorder.Code)
@@ -239,7 +269,7 @@ func TestAuthenticate_ExcludedPaths(t *testing.T) {
 				_, _ = w.Write([]byte("OK"))
 			})
 
-			handler := Authenticate(testJWTSecret)(simpleHandler)
+			handler := Authenticate(testJWTSecret, setupUserDB(t, testUser))(simpleHandler)
 			handler.ServeHTTP(recorder, req)
 
 			assert.Equal(t, tt.expectedStatus, recorder.Code, tt.description)
@@ -283,6 +313,24 @@ func TestIsExcluded(t *testing.T) {
 			method:   "GET",
 			expected: true,
 		},
+		{
+			name:     "Excluded - unsubscribe link with GET",
+			path:     "/api/v1/notifications/unsubscribe",
+			method:   "GET",
+			expected: true,
+		},
+		{
+			name:     "Excluded - one-click unsubscription with POST",
+			path:     "/api/v1/notifications/unsubscribe",
+			method:   "POST",
+			expected: true,
+		},
+		{
+			name:     "Not excluded - /api/v1/notifications/mails (RequireAuth: true)",
+			path:     "/api/v1/notifications/mails",
+			method:   "GET",
+			expected: false,
+		},
 		{
 			name:     "Not excluded - /api/v1/buckets (RequireAuth: true)",
 			path:     "/api/v1/buckets",
@@ -346,7 +394,7 @@ func TestAuthenticate_UserClaimsInContext(t *testing.T) {
 	req.Header.Set("Authorization", "Bearer "+validToken)
 	recorder := httptest.NewRecorder()
 
-	handler := Authenticate(testJWTSecret)(testHandler)
+	handler := Authenticate(testJWTSecret, setupUserDB(t, testUser))(testHandler)
 	handler.ServeHTTP(recorder, req)
 
 	assert.Equal(t, http.StatusOK, recorder.Code)
@@ -389,8 +437,79 @@ func TestAuthenticate_ContextPropagation(t *testing.T) {
 
 	recorder := httptest.NewRecorder()
 
-	handler := Authenticate(testJWTSecret)(testHandler)
+	handler := Authenticate(testJWTSecret, setupUserDB(t, testUser))(testHandler)
 	handler.ServeHTTP(recorder, req)
 
 	assert.Equal(t, http.StatusOK, recorder.Code)
 }
+
+func TestAuthenticate_AccountStatus(t *testing.T) {
+	testUser := &models.User{
+		ID:    uuid.New(),
+		Email: "status@example.com",
+		Role:  models.RoleAdmin,
+	}
+
+	validToken, err := generateTestToken(testJWTSecret, testUser, time.Hour)
+	require.NoError(t, err)
+
+	disabledAt := time.Now()
+
+	testCases := []struct {
+		name           string
+		user           *models.User
+		expectedStatus int
+		expectedErrors []string
+		expectedRole   models.Role
+	}{
+		{
+			name:           "Active user",
+			user:           &models.User{ID: testUser.ID, Role: models.RoleAdmin},
+			expectedStatus: http.StatusOK,
+			expectedRole:   models.RoleAdmin,
+		},
+		{
+			name:           "Role changed since the token was issued",
+			user:           &models.User{ID: testUser.ID, Role: models.RoleGuest},
+			expectedStatus: http.StatusOK,
+			expectedRole:   models.RoleGuest,
+		},
+		{
+			name:           "Disabled user",
+			user:           &models.User{ID: testUser.ID, Role: models.RoleAdmin, DisabledAt: &disabledAt},
+			expectedStatus: http.StatusForbidden,
+			expectedErrors: []string{"USER_DISABLED"},
+		},
+		{
+			name:           "Deleted user",
+			user:           nil,
+			expectedStatus: http.StatusForbidden,
+			expectedErrors: []string{"FORBIDDEN"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var capturedClaims models.UserClaims
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				capturedClaims, _ = r.Context().Value(models.UserClaimKey{}).(models.UserClaims)
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/buckets", nil)
+			req.Header.Set("Authorization", "Bearer "+validToken)
+			recorder := httptest.NewRecorder()
+
+			handler := Authenticate(testJWTSecret, setupUserDB(t, tt.user))(testHandler)
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, tt.expectedRole, capturedClaims.Role)
+			} else {
+				expected := models.Error{Status: http.StatusForbidden, Error: tt.expectedErrors}
+				tests.AssertJSONResponse(t, recorder, http.StatusForbidden, expected)
+			}
+		})
+	}
+}
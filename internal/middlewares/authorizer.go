@@ -10,9 +10,13 @@ import (
 	"gorm.io/gorm"
 )
 
-// AuthorizeRole checks if the authenticated user has at least the required role
-// Uses hierarchical role checking (Admin > User > Guest).
-func AuthorizeRole(requiredRole models.Role) func(next http.Handler) http.Handler {
+// AuthorizeRole checks if the authenticated user's platform role, or one of their global custom roles,
+// is granted the action on the resource by the policy engine.
+func AuthorizeRole(
+	engine rbac.IPolicyEngine,
+	resource rbac.Resource,
+	action rbac.Action,
+) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			userClaims, ok := r.Context().Value(models.UserClaimKey{}).(models.UserClaims)
@@ -21,7 +25,12 @@ func AuthorizeRole(requiredRole models.Role) func(next http.Handler) http.Handle
 				return
 			}
 
-			if !rbac.HasRole(userClaims.Role, requiredRole) {
+			subjects := append(
+				[]string{string(userClaims.Role)},
+				engine.RolesFor(userClaims.UserID, rbac.Wildcard)...,
+			)
+
+			if !engine.Enforce(subjects, resource, action) {
 				h.RespondWithError(w, 403, []string{"FORBIDDEN"})
 				return
 			}
@@ -31,12 +40,15 @@ func AuthorizeRole(requiredRole models.Role) func(next http.Handler) http.Handle
 	}
 }
 
-// AuthorizeGroup checks if the authenticated user has at least the required group access to a bucket
-// Uses hierarchical group checking (Owner > Contributor > Viewer)
+// AuthorizeGroup checks if the authenticated user's group on a bucket, or one of their custom roles
+// on that bucket, is granted the action on the resource by the policy engine.
+// Users without membership on the bucket are denied, whatever their global custom roles.
 // The bucketIdIndex parameter specifies which URL parameter contains the bucket ID.
 func AuthorizeGroup(
 	db *gorm.DB,
-	requiredGroup models.Group,
+	engine rbac.IPolicyEngine,
+	resource rbac.Resource,
+	action rbac.Action,
 	bucketIDIndex int,
 ) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -60,13 +72,23 @@ func AuthorizeGroup(
 
 			bucketID := ids[bucketIDIndex]
 
-			hasAccess, err := rbac.HasBucketAccess(db, userClaims.UserID, bucketID, requiredGroup)
+			membership, err := rbac.GetUserMembership(db, userClaims.UserID, bucketID)
 			if err != nil {
 				h.RespondWithError(w, 500, []string{"INTERNAL_SERVER_ERROR"})
 				return
 			}
 
-			if !hasAccess {
+			if membership == nil {
+				h.RespondWithError(w, 403, []string{"FORBIDDEN"})
+				return
+			}
+
+			subjects := append(
+				[]string{string(membership.Group)},
+				engine.RolesFor(userClaims.UserID, bucketID.String())...,
+			)
+
+			if !engine.Enforce(subjects, resource, action) {
 				h.RespondWithError(w, 403, []string{"FORBIDDEN"})
 				return
 			}
@@ -76,6 +98,41 @@ func AuthorizeGroup(
 	}
 }
 
+// AuthorizeRoleOrGroup allows the request if the authenticated user's platform role, or one of their global
+// custom roles, is granted the action on the resource, as AuthorizeRole does, or otherwise if their group
+// on the bucket is granted it, as AuthorizeGroup does.
+func AuthorizeRoleOrGroup(
+	db *gorm.DB,
+	engine rbac.IPolicyEngine,
+	resource rbac.Resource,
+	action rbac.Action,
+	bucketIDIndex int,
+) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		group := AuthorizeGroup(db, engine, resource, action, bucketIDIndex)(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userClaims, ok := r.Context().Value(models.UserClaimKey{}).(models.UserClaims)
+			if !ok {
+				h.RespondWithError(w, 401, []string{"UNAUTHORIZED"})
+				return
+			}
+
+			subjects := append(
+				[]string{string(userClaims.Role)},
+				engine.RolesFor(userClaims.UserID, rbac.Wildcard)...,
+			)
+
+			if engine.Enforce(subjects, resource, action) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			group.ServeHTTP(w, r)
+		})
+	}
+}
+
 // AuthorizeSelfOrAdmin allows the request if either:
 // 1. The authenticated user is accessing their own resource (user ID matches target ID in URL)
 // 2. The authenticated user has Admin role
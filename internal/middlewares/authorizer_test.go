@@ -9,6 +9,7 @@ import (
 	"testing"
 
 	"api/internal/models"
+	"api/internal/rbac"
 	"api/internal/tests"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -26,11 +27,15 @@ func mockAuthNextHandler(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write([]byte("OK"))
 }
 
+// policyEngine evaluates the built-in rules, which reproduce the platform roles and bucket groups.
+var policyEngine = rbac.NewStaticPolicyEngine(rbac.DefaultPolicies())
+
 func TestAuthorizeRole(t *testing.T) {
 	testCases := []struct {
 		name           string
 		userRole       models.Role
-		requiredRole   models.Role
+		resource       rbac.Resource
+		action         rbac.Action
 		hasUserClaims  bool
 		expectedStatus int
 		expectedErrors []string
@@ -38,28 +43,32 @@ func TestAuthorizeRole(t *testing.T) {
 		{
 			name:           "Admin accessing User-required endpoint",
 			userRole:       models.RoleAdmin,
-			requiredRole:   models.RoleUser,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionCreate,
 			hasUserClaims:  true,
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "User accessing User-required endpoint",
 			userRole:       models.RoleUser,
-			requiredRole:   models.RoleUser,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionCreate,
 			hasUserClaims:  true,
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "Admin accessing Admin-required endpoint",
 			userRole:       models.RoleAdmin,
-			requiredRole:   models.RoleAdmin,
+			resource:       rbac.ResourceUser,
+			action:         rbac.ActionRead,
 			hasUserClaims:  true,
 			expectedStatus: http.StatusOK,
 		},
 		{
 			name:           "Guest accessing User-required endpoint",
 			userRole:       models.RoleGuest,
-			requiredRole:   models.RoleUser,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionCreate,
 			hasUserClaims:  true,
 			expectedStatus: http.StatusForbidden,
 			expectedErrors: []string{"FORBIDDEN"},
@@ -67,7 +76,8 @@ func TestAuthorizeRole(t *testing.T) {
 		{
 			name:           "User accessing Admin-required endpoint",
 			userRole:       models.RoleUser,
-			requiredRole:   models.RoleAdmin,
+			resource:       rbac.ResourceUser,
+			action:         rbac.ActionRead,
 			hasUserClaims:  true,
 			expectedStatus: http.StatusForbidden,
 			expectedErrors: []string{"FORBIDDEN"},
@@ -75,7 +85,8 @@ func TestAuthorizeRole(t *testing.T) {
 		{
 			name:           "Missing user claims in context",
 			hasUserClaims:  false,
-			requiredRole:   models.RoleUser,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionCreate,
 			expectedStatus: http.StatusUnauthorized,
 			expectedErrors: []string{"UNAUTHORIZED"},
 		},
@@ -96,7 +107,7 @@ func TestAuthorizeRole(t *testing.T) {
 				req = req.WithContext(ctx)
 			}
 
-			handler := AuthorizeRole(tt.requiredRole)(http.HandlerFunc(mockAuthNextHandler))
+			handler := AuthorizeRole(policyEngine, tt.resource, tt.action)(http.HandlerFunc(mockAuthNextHandler))
 			handler.ServeHTTP(recorder, req)
 
 			assert.Equal(t, tt.expectedStatus, recorder.Code)
@@ -116,7 +127,8 @@ func TestAuthorizeGroup(t *testing.T) {
 	testCases := []struct {
 		name             string
 		userGroup        models.Group
-		requiredGroup    models.Group
+		resource         rbac.Resource
+		action           rbac.Action
 		hasUserClaims    bool
 		bucketIDIndex    int
 		setupURLParams   bool
@@ -129,7 +141,8 @@ func TestAuthorizeGroup(t *testing.T) {
 		{
 			name:           "Owner accessing Viewer-required endpoint",
 			userGroup:      models.GroupOwner,
-			requiredGroup:  models.GroupViewer,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionRead,
 			hasUserClaims:  true,
 			bucketIDIndex:  0,
 			setupURLParams: true,
@@ -146,7 +159,8 @@ func TestAuthorizeGroup(t *testing.T) {
 		{
 			name:           "Contributor accessing Contributor-required endpoint",
 			userGroup:      models.GroupContributor,
-			requiredGroup:  models.GroupContributor,
+			resource:       rbac.ResourceFile,
+			action:         rbac.ActionCreate,
 			hasUserClaims:  true,
 			bucketIDIndex:  0,
 			setupURLParams: true,
@@ -163,7 +177,8 @@ func TestAuthorizeGroup(t *testing.T) {
 		{
 			name:           "Viewer accessing Owner-required endpoint",
 			userGroup:      models.GroupViewer,
-			requiredGroup:  models.GroupOwner,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionDelete,
 			hasUserClaims:  true,
 			bucketIDIndex:  0,
 			setupURLParams: true,
@@ -180,7 +195,8 @@ func TestAuthorizeGroup(t *testing.T) {
 		},
 		{
 			name:           "User with no membership",
-			requiredGroup:  models.GroupViewer,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionRead,
 			hasUserClaims:  true,
 			bucketIDIndex:  0,
 			setupURLParams: true,
@@ -195,7 +211,8 @@ func TestAuthorizeGroup(t *testing.T) {
 		},
 		{
 			name:           "Missing user claims",
-			requiredGroup:  models.GroupViewer,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionRead,
 			hasUserClaims:  false,
 			bucketIDIndex:  0,
 			setupURLParams: true,
@@ -206,7 +223,8 @@ func TestAuthorizeGroup(t *testing.T) {
 		},
 		{
 			name:           "Invalid bucket UUID in URL",
-			requiredGroup:  models.GroupViewer,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionRead,
 			hasUserClaims:  true,
 			bucketIDIndex:  0,
 			setupURLParams: false,
@@ -217,7 +235,8 @@ func TestAuthorizeGroup(t *testing.T) {
 		},
 		{
 			name:           "Database error",
-			requiredGroup:  models.GroupViewer,
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionRead,
 			hasUserClaims:  true,
 			bucketIDIndex:  0,
 			setupURLParams: true,
@@ -269,7 +288,9 @@ func TestAuthorizeGroup(t *testing.T) {
 
 			handler := AuthorizeGroup(
 				gormDB,
-				tt.requiredGroup,
+				policyEngine,
+				tt.resource,
+				tt.action,
 				tt.bucketIDIndex,
 			)(
 				http.HandlerFunc(mockAuthNextHandler),
@@ -288,6 +309,221 @@ func TestAuthorizeGroup(t *testing.T) {
 	}
 }
 
+func TestAuthorizeGroupCustomRoles(t *testing.T) {
+	userID := uuid.New()
+	bucketID := uuid.New()
+	viewer := models.GroupViewer
+	membershipQuery := regexp.QuoteMeta(`SELECT * FROM "memberships" WHERE (user_id = $1 AND bucket_id = $2) AND "memberships"."deleted_at" IS NULL ORDER BY "memberships"."id" LIMIT $3`)
+
+	engine := rbac.NewStaticPolicyEngine(append(rbac.DefaultPolicies(),
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "auditor", V1: rbac.ResourceBucket.String(), V2: "*"},
+		models.Policy{Ptype: models.PolicyTypeRole, V0: userID.String(), V1: "auditor", V2: rbac.Wildcard},
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "editor", V1: rbac.ResourceBucket.String(), V2: "update"},
+		models.Policy{Ptype: models.PolicyTypeRole, V0: userID.String(), V1: "editor", V2: bucketID.String()},
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "uploader", V1: rbac.ResourceFile.String(), V2: "create"},
+		models.Policy{
+			Ptype: models.PolicyTypePermission,
+			V0:    "uploader",
+			V1:    rbac.ResourceFile.String(),
+			V2:    "download",
+			V3:    models.PolicyEffectDeny,
+		},
+		models.Policy{Ptype: models.PolicyTypeRole, V0: userID.String(), V1: "uploader", V2: bucketID.String()},
+	))
+
+	testCases := []struct {
+		name           string
+		resource       rbac.Resource
+		action         rbac.Action
+		membership     *models.Group
+		expectedStatus int
+	}{
+		{
+			name:           "Non-member with a global custom role",
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionRead,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Non-member with a custom role on the bucket",
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionUpdate,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Viewer with a custom role on the bucket",
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionUpdate,
+			membership:     &viewer,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Viewer with a global custom role",
+			resource:       rbac.ResourceBucket,
+			action:         rbac.ActionDelete,
+			membership:     &viewer,
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Viewer with an uploader role uploads",
+			resource:       rbac.ResourceFile,
+			action:         rbac.ActionCreate,
+			membership:     &viewer,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Viewer with an uploader role cannot download",
+			resource:       rbac.ResourceFile,
+			action:         rbac.ActionDownload,
+			membership:     &viewer,
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func(db *sql.DB) {
+				_ = db.Close()
+			}(db)
+
+			gormDB, err := gorm.Open(postgres.New(postgres.Config{
+				Conn: db,
+			}), &gorm.Config{})
+			require.NoError(t, err)
+
+			rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"})
+			if tt.membership != nil {
+				rows.AddRow(uuid.New(), userID, bucketID, *tt.membership)
+			}
+			mock.ExpectQuery(membershipQuery).
+				WithArgs(userID, bucketID, 1).
+				WillReturnRows(rows)
+
+			req := httptest.NewRequest(http.MethodGet, "/buckets/"+bucketID.String(), nil)
+			recorder := httptest.NewRecorder()
+
+			userClaims := models.UserClaims{
+				UserID: userID,
+				Email:  "test@example.com",
+				Role:   models.RoleUser,
+			}
+			ctx := context.WithValue(req.Context(), models.UserClaimKey{}, userClaims)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id0", bucketID.String())
+			ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			handler := AuthorizeGroup(gormDB, engine, tt.resource, tt.action, 0)(
+				http.HandlerFunc(mockAuthNextHandler),
+			)
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuthorizeRoleOrGroup(t *testing.T) {
+	userID := uuid.New()
+	bucketID := uuid.New()
+	membershipQuery := regexp.QuoteMeta(`SELECT * FROM "memberships" WHERE (user_id = $1 AND bucket_id = $2) AND "memberships"."deleted_at" IS NULL ORDER BY "memberships"."id" LIMIT $3`)
+
+	testCases := []struct {
+		name             string
+		userRole         models.Role
+		expectedStatus   int
+		expectedErrors   []string
+		setupMockQueries func(sqlmock.Sqlmock)
+	}{
+		{
+			name:           "Admin without membership",
+			userRole:       models.RoleAdmin,
+			expectedStatus: http.StatusOK,
+			setupMockQueries: func(_ sqlmock.Sqlmock) {
+			},
+		},
+		{
+			name:           "Owner of the bucket",
+			userRole:       models.RoleUser,
+			expectedStatus: http.StatusOK,
+			setupMockQueries: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}).
+					AddRow(uuid.New(), userID, bucketID, models.GroupOwner)
+				mock.ExpectQuery(membershipQuery).
+					WithArgs(userID, bucketID, 1).
+					WillReturnRows(rows)
+			},
+		},
+		{
+			name:           "Contributor of the bucket",
+			userRole:       models.RoleUser,
+			expectedStatus: http.StatusForbidden,
+			expectedErrors: []string{"FORBIDDEN"},
+			setupMockQueries: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}).
+					AddRow(uuid.New(), userID, bucketID, models.GroupContributor)
+				mock.ExpectQuery(membershipQuery).
+					WithArgs(userID, bucketID, 1).
+					WillReturnRows(rows)
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer func(db *sql.DB) {
+				_ = db.Close()
+			}(db)
+
+			gormDB, err := gorm.Open(postgres.New(postgres.Config{
+				Conn: db,
+			}), &gorm.Config{})
+			require.NoError(t, err)
+
+			tt.setupMockQueries(mock)
+
+			req := httptest.NewRequest(http.MethodPut, "/buckets/"+bucketID.String()+"/lock", nil)
+			recorder := httptest.NewRecorder()
+
+			userClaims := models.UserClaims{
+				UserID: userID,
+				Email:  "test@example.com",
+				Role:   tt.userRole,
+			}
+			ctx := context.WithValue(req.Context(), models.UserClaimKey{}, userClaims)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id0", bucketID.String())
+			ctx = context.WithValue(ctx, chi.RouteCtxKey, rctx)
+			req = req.WithContext(ctx)
+
+			handler := AuthorizeRoleOrGroup(
+				gormDB,
+				policyEngine,
+				rbac.ResourceBucket,
+				rbac.ActionLock,
+				0,
+			)(
+				http.HandlerFunc(mockAuthNextHandler),
+			)
+			handler.ServeHTTP(recorder, req)
+
+			assert.Equal(t, tt.expectedStatus, recorder.Code)
+
+			if tt.expectedStatus != http.StatusOK {
+				expected := models.Error{Status: tt.expectedStatus, Error: tt.expectedErrors}
+				tests.AssertJSONResponse(t, recorder, tt.expectedStatus, expected)
+			}
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestAuthorizeSelfOrAdmin(t *testing.T) {
 	userID := uuid.New()
 	otherUserID := uuid.New()
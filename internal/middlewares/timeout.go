@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Timeout cancels the requests still running after the timeout, except the requests of the exempted route
+// patterns, such as the event streams, which stay open until the client disconnects.
+// The middleware runs before the routing, so the route of the request is matched against the routes given.
+func Timeout(timeout time.Duration, routes chi.Routes, exemptPatterns ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		timed := middleware.Timeout(timeout)(next)
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			if routes.Match(rctx, r.Method, r.URL.Path) && slices.Contains(exemptPatterns, rctx.RoutePattern()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timed.ServeHTTP(w, r)
+		}
+		return http.HandlerFunc(fn)
+	}
+}
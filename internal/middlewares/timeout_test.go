@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeout(t *testing.T) {
+	testCases := []struct {
+		name             string
+		path             string
+		accept           string
+		expectedDeadline bool
+	}{
+		{
+			name:             "Regular request has a deadline",
+			path:             "/api/v1/buckets/1",
+			accept:           "application/json",
+			expectedDeadline: true,
+		},
+		{
+			name:             "Event stream has no deadline",
+			path:             "/api/v1/buckets/1/events",
+			accept:           "text/event-stream",
+			expectedDeadline: false,
+		},
+		{
+			name:             "Event stream header does not remove the deadline of other routes",
+			path:             "/api/v1/buckets/1",
+			accept:           "text/event-stream",
+			expectedDeadline: true,
+		},
+		{
+			name:             "Unknown route has a deadline",
+			path:             "/api/v1/unknown",
+			accept:           "application/json",
+			expectedDeadline: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			hasDeadline := false
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				_, hasDeadline = r.Context().Deadline()
+				w.WriteHeader(http.StatusOK)
+			}
+
+			r := chi.NewRouter()
+			r.Use(Timeout(5*time.Second, r, "/api/v1/buckets/{id0}/events"))
+			r.Route("/api", func(apiRouter chi.Router) {
+				buckets := chi.NewRouter()
+				buckets.Route("/{id0}", func(r chi.Router) {
+					r.Get("/", handler)
+					r.Get("/events", handler)
+				})
+				apiRouter.Mount("/v1/buckets", buckets)
+			})
+			r.NotFound(handler)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept", tt.accept)
+			recorder := httptest.NewRecorder()
+
+			r.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, tt.expectedDeadline, hasDeadline)
+		})
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"errors"
 	"net/http"
 	"regexp"
+	"slices"
 
 	h "api/internal/helpers"
+	"api/internal/models"
 
 	"github.com/go-playground/validator/v10"
 	"go.uber.org/zap"
@@ -43,6 +45,14 @@ func validateFoldername(fl validator.FieldLevel) bool {
 	return regex.MatchString(foldername) && !prohibited.MatchString(foldername)
 }
 
+func validateWebhookEvent(fl validator.FieldLevel) bool {
+	return slices.Contains(models.WebhookEventTypes, fl.Field().String())
+}
+
+func validateNotificationEvent(fl validator.FieldLevel) bool {
+	return slices.Contains(models.NotificationEventTypes, fl.Field().String())
+}
+
 func Validate[T any](next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		r.Body = http.MaxBytesReader(w, r.Body, 10<<20) // 10MB limit
@@ -58,6 +68,8 @@ func Validate[T any](next http.Handler) http.Handler {
 		validate := validator.New()
 		_ = validate.RegisterValidation("filename", validateFilename)
 		_ = validate.RegisterValidation("foldername", validateFoldername)
+		_ = validate.RegisterValidation("webhook_event", validateWebhookEvent)
+		_ = validate.RegisterValidation("notification_event", validateNotificationEvent)
 
 		err = validate.Struct(data)
 		if err != nil {
@@ -14,10 +14,12 @@ import (
 )
 
 type TestValidate struct {
-	Name     string `json:"name"     validate:"required"`
-	Email    string `json:"email"    validate:"required,email"`
-	Filename string `json:"filename" validate:"filename"`
-	Type     string `json:"type"     validate:"omitempty,oneof=file folder"`
+	Name     string   `json:"name"     validate:"required"`
+	Email    string   `json:"email"    validate:"required,email"`
+	Filename string   `json:"filename" validate:"filename"`
+	Type     string   `json:"type"     validate:"omitempty,oneof=file folder"`
+	Events   []string `json:"events"   validate:"omitempty,dive,webhook_event"`
+	Notify   []string `json:"notify"   validate:"omitempty,dive,notification_event"`
 }
 
 func mockNextHandler(w http.ResponseWriter, r *http.Request) {
@@ -82,6 +84,32 @@ func TestValidateMiddleware(t *testing.T) {
 				"Key: 'TestValidate.Filename' Error:Field validation for 'Filename' failed on the 'filename' tag",
 			},
 		},
+		{
+			name:           "Valid webhook events",
+			inputBody:      `{"name": "John Doe", "email": "john@example.com", "filename": "file.txt", "events": ["file.uploaded"]}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid webhook event",
+			inputBody:      `{"name": "John Doe", "email": "john@example.com", "filename": "file.txt", "events": ["file.moved"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []string{
+				"Key: 'TestValidate.Events[0]' Error:Field validation for 'Events[0]' failed on the 'webhook_event' tag",
+			},
+		},
+		{
+			name:           "Valid notification events",
+			inputBody:      `{"name": "John Doe", "email": "john@example.com", "filename": "file.txt", "notify": ["file.deleted"]}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid notification event",
+			inputBody:      `{"name": "John Doe", "email": "john@example.com", "filename": "file.txt", "notify": ["file.trashed"]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedErrors: []string{
+				"Key: 'TestValidate.Notify[0]' Error:Field validation for 'Notify[0]' failed on the 'notification_event' tag",
+			},
+		},
 	}
 
 	for _, tt := range testCases {
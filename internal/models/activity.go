@@ -1,5 +1,12 @@
 package models
 
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
 // IActivityLoggable defines the interface for models that can be logged to activity logs.
 // Implementations should return a minimal struct containing only safe fields for logging.
 type IActivityLoggable interface {
@@ -16,3 +23,146 @@ type LogFilter struct {
 	Fields    map[string]string
 	Timestamp string
 }
+
+// ActivityLog is an entry of the append-only activity_log table used by the database activity logger.
+// Known filter fields are stored in indexed columns, the remaining ones in Metadata.
+type ActivityLog struct {
+	ID                uuid.UUID       `gorm:"type:uuid;primarykey;default:gen_random_uuid()"`
+	Timestamp         time.Time       `gorm:"not null"`
+	Message           string          `gorm:"not null"`
+	Action            string          `gorm:"default:null"`
+	ObjectType        string          `gorm:"default:null"`
+	Domain            string          `gorm:"default:null"`
+	UserID            *uuid.UUID      `gorm:"type:uuid"`
+	BucketID          *uuid.UUID      `gorm:"type:uuid"`
+	FileID            *uuid.UUID      `gorm:"type:uuid"`
+	FolderID          *uuid.UUID      `gorm:"type:uuid"`
+	BucketMemberEmail string          `gorm:"default:null"`
+	Object            json.RawMessage `gorm:"type:jsonb"`
+	Metadata          json.RawMessage `gorm:"type:jsonb;not null"`
+}
+
+func (ActivityLog) TableName() string {
+	return "activity_log"
+}
+
+// ActivityQueryParams defines query parameters for searching the activity.
+// From and To are RFC 3339 dates, Cursor is the opaque next_cursor value returned by the previous page.
+type ActivityQueryParams struct {
+	From       string `json:"from"        validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To         string `json:"to"          validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Action     string `json:"action"      validate:"omitempty,alpha,max=50"`
+	ObjectType string `json:"object_type" validate:"omitempty,oneof=bucket file folder user auth"`
+	UserID     string `json:"user_id"     validate:"omitempty,uuid"`
+	BucketID   string `json:"bucket_id"   validate:"omitempty,uuid"`
+	FileID     string `json:"file_id"     validate:"omitempty,uuid"`
+	FolderID   string `json:"folder_id"   validate:"omitempty,uuid"`
+	Limit      int    `json:"limit"       validate:"omitempty,min=1,max=100"`
+	Cursor     string `json:"cursor"      validate:"omitempty,max=512"`
+}
+
+// ActivityExportQueryParams defines query parameters for exporting the activity of a date range.
+type ActivityExportQueryParams struct {
+	Format     string `json:"format"      validate:"required,oneof=csv ndjson"`
+	From       string `json:"from"        validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	To         string `json:"to"          validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Action     string `json:"action"      validate:"omitempty,alpha,max=50"`
+	ObjectType string `json:"object_type" validate:"omitempty,oneof=bucket file folder user auth"`
+	UserID     string `json:"user_id"     validate:"omitempty,uuid"`
+	BucketID   string `json:"bucket_id"   validate:"omitempty,uuid"`
+	FileID     string `json:"file_id"     validate:"omitempty,uuid"`
+	FolderID   string `json:"folder_id"   validate:"omitempty,uuid"`
+}
+
+// SearchParams returns the search filters of the export.
+func (q ActivityExportQueryParams) SearchParams() ActivityQueryParams {
+	return ActivityQueryParams{
+		From:       q.From,
+		To:         q.To,
+		Action:     q.Action,
+		ObjectType: q.ObjectType,
+		UserID:     q.UserID,
+		BucketID:   q.BucketID,
+		FileID:     q.FileID,
+		FolderID:   q.FolderID,
+	}
+}
+
+// ActivityChainHead is the last record appended to the activity hash chain.
+type ActivityChainHead struct {
+	ID       int16  `gorm:"primarykey"`
+	Sequence int64  `gorm:"not null"`
+	Hash     string `gorm:"not null"`
+}
+
+func (ActivityChainHead) TableName() string {
+	return "activity_chain_head"
+}
+
+// ActivityChainCheckpoint is a signed chain head.
+type ActivityChainCheckpoint struct {
+	ID        uuid.UUID `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	Sequence  int64     `gorm:"not null;uniqueIndex"                           json:"sequence"`
+	Hash      string    `gorm:"not null"                                       json:"hash"`
+	Signature string    `gorm:"not null"                                       json:"signature"`
+	CreatedAt time.Time `                                                      json:"created_at"`
+}
+
+// ActivityVerifyQueryParams defines the time range of the activity to verify, as RFC 3339 dates.
+type ActivityVerifyQueryParams struct {
+	From string `json:"from" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	To   string `json:"to"   validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+}
+
+// ActivityChainIssue describes a record of the activity hash chain that failed the verification.
+type ActivityChainIssue struct {
+	Type      string `json:"type"`
+	Sequence  int64  `json:"sequence,omitempty"`
+	Timestamp string `json:"timestamp,omitempty"`
+	Detail    string `json:"detail"`
+}
+
+// ActivityChainReport is the result of the verification of the activity hash chain over a time range.
+type ActivityChainReport struct {
+	From          time.Time            `json:"from"`
+	To            time.Time            `json:"to"`
+	Valid         bool                 `json:"valid"`
+	Records       int                  `json:"records"`
+	FirstSequence int64                `json:"first_sequence"`
+	LastSequence  int64                `json:"last_sequence"`
+	Checkpoints   int                  `json:"checkpoints"`
+	Issues        []ActivityChainIssue `json:"issues"`
+}
+
+// ActivityOutbox is an activity waiting to be delivered to the activity backend.
+// It is dead-lettered, with its failure date, once it has failed the maximum number of attempts.
+type ActivityOutbox struct {
+	ID            int64           `gorm:"primarykey"`
+	Message       string          `gorm:"not null"`
+	Timestamp     int64           `gorm:"not null"`
+	Fields        json.RawMessage `gorm:"type:jsonb;not null"`
+	Object        json.RawMessage `gorm:"type:jsonb"`
+	ChainSequence *int64          `gorm:"default:null"`
+	Attempts      int             `gorm:"not null;default:0"`
+	LastError     string          `gorm:"default:null"`
+	NextAttemptAt time.Time       `gorm:"not null;default:CURRENT_TIMESTAMP"`
+	FailedAt      *time.Time      `gorm:"default:null"`
+	CreatedAt     time.Time
+}
+
+func (ActivityOutbox) TableName() string {
+	return "activity_outbox"
+}
+
+// ActivityQueueStats describes the activity waiting for delivery, and the deliveries of the current instance.
+type ActivityQueueStats struct {
+	Pending         int64      `json:"pending"`
+	Retrying        int64      `json:"retrying"`
+	DeadLettered    int64      `json:"dead_lettered"`
+	MaxAttempts     int        `json:"max_attempts"`
+	OldestPendingAt *time.Time `json:"oldest_pending_at"`
+	LastError       string     `json:"last_error,omitempty"`
+	Delivered       int64      `json:"delivered"`
+	Failed          int64      `json:"failed"`
+	Synchronous     []string   `json:"synchronous_messages"`
+}
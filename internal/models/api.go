@@ -1,10 +1,21 @@
 package models
 
+import "io"
+
 type Page[T any] struct {
-	Data []T `json:"data"`
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 type Error struct {
 	Status int      `json:"status"`
 	Error  []string `json:"error"`
 }
+
+// Stream is a response written progressively to the client, such as a file export.
+// Write is only called once the response headers have been sent.
+type Stream struct {
+	ContentType string
+	Filename    string
+	Write       func(w io.Writer) error
+}
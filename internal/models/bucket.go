@@ -8,14 +8,17 @@ import (
 )
 
 type Bucket struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
-	Name      string         `gorm:"not null;default:null"                          json:"name"       validate:"required"`
-	Files     []File         `                                                      json:"files"`
-	Folders   []Folder       `                                                      json:"folders"`
-	CreatedAt time.Time      `                                                      json:"created_at"`
-	CreatedBy uuid.UUID      `gorm:"type:uuid;not null"                             json:"-"`
-	UpdatedAt time.Time      `                                                      json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index"                                          json:"-"`
+	ID                 uuid.UUID      `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	Name               string         `gorm:"not null;default:null"                          json:"name"                 validate:"required"`
+	Files              []File         `                                                      json:"files"`
+	Folders            []Folder       `                                                      json:"folders"`
+	CreatedAt          time.Time      `                                                      json:"created_at"`
+	CreatedBy          uuid.UUID      `gorm:"type:uuid;not null"                             json:"-"`
+	TrashRetentionDays *int           `gorm:"default:null"                                   json:"trash_retention_days"`
+	UpdatedAt          time.Time      `                                                      json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `gorm:"index"                                          json:"-"`
+
+	RetentionLock
 }
 
 type BucketActivity struct {
@@ -23,6 +26,13 @@ type BucketActivity struct {
 	Name string    `json:"name"`
 }
 
+// BucketRetentionActivity records a change of the trash retention of a bucket, null for the platform retention.
+type BucketRetentionActivity struct {
+	ID                 uuid.UUID `json:"id"`
+	Name               string    `json:"name"`
+	TrashRetentionDays *int      `json:"trash_retention_days"`
+}
+
 func (b *Bucket) ToActivity() BucketActivity {
 	return BucketActivity{
 		ID:   b.ID,
@@ -34,6 +44,27 @@ type BucketCreateUpdateBody struct {
 	Name string `json:"name" validate:"required,max=100"`
 }
 
+// BucketTrashRetentionBody sets the number of days the trashed files and folders of a bucket are kept.
+// A null value resets the bucket to the retention of the platform.
+type BucketTrashRetentionBody struct {
+	RetentionDays *int `json:"retention_days" validate:"omitempty,gte=1"`
+}
+
+// TrashRetention returns the retention of the trash of the bucket, or the given default when it is not set.
+func (b *Bucket) TrashRetention(defaultDays int) int {
+	if b.TrashRetentionDays != nil {
+		return *b.TrashRetentionDays
+	}
+	return defaultDays
+}
+
+// BucketTransferBody is the request body for transferring the ownership of a bucket to another user.
+// The provider is only required when the email is registered with several providers.
+type BucketTransferBody struct {
+	Email    string `json:"email"    validate:"required,email,max=254"`
+	Provider string `json:"provider" validate:"omitempty,max=100"`
+}
+
 // BucketQueryParams defines query parameters for filtering bucket contents.
 // Use with the ValidateQuery middleware:
 //
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	BucketEventFileCreated     = "file.created"
+	BucketEventFileUploaded    = "file.uploaded"
+	BucketEventFileTrashed     = "file.trashed"
+	BucketEventFileRestored    = "file.restored"
+	BucketEventFilePurged      = "file.purged"
+	BucketEventFileQuarantined = "file.quarantined"
+	BucketEventFileFailed      = "file.failed"
+	BucketEventFolderCreated   = "folder.created"
+	BucketEventFolderUpdated   = "folder.updated"
+	BucketEventFolderTrashed   = "folder.trashed"
+	BucketEventFolderRestored  = "folder.restored"
+	BucketEventFolderPurged    = "folder.purged"
+)
+
+// BucketEvent is a change of the content of a bucket, pushed to the users viewing the bucket.
+// FolderID is the folder whose visibility applies to the event: the parent folder of a file,
+// or the folder itself. It is only used to filter the events and is not sent to the clients.
+type BucketEvent struct {
+	Type       string      `json:"type"`
+	BucketID   uuid.UUID   `json:"bucket_id"`
+	UserID     *uuid.UUID  `json:"user_id,omitempty"`
+	Object     interface{} `json:"object"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	FolderID   *uuid.UUID  `json:"-"`
+}
+
+// NewFileEvent returns the event of a change of a file.
+func NewFileEvent(eventType string, file File, userID *uuid.UUID) BucketEvent {
+	return BucketEvent{
+		Type:       eventType,
+		BucketID:   file.BucketID,
+		UserID:     userID,
+		Object:     file,
+		OccurredAt: time.Now().UTC(),
+		FolderID:   file.FolderID,
+	}
+}
+
+// NewFolderEvent returns the event of a change of a folder.
+func NewFolderEvent(eventType string, folder Folder, userID *uuid.UUID) BucketEvent {
+	folderID := folder.ID
+	return BucketEvent{
+		Type:       eventType,
+		BucketID:   folder.BucketID,
+		UserID:     userID,
+		Object:     folder,
+		OccurredAt: time.Now().UTC(),
+		FolderID:   &folderID,
+	}
+}
@@ -1,10 +1,15 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type BucketMemberBody struct {
-	Email string `json:"email" validate:"required,email,max=254"`
-	Group Group  `json:"group" validate:"required,oneof=owner contributor viewer"`
+	Email     string     `json:"email"                validate:"required,email,max=254"`
+	Group     Group      `json:"group"                validate:"required,oneof=owner contributor viewer"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type UpdateMembersBody struct {
@@ -12,18 +17,20 @@ type UpdateMembersBody struct {
 }
 
 type BucketMember struct {
-	UserID    uuid.UUID `json:"user_id,omitempty"`
-	Email     string    `json:"email"                validate:"required"`
-	FirstName string    `json:"first_name,omitempty"`
-	LastName  string    `json:"last_name,omitempty"`
-	Group     Group     `json:"group"                validate:"required,oneof=owner contributor viewer"`
-	Status    string    `json:"status"               validate:"required,oneof=active invited"`
+	UserID    uuid.UUID  `json:"user_id,omitempty"`
+	Email     string     `json:"email"                validate:"required"`
+	FirstName string     `json:"first_name,omitempty"`
+	LastName  string     `json:"last_name,omitempty"`
+	Group     Group      `json:"group"                validate:"required,oneof=owner contributor viewer"`
+	Status    string     `json:"status"               validate:"required,oneof=active invited"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type BucketMemberToUpdate struct {
 	BucketMember
 
-	NewGroup Group `validate:"required,oneof=owner contributor viewer"`
+	NewGroup     Group `validate:"required,oneof=owner contributor viewer"`
+	NewExpiresAt *time.Time
 }
 
 type MembershipChanges struct {
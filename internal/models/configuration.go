@@ -1,28 +1,33 @@
 package models
 
 type Configuration struct {
-	App      AppConfiguration      `mapstructure:"app"      validate:"required"`
-	Database DatabaseConfiguration `mapstructure:"database" validate:"required"`
-	Auth     AuthConfiguration     `mapstructure:"auth"     validate:"required"`
-	Cache    CacheConfiguration    `mapstructure:"cache"    validate:"required"`
-	Storage  StorageConfiguration  `mapstructure:"storage"  validate:"required"`
-	Events   EventsConfiguration   `mapstructure:"events"   validate:"required"`
-	Notifier NotifierConfiguration `mapstructure:"notifier" validate:"required"`
-	Activity ActivityConfiguration `mapstructure:"activity" validate:"required"`
+	App       AppConfiguration        `mapstructure:"app"       validate:"required"`
+	Database  DatabaseConfiguration   `mapstructure:"database"  validate:"required"`
+	Auth      AuthConfiguration       `mapstructure:"auth"      validate:"required"`
+	Cache     CacheConfiguration      `mapstructure:"cache"     validate:"required"`
+	Storage   StorageConfiguration    `mapstructure:"storage"   validate:"required"`
+	Events    EventsConfiguration     `mapstructure:"events"    validate:"required"`
+	Notifier  NotifierConfiguration   `mapstructure:"notifier"  validate:"required"`
+	Activity  ActivityConfiguration   `mapstructure:"activity"  validate:"required"`
+	Antivirus *AntivirusConfiguration `mapstructure:"antivirus"`
 }
 
 type AppConfiguration struct {
-	AdminEmail         string              `mapstructure:"admin_email"          validate:"required,email"`
-	AdminPassword      string              `mapstructure:"admin_password"       validate:"required"`
-	APIURL             string              `mapstructure:"api_url"              validate:"required"`
-	AllowedOrigins     []string            `mapstructure:"allowed_origins"      validate:"required"`
-	JWTSecret          string              `mapstructure:"jwt_secret"           validate:"required"`
-	LogLevel           string              `mapstructure:"log_level"            validate:"oneof=debug info warn error fatal panic" default:"info"`
-	Port               int                 `mapstructure:"port"                 validate:"gte=80,lte=65535"                        default:"8080"`
-	StaticFiles        StaticConfiguration `mapstructure:"static_files"`
-	TrustedProxies     []string            `mapstructure:"trusted_proxies"      validate:"required"`
-	WebURL             string              `mapstructure:"web_url"              validate:"required"`
-	TrashRetentionDays int                 `mapstructure:"trash_retention_days" validate:"gte=1,lte=365"                           default:"7"`
+	AdminEmail                  string              `mapstructure:"admin_email"                    validate:"required,email"`
+	AdminPassword               string              `mapstructure:"admin_password"                 validate:"required"`
+	APIURL                      string              `mapstructure:"api_url"                        validate:"required"`
+	AllowedOrigins              []string            `mapstructure:"allowed_origins"                validate:"required"`
+	JWTSecret                   string              `mapstructure:"jwt_secret"                     validate:"required"`
+	LogLevel                    string              `mapstructure:"log_level"                      validate:"oneof=debug info warn error fatal panic"       default:"info"`
+	Port                        int                 `mapstructure:"port"                           validate:"gte=80,lte=65535"                              default:"8080"`
+	StaticFiles                 StaticConfiguration `mapstructure:"static_files"`
+	TrustedProxies              []string            `mapstructure:"trusted_proxies"                validate:"required"`
+	WebURL                      string              `mapstructure:"web_url"                        validate:"required"`
+	TrashRetentionDays          int                 `mapstructure:"trash_retention_days"           validate:"gte=1,lte=365"                                 default:"7"`
+	TrashRetentionMinDays       int                 `mapstructure:"trash_retention_min_days"       validate:"gte=1,lte=3650"                                default:"1"`
+	TrashRetentionMaxDays       int                 `mapstructure:"trash_retention_max_days"       validate:"gte=1,lte=3650,gtefield=TrashRetentionMinDays" default:"365"`
+	MembershipExpiryWarningDays int                 `mapstructure:"membership_expiry_warning_days" validate:"gte=0,lte=90"                                  default:"3"`
+	WebhookAllowedHosts         []string            `mapstructure:"webhook_allowed_hosts"          validate:"dive,required"`
 }
 
 type DatabaseConfiguration struct {
@@ -157,23 +162,69 @@ type JetStreamEventsConfig struct {
 }
 
 type MailerConfiguration struct {
-	Host          string `mapstructure:"host"            validate:"required"`
-	Port          int    `mapstructure:"port"            validate:"required"`
-	Username      string `mapstructure:"username"`
-	Password      string `mapstructure:"password"`
-	Sender        string `mapstructure:"sender"          validate:"required"`
-	EnableTLS     bool   `mapstructure:"enable_tls"                          default:"true"`
-	SkipVerifyTLS bool   `mapstructure:"skip_verify_tls"                     default:"false"`
+	Host          string  `mapstructure:"host"            validate:"required"`
+	Port          int     `mapstructure:"port"            validate:"required"`
+	Username      string  `mapstructure:"username"`
+	Password      string  `mapstructure:"password"`
+	Sender        string  `mapstructure:"sender"          validate:"required"`
+	EnableTLS     bool    `mapstructure:"enable_tls"                                        default:"true"`
+	SkipVerifyTLS bool    `mapstructure:"skip_verify_tls"                                   default:"false"`
+	TemplatesDir  string  `mapstructure:"templates_dir"`
+	PoolSize      int     `mapstructure:"pool_size"       validate:"omitempty,min=1,max=20"`
+	RatePerSecond float64 `mapstructure:"rate_per_second" validate:"omitempty,gt=0"`
+	MaxAttempts   int     `mapstructure:"max_attempts"    validate:"omitempty,min=1,max=50"`
+}
+
+// NotifierConfiguration configures the delivery of the notifications. The emails are required, as they are
+// the only delivery of the notifications meant for their recipient, such as the invitation and password reset codes.
+// Chat and HTTP endpoints can only be added as channels receiving a copy of the other notifications.
+type NotifierConfiguration struct {
+	Type     string                         `mapstructure:"type"     validate:"required,oneof=smtp"`
+	SMTP     *MailerConfiguration           `mapstructure:"smtp"     validate:"required"`
+	Channels []NotifierChannelConfiguration `mapstructure:"channels" validate:"omitempty,dive"`
 }
 
-type NotifierConfiguration struct {
-	Type string               `mapstructure:"type" validate:"required,oneof=smtp"`
-	SMTP *MailerConfiguration `mapstructure:"smtp" validate:"required_if=Type smtp"`
+// NotifierEndpointConfiguration configures a chat or HTTP notifier. Only the notifications with a chat template
+// are sent, optionally restricted to the listed templates. Headers are only sent by the generic webhook notifier.
+type NotifierEndpointConfiguration struct {
+	URL       string            `mapstructure:"url"       validate:"required,http_url"`
+	Headers   map[string]string `mapstructure:"headers"`
+	Templates []string          `mapstructure:"templates"`
+}
+
+// NotifierChannelConfiguration is an additional notifier receiving a copy of the notifications,
+// such as the channel of a team.
+type NotifierChannelConfiguration struct {
+	Type                          string `mapstructure:"type" validate:"required,oneof=slack teams webhook"`
+	NotifierEndpointConfiguration `mapstructure:",squash"`
 }
 
 type ActivityConfiguration struct {
-	Type string            `mapstructure:"type" validate:"required,oneof=loki"`
-	Loki LokiConfiguration `mapstructure:"loki" validate:"required_if=Type loki"`
+	Type       string                      `mapstructure:"type"        validate:"required,oneof=loki postgres"`
+	Loki       *LokiConfiguration          `mapstructure:"loki"        validate:"required_if=Type loki"`
+	SigningKey string                      `mapstructure:"signing_key"`
+	Syslog     *SyslogConfiguration        `mapstructure:"syslog"`
+	Queue      *ActivityQueueConfiguration `mapstructure:"queue"`
+}
+
+// ActivityQueueConfiguration enables the delivery of the activity through a queue stored in the database.
+// The synchronous messages are sent to the backend directly, and fail the request when the backend is down.
+type ActivityQueueConfiguration struct {
+	SynchronousMessages []string `mapstructure:"synchronous_messages" validate:"dive,required,max=64"`
+	BatchSize           int      `mapstructure:"batch_size"           validate:"omitempty,min=1,max=1000"`
+	IntervalSeconds     int      `mapstructure:"interval_seconds"     validate:"omitempty,min=1"`
+	MaxAttempts         int      `mapstructure:"max_attempts"         validate:"omitempty,min=1"`
+}
+
+// SyslogConfiguration defines the syslog server receiving a copy of the activity, such as a SIEM.
+type SyslogConfiguration struct {
+	Network       string `mapstructure:"network"         validate:"required,oneof=udp tcp tls"`
+	Address       string `mapstructure:"address"         validate:"required,hostname_port"`
+	Format        string `mapstructure:"format"          validate:"omitempty,oneof=rfc5424 cef leef"`
+	AppName       string `mapstructure:"app_name"        validate:"omitempty,max=48"`
+	TLSServerName string `mapstructure:"tls_server_name"`
+	SkipVerifyTLS bool   `mapstructure:"skip_verify_tls"`
+	BufferSize    int    `mapstructure:"buffer_size"     validate:"omitempty,min=1"`
 }
 
 type LokiConfiguration struct {
@@ -184,3 +235,14 @@ type StaticConfiguration struct {
 	Enabled   bool   `mapstructure:"enabled"   default:"true"`
 	Directory string `mapstructure:"directory" default:"web/dist"`
 }
+
+// AntivirusConfiguration enables the scanning of the uploaded files by a clamd daemon,
+// the files are only available once scanned. Files larger than the maximum size are not scanned:
+// they are quarantined, unless SkipLargeFiles makes them available without a scan.
+type AntivirusConfiguration struct {
+	Network        string `mapstructure:"network"          validate:"required,oneof=tcp unix"`
+	Address        string `mapstructure:"address"          validate:"required"`
+	TimeoutSeconds int    `mapstructure:"timeout_seconds"  validate:"omitempty,min=1"`
+	MaxFileSize    int64  `mapstructure:"max_file_size"    validate:"omitempty,min=1"`
+	SkipLargeFiles bool   `mapstructure:"skip_large_files"`
+}
@@ -15,23 +15,62 @@ const (
 	FileStatusDeleting  FileStatus = "deleting"
 	FileStatusDeleted   FileStatus = "deleted"
 	FileStatusRestoring FileStatus = "restoring"
+	// FileStatusScanning is the status of an uploaded file until the antivirus has scanned it.
+	FileStatusScanning FileStatus = "scanning"
+	// FileStatusQuarantined is the status of a file the antivirus found infected, which cannot be downloaded.
+	FileStatusQuarantined FileStatus = "quarantined"
+	// FileStatusFailed is the status of an upload whose content does not match the declared size or checksum.
+	FileStatusFailed FileStatus = "failed"
 )
 
+type ChecksumAlgorithm string
+
+const (
+	ChecksumAlgorithmSHA256 ChecksumAlgorithm = "sha256"
+	ChecksumAlgorithmCRC32C ChecksumAlgorithm = "crc32c"
+	ChecksumAlgorithmMD5    ChecksumAlgorithm = "md5"
+)
+
+// DigestSize returns the size in bytes of a checksum computed with the algorithm.
+func (a ChecksumAlgorithm) DigestSize() int {
+	switch a {
+	case ChecksumAlgorithmSHA256:
+		return 32
+	case ChecksumAlgorithmCRC32C:
+		return 4
+	case ChecksumAlgorithmMD5:
+		return 16
+	default:
+		return 0
+	}
+}
+
+// FileChecksum is the checksum of the content of a file, encoded in base64.
+type FileChecksum struct {
+	Algorithm ChecksumAlgorithm
+	Value     string
+}
+
 type File struct {
-	ID           uuid.UUID      `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
-	Name         string         `gorm:"not null;default:null"                          json:"name"`
-	Extension    string         `gorm:"default:null"                                   json:"extension"`
-	Status       FileStatus     `gorm:"type:file_status;default:null"                  json:"status"`
-	BucketID     uuid.UUID      `gorm:"type:uuid;"                                     json:"bucket_id"`
-	Bucket       Bucket         `                                                      json:"-"`
-	FolderID     *uuid.UUID     `gorm:"type:uuid;default:null"                         json:"folder_id,omitempty"`
-	ParentFolder *Folder        `gorm:"foreignKey:FolderID"                            json:"parent_folder,omitempty"`
-	Size         int            `gorm:"type:bigint;default:null"                       json:"size"`
-	DeletedBy    *uuid.UUID     `gorm:"type:uuid;default:null"                         json:"deleted_by,omitempty"`
-	OriginalPath string         `gorm:"-"                                              json:"original_path,omitempty"`
-	CreatedAt    time.Time      `                                                      json:"created_at"`
-	UpdatedAt    time.Time      `                                                      json:"updated_at"`
-	DeletedAt    gorm.DeletedAt `                                                      json:"deleted_at"`
+	ID                uuid.UUID          `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	Name              string             `gorm:"not null;default:null"                          json:"name"`
+	Extension         string             `gorm:"default:null"                                   json:"extension"`
+	Status            FileStatus         `gorm:"type:file_status;default:null"                  json:"status"`
+	BucketID          uuid.UUID          `gorm:"type:uuid;"                                     json:"bucket_id"`
+	Bucket            Bucket             `                                                      json:"-"`
+	FolderID          *uuid.UUID         `gorm:"type:uuid;default:null"                         json:"folder_id,omitempty"`
+	ParentFolder      *Folder            `gorm:"foreignKey:FolderID"                            json:"parent_folder,omitempty"`
+	Size              int                `gorm:"type:bigint;default:null"                       json:"size"`
+	ChecksumAlgorithm *ChecksumAlgorithm `gorm:"type:checksum_algorithm;default:null"           json:"checksum_algorithm,omitempty"`
+	Checksum          *string            `gorm:"default:null"                                   json:"checksum,omitempty"`
+	DeletedBy         *uuid.UUID         `gorm:"type:uuid;default:null"                         json:"deleted_by,omitempty"`
+	ScanAttempts      int                `gorm:"not null;default:0"                             json:"-"`
+	OriginalPath      string             `gorm:"-"                                              json:"original_path,omitempty"`
+	CreatedAt         time.Time          `                                                      json:"created_at"`
+	UpdatedAt         time.Time          `                                                      json:"updated_at"`
+	DeletedAt         gorm.DeletedAt     `                                                      json:"deleted_at"`
+
+	RetentionLock
 }
 
 type FileActivity struct {
@@ -39,6 +78,14 @@ type FileActivity struct {
 	Name string    `json:"name"`
 }
 
+// GetChecksum returns the checksum of the file, or nil when it is unknown.
+func (f *File) GetChecksum() *FileChecksum {
+	if f.ChecksumAlgorithm == nil || f.Checksum == nil {
+		return nil
+	}
+	return &FileChecksum{Algorithm: *f.ChecksumAlgorithm, Value: *f.Checksum}
+}
+
 func (f *File) ToActivity() FileActivity {
 	return FileActivity{
 		ID:   f.ID,
@@ -46,10 +93,40 @@ func (f *File) ToActivity() FileActivity {
 	}
 }
 
+type FileScanResult string
+
+const (
+	FileScanClean    FileScanResult = "clean"
+	FileScanInfected FileScanResult = "infected"
+	FileScanSkipped  FileScanResult = "skipped"
+	// FileScanFailed is the result of a file the antivirus could not scan, which is quarantined.
+	FileScanFailed FileScanResult = "failed"
+)
+
+// FileScanActivity describes the file and the result of the antivirus scan in the activity.
+type FileScanActivity struct {
+	ID        uuid.UUID      `json:"id"`
+	Name      string         `json:"name"`
+	Result    FileScanResult `json:"result"`
+	Signature string         `json:"signature,omitempty"`
+	Reason    string         `json:"reason,omitempty"`
+}
+
+// FileUploadFailedActivity describes the file and why its upload failed in the activity.
+type FileUploadFailedActivity struct {
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+	Reason string    `json:"reason"`
+}
+
+// FileTransferBody describes a file to upload. The checksum of its content, encoded in base64, is optional
+// and is verified once the file is uploaded.
 type FileTransferBody struct {
-	Name     string     `json:"name"      validate:"required,filename,max=255"`
-	FolderID *uuid.UUID `json:"folder_id" validate:"omitempty,uuid"`
-	Size     int        `json:"size"      validate:"required,max=1099511627776"`
+	Name              string            `json:"name"               validate:"required,filename,max=255"`
+	FolderID          *uuid.UUID        `json:"folder_id"          validate:"omitempty,uuid"`
+	Size              int               `json:"size"               validate:"required,max=1099511627776"`
+	ChecksumAlgorithm ChecksumAlgorithm `json:"checksum_algorithm" validate:"required_with=Checksum,omitempty,oneof=sha256 crc32c md5"`
+	Checksum          string            `json:"checksum"           validate:"required_with=ChecksumAlgorithm,omitempty,base64,max=64"`
 }
 
 type FileTransferResponse struct {
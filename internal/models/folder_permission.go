@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FolderPermission is an ACL entry overriding a member's bucket group on a folder and its subfolders.
+type FolderPermission struct {
+	ID        uuid.UUID `gorm:"type:uuid;primarykey;default:gen_random_uuid()"               json:"id"`
+	FolderID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_folder_permissions_unique" json:"folder_id"`
+	Folder    Folder    `gorm:"foreignKey:FolderID;constraint:OnDelete:CASCADE"              json:"-"`
+	BucketID  uuid.UUID `gorm:"type:uuid;not null;index"                                     json:"bucket_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_folder_permissions_unique" json:"user_id"`
+	User      User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"                json:"user,omitempty"`
+	Group     Group     `gorm:"type:folder_group_type;not null"                              json:"group"          validate:"required,oneof=none viewer contributor owner"`
+	CreatedBy uuid.UUID `gorm:"type:uuid;not null"                                           json:"-"`
+	CreatedAt time.Time `                                                                    json:"created_at"`
+	UpdatedAt time.Time `                                                                    json:"updated_at"`
+}
+
+type FolderPermissionBody struct {
+	Email string `json:"email" validate:"required,email,max=254"`
+	Group Group  `json:"group" validate:"required,oneof=none viewer contributor owner"`
+}
+
+// UpdateFolderPermissionsBody replaces the full set of ACL entries of a folder.
+type UpdateFolderPermissionsBody struct {
+	Permissions []FolderPermissionBody `json:"permissions" validate:"max=1000,dive"`
+}
+
+type FolderPermissionResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	Group     Group     `json:"group"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MailDeliveryStatus string
+
+const (
+	MailDeliveryPending MailDeliveryStatus = "pending"
+	MailDeliverySent    MailDeliveryStatus = "sent"
+	MailDeliveryFailed  MailDeliveryStatus = "failed"
+	MailDeliveryBounced MailDeliveryStatus = "bounced"
+)
+
+// MailDelivery is an email waiting to be sent, kept as the mail log once sent or failed.
+// The bodies are rendered when the email is queued, and cleared once it is sent,
+// as they may contain secrets such as the challenges of the invitations.
+type MailDelivery struct {
+	ID             uuid.UUID          `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	Recipient      string             `gorm:"not null"                                       json:"recipient"`
+	Template       string             `gorm:"not null"                                       json:"template"`
+	Language       string             `gorm:"not null"                                       json:"language"`
+	Subject        string             `gorm:"not null"                                       json:"subject"`
+	TextBody       string             `gorm:"not null"                                       json:"-"`
+	HTMLBody       string             `gorm:"column:html_body;not null"                      json:"-"`
+	UnsubscribeURL string             `gorm:"default:null"                                   json:"-"`
+	Status         MailDeliveryStatus `gorm:"not null;default:pending"                       json:"status"`
+	Attempts       int                `gorm:"not null;default:0"                             json:"attempts"`
+	ResponseCode   int                `gorm:"default:null"                                   json:"response_code,omitempty"`
+	LastError      string             `gorm:"default:null"                                   json:"last_error,omitempty"`
+	NextAttemptAt  time.Time          `gorm:"not null;default:CURRENT_TIMESTAMP"             json:"next_attempt_at"`
+	CreatedAt      time.Time          `                                                      json:"created_at"`
+	SentAt         *time.Time         `gorm:"default:null"                                   json:"sent_at,omitempty"`
+}
+
+type MailDeliveryQueryParams struct {
+	Status    string `json:"status"    validate:"omitempty,oneof=pending sent failed bounced"`
+	Recipient string `json:"recipient" validate:"omitempty,max=255"`
+	Limit     int    `json:"limit"     validate:"omitempty,min=1,max=100"`
+}
+
+// MailResendBody is the request body for sending failed or bounced emails again.
+type MailResendBody struct {
+	IDs []uuid.UUID `json:"ids" validate:"required,min=1,max=100,unique"`
+}
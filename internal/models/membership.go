@@ -14,19 +14,28 @@ const (
 	GroupOwner       Group = "owner"
 	GroupContributor Group = "contributor"
 	GroupViewer      Group = "viewer"
+	// GroupNone is only valid on folder permissions, where it revokes access to a subtree.
+	GroupNone Group = "none"
 )
 
 // Membership represents a user's access level to a specific bucket.
 type Membership struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primarykey;default:gen_random_uuid()"  json:"id"`
-	UserID    uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_user_bucket"  json:"user_id"`
-	User      User           `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"   json:"user,omitempty"`
-	BucketID  uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_user_bucket"  json:"bucket_id"`
-	Bucket    Bucket         `gorm:"foreignKey:BucketID;constraint:OnDelete:CASCADE" json:"bucket,omitempty"`
-	Group     Group          `gorm:"type:group_type;not null"                        json:"group"            validate:"required,oneof=owner contributor viewer"`
-	CreatedAt time.Time      `                                                       json:"created_at"`
-	UpdatedAt time.Time      `                                                       json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index"                                           json:"-"`
+	ID               uuid.UUID      `gorm:"type:uuid;primarykey;default:gen_random_uuid()"  json:"id"`
+	UserID           uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_user_bucket"  json:"user_id"`
+	User             User           `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"   json:"user,omitempty"`
+	BucketID         uuid.UUID      `gorm:"type:uuid;not null;uniqueIndex:idx_user_bucket"  json:"bucket_id"`
+	Bucket           Bucket         `gorm:"foreignKey:BucketID;constraint:OnDelete:CASCADE" json:"bucket,omitempty"`
+	Group            Group          `gorm:"type:group_type;not null"                        json:"group"                validate:"required,oneof=owner contributor viewer"`
+	ExpiresAt        *time.Time     `gorm:"index"                                           json:"expires_at,omitempty"`
+	ExpiryNotifiedAt *time.Time     `                                                       json:"-"`
+	CreatedAt        time.Time      `                                                       json:"created_at"`
+	UpdatedAt        time.Time      `                                                       json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index"                                           json:"-"`
+}
+
+// IsExpired checks if a time-limited membership has reached its expiry date.
+func (m *Membership) IsExpired(now time.Time) bool {
+	return m.ExpiresAt != nil && !m.ExpiresAt.After(now)
 }
 
 // MembershipCreateBody is the request body for creating a membership.
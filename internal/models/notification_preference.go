@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	NotificationEventFileUploaded  = "file.uploaded"
+	NotificationEventFileDeleted   = "file.deleted"
+	NotificationEventMemberCreated = "member.created"
+)
+
+// NotificationEventTypes lists the bucket activity users can be notified of.
+var NotificationEventTypes = []string{
+	NotificationEventFileUploaded,
+	NotificationEventFileDeleted,
+	NotificationEventMemberCreated,
+}
+
+type NotificationFrequency string
+
+const (
+	NotificationFrequencyImmediate NotificationFrequency = "immediate"
+	NotificationFrequencyDaily     NotificationFrequency = "daily"
+	NotificationFrequencyWeekly    NotificationFrequency = "weekly"
+)
+
+// NotificationPreference is the opt-in of a user to be notified of the activity on the buckets they belong to.
+// A preference without bucket applies to every bucket of the user that has no preference of its own,
+// so that a disabled bucket preference mutes a single bucket.
+type NotificationPreference struct {
+	ID               uuid.UUID             `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	UserID           uuid.UUID             `gorm:"type:uuid;not null"                             json:"user_id"`
+	User             User                  `gorm:"foreignKey:UserID"                              json:"-"`
+	BucketID         *uuid.UUID            `gorm:"type:uuid;default:null"                         json:"bucket_id"`
+	EventTypes       []string              `gorm:"type:jsonb;serializer:json;not null"            json:"event_types"`
+	Frequency        NotificationFrequency `gorm:"type:notification_frequency;not null"           json:"frequency"`
+	Enabled          bool                  `gorm:"not null;default:true"                          json:"enabled"`
+	UnsubscribeToken string                `gorm:"not null"                                       json:"-"`
+	LastDigestAt     time.Time             `gorm:"not null"                                       json:"last_digest_at"`
+	CreatedAt        time.Time             `                                                      json:"created_at"`
+	UpdatedAt        time.Time             `                                                      json:"updated_at"`
+}
+
+// NotificationPreferenceBody creates or replaces the preference of the user for a bucket,
+// or for all their buckets when no bucket is given.
+type NotificationPreferenceBody struct {
+	BucketID   *uuid.UUID            `json:"bucket_id"   validate:"omitempty"`
+	EventTypes []string              `json:"event_types" validate:"required,min=1,unique,dive,notification_event"`
+	Frequency  NotificationFrequency `json:"frequency"   validate:"required,oneof=immediate daily weekly"`
+	Enabled    *bool                 `json:"enabled"     validate:"omitempty"`
+}
+
+type NotificationUnsubscribeQueryParams struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
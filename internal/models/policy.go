@@ -1,12 +1,42 @@
 package models
 
+import "github.com/google/uuid"
+
+// PolicyType defines the kind of casbin-style policy rule.
+type PolicyType string
+
+const (
+	// PolicyTypePermission rules grant a subject an action on a resource: (subject, resource, action).
+	PolicyTypePermission PolicyType = "p"
+	// PolicyTypeRole rules assign a role to a user or another role: (user or role, role, domain).
+	PolicyTypeRole PolicyType = "g"
+)
+
+const (
+	// PolicyEffectAllow is the effect of the permission rules without effect.
+	PolicyEffectAllow = "allow"
+	// PolicyEffectDeny is the effect of the permission rules denying an action, whatever the other rules grant.
+	PolicyEffectDeny = "deny"
+)
+
 type Policy struct {
-	ID    uint   `gorm:"primaryKey;autoIncrement"`
-	Ptype string `gorm:"size:512;uniqueIndex:unique_index"`
-	V0    string `gorm:"size:512;uniqueIndex:unique_index"`
-	V1    string `gorm:"size:512;uniqueIndex:unique_index"`
-	V2    string `gorm:"size:512;uniqueIndex:unique_index"`
-	V3    string `gorm:"size:512;uniqueIndex:unique_index"`
-	V4    string `gorm:"size:512;uniqueIndex:unique_index"`
-	V5    string `gorm:"size:512;uniqueIndex:unique_index"`
+	ID    uuid.UUID  `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	Ptype PolicyType `gorm:"size:512;uniqueIndex:unique_index"              json:"ptype"`
+	V0    string     `gorm:"size:512;uniqueIndex:unique_index"              json:"v0"`
+	V1    string     `gorm:"size:512;uniqueIndex:unique_index"              json:"v1"`
+	V2    string     `gorm:"size:512;uniqueIndex:unique_index"              json:"v2"`
+	V3    string     `gorm:"size:512;uniqueIndex:unique_index"              json:"v3"`
+	V4    string     `gorm:"size:512;uniqueIndex:unique_index"              json:"v4"`
+	V5    string     `gorm:"size:512;uniqueIndex:unique_index"              json:"v5"`
+}
+
+// PolicyCreateBody is the request body for creating a policy rule.
+// For permission rules V0 is the subject, V1 the resource, V2 the action and V3 the optional effect.
+// For role rules V0 is a user ID or role, V1 the assigned role and V2 an optional bucket ID domain.
+type PolicyCreateBody struct {
+	Ptype PolicyType `json:"ptype" validate:"required,oneof=p g"`
+	V0    string     `json:"v0"    validate:"required,max=512"`
+	V1    string     `json:"v1"    validate:"required,max=512"`
+	V2    string     `json:"v2"    validate:"required_if=Ptype p,max=512"`
+	V3    string     `json:"v3"    validate:"excluded_if=Ptype g,omitempty,oneof=allow deny"`
 }
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ReconciliationStatus string
+
+const (
+	ReconciliationStatusRunning   ReconciliationStatus = "running"
+	ReconciliationStatusCompleted ReconciliationStatus = "completed"
+	ReconciliationStatusFailed    ReconciliationStatus = "failed"
+)
+
+type ReconciliationIssueType string
+
+const (
+	// ReconciliationOrphanedObject is an object stored for a file which does not exist anymore.
+	ReconciliationOrphanedObject ReconciliationIssueType = "orphaned_object"
+	// ReconciliationMissingObject is a file whose object is not in the storage.
+	ReconciliationMissingObject ReconciliationIssueType = "missing_object"
+	// ReconciliationStaleTrashMarker is a trash marker of a file or a folder which is not in the trash.
+	ReconciliationStaleTrashMarker ReconciliationIssueType = "stale_trash_marker"
+)
+
+// StorageReconciliation is a run of the reconciliation of the storage with the database.
+type StorageReconciliation struct {
+	ID          uuid.UUID             `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	Repair      bool                  `gorm:"not null"                                       json:"repair"`
+	Status      ReconciliationStatus  `gorm:"type:reconciliation_status;not null"            json:"status"`
+	Report      *ReconciliationReport `gorm:"type:jsonb;serializer:json"                     json:"report,omitempty"`
+	Error       *string               `gorm:"default:null"                                   json:"error,omitempty"`
+	StartedBy   *uuid.UUID            `gorm:"type:uuid;default:null"                         json:"started_by,omitempty"`
+	CreatedAt   time.Time             `                                                      json:"created_at"`
+	CompletedAt *time.Time            `gorm:"default:null"                                   json:"completed_at,omitempty"`
+}
+
+// ReconciliationReport summarizes the differences found between the storage and the database.
+// Only the first issues are listed, the counters include all of them.
+type ReconciliationReport struct {
+	ObjectsScanned      int                   `json:"objects_scanned"`
+	MarkersScanned      int                   `json:"markers_scanned"`
+	FilesScanned        int                   `json:"files_scanned"`
+	OrphanedObjects     int                   `json:"orphaned_objects"`
+	MissingObjects      int                   `json:"missing_objects"`
+	StaleTrashMarkers   int                   `json:"stale_trash_markers"`
+	UnrecognizedObjects int                   `json:"unrecognized_objects"`
+	Repaired            int                   `json:"repaired"`
+	Issues              []ReconciliationIssue `json:"issues"`
+	IssuesTruncated     bool                  `json:"issues_truncated"`
+}
+
+// ReconciliationIssue is a difference between the storage and the database.
+// ObjectID is the ID of the file, or of the folder of a trash marker.
+type ReconciliationIssue struct {
+	Type     ReconciliationIssueType `json:"type"`
+	Path     string                  `json:"path"`
+	BucketID uuid.UUID               `json:"bucket_id"`
+	ObjectID uuid.UUID               `json:"object_id"`
+	Repaired bool                    `json:"repaired"`
+	Error    string                  `json:"error,omitempty"`
+}
+
+type StorageReconciliationBody struct {
+	Repair bool `json:"repair"`
+}
+
+type StorageReconciliationActivity struct {
+	ID     uuid.UUID `json:"id"`
+	Repair bool      `json:"repair"`
+}
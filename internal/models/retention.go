@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RetentionLock prevents a file, or all the files of a bucket, from being trashed or purged
+// while the legal hold is set and until the retention date has passed.
+type RetentionLock struct {
+	LegalHold   bool       `gorm:"not null;default:false" json:"legal_hold"`
+	RetainUntil *time.Time `gorm:"default:null"           json:"retain_until"`
+}
+
+// IsLocked checks if the legal hold is set or the retention date has not passed.
+func (l RetentionLock) IsLocked() bool {
+	return l.LegalHold || (l.RetainUntil != nil && l.RetainUntil.After(time.Now()))
+}
+
+// RetentionLockBody sets the legal hold and the retention date of a file or a bucket.
+// The legal hold is kept when omitted, so that it is only released explicitly.
+// The retention date cannot be brought forward or removed before it has passed.
+type RetentionLockBody struct {
+	LegalHold   *bool      `json:"legal_hold"`
+	RetainUntil *time.Time `json:"retain_until"`
+}
+
+// RetentionLockActivity records a change of the retention lock of a file or a bucket, with the previous lock.
+type RetentionLockActivity struct {
+	ID                  uuid.UUID  `json:"id"`
+	Name                string     `json:"name"`
+	LegalHold           bool       `json:"legal_hold"`
+	RetainUntil         *time.Time `json:"retain_until"`
+	PreviousLegalHold   bool       `json:"previous_legal_hold"`
+	PreviousRetainUntil *time.Time `json:"previous_retain_until"`
+}
@@ -26,11 +26,18 @@ type User struct {
 	ProviderType   ProviderType   `gorm:"not null;type:provider_type;"                             json:"provider_type"`
 	ProviderKey    string         `gorm:"not null;uniqueIndex:idx_email_provider_key"              json:"provider_key"`
 	Role           Role           `gorm:"type:role_type;not null;"                                 json:"role"`
+	Language       string         `gorm:"default:null"                                             json:"language"`
+	DisabledAt     *time.Time     `                                                                json:"disabled_at"`
 	CreatedAt      time.Time      `                                                                json:"created_at"`
 	UpdatedAt      time.Time      `                                                                json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index"                                                    json:"-"`
 }
 
+// IsDisabled checks if the account has been suspended by an administrator.
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != nil
+}
+
 type UserActivity struct {
 	ID        uuid.UUID `json:"id"`
 	FirstName string    `json:"first_name"`
@@ -59,6 +66,34 @@ type UserUpdateBody struct {
 	LastName    string `json:"last_name"    validate:"omitempty,max=100"`
 	OldPassword string `json:"old_password" validate:"omitempty,required_with=NewPassword,max=72"`
 	NewPassword string `json:"new_password" validate:"omitempty,min=8,max=72"`
+	Language    string `json:"language"     validate:"omitempty,oneof=en fr"`
+}
+
+// UserDeleteQueryParams defines query parameters for deleting a user.
+// TransferTo is required when the user is the sole owner of a bucket.
+type UserDeleteQueryParams struct {
+	TransferTo string `json:"transfer_to" validate:"omitempty,uuid"`
+}
+
+// UserListQueryParams defines query parameters for searching users.
+// Cursor is the opaque next_cursor value returned by the previous page.
+type UserListQueryParams struct {
+	Search   string `json:"search"   validate:"omitempty,max=254"`
+	Provider string `json:"provider" validate:"omitempty,max=100"`
+	Role     string `json:"role"     validate:"omitempty,oneof=admin user guest"`
+	Status   string `json:"status"   validate:"omitempty,oneof=active disabled"`
+	Sort     string `json:"sort"     validate:"omitempty,oneof=created_at email first_name last_name"`
+	Order    string `json:"order"    validate:"omitempty,oneof=asc desc"`
+	Limit    int    `json:"limit"    validate:"omitempty,min=1,max=100"`
+	Cursor   string `json:"cursor"   validate:"omitempty,max=512"`
+}
+
+type UserRoleUpdateBody struct {
+	Role Role `json:"role" validate:"required,oneof=admin user guest"`
+}
+
+type UserStatusUpdateBody struct {
+	Disabled *bool `json:"disabled" validate:"required"`
 }
 
 type UserStatsResponse struct {
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WebhookEventFileUploaded   = "file.uploaded"
+	WebhookEventFileDownloaded = "file.downloaded"
+	WebhookEventFileTrashed    = "file.trashed"
+	WebhookEventFileRestored   = "file.restored"
+	WebhookEventFilePurged     = "file.purged"
+	WebhookEventMemberCreated  = "member.created"
+	WebhookEventMemberUpdated  = "member.updated"
+	WebhookEventMemberDeleted  = "member.deleted"
+)
+
+// WebhookEventTypes lists the events webhooks can subscribe to.
+var WebhookEventTypes = []string{
+	WebhookEventFileUploaded,
+	WebhookEventFileDownloaded,
+	WebhookEventFileTrashed,
+	WebhookEventFileRestored,
+	WebhookEventFilePurged,
+	WebhookEventMemberCreated,
+	WebhookEventMemberUpdated,
+	WebhookEventMemberDeleted,
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	WebhookDeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// Webhook is an endpoint of a bucket receiving signed notifications of the subscribed events.
+type Webhook struct {
+	ID         uuid.UUID `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	BucketID   uuid.UUID `gorm:"type:uuid;not null"                             json:"bucket_id"`
+	URL        string    `gorm:"not null"                                       json:"url"`
+	Secret     string    `gorm:"not null"                                       json:"-"`
+	EventTypes []string  `gorm:"type:jsonb;serializer:json;not null"            json:"event_types"`
+	Enabled    bool      `gorm:"not null;default:true"                          json:"enabled"`
+	CreatedBy  uuid.UUID `gorm:"type:uuid;not null"                             json:"created_by"`
+	CreatedAt  time.Time `                                                      json:"created_at"`
+	UpdatedAt  time.Time `                                                      json:"updated_at"`
+}
+
+type WebhookActivity struct {
+	ID  uuid.UUID `json:"id"`
+	URL string    `json:"url"`
+}
+
+func (w *Webhook) ToActivity() WebhookActivity {
+	return WebhookActivity{
+		ID:  w.ID,
+		URL: w.URL,
+	}
+}
+
+// WebhookWithSecret is returned once when a webhook is created, as the secret cannot be read afterwards.
+type WebhookWithSecret struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+type WebhookCreateBody struct {
+	URL        string   `json:"url"         validate:"required,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"required,min=1,unique,dive,webhook_event"`
+}
+
+type WebhookUpdateBody struct {
+	URL        string   `json:"url"         validate:"omitempty,http_url,max=2048"`
+	EventTypes []string `json:"event_types" validate:"omitempty,min=1,unique,dive,webhook_event"`
+	Enabled    *bool    `json:"enabled"     validate:"omitempty"`
+}
+
+// WebhookRedeliverBody is the request body for delivering an event of the delivery log again.
+type WebhookRedeliverBody struct {
+	DeliveryID uuid.UUID `json:"delivery_id" validate:"required"`
+}
+
+// WebhookDelivery is an attempt to deliver an event to a webhook, kept as the delivery log.
+type WebhookDelivery struct {
+	ID             uuid.UUID             `gorm:"type:uuid;primarykey;default:gen_random_uuid()" json:"id"`
+	WebhookID      uuid.UUID             `gorm:"type:uuid;not null"                             json:"webhook_id"`
+	Webhook        Webhook               `                                                      json:"-"`
+	EventType      string                `gorm:"not null"                                       json:"event_type"`
+	Payload        json.RawMessage       `gorm:"type:jsonb;not null"                            json:"payload"`
+	Status         WebhookDeliveryStatus `gorm:"not null;default:pending"                       json:"status"`
+	Attempts       int                   `gorm:"not null;default:0"                             json:"attempts"`
+	ResponseStatus int                   `gorm:"default:null"                                   json:"response_status,omitempty"`
+	LastError      string                `gorm:"default:null"                                   json:"last_error,omitempty"`
+	NextAttemptAt  time.Time             `gorm:"not null;default:CURRENT_TIMESTAMP"             json:"next_attempt_at"`
+	CreatedAt      time.Time             `                                                      json:"created_at"`
+	DeliveredAt    *time.Time            `gorm:"default:null"                                   json:"delivered_at,omitempty"`
+}
+
+// WebhookPayload is the JSON body posted to the webhooks.
+type WebhookPayload struct {
+	ID         uuid.UUID   `json:"id"`
+	Event      string      `json:"event"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	BucketID   uuid.UUID   `json:"bucket_id"`
+	UserID     *uuid.UUID  `json:"user_id,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+// WebhookMember describes the member of a member event.
+type WebhookMember struct {
+	Email     string     `json:"email"`
+	Group     Group      `json:"group,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
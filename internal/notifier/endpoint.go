@@ -0,0 +1,126 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"api/internal/cache"
+	c "api/internal/configuration"
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// chatTemplates are the notifications sent to chat and HTTP endpoints. The notifications without a template,
+// such as the password reset codes, are meant for their recipient only and are never sent to an endpoint.
+//
+//go:embed templates/*.tmpl
+var chatTemplates embed.FS
+
+var endpointClient = &http.Client{Timeout: c.NotifierTimeoutSeconds * time.Second}
+
+// endpoint posts the notifications rendered from the chat templates to an incoming webhook.
+// Each backend renders the templates with its own formatting functions:
+//   - bold emphasizes a value
+//   - link renders a link to a URL with a label
+//   - escape protects a value from being interpreted as formatting
+type endpoint struct {
+	url       string
+	headers   map[string]string
+	filter    []string
+	templates *template.Template
+	cache     cache.ICache
+}
+
+func newEndpoint(config models.NotifierEndpointConfiguration, cache cache.ICache, funcs template.FuncMap) endpoint {
+	templates := template.Must(template.New("").Funcs(funcs).ParseFS(chatTemplates, "templates/*.tmpl"))
+	return endpoint{
+		url:       config.URL,
+		headers:   config.Headers,
+		filter:    config.Templates,
+		templates: templates,
+		cache:     cache,
+	}
+}
+
+// render returns the text of a notification, and false when the endpoint does not receive the notification.
+func (e endpoint) render(templateName string, data interface{}) (string, bool, error) {
+	if len(e.filter) > 0 && !slices.Contains(e.filter, templateName) {
+		return "", false, nil
+	}
+
+	tmpl := e.templates.Lookup(templateName + ".tmpl")
+	if tmpl == nil {
+		return "", false, nil
+	}
+
+	var text bytes.Buffer
+	if err := tmpl.Execute(&text, data); err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(text.String()), true, nil
+}
+
+// isDuplicate checks if the same text was recently posted to the endpoint, by any instance. A notification sent
+// to each of its recipients, such as the quarantine of a file to the owners of its bucket, renders the same text
+// for all of them, so that a shared channel only receives it once. The text is posted when the cache is unavailable.
+func (e endpoint) isDuplicate(templateName string, text string) bool {
+	if e.cache == nil {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(e.url + "\n" + templateName + "\n" + text))
+	posted, err := e.cache.AcquireLock(
+		"notifier:"+hex.EncodeToString(sum[:]),
+		uuid.NewString(),
+		c.NotifierChannelDedupSeconds*time.Second,
+	)
+	if err != nil {
+		zap.L().Warn("Failed to check if the notification was already posted", zap.Error(err))
+		return false
+	}
+	return !posted
+}
+
+func (e endpoint) post(message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.NotifierTimeoutSeconds*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := endpointClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, c.NotifierResponseErrorMaxBytes))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, response)
+	}
+	return nil
+}
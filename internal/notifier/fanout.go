@@ -0,0 +1,35 @@
+package notifier
+
+import "go.uber.org/zap"
+
+// FanOutNotifier sends the notifications with the primary notifier and a copy to the channels,
+// such as the channel of a team. The channels only receive the notifications sent by the primary notifier,
+// so that a notification retried after a failure is not posted twice, and never affect the result.
+type FanOutNotifier struct {
+	Primary  INotifier
+	Channels []INotifier
+}
+
+// NewFanOutNotifier wraps a notifier to send a copy of its notifications to the channels.
+func NewFanOutNotifier(primary INotifier, channels ...INotifier) INotifier {
+	return &FanOutNotifier{Primary: primary, Channels: channels}
+}
+
+func (n *FanOutNotifier) NotifyFromTemplate(
+	to string,
+	language string,
+	subject string,
+	templateName string,
+	data interface{},
+) error {
+	if err := n.Primary.NotifyFromTemplate(to, language, subject, templateName, data); err != nil {
+		return err
+	}
+
+	for _, channel := range n.Channels {
+		if err := channel.NotifyFromTemplate(to, language, subject, templateName, data); err != nil {
+			zap.L().Warn("Failed to notify channel", zap.String("template", templateName), zap.Error(err))
+		}
+	}
+	return nil
+}
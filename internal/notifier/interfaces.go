@@ -1,6 +1,14 @@
 package notifier
 
 // INotifier defines the interface for sending notifications.
+// The language is the preference of the recipient, the default language is used when it is empty or not supported.
+// The subject is used when the template of the language does not define one.
 type INotifier interface {
-	NotifyFromTemplate(to string, subject string, templateName string, data interface{}) error
+	NotifyFromTemplate(to string, language string, subject string, templateName string, data interface{}) error
+}
+
+// IUnsubscribable is implemented by the data of the notifications the recipient can unsubscribe from.
+// Their emails carry the List-Unsubscribe headers, with the one-click unsubscription of RFC 8058.
+type IUnsubscribable interface {
+	ListUnsubscribeURL() string
 }
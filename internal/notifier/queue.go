@@ -0,0 +1,170 @@
+package notifier
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MailQueue implements INotifier by storing the rendered emails in the mail_deliveries table
+// of the application database, and sending them in the background with the SMTP notifier,
+// so that the events do not depend on the SMTP server being up or accepting the rate of the notifications.
+// Failed emails are retried with an exponential backoff, and emails rejected by the server are marked as bounced.
+type MailQueue struct {
+	DB          *gorm.DB
+	Mailer      *SMTPNotifier
+	MaxAttempts int
+}
+
+// NewMailQueue wraps the SMTP notifier with a delivery queue.
+func NewMailQueue(db *gorm.DB, mailer *SMTPNotifier, maxAttempts int) *MailQueue {
+	if maxAttempts == 0 {
+		maxAttempts = c.MailQueueDefaultMaxAttempts
+	}
+	return &MailQueue{DB: db, Mailer: mailer, MaxAttempts: maxAttempts}
+}
+
+// NotifyFromTemplate renders the email and queues it.
+func (q *MailQueue) NotifyFromTemplate(
+	to string,
+	language string,
+	subject string,
+	templateName string,
+	data interface{},
+) error {
+	mail, err := q.Mailer.Render(to, language, subject, templateName, data)
+	if err != nil {
+		return err
+	}
+
+	mail.Status = models.MailDeliveryPending
+	mail.NextAttemptAt = time.Now()
+	if err = q.DB.Create(&mail).Error; err != nil {
+		zap.L().Error("Failed to queue email", zap.String("template", templateName), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Deliver sends a batch of due emails, with as many concurrent sends as pooled connections,
+// and returns the number of emails of the batch. The emails are leased before being sent,
+// so that several instances can deliver concurrently, and an email whose instance stopped is sent again later.
+func (q *MailQueue) Deliver(batchSize int) (int, error) {
+	var mails []models.MailDelivery
+	err := q.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.MailDeliveryPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(batchSize).
+			Find(&mails).Error
+		if err != nil || len(mails) == 0 {
+			return err
+		}
+
+		ids := make([]string, 0, len(mails))
+		for _, mail := range mails {
+			ids = append(ids, mail.ID.String())
+		}
+		return tx.Model(&models.MailDelivery{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": time.Now().Add(c.MailQueueLeaseSeconds * time.Second),
+			}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	queue := make(chan models.MailDelivery)
+	var wg sync.WaitGroup
+	for range min(q.Mailer.PoolSize(), len(mails)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for mail := range queue {
+				q.send(mail)
+			}
+		}()
+	}
+	for _, mail := range mails {
+		queue <- mail
+	}
+	close(queue)
+	wg.Wait()
+
+	return len(mails), nil
+}
+
+// Purge removes the emails sent or given up on before the retention period.
+func (q *MailQueue) Purge() error {
+	return q.DB.
+		Where("status <> ? AND created_at < ?",
+			models.MailDeliveryPending, time.Now().AddDate(0, 0, -c.MailDeliveryRetentionDays)).
+		Delete(&models.MailDelivery{}).Error
+}
+
+// send sends a leased email and records the result.
+func (q *MailQueue) send(mail models.MailDelivery) {
+	mail.Attempts++
+	code, err := q.Mailer.Send(mail)
+
+	updates := map[string]interface{}{"response_code": nil}
+	if code != 0 {
+		updates["response_code"] = code
+	}
+
+	switch {
+	case err == nil:
+		updates["status"] = models.MailDeliverySent
+		updates["sent_at"] = time.Now()
+		updates["last_error"] = nil
+		updates["text_body"] = ""
+		updates["html_body"] = ""
+	case isBounce(code):
+		zap.L().Warn("Email bounced",
+			zap.String("mail_id", mail.ID.String()),
+			zap.String("template", mail.Template),
+			zap.Int("code", code),
+			zap.Error(err))
+
+		updates["status"] = models.MailDeliveryBounced
+		updates["last_error"] = err.Error()
+	default:
+		zap.L().Warn("Failed to send email",
+			zap.String("mail_id", mail.ID.String()),
+			zap.String("template", mail.Template),
+			zap.Int("attempts", mail.Attempts),
+			zap.Error(err))
+
+		updates["last_error"] = err.Error()
+		if mail.Attempts >= q.MaxAttempts {
+			updates["status"] = models.MailDeliveryFailed
+		} else {
+			updates["next_attempt_at"] = time.Now().Add(mailRetryDelay(mail.Attempts))
+		}
+	}
+
+	if err = q.DB.Model(&models.MailDelivery{}).Where("id = ?", mail.ID).Updates(updates).Error; err != nil {
+		// The lease expires and the email is sent again
+		zap.L().Error("Failed to record email delivery", zap.String("mail_id", mail.ID.String()), zap.Error(err))
+	}
+}
+
+// isBounce checks if the SMTP server permanently rejected the recipient, in which case retrying is pointless.
+func isBounce(code int) bool {
+	return code >= 550 && code <= 553
+}
+
+// mailRetryDelay doubles the delay after each failed attempt, up to the configured maximum.
+func mailRetryDelay(attempts int) time.Duration {
+	delay := c.MailQueueRetryBaseDelaySeconds * math.Pow(2, float64(attempts-1))
+	return time.Duration(math.Min(delay, c.MailQueueRetryMaxDelaySeconds)) * time.Second
+}
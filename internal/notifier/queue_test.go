@@ -0,0 +1,213 @@
+package notifier
+
+import (
+	"database/sql/driver"
+	"io"
+	"net/textproto"
+	"regexp"
+	"testing"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+// smtpClientStub is a pooled SMTP connection replying to every email with the given error.
+type smtpClientStub struct {
+	err    error
+	sent   []string
+	closed bool
+}
+
+func (s *smtpClientStub) Send(_ string, to []string, _ io.WriterTo) error {
+	s.sent = append(s.sent, to...)
+	return s.err
+}
+
+func (s *smtpClientStub) Close() error {
+	s.closed = true
+	return nil
+}
+
+// newPooledMailer returns an SMTP notifier without rate limit, whose pool holds the given connection.
+func newPooledMailer(client *smtpClientStub) *SMTPNotifier {
+	mailer := &SMTPNotifier{
+		sender:      "safebucket@example.com",
+		limiter:     rate.NewLimiter(rate.Inf, 1),
+		connections: make(chan *smtpConnection, 1),
+	}
+	mailer.connections <- &smtpConnection{client: client, lastUsed: time.Now()}
+	return mailer
+}
+
+// anyTime matches the timestamps computed when the delivery is recorded.
+type anyTime struct{}
+
+func (anyTime) Match(v driver.Value) bool {
+	_, ok := v.(time.Time)
+	return ok
+}
+
+func TestMailRetryDelay(t *testing.T) {
+	testCases := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: c.MailQueueRetryBaseDelaySeconds * time.Second},
+		{attempts: 2, expected: 2 * c.MailQueueRetryBaseDelaySeconds * time.Second},
+		{attempts: 4, expected: 8 * c.MailQueueRetryBaseDelaySeconds * time.Second},
+		{attempts: 20, expected: c.MailQueueRetryMaxDelaySeconds * time.Second},
+	}
+
+	for _, tt := range testCases {
+		assert.Equal(t, tt.expected, mailRetryDelay(tt.attempts), "attempts %d", tt.attempts)
+	}
+}
+
+func TestIsBounce(t *testing.T) {
+	assert.False(t, isBounce(0))
+	assert.False(t, isBounce(421))
+	assert.False(t, isBounce(451))
+	assert.True(t, isBounce(550))
+	assert.True(t, isBounce(553))
+	assert.False(t, isBounce(554))
+}
+
+func TestMailQueueSend(t *testing.T) {
+	temporaryFailure := &textproto.Error{Code: 451, Msg: "try again later"}
+	rejected := &textproto.Error{Code: 550, Msg: "mailbox unavailable"}
+
+	testCases := []struct {
+		name          string
+		err           error
+		attempts      int
+		expectedSQL   string
+		expectedArgs  []driver.Value
+		expectedClose bool
+	}{
+		{
+			name: "Sent email",
+			expectedSQL: `UPDATE "mail_deliveries" SET "html_body"=$1,"last_error"=$2,"response_code"=$3,` +
+				`"sent_at"=$4,"status"=$5,"text_body"=$6 WHERE id = $7`,
+			expectedArgs: []driver.Value{"", nil, nil, anyTime{}, models.MailDeliverySent, ""},
+		},
+		{
+			name:     "Temporary failure",
+			err:      temporaryFailure,
+			attempts: 1,
+			expectedSQL: `UPDATE "mail_deliveries" SET "last_error"=$1,"next_attempt_at"=$2,"response_code"=$3 ` +
+				`WHERE id = $4`,
+			expectedArgs:  []driver.Value{temporaryFailure.Error(), anyTime{}, int64(451)},
+			expectedClose: true,
+		},
+		{
+			name:          "Temporary failure of the last attempt",
+			err:           temporaryFailure,
+			attempts:      c.MailQueueDefaultMaxAttempts - 1,
+			expectedSQL:   `UPDATE "mail_deliveries" SET "last_error"=$1,"response_code"=$2,"status"=$3 WHERE id = $4`,
+			expectedArgs:  []driver.Value{temporaryFailure.Error(), int64(451), models.MailDeliveryFailed},
+			expectedClose: true,
+		},
+		{
+			name:          "Rejected recipient",
+			err:           rejected,
+			expectedSQL:   `UPDATE "mail_deliveries" SET "last_error"=$1,"response_code"=$2,"status"=$3 WHERE id = $4`,
+			expectedArgs:  []driver.Value{rejected.Error(), int64(550), models.MailDeliveryBounced},
+			expectedClose: true,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			client := &smtpClientStub{err: tt.err}
+			queue := NewMailQueue(db, newPooledMailer(client), 0)
+			mail := models.MailDelivery{
+				ID:        uuid.New(),
+				Recipient: "user@example.com",
+				Template:  "user_welcome",
+				Subject:   "Welcome to Safebucket!",
+				Attempts:  tt.attempts,
+			}
+
+			dbMock.ExpectBegin()
+			dbMock.ExpectExec(regexp.QuoteMeta(tt.expectedSQL)).
+				WithArgs(append(tt.expectedArgs, mail.ID)...).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			dbMock.ExpectCommit()
+
+			queue.send(mail)
+
+			assert.Equal(t, []string{"user@example.com"}, client.sent)
+			assert.Equal(t, tt.expectedClose, client.closed)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMailQueueDeliver(t *testing.T) {
+	db, dbMock := newMockDB(t)
+	client := &smtpClientStub{}
+	queue := NewMailQueue(db, newPooledMailer(client), 0)
+	mailID := uuid.New()
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mail_deliveries" WHERE status = $1 AND next_attempt_at <= $2 `+
+		`ORDER BY next_attempt_at LIMIT $3 FOR UPDATE SKIP LOCKED`)).
+		WithArgs(models.MailDeliveryPending, anyTime{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "recipient", "template", "attempts"}).
+			AddRow(mailID, "user@example.com", "user_welcome", 0))
+	// The email is leased before being sent
+	dbMock.ExpectExec(regexp.QuoteMeta(`UPDATE "mail_deliveries" SET "attempts"=attempts + 1,"next_attempt_at"=$1 `+
+		`WHERE id IN ($2)`)).
+		WithArgs(anyTime{}, mailID.String()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+	dbMock.ExpectBegin()
+	dbMock.ExpectExec(regexp.QuoteMeta(`UPDATE "mail_deliveries" SET`)).
+		WithArgs("", nil, nil, anyTime{}, models.MailDeliverySent, "", mailID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	dbMock.ExpectCommit()
+
+	delivered, err := queue.Deliver(10)
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{"user@example.com"}, client.sent)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestMailQueueDeliverWithoutDueEmails(t *testing.T) {
+	db, dbMock := newMockDB(t)
+	queue := NewMailQueue(db, newPooledMailer(&smtpClientStub{}), 0)
+
+	dbMock.ExpectBegin()
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mail_deliveries"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	dbMock.ExpectCommit()
+
+	delivered, err := queue.Deliver(10)
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"api/internal/cache"
+	"api/internal/models"
+)
+
+var slackEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// SlackNotifier implements INotifier using a Slack incoming webhook.
+type SlackNotifier struct {
+	endpoint endpoint
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackBlock struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+}
+
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// NewSlackNotifier creates a notifier posting each notification once to the channel, using the cache
+// to recognize the copies sent to its other recipients.
+func NewSlackNotifier(config models.NotifierEndpointConfiguration, cache cache.ICache) *SlackNotifier {
+	return &SlackNotifier{endpoint: newEndpoint(config, cache, template.FuncMap{
+		"escape": func(value interface{}) string {
+			return slackEscaper.Replace(fmt.Sprint(value))
+		},
+		"bold": func(value interface{}) string {
+			return fmt.Sprintf("*%s*", slackEscaper.Replace(fmt.Sprint(value)))
+		},
+		"link": func(url string, label interface{}) string {
+			return fmt.Sprintf("<%s|%s>", url, slackEscaper.Replace(fmt.Sprint(label)))
+		},
+	})}
+}
+
+// NotifyFromTemplate posts a message with the subject as header and the rendered template in mrkdwn.
+// The recipient and its language are part of the template, since the message is posted to a channel.
+func (s *SlackNotifier) NotifyFromTemplate(
+	_ string,
+	_ string,
+	subject string,
+	templateName string,
+	data interface{},
+) error {
+	text, ok, err := s.endpoint.render(templateName, data)
+	if err != nil || !ok || s.endpoint.isDuplicate(templateName, text) {
+		return err
+	}
+
+	return s.endpoint.post(slackMessage{
+		Text: subject,
+		Blocks: []slackBlock{
+			{Type: "header", Text: slackText{Type: "plain_text", Text: subject}},
+			{Type: "section", Text: slackText{Type: "mrkdwn", Text: text}},
+		},
+	})
+}
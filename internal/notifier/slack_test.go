@@ -0,0 +1,80 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"api/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockCache implements the locks of cache.ICache in memory.
+type lockCache struct {
+	mu    sync.Mutex
+	locks map[string]string
+}
+
+func (c *lockCache) RegisterPlatform(_ string) error { return nil }
+func (c *lockCache) DeleteInactivePlatform() error   { return nil }
+func (c *lockCache) StartIdentityTicker(_ string)    {}
+func (c *lockCache) GetRateLimit(_ string, _ int) (int, error) {
+	return 0, nil
+}
+func (c *lockCache) Publish(_ string, _ string) error { return nil }
+func (c *lockCache) Subscribe(_ context.Context, _ string, _ func(message string)) error {
+	return nil
+}
+func (c *lockCache) Close() error { return nil }
+
+func (c *lockCache) AcquireLock(name string, owner string, _ time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if current, exists := c.locks[name]; exists && current != owner {
+		return false, nil
+	}
+	c.locks[name] = owner
+	return true, nil
+}
+
+func TestSlackNotifierPostsOncePerNotification(t *testing.T) {
+	var posts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		posts.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	slack := NewSlackNotifier(
+		models.NotifierEndpointConfiguration{URL: server.URL},
+		&lockCache{locks: map[string]string{}},
+	)
+
+	data := map[string]interface{}{
+		"Bucket":    models.Bucket{Name: "reports"},
+		"BucketURL": "https://safebucket.io/buckets/1",
+		"FileName":  "invoice.pdf",
+		"Signature": "Eicar-Test-Signature",
+		"Uploader":  "jane@safebucket.io",
+	}
+
+	for _, owner := range []string{"owner@safebucket.io", "other-owner@safebucket.io"} {
+		require.NoError(t,
+			slack.NotifyFromTemplate(owner, "en", "A file has been quarantined", "file_quarantined", data))
+	}
+	assert.Equal(t, int32(1), posts.Load())
+
+	data["FileName"] = "report.pdf"
+	require.NoError(t, slack.NotifyFromTemplate("owner@safebucket.io", "en", "", "file_quarantined", data))
+	assert.Equal(t, int32(2), posts.Load())
+
+	// Notifications without a chat template are never posted
+	require.NoError(t, slack.NotifyFromTemplate("owner@safebucket.io", "en", "", "password_reset", data))
+	assert.Equal(t, int32(2), posts.Load())
+}
@@ -2,20 +2,43 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
-	"html/template"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"net/textproto"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+	"time"
 
+	c "api/internal/configuration"
+	"api/internal/mails"
 	"api/internal/models"
 
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 	"gopkg.in/gomail.v2"
 )
 
 // SMTPNotifier implements INotifier using SMTP protocol.
+// The connections are kept open in a pool, and the emails are sent at the configured rate,
+// so that a burst of notifications does not hit the limits of the SMTP server.
 type SMTPNotifier struct {
-	dialer *gomail.Dialer
-	sender string
+	dialer       *gomail.Dialer
+	sender       string
+	templatesDir string
+	limiter      *rate.Limiter
+	connections  chan *smtpConnection
+}
+
+type smtpConnection struct {
+	client   gomail.SendCloser
+	lastUsed time.Time
 }
 
 // NewSMTPNotifier initializes the SMTP notifier and checks the connection.
@@ -41,35 +64,234 @@ func NewSMTPNotifier(config models.MailerConfiguration) *SMTPNotifier {
 		_ = connection.Close()
 	}
 
-	return &SMTPNotifier{dialer: dialer, sender: config.Sender}
+	poolSize := config.PoolSize
+	if poolSize == 0 {
+		poolSize = c.MailerDefaultPoolSize
+	}
+	ratePerSecond := config.RatePerSecond
+	if ratePerSecond == 0 {
+		ratePerSecond = c.MailerDefaultRatePerSecond
+	}
+
+	return &SMTPNotifier{
+		dialer:       dialer,
+		sender:       config.Sender,
+		templatesDir: config.TemplatesDir,
+		limiter:      rate.NewLimiter(rate.Limit(ratePerSecond), 1),
+		connections:  make(chan *smtpConnection, poolSize),
+	}
+}
+
+// PoolSize returns the number of connections kept open, which is also the number of emails sent concurrently.
+func (s *SMTPNotifier) PoolSize() int {
+	return cap(s.connections)
 }
 
-// NotifyFromTemplate sends an email using a given template and data.
+// NotifyFromTemplate sends a multipart email, with a plain-text and an HTML part, using a given template and data.
 func (s *SMTPNotifier) NotifyFromTemplate(
 	to string,
+	language string,
 	subject string,
 	templateName string,
 	data interface{},
 ) error {
-	tmpl, err := template.ParseFiles(fmt.Sprintf("./internal/mails/%s.html", templateName))
+	mail, err := s.Render(to, language, subject, templateName, data)
 	if err != nil {
 		return err
 	}
+	_, err = s.Send(mail)
+	return err
+}
 
-	var body bytes.Buffer
-	if err = tmpl.Execute(&body, data); err != nil {
-		return err
+// Render renders the subject and the bodies of an email in the language of the recipient.
+// The unsubscribe link of the notifications is kept for the headers of the email.
+func (s *SMTPNotifier) Render(
+	to string,
+	language string,
+	subject string,
+	templateName string,
+	data interface{},
+) (models.MailDelivery, error) {
+	language = s.resolveLanguage(language, templateName)
+
+	htmlContent, err := s.readTemplate(language, templateName+".html")
+	if err != nil {
+		return models.MailDelivery{}, err
+	}
+	htmlTmpl, err := htmltemplate.New(templateName).Parse(string(htmlContent))
+	if err != nil {
+		return models.MailDelivery{}, err
 	}
 
+	textContent, err := s.readTemplate(language, templateName+".txt")
+	if err != nil {
+		return models.MailDelivery{}, err
+	}
+	textTmpl, err := texttemplate.New(templateName).Parse(string(textContent))
+	if err != nil {
+		return models.MailDelivery{}, err
+	}
+
+	var htmlBody, textBody bytes.Buffer
+	if err = htmlTmpl.Execute(&htmlBody, data); err != nil {
+		return models.MailDelivery{}, err
+	}
+	if err = textTmpl.Execute(&textBody, data); err != nil {
+		return models.MailDelivery{}, err
+	}
+
+	if subjectTmpl := textTmpl.Lookup("subject"); subjectTmpl != nil {
+		var translated bytes.Buffer
+		if err = subjectTmpl.Execute(&translated, data); err != nil {
+			return models.MailDelivery{}, err
+		}
+		subject = strings.TrimSpace(translated.String())
+	}
+
+	mail := models.MailDelivery{
+		Recipient: to,
+		Template:  templateName,
+		Language:  language,
+		Subject:   subject,
+		TextBody:  strings.TrimSpace(textBody.String()),
+		HTMLBody:  htmlBody.String(),
+	}
+	if unsubscribable, ok := data.(IUnsubscribable); ok {
+		mail.UnsubscribeURL = unsubscribable.ListUnsubscribeURL()
+	}
+	return mail, nil
+}
+
+// Send sends a rendered email once the rate allows it, and returns the reply code of the SMTP server on failure.
+func (s *SMTPNotifier) Send(mail models.MailDelivery) (int, error) {
 	msg := gomail.NewMessage()
 	msg.SetHeader("From", s.sender)
-	msg.SetHeader("To", to)
-	msg.SetHeader("Subject", subject)
-	msg.SetBody("text/html", body.String())
+	msg.SetHeader("To", mail.Recipient)
+	msg.SetHeader("Subject", mail.Subject)
+	if mail.UnsubscribeURL != "" {
+		msg.SetHeader("List-Unsubscribe", "<"+mail.UnsubscribeURL+">")
+		msg.SetHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	msg.SetBody("text/plain", mail.TextBody)
+	msg.AddAlternative("text/html", mail.HTMLBody)
 
-	if err = s.dialer.DialAndSend(msg); err != nil {
-		return err
+	if err := s.limiter.Wait(context.Background()); err != nil {
+		return 0, err
+	}
+
+	connection, reused, err := s.acquire()
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.send(connection, msg)
+	if err != nil && reused && smtpReplyCode(err) == 0 {
+		// The server may have closed the idle connection, try once more with a new one
+		_ = connection.client.Close()
+		if connection, err = s.dial(); err != nil {
+			return 0, err
+		}
+		err = s.send(connection, msg)
+	}
+
+	if err != nil {
+		_ = connection.client.Close()
+		return smtpReplyCode(err), err
+	}
+
+	s.release(connection)
+	return 0, nil
+}
+
+// send sends a message on a connection, keeping the error of the SMTP server that gomail only formats.
+func (s *SMTPNotifier) send(connection *smtpConnection, msg *gomail.Message) error {
+	var sendErr error
+	err := gomail.Send(gomail.SendFunc(func(from string, to []string, message io.WriterTo) error {
+		sendErr = connection.client.Send(from, to, message)
+		return sendErr
+	}), msg)
+	if sendErr != nil {
+		return sendErr
 	}
+	return err
+}
 
-	return nil
+// acquire returns an open connection of the pool, or a new one. Connections idle for too long are closed,
+// as most servers drop them anyway.
+func (s *SMTPNotifier) acquire() (*smtpConnection, bool, error) {
+	for {
+		select {
+		case connection := <-s.connections:
+			if time.Since(connection.lastUsed) > c.MailerPoolIdleSeconds*time.Second {
+				_ = connection.client.Close()
+				continue
+			}
+			return connection, true, nil
+		default:
+			connection, err := s.dial()
+			return connection, false, err
+		}
+	}
+}
+
+func (s *SMTPNotifier) dial() (*smtpConnection, error) {
+	client, err := s.dialer.Dial()
+	if err != nil {
+		return nil, err
+	}
+	return &smtpConnection{client: client}, nil
+}
+
+// release puts a connection back in the pool, or closes it when the pool is full.
+func (s *SMTPNotifier) release(connection *smtpConnection) {
+	connection.lastUsed = time.Now()
+	select {
+	case s.connections <- connection:
+	default:
+		_ = connection.client.Close()
+	}
+}
+
+// smtpReplyCode returns the code of the reply of the SMTP server an error comes from, or 0 for connection errors.
+func smtpReplyCode(err error) int {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code
+	}
+	return 0
+}
+
+// resolveLanguage returns the language of the templates to use, falling back to the default language
+// when there is no template for the language of the recipient. Regional variants use their base language.
+func (s *SMTPNotifier) resolveLanguage(language string, templateName string) string {
+	language, _, _ = strings.Cut(strings.ToLower(language), "-")
+	if language == "" || strings.ContainsAny(language, "./\\") {
+		return c.DefaultLanguage
+	}
+
+	if _, err := s.readTemplate(language, templateName+".html"); err != nil {
+		return c.DefaultLanguage
+	}
+	return language
+}
+
+// readTemplate reads a template from the configured templates directory, which overrides the embedded templates.
+func (s *SMTPNotifier) readTemplate(language string, filename string) ([]byte, error) {
+	name := path.Join(language, filename)
+
+	if s.templatesDir != "" {
+		content, err := fs.ReadFile(os.DirFS(s.templatesDir), name)
+		if err == nil {
+			return content, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	content, err := fs.ReadFile(mails.Templates, name)
+	if err != nil {
+		return nil, fmt.Errorf("mail template %s: %w", name, err)
+	}
+	return content, nil
 }
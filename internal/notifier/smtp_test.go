@@ -0,0 +1,147 @@
+package notifier
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"api/internal/mails"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type welcomeData struct {
+	WebURL string
+}
+
+func TestRenderLocalizedTemplates(t *testing.T) {
+	testCases := []struct {
+		name             string
+		language         string
+		expectedLanguage string
+		expectedSubject  string
+		expectedText     string
+	}{
+		{
+			name:             "Default language",
+			language:         "en",
+			expectedLanguage: "en",
+			expectedSubject:  "Welcome to Safebucket!",
+			expectedText:     "Get started: https://safebucket.example.com",
+		},
+		{
+			name:             "Translated language",
+			language:         "fr",
+			expectedLanguage: "fr",
+			expectedSubject:  "Bienvenue sur Safebucket !",
+			expectedText:     "Commencer : https://safebucket.example.com",
+		},
+		{
+			name:             "Regional variant",
+			language:         "fr-CA",
+			expectedLanguage: "fr",
+			expectedSubject:  "Bienvenue sur Safebucket !",
+			expectedText:     "Commencer : https://safebucket.example.com",
+		},
+		{
+			name:             "Language without templates",
+			language:         "de",
+			expectedLanguage: "en",
+			expectedSubject:  "Welcome to Safebucket!",
+			expectedText:     "Get started: https://safebucket.example.com",
+		},
+		{
+			name:             "Unknown language",
+			expectedLanguage: "en",
+			expectedSubject:  "Welcome to Safebucket!",
+			expectedText:     "Get started: https://safebucket.example.com",
+		},
+		{
+			name:             "Language escaping the templates directory",
+			language:         "../en",
+			expectedLanguage: "en",
+			expectedSubject:  "Welcome to Safebucket!",
+			expectedText:     "Get started: https://safebucket.example.com",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier := &SMTPNotifier{}
+
+			mail, err := notifier.Render("user@example.com", tt.language, "Fallback subject", "user_welcome",
+				welcomeData{WebURL: "https://safebucket.example.com"})
+			require.NoError(t, err)
+
+			assert.Equal(t, "user@example.com", mail.Recipient)
+			assert.Equal(t, "user_welcome", mail.Template)
+			assert.Equal(t, tt.expectedLanguage, mail.Language)
+			assert.Equal(t, tt.expectedSubject, mail.Subject)
+			assert.Contains(t, mail.TextBody, tt.expectedText)
+			assert.NotContains(t, mail.TextBody, "subject")
+			assert.Contains(t, mail.HTMLBody, "https://safebucket.example.com")
+			assert.Empty(t, mail.UnsubscribeURL)
+		})
+	}
+}
+
+func TestRenderTemplatesDirectory(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "fr"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fr", "user_welcome.html"),
+		[]byte("<p>{{.WebURL}}</p>"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "fr", "user_welcome.txt"),
+		[]byte(`{{define "subject"}}Bienvenue{{end -}}`+"\nSite : {{.WebURL}}\n"), 0o600))
+
+	notifier := &SMTPNotifier{templatesDir: dir}
+	data := welcomeData{WebURL: "https://safebucket.example.com"}
+
+	t.Run("Overridden template", func(t *testing.T) {
+		mail, err := notifier.Render("user@example.com", "fr", "Fallback subject", "user_welcome", data)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Bienvenue", mail.Subject)
+		assert.Equal(t, "Site : https://safebucket.example.com", mail.TextBody)
+		assert.Equal(t, "<p>https://safebucket.example.com</p>", mail.HTMLBody)
+	})
+
+	t.Run("Embedded template", func(t *testing.T) {
+		mail, err := notifier.Render("user@example.com", "en", "Fallback subject", "user_welcome", data)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Welcome to Safebucket!", mail.Subject)
+	})
+}
+
+func TestEmbeddedTemplatesAreTranslated(t *testing.T) {
+	languages, err := fs.ReadDir(mails.Templates, ".")
+	require.NoError(t, err)
+
+	defaults, err := fs.Glob(mails.Templates, "en/*")
+	require.NoError(t, err)
+	require.NotEmpty(t, defaults)
+
+	for _, language := range languages {
+		for _, name := range defaults {
+			filename := strings.TrimPrefix(name, "en/")
+
+			t.Run(language.Name()+"/"+filename, func(t *testing.T) {
+				notifier := &SMTPNotifier{}
+				content, err := notifier.readTemplate(language.Name(), filename)
+				require.NoError(t, err)
+				if !strings.HasSuffix(filename, ".txt") {
+					return
+				}
+
+				templateName := strings.TrimSuffix(filename, ".txt")
+				mail, err := notifier.Render("user@example.com", language.Name(), "", templateName, nil)
+				require.NoError(t, err, string(content))
+				assert.NotEmpty(t, mail.Subject)
+				assert.Equal(t, language.Name(), mail.Language)
+			})
+		}
+	}
+}
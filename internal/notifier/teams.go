@@ -0,0 +1,89 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"api/internal/cache"
+	"api/internal/models"
+)
+
+// teamsEscaper keeps the values from being interpreted as the markdown supported by the Adaptive Cards.
+var teamsEscaper = strings.NewReplacer("*", "\\*", "_", "\\_", "[", "\\[", "]", "\\]")
+
+// TeamsNotifier implements INotifier using a Microsoft Teams incoming webhook, posting Adaptive Cards.
+type TeamsNotifier struct {
+	endpoint endpoint
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Wrap   bool   `json:"wrap"`
+}
+
+type teamsCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []teamsTextBlock `json:"body"`
+}
+
+type teamsAttachment struct {
+	ContentType string    `json:"contentType"`
+	Content     teamsCard `json:"content"`
+}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+// NewTeamsNotifier creates a notifier posting each notification once to the channel, using the cache
+// to recognize the copies sent to its other recipients.
+func NewTeamsNotifier(config models.NotifierEndpointConfiguration, cache cache.ICache) *TeamsNotifier {
+	return &TeamsNotifier{endpoint: newEndpoint(config, cache, template.FuncMap{
+		"escape": func(value interface{}) string {
+			return teamsEscaper.Replace(fmt.Sprint(value))
+		},
+		"bold": func(value interface{}) string {
+			return fmt.Sprintf("**%s**", teamsEscaper.Replace(fmt.Sprint(value)))
+		},
+		"link": func(url string, label interface{}) string {
+			return fmt.Sprintf("[%s](%s)", teamsEscaper.Replace(fmt.Sprint(label)), url)
+		},
+	})}
+}
+
+// NotifyFromTemplate posts an Adaptive Card with the subject as title and the rendered template as text.
+func (s *TeamsNotifier) NotifyFromTemplate(
+	_ string,
+	_ string,
+	subject string,
+	templateName string,
+	data interface{},
+) error {
+	text, ok, err := s.endpoint.render(templateName, data)
+	if err != nil || !ok || s.endpoint.isDuplicate(templateName, text) {
+		return err
+	}
+
+	return s.endpoint.post(teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body: []teamsTextBlock{
+					{Type: "TextBlock", Text: subject, Weight: "Bolder", Size: "Medium", Wrap: true},
+					{Type: "TextBlock", Text: text, Wrap: true},
+				},
+			},
+		}},
+	})
+}
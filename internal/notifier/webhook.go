@@ -0,0 +1,55 @@
+package notifier
+
+import (
+	"fmt"
+	"text/template"
+
+	"api/internal/models"
+)
+
+// WebhookNotifier implements INotifier by posting the notifications as JSON to an HTTP endpoint,
+// with the configured headers, typically used for authentication. Unlike the chat channels, the endpoint
+// receives the notification once per recipient, as the recipient is part of the message.
+type WebhookNotifier struct {
+	endpoint endpoint
+}
+
+type webhookMessage struct {
+	Event     string      `json:"event"`
+	Recipient string      `json:"recipient"`
+	Subject   string      `json:"subject"`
+	Text      string      `json:"text"`
+	Data      interface{} `json:"data"`
+}
+
+func NewWebhookNotifier(config models.NotifierEndpointConfiguration) *WebhookNotifier {
+	return &WebhookNotifier{endpoint: newEndpoint(config, nil, template.FuncMap{
+		"escape": fmt.Sprint,
+		"bold":   fmt.Sprint,
+		"link": func(url string, label interface{}) string {
+			return fmt.Sprintf("%s (%s)", label, url)
+		},
+	})}
+}
+
+// NotifyFromTemplate posts the notification as plain text, along with the data of the template.
+func (s *WebhookNotifier) NotifyFromTemplate(
+	to string,
+	_ string,
+	subject string,
+	templateName string,
+	data interface{},
+) error {
+	text, ok, err := s.endpoint.render(templateName, data)
+	if err != nil || !ok {
+		return err
+	}
+
+	return s.endpoint.post(webhookMessage{
+		Event:     templateName,
+		Recipient: to,
+		Subject:   subject,
+		Text:      text,
+		Data:      data,
+	})
+}
@@ -13,10 +13,17 @@ const (
 	ActionDelete   = Action("delete")
 	ActionDownload = Action("download")
 	ActionErase    = Action("erase")
+	ActionExport   = Action("export")
 	ActionRestore  = Action("restore")
+	ActionScan     = Action("scan")
 	ActionGrant    = Action("grant")
+	ActionLock     = Action("lock")
+	ActionLogin    = Action("login")
 	ActionPurge    = Action("purge")
+	ActionRead     = Action("read")
+	ActionTransfer = Action("transfer")
 	ActionUpdate   = Action("update")
+	ActionVerify   = Action("verify")
 )
 
 // Resource represents an object type in the RBAC system.
@@ -28,7 +35,16 @@ func (r Resource) String() string {
 
 // Predefined Resource constants for common resources.
 const (
-	ResourceBucket = Resource("bucket")
-	ResourceFile   = Resource("file")
-	ResourceFolder = Resource("folder")
+	ResourceBucket   = Resource("bucket")
+	ResourceFile     = Resource("file")
+	ResourceFolder   = Resource("folder")
+	ResourceMember   = Resource("member")
+	ResourceActivity = Resource("activity")
+	ResourceUser     = Resource("user")
+	ResourcePolicy   = Resource("policy")
+	ResourceAuth     = Resource("auth")
+	ResourceAudit    = Resource("audit")
+	ResourceWebhook  = Resource("webhook")
+	ResourceMail     = Resource("mail")
+	ResourceStorage  = Resource("storage")
 )
@@ -0,0 +1,183 @@
+package rbac
+
+import (
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxFolderDepth bounds folder tree traversal to prevent infinite loops on corrupted hierarchies.
+const maxFolderDepth = 100
+
+// FolderAccess resolves the effective group of a user on the folders of a bucket.
+// The closest folder permission found walking up the tree overrides the bucket group,
+// except for bucket owners who always keep full access so they can manage permissions.
+type FolderAccess struct {
+	BucketGroup models.Group
+	Entries     map[uuid.UUID]models.Group
+	Parents     map[uuid.UUID]*uuid.UUID
+}
+
+// LoadFolderAccess loads the membership, folder permissions and (when needed) the folder tree
+// required to resolve a user's effective group anywhere in a bucket.
+func LoadFolderAccess(db *gorm.DB, userID uuid.UUID, bucketID uuid.UUID) (*FolderAccess, error) {
+	access := &FolderAccess{
+		Entries: map[uuid.UUID]models.Group{},
+		Parents: map[uuid.UUID]*uuid.UUID{},
+	}
+
+	membership, err := GetUserMembership(db, userID, bucketID)
+	if err != nil {
+		return nil, err
+	}
+	if membership == nil {
+		return access, nil
+	}
+	access.BucketGroup = membership.Group
+
+	if membership.Group == models.GroupOwner {
+		return access, nil
+	}
+
+	var permissions []models.FolderPermission
+	if err = db.Where("bucket_id = ? AND user_id = ?", bucketID, userID).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	if len(permissions) == 0 {
+		return access, nil
+	}
+
+	for _, permission := range permissions {
+		access.Entries[permission.FolderID] = permission.Group
+	}
+
+	// Trashed folders are included so restores resolve the same permissions as before trashing
+	var folders []models.Folder
+	if err = db.Unscoped().Select("id", "folder_id").Where("bucket_id = ?", bucketID).Find(&folders).Error; err != nil {
+		return nil, err
+	}
+	for _, folder := range folders {
+		access.Parents[folder.ID] = folder.FolderID
+	}
+
+	return access, nil
+}
+
+// GroupFor returns the effective group on the given folder (nil is the bucket root).
+func (a *FolderAccess) GroupFor(folderID *uuid.UUID) models.Group {
+	if a.BucketGroup == "" || a.BucketGroup == models.GroupOwner || len(a.Entries) == 0 {
+		return a.BucketGroup
+	}
+
+	currentFolderID := folderID
+	for i := 0; i < maxFolderDepth && currentFolderID != nil; i++ {
+		if group, exists := a.Entries[*currentFolderID]; exists {
+			return group
+		}
+		currentFolderID = a.Parents[*currentFolderID]
+	}
+
+	return a.BucketGroup
+}
+
+// Has checks if the effective group on the given folder meets or exceeds the required group.
+func (a *FolderAccess) Has(folderID *uuid.UUID, requiredGroup models.Group) bool {
+	group := a.GroupFor(folderID)
+	if group == "" || group == models.GroupNone {
+		return false
+	}
+	return HasGroup(group, requiredGroup)
+}
+
+// HasFolderAccess checks if a user has at least the required group on a folder of a bucket.
+func HasFolderAccess(
+	db *gorm.DB,
+	userID uuid.UUID,
+	bucketID uuid.UUID,
+	folderID *uuid.UUID,
+	requiredGroup models.Group,
+) (bool, error) {
+	access, err := LoadFolderAccess(db, userID, bucketID)
+	if err != nil {
+		return false, err
+	}
+	return access.Has(folderID, requiredGroup), nil
+}
+
+// GetFolderPermissions returns all permission entries of a folder.
+func GetFolderPermissions(db *gorm.DB, folderID uuid.UUID) ([]models.FolderPermission, error) {
+	var permissions []models.FolderPermission
+	err := db.Where("folder_id = ?", folderID).Preload("User").Find(&permissions).Error
+	return permissions, err
+}
+
+// SetFolderPermission creates or updates the permission entry of a user on a folder.
+func SetFolderPermission(db *gorm.DB, permission models.FolderPermission) error {
+	result := db.Model(&models.FolderPermission{}).
+		Where("folder_id = ? AND user_id = ?", permission.FolderID, permission.UserID).
+		Update("group", permission.Group)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected > 0 {
+		return nil
+	}
+	return db.Create(&permission).Error
+}
+
+// DeleteFolderPermission removes the permission entry of a user on a folder.
+func DeleteFolderPermission(db *gorm.DB, folderID uuid.UUID, userID uuid.UUID) error {
+	return db.Where("folder_id = ? AND user_id = ?", folderID, userID).
+		Delete(&models.FolderPermission{}).Error
+}
+
+// DeleteFolderPermissions removes the folder permissions of a user on a bucket, once their membership is removed.
+func DeleteFolderPermissions(db *gorm.DB, userID uuid.UUID, bucketID uuid.UUID) error {
+	return db.Where("bucket_id = ? AND user_id = ?", bucketID, userID).Delete(&models.FolderPermission{}).Error
+}
+
+// VisibleActivity returns whether an activity entry of a bucket concerns an object the user can view.
+// Files and folders follow the folder permissions of the user, purged files are resolved at the bucket root
+// and are therefore only shown to the users who can view the whole bucket.
+func VisibleActivity(db *gorm.DB, userID uuid.UUID) func(map[string]interface{}) bool {
+	accesses := make(map[string]*FolderAccess)
+	folders := make(map[string]*uuid.UUID)
+
+	return func(entry map[string]interface{}) bool {
+		bucketID, _ := entry["bucket_id"].(string)
+		access, loaded := accesses[bucketID]
+		if !loaded {
+			if parsedBucketID, err := uuid.Parse(bucketID); err == nil {
+				access, _ = LoadFolderAccess(db, userID, parsedBucketID)
+			}
+			accesses[bucketID] = access
+		}
+		if access == nil {
+			return false
+		}
+
+		objectType, _ := entry["object_type"].(string)
+		switch objectType {
+		case ResourceFile.String():
+			fileID, _ := entry["file_id"].(string)
+			folderID, resolved := folders[fileID]
+			if !resolved {
+				var file models.File
+				db.Unscoped().Select("id", "folder_id").Where("id = ?", fileID).Limit(1).Find(&file)
+				folderID = file.FolderID
+				folders[fileID] = folderID
+			}
+			return access.Has(folderID, models.GroupViewer)
+		case ResourceFolder.String():
+			folderID, _ := entry["folder_id"].(string)
+			parsedFolderID, err := uuid.Parse(folderID)
+			if err != nil {
+				return access.Has(nil, models.GroupViewer)
+			}
+			return access.Has(&parsedFolderID, models.GroupViewer)
+		default:
+			return access.Has(nil, models.GroupViewer)
+		}
+	}
+}
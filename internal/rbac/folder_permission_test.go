@@ -0,0 +1,301 @@
+package rbac
+
+import (
+	"testing"
+
+	"api/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFolderAccessGroupFor tests the resolution of effective groups through the folder tree.
+func TestFolderAccessGroupFor(t *testing.T) {
+	root := uuid.New()
+	finance := uuid.New()
+	reports := uuid.New()
+	public := uuid.New()
+
+	parents := map[uuid.UUID]*uuid.UUID{
+		root:    nil,
+		finance: &root,
+		reports: &finance,
+		public:  &finance,
+	}
+
+	tests := []struct {
+		name        string
+		bucketGroup models.Group
+		entries     map[uuid.UUID]models.Group
+		folderID    *uuid.UUID
+		expected    models.Group
+	}{
+		{
+			name:        "Bucket root uses the bucket group",
+			bucketGroup: models.GroupContributor,
+			entries:     map[uuid.UUID]models.Group{finance: models.GroupNone},
+			folderID:    nil,
+			expected:    models.GroupContributor,
+		},
+		{
+			name:        "Folder without entries inherits the bucket group",
+			bucketGroup: models.GroupViewer,
+			entries:     map[uuid.UUID]models.Group{},
+			folderID:    &reports,
+			expected:    models.GroupViewer,
+		},
+		{
+			name:        "Entry narrows the bucket group",
+			bucketGroup: models.GroupContributor,
+			entries:     map[uuid.UUID]models.Group{finance: models.GroupNone},
+			folderID:    &finance,
+			expected:    models.GroupNone,
+		},
+		{
+			name:        "Entry is inherited by subfolders",
+			bucketGroup: models.GroupContributor,
+			entries:     map[uuid.UUID]models.Group{finance: models.GroupNone},
+			folderID:    &reports,
+			expected:    models.GroupNone,
+		},
+		{
+			name:        "Closest entry wins over ancestors",
+			bucketGroup: models.GroupContributor,
+			entries: map[uuid.UUID]models.Group{
+				finance: models.GroupNone,
+				public:  models.GroupViewer,
+			},
+			folderID: &public,
+			expected: models.GroupViewer,
+		},
+		{
+			name:        "Entry extends the bucket group",
+			bucketGroup: models.GroupViewer,
+			entries:     map[uuid.UUID]models.Group{finance: models.GroupContributor},
+			folderID:    &reports,
+			expected:    models.GroupContributor,
+		},
+		{
+			name:        "Owners are never narrowed",
+			bucketGroup: models.GroupOwner,
+			entries:     map[uuid.UUID]models.Group{finance: models.GroupNone},
+			folderID:    &finance,
+			expected:    models.GroupOwner,
+		},
+		{
+			name:        "Non-members have no group",
+			bucketGroup: "",
+			entries:     map[uuid.UUID]models.Group{finance: models.GroupOwner},
+			folderID:    &finance,
+			expected:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := &FolderAccess{
+				BucketGroup: tt.bucketGroup,
+				Entries:     tt.entries,
+				Parents:     parents,
+			}
+			assert.Equal(t, tt.expected, access.GroupFor(tt.folderID))
+		})
+	}
+}
+
+// TestFolderAccessHas tests the hierarchical check against the effective group.
+func TestFolderAccessHas(t *testing.T) {
+	folderID := uuid.New()
+
+	t.Run("should deny access when the folder is revoked", func(t *testing.T) {
+		access := &FolderAccess{
+			BucketGroup: models.GroupContributor,
+			Entries:     map[uuid.UUID]models.Group{folderID: models.GroupNone},
+			Parents:     map[uuid.UUID]*uuid.UUID{folderID: nil},
+		}
+		assert.False(t, access.Has(&folderID, models.GroupViewer))
+		assert.True(t, access.Has(nil, models.GroupContributor))
+	})
+
+	t.Run("should deny access to non-members", func(t *testing.T) {
+		access := &FolderAccess{}
+		assert.False(t, access.Has(nil, models.GroupViewer))
+	})
+
+	t.Run("should allow extended access", func(t *testing.T) {
+		access := &FolderAccess{
+			BucketGroup: models.GroupViewer,
+			Entries:     map[uuid.UUID]models.Group{folderID: models.GroupContributor},
+			Parents:     map[uuid.UUID]*uuid.UUID{folderID: nil},
+		}
+		assert.True(t, access.Has(&folderID, models.GroupContributor))
+		assert.False(t, access.Has(nil, models.GroupContributor))
+	})
+}
+
+// TestLoadFolderAccess tests loading the data needed to resolve folder permissions.
+func TestLoadFolderAccess(t *testing.T) {
+	t.Run("should skip folder permissions for owners", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		userID := uuid.New()
+		bucketID := uuid.New()
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}).
+			AddRow(uuid.New(), userID, bucketID, "owner")
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(userID, bucketID, 1).
+			WillReturnRows(rows)
+
+		access, err := LoadFolderAccess(gormDB, userID, bucketID)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.GroupOwner, access.BucketGroup)
+		assert.Empty(t, access.Entries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should not load the folder tree without folder permissions", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		userID := uuid.New()
+		bucketID := uuid.New()
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}).
+			AddRow(uuid.New(), userID, bucketID, "viewer")
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(userID, bucketID, 1).
+			WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "folder_permissions"`).
+			WithArgs(bucketID, userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id", "user_id", "group"}))
+
+		access, err := LoadFolderAccess(gormDB, userID, bucketID)
+
+		require.NoError(t, err)
+		assert.Equal(t, models.GroupViewer, access.BucketGroup)
+		assert.Empty(t, access.Parents)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should load the folder tree when folder permissions exist", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		userID := uuid.New()
+		bucketID := uuid.New()
+		parentID := uuid.New()
+		childID := uuid.New()
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}).
+			AddRow(uuid.New(), userID, bucketID, "contributor")
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(userID, bucketID, 1).
+			WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT \* FROM "folder_permissions"`).
+			WithArgs(bucketID, userID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id", "user_id", "group"}).
+				AddRow(uuid.New(), parentID, userID, "none"))
+		mock.ExpectQuery(`SELECT "id","folder_id" FROM "folders"`).
+			WithArgs(bucketID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id"}).
+				AddRow(parentID, nil).
+				AddRow(childID, parentID))
+
+		access, err := LoadFolderAccess(gormDB, userID, bucketID)
+
+		require.NoError(t, err)
+		assert.False(t, access.Has(&childID, models.GroupViewer))
+		assert.True(t, access.Has(nil, models.GroupContributor))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestVisibleActivity tests hiding the activity of the files and folders a user cannot view.
+func TestVisibleActivity(t *testing.T) {
+	gormDB, mock, db := setupMockDB(t)
+	defer db.Close()
+
+	userID, bucketID, otherBucketID := uuid.New(), uuid.New(), uuid.New()
+	finance, reports := uuid.New(), uuid.New()
+	financeFile, rootFile := uuid.New(), uuid.New()
+
+	mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+		WithArgs(userID, bucketID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}).
+			AddRow(uuid.New(), userID, bucketID, "viewer"))
+	mock.ExpectQuery(`SELECT \* FROM "folder_permissions"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "bucket_id", "folder_id", "user_id", "group"}).
+			AddRow(uuid.New(), bucketID, finance, userID, "none"))
+	mock.ExpectQuery(`SELECT "id","folder_id" FROM "folders"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id"}).
+			AddRow(finance, nil).
+			AddRow(reports, finance))
+	mock.ExpectQuery(`SELECT "id","folder_id" FROM "files"`).
+		WithArgs(financeFile.String(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id"}).AddRow(financeFile, finance))
+	mock.ExpectQuery(`SELECT "id","folder_id" FROM "files"`).
+		WithArgs(rootFile.String(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "folder_id"}).AddRow(rootFile, nil))
+	mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+		WithArgs(userID, otherBucketID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	visible := VisibleActivity(gormDB, userID)
+
+	tests := []struct {
+		name     string
+		entry    map[string]interface{}
+		expected bool
+	}{
+		{
+			name:     "Bucket activity is shown to members",
+			entry:    map[string]interface{}{"object_type": "bucket", "bucket_id": bucketID.String()},
+			expected: true,
+		},
+		{
+			name: "File in a revoked folder is hidden",
+			entry: map[string]interface{}{
+				"object_type": "file", "bucket_id": bucketID.String(), "file_id": financeFile.String(),
+			},
+			expected: false,
+		},
+		{
+			name: "File at the bucket root is shown",
+			entry: map[string]interface{}{
+				"object_type": "file", "bucket_id": bucketID.String(), "file_id": rootFile.String(),
+			},
+			expected: true,
+		},
+		{
+			name: "Subfolder of a revoked folder is hidden",
+			entry: map[string]interface{}{
+				"object_type": "folder", "bucket_id": bucketID.String(), "folder_id": reports.String(),
+			},
+			expected: false,
+		},
+		{
+			name: "Folder of a file is only resolved once",
+			entry: map[string]interface{}{
+				"object_type": "file", "bucket_id": bucketID.String(), "file_id": financeFile.String(),
+			},
+			expected: false,
+		},
+		{
+			name:     "Activity of other buckets is hidden",
+			entry:    map[string]interface{}{"object_type": "bucket", "bucket_id": otherBucketID.String()},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, visible(tt.entry))
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
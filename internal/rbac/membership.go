@@ -3,13 +3,14 @@ package rbac
 import (
 	"api/internal/models"
 	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 // GetUserMembership returns the user's membership for a specific bucket
-// Returns nil if no membership exists.
+// Returns nil if no membership exists or if it has expired.
 func GetUserMembership(
 	db *gorm.DB,
 	userID uuid.UUID,
@@ -23,6 +24,9 @@ func GetUserMembership(
 	if err != nil {
 		return nil, err
 	}
+	if membership.IsExpired(time.Now()) {
+		return nil, nil
+	}
 	return &membership, nil
 }
 
@@ -33,39 +37,72 @@ func GetBucketMembers(db *gorm.DB, bucketID uuid.UUID) ([]models.Membership, err
 	return memberships, err
 }
 
-// GetUserBuckets returns all bucket memberships for a specific user.
+// GetUserBuckets returns all active bucket memberships for a specific user.
+// Expired memberships waiting for the expiration job are skipped.
 func GetUserBuckets(db *gorm.DB, userID uuid.UUID) ([]models.Membership, error) {
 	var memberships []models.Membership
 	err := db.Where("user_id = ?", userID).Preload("Bucket").Find(&memberships).Error
-	return memberships, err
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	active := memberships[:0]
+	for _, membership := range memberships {
+		if !membership.IsExpired(now) {
+			active = append(active, membership)
+		}
+	}
+	return active, nil
 }
 
 // CreateMembership creates a new membership record.
-func CreateMembership(db *gorm.DB, userID uuid.UUID, bucketID uuid.UUID, group models.Group) error {
+// A nil expiresAt creates a permanent membership.
+func CreateMembership(
+	db *gorm.DB,
+	userID uuid.UUID,
+	bucketID uuid.UUID,
+	group models.Group,
+	expiresAt *time.Time,
+) error {
 	membership := models.Membership{
-		UserID:   userID,
-		BucketID: bucketID,
-		Group:    group,
+		UserID:    userID,
+		BucketID:  bucketID,
+		Group:     group,
+		ExpiresAt: expiresAt,
 	}
 	return db.Create(&membership).Error
 }
 
-// UpdateMembership updates an existing membership's group.
+// UpdateMembership updates an existing membership's group and expiry.
+// The expiry warning is reset so that a new warning is sent for the new expiry date.
 func UpdateMembership(
 	db *gorm.DB,
 	userID uuid.UUID,
 	bucketID uuid.UUID,
 	newGroup models.Group,
+	expiresAt *time.Time,
 ) error {
 	return db.Model(&models.Membership{}).
 		Where("user_id = ? AND bucket_id = ?", userID, bucketID).
-		Update("group", newGroup).Error
+		Updates(map[string]interface{}{
+			"group":              newGroup,
+			"expires_at":         expiresAt,
+			"expiry_notified_at": nil,
+		}).Error
 }
 
-// DeleteMembership removes a membership record.
+// DeleteMembership removes a membership record, with the folder permissions of the user on the bucket,
+// so that a user invited again does not get back their former folder extensions and restrictions.
 func DeleteMembership(db *gorm.DB, userID uuid.UUID, bucketID uuid.UUID) error {
-	return db.Where("user_id = ? AND bucket_id = ?", userID, bucketID).
-		Delete(&models.Membership{}).Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND bucket_id = ?", userID, bucketID).
+			Delete(&models.Membership{}).Error
+		if err != nil {
+			return err
+		}
+		return DeleteFolderPermissions(tx, userID, bucketID)
+	})
 }
 
 // HasBucketAccess checks if a user has at least the required group access to a bucket.
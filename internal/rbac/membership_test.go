@@ -2,7 +2,9 @@ package rbac
 
 import (
 	"database/sql"
+	"regexp"
 	"testing"
+	"time"
 
 	"api/internal/models"
 
@@ -92,6 +94,48 @@ func TestGetUserMembership(t *testing.T) {
 		assert.Nil(t, membership)
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
+
+	t.Run("should return nil when membership has expired", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		userID := uuid.New()
+		bucketID := uuid.New()
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group", "expires_at"}).
+			AddRow(uuid.New(), userID, bucketID, "viewer", time.Now().Add(-time.Hour))
+
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(userID, bucketID, 1).
+			WillReturnRows(rows)
+
+		membership, err := GetUserMembership(gormDB, userID, bucketID)
+
+		require.NoError(t, err)
+		assert.Nil(t, membership, "Expired membership should be treated as absent")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should return membership before its expiry", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		userID := uuid.New()
+		bucketID := uuid.New()
+
+		rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group", "expires_at"}).
+			AddRow(uuid.New(), userID, bucketID, "viewer", time.Now().Add(time.Hour))
+
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(userID, bucketID, 1).
+			WillReturnRows(rows)
+
+		membership, err := GetUserMembership(gormDB, userID, bucketID)
+
+		require.NoError(t, err)
+		assert.NotNil(t, membership)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 // TestGetBucketMembers tests retrieving all members of a bucket.
@@ -208,7 +252,7 @@ func TestCreateMembership(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
 		mock.ExpectCommit()
 
-		err := CreateMembership(gormDB, userID, bucketID, group)
+		err := CreateMembership(gormDB, userID, bucketID, group, nil)
 
 		require.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -227,7 +271,7 @@ func TestCreateMembership(t *testing.T) {
 			WillReturnError(sql.ErrConnDone)
 		mock.ExpectRollback()
 
-		err := CreateMembership(gormDB, userID, bucketID, group)
+		err := CreateMembership(gormDB, userID, bucketID, group, nil)
 
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -249,7 +293,7 @@ func TestUpdateMembership(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(0, 1))
 		mock.ExpectCommit()
 
-		err := UpdateMembership(gormDB, userID, bucketID, newGroup)
+		err := UpdateMembership(gormDB, userID, bucketID, newGroup, nil)
 
 		require.NoError(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -268,7 +312,7 @@ func TestUpdateMembership(t *testing.T) {
 			WillReturnError(sql.ErrConnDone)
 		mock.ExpectRollback()
 
-		err := UpdateMembership(gormDB, userID, bucketID, newGroup)
+		err := UpdateMembership(gormDB, userID, bucketID, newGroup, nil)
 
 		assert.Error(t, err)
 		assert.NoError(t, mock.ExpectationsWereMet())
@@ -284,9 +328,13 @@ func TestDeleteMembership(t *testing.T) {
 		userID := uuid.New()
 		bucketID := uuid.New()
 
+		// The folder permissions of the user go with the membership
 		mock.ExpectBegin()
 		mock.ExpectExec(`UPDATE "memberships"`).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "folder_permissions" WHERE bucket_id = $1 AND user_id = $2`)).
+			WithArgs(bucketID, userID).
+			WillReturnResult(sqlmock.NewResult(0, 2))
 		mock.ExpectCommit()
 
 		err := DeleteMembership(gormDB, userID, bucketID)
@@ -381,6 +429,28 @@ func TestHasBucketAccess(t *testing.T) {
 		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("should return false when membership has expired", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		userID := uuid.New()
+		bucketID := uuid.New()
+
+		// Expired owner, checking for viewer access
+		rows := sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group", "expires_at"}).
+			AddRow(uuid.New(), userID, bucketID, "owner", time.Now().Add(-time.Minute))
+
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(userID, bucketID, 1).
+			WillReturnRows(rows)
+
+		hasAccess, err := HasBucketAccess(gormDB, userID, bucketID, models.GroupViewer)
+
+		require.NoError(t, err)
+		assert.False(t, hasAccess, "Expired membership should not grant access")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
 	t.Run("should return error on database failure", func(t *testing.T) {
 		gormDB, mock, db := setupMockDB(t)
 		defer db.Close()
@@ -0,0 +1,74 @@
+package rbac
+
+import (
+	"time"
+
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// activeOwnerCondition matches the memberships aliased "m" that still give ownership of a bucket:
+// owner memberships that are not deleted or expired, held by users that are not deleted.
+const activeOwnerCondition = `m."group" = 'owner' AND m.deleted_at IS NULL ` +
+	`AND (m.expires_at IS NULL OR m.expires_at > ?) ` +
+	`AND EXISTS (SELECT 1 FROM users u WHERE u.id = m.user_id AND u.deleted_at IS NULL)`
+
+// GetSoleOwnedBuckets returns the buckets where the user is the only active owner.
+func GetSoleOwnedBuckets(db *gorm.DB, userID uuid.UUID) ([]models.Bucket, error) {
+	now := time.Now()
+
+	var buckets []models.Bucket
+	err := db.
+		Where(
+			"EXISTS (SELECT 1 FROM memberships m WHERE m.bucket_id = buckets.id AND m.user_id = ? AND "+
+				activeOwnerCondition+")",
+			userID, now,
+		).
+		Where(
+			"NOT EXISTS (SELECT 1 FROM memberships m WHERE m.bucket_id = buckets.id AND m.user_id <> ? AND "+
+				activeOwnerCondition+")",
+			userID, now,
+		).
+		Find(&buckets).Error
+	return buckets, err
+}
+
+// GetOrphanedBuckets returns the buckets without any active owner.
+func GetOrphanedBuckets(db *gorm.DB) ([]models.Bucket, error) {
+	var buckets []models.Bucket
+	err := db.
+		Where(
+			"NOT EXISTS (SELECT 1 FROM memberships m WHERE m.bucket_id = buckets.id AND "+activeOwnerCondition+")",
+			time.Now(),
+		).
+		Order("created_at").
+		Find(&buckets).Error
+	return buckets, err
+}
+
+// TransferOwnership makes the target user a permanent owner of the bucket.
+// The previous owner, if any, is kept as a contributor.
+func TransferOwnership(db *gorm.DB, bucketID uuid.UUID, fromUserID *uuid.UUID, toUserID uuid.UUID) error {
+	var existing models.Membership
+	result := db.Where("user_id = ? AND bucket_id = ?", toUserID, bucketID).Find(&existing)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	var err error
+	if result.RowsAffected > 0 {
+		err = UpdateMembership(db, toUserID, bucketID, models.GroupOwner, nil)
+	} else {
+		err = CreateMembership(db, toUserID, bucketID, models.GroupOwner, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	if fromUserID == nil || *fromUserID == toUserID {
+		return nil
+	}
+	return UpdateMembership(db, *fromUserID, bucketID, models.GroupContributor, nil)
+}
@@ -0,0 +1,83 @@
+package rbac
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTransferOwnership tests transferring the ownership of a bucket.
+func TestTransferOwnership(t *testing.T) {
+	t.Run("should promote an existing member and demote the previous owner", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		bucketID := uuid.New()
+		fromUserID := uuid.New()
+		toUserID := uuid.New()
+
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(toUserID, bucketID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}).
+				AddRow(uuid.New(), toUserID, bucketID, "viewer"))
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "memberships"`).
+			WithArgs(nil, nil, "owner", sqlmock.AnyArg(), toUserID, bucketID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(`UPDATE "memberships"`).
+			WithArgs(nil, nil, "contributor", sqlmock.AnyArg(), fromUserID, bucketID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := TransferOwnership(gormDB, bucketID, &fromUserID, toUserID)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should create an owner membership for a non-member without previous owner", func(t *testing.T) {
+		gormDB, mock, db := setupMockDB(t)
+		defer db.Close()
+
+		bucketID := uuid.New()
+		toUserID := uuid.New()
+
+		mock.ExpectQuery(`SELECT \* FROM "memberships"`).
+			WithArgs(toUserID, bucketID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "bucket_id", "group"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO "memberships"`).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()))
+		mock.ExpectCommit()
+
+		err := TransferOwnership(gormDB, bucketID, nil, toUserID)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestGetSoleOwnedBuckets tests that other active owners are excluded.
+func TestGetSoleOwnedBuckets(t *testing.T) {
+	gormDB, mock, db := setupMockDB(t)
+	defer db.Close()
+
+	userID := uuid.New()
+	bucketID := uuid.New()
+
+	mock.ExpectQuery(`SELECT \* FROM "buckets" WHERE \(EXISTS .* AND \(NOT EXISTS .*m.user_id <> \$3`).
+		WithArgs(userID, sqlmock.AnyArg(), userID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(bucketID, "Finance"))
+
+	buckets, err := GetSoleOwnedBuckets(gormDB, userID)
+
+	require.NoError(t, err)
+	assert.Len(t, buckets, 1)
+	assert.Equal(t, bucketID, buckets[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,243 @@
+package rbac
+
+import (
+	"sync"
+	"time"
+
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Wildcard matches any resource, action or domain in a policy rule.
+const Wildcard = "*"
+
+// maxRoleDepth bounds role inheritance resolution to prevent infinite loops on cyclic rules.
+const maxRoleDepth = 10
+
+// IPolicyEngine evaluates (subject, resource, action) requests against policy rules.
+type IPolicyEngine interface {
+	Enforce(subjects []string, resource Resource, action Action) bool
+	RolesFor(userID uuid.UUID, domain string) []string
+	Reload() error
+}
+
+type permissionRule struct {
+	resource string
+	action   string
+	deny     bool
+}
+
+type roleAssignment struct {
+	role   string
+	domain string
+}
+
+// PolicyEngine is an in-memory policy engine loaded from the policies table.
+type PolicyEngine struct {
+	db          *gorm.DB
+	mu          sync.RWMutex
+	permissions map[string][]permissionRule
+	inherits    map[string][]string
+	assignments map[string][]roleAssignment
+}
+
+// NewPolicyEngine loads all policy rules from the database.
+func NewPolicyEngine(db *gorm.DB) (*PolicyEngine, error) {
+	engine := &PolicyEngine{db: db}
+	if err := engine.Reload(); err != nil {
+		return nil, err
+	}
+	return engine, nil
+}
+
+// NewStaticPolicyEngine creates a policy engine from a fixed set of rules.
+func NewStaticPolicyEngine(policies []models.Policy) *PolicyEngine {
+	engine := &PolicyEngine{}
+	engine.load(policies)
+	return engine
+}
+
+// Reload replaces the in-memory rules with the current content of the policies table.
+func (e *PolicyEngine) Reload() error {
+	if e.db == nil {
+		return nil
+	}
+
+	var policies []models.Policy
+	if err := e.db.Find(&policies).Error; err != nil {
+		return err
+	}
+
+	e.load(policies)
+	return nil
+}
+
+// StartReloadTicker periodically reloads the rules so that changes made on other instances are applied.
+func (e *PolicyEngine) StartReloadTicker(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := e.Reload(); err != nil {
+			zap.L().Error("Failed to reload policies", zap.Error(err))
+		}
+	}
+}
+
+func (e *PolicyEngine) load(policies []models.Policy) {
+	permissions := map[string][]permissionRule{}
+	inherits := map[string][]string{}
+	assignments := map[string][]roleAssignment{}
+
+	for _, policy := range policies {
+		switch policy.Ptype {
+		case models.PolicyTypePermission:
+			permissions[policy.V0] = append(permissions[policy.V0], permissionRule{
+				resource: policy.V1,
+				action:   policy.V2,
+				deny:     policy.V3 == models.PolicyEffectDeny,
+			})
+		case models.PolicyTypeRole:
+			if _, err := uuid.Parse(policy.V0); err == nil {
+				domain := policy.V2
+				if domain == "" {
+					domain = Wildcard
+				}
+				assignments[policy.V0] = append(assignments[policy.V0], roleAssignment{
+					role:   policy.V1,
+					domain: domain,
+				})
+			} else {
+				inherits[policy.V0] = append(inherits[policy.V0], policy.V1)
+			}
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.permissions = permissions
+	e.inherits = inherits
+	e.assignments = assignments
+}
+
+// Enforce returns true if any of the subjects, or a role they inherit, is granted the action on the resource,
+// and none of them is denied it. A denial overrides the grants, so that a custom role assigned to a member
+// can take away what the group of the member grants.
+func (e *PolicyEngine) Enforce(subjects []string, resource Resource, action Action) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	granted := false
+	visited := map[string]bool{}
+	current := subjects
+	for depth := 0; depth < maxRoleDepth && len(current) > 0; depth++ {
+		var next []string
+		for _, subject := range current {
+			if subject == "" || visited[subject] {
+				continue
+			}
+			visited[subject] = true
+
+			for _, rule := range e.permissions[subject] {
+				if !matches(rule.resource, resource.String()) || !matches(rule.action, action.String()) {
+					continue
+				}
+				if rule.deny {
+					return false
+				}
+				granted = true
+			}
+			next = append(next, e.inherits[subject]...)
+		}
+		current = next
+	}
+
+	return granted
+}
+
+// RolesFor returns the custom roles assigned to a user on the given domain, or the global ones for Wildcard.
+// Global roles are not returned for a bucket, so that they do not grant access to buckets without membership.
+func (e *PolicyEngine) RolesFor(userID uuid.UUID, domain string) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var roles []string
+	for _, assignment := range e.assignments[userID.String()] {
+		if assignment.domain == domain {
+			roles = append(roles, assignment.role)
+		}
+	}
+	return roles
+}
+
+func matches(pattern string, value string) bool {
+	return pattern == Wildcard || pattern == value
+}
+
+// DefaultPolicies returns the built-in rules reproducing the platform roles and bucket groups.
+func DefaultPolicies() []models.Policy {
+	permission := func(subject string, resource Resource, action string) models.Policy {
+		return models.Policy{Ptype: models.PolicyTypePermission, V0: subject, V1: resource.String(), V2: action}
+	}
+	inherit := func(role string, parent string) models.Policy {
+		return models.Policy{Ptype: models.PolicyTypeRole, V0: role, V1: parent, V2: Wildcard}
+	}
+
+	return []models.Policy{
+		// Platform roles (Admin > User > Guest)
+		permission(string(models.RoleAdmin), Wildcard, Wildcard),
+		inherit(string(models.RoleAdmin), string(models.RoleUser)),
+		permission(string(models.RoleUser), ResourceBucket, ActionCreate.String()),
+		inherit(string(models.RoleUser), string(models.RoleGuest)),
+		permission(string(models.RoleGuest), ResourceBucket, ActionRead.String()),
+		permission(string(models.RoleGuest), ResourceActivity, ActionRead.String()),
+
+		// Bucket groups (Owner > Contributor > Viewer)
+		permission(string(models.GroupOwner), Wildcard, Wildcard),
+		inherit(string(models.GroupOwner), string(models.GroupContributor)),
+		permission(string(models.GroupContributor), ResourceFile, ActionCreate.String()),
+		permission(string(models.GroupContributor), ResourceFile, ActionErase.String()),
+		permission(string(models.GroupContributor), ResourceFile, ActionRestore.String()),
+		permission(string(models.GroupContributor), ResourceFile, ActionPurge.String()),
+		permission(string(models.GroupContributor), ResourceFolder, ActionCreate.String()),
+		permission(string(models.GroupContributor), ResourceFolder, ActionUpdate.String()),
+		permission(string(models.GroupContributor), ResourceFolder, ActionErase.String()),
+		permission(string(models.GroupContributor), ResourceFolder, ActionRestore.String()),
+		permission(string(models.GroupContributor), ResourceFolder, ActionPurge.String()),
+		inherit(string(models.GroupContributor), string(models.GroupViewer)),
+		permission(string(models.GroupViewer), ResourceBucket, ActionRead.String()),
+		permission(string(models.GroupViewer), ResourceFile, ActionDownload.String()),
+		permission(string(models.GroupViewer), ResourceMember, ActionRead.String()),
+		permission(string(models.GroupViewer), ResourceActivity, ActionRead.String()),
+	}
+}
+
+// IsDefaultPolicy checks if a rule is part of the built-in rules, which cannot be modified.
+func IsDefaultPolicy(policy models.Policy) bool {
+	for _, builtIn := range DefaultPolicies() {
+		if builtIn.Ptype == policy.Ptype && builtIn.V0 == policy.V0 &&
+			builtIn.V1 == policy.V1 && builtIn.V2 == policy.V2 {
+			return true
+		}
+	}
+	return false
+}
+
+// IsBuiltInSubject checks if a name is one of the platform roles or bucket groups.
+func IsBuiltInSubject(name string) bool {
+	switch name {
+	case string(models.RoleAdmin), string(models.RoleUser), string(models.RoleGuest),
+		string(models.GroupOwner), string(models.GroupContributor), string(models.GroupViewer):
+		return true
+	default:
+		return false
+	}
+}
+
+// SeedDefaultPolicies inserts the built-in rules, leaving existing rules untouched.
+func SeedDefaultPolicies(db *gorm.DB) error {
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(DefaultPolicies()).Error
+}
@@ -0,0 +1,83 @@
+package rbac
+
+import (
+	"testing"
+
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestPolicyEngineEnforce tests the evaluation of the built-in and custom rules.
+func TestPolicyEngineEnforce(t *testing.T) {
+	policies := append(DefaultPolicies(),
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "uploader", V1: "file", V2: "create"},
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "auditor", V1: "activity", V2: "read"},
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "dropbox", V1: "file", V2: "create"},
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "dropbox", V1: "file", V2: "download", V3: "deny"},
+		models.Policy{Ptype: models.PolicyTypePermission, V0: "activity-only", V1: "file", V2: "*", V3: "deny"},
+		models.Policy{Ptype: models.PolicyTypeRole, V0: "loop-a", V1: "loop-b", V2: Wildcard},
+		models.Policy{Ptype: models.PolicyTypeRole, V0: "loop-b", V1: "loop-a", V2: Wildcard},
+	)
+	engine := NewStaticPolicyEngine(policies)
+
+	tests := []struct {
+		name     string
+		subjects []string
+		resource Resource
+		action   Action
+		expected bool
+	}{
+		{"Admin has every permission", []string{"admin"}, ResourcePolicy, ActionDelete, true},
+		{"Admin inherits user permissions", []string{"admin"}, ResourceBucket, ActionCreate, true},
+		{"User inherits guest permissions", []string{"user"}, ResourceBucket, ActionRead, true},
+		{"Guest cannot create buckets", []string{"guest"}, ResourceBucket, ActionCreate, false},
+		{"Owner can manage members", []string{"owner"}, ResourceMember, ActionGrant, true},
+		{"Contributor inherits viewer permissions", []string{"contributor"}, ResourceFile, ActionDownload, true},
+		{"Contributor cannot delete the bucket", []string{"contributor"}, ResourceBucket, ActionDelete, false},
+		{"Viewer cannot upload", []string{"viewer"}, ResourceFile, ActionCreate, false},
+		{"Uploader can upload", []string{"uploader"}, ResourceFile, ActionCreate, true},
+		{"Uploader cannot download", []string{"uploader"}, ResourceFile, ActionDownload, false},
+		{"Auditor can read activity", []string{"auditor"}, ResourceActivity, ActionRead, true},
+		{"Auditor cannot read the bucket", []string{"auditor"}, ResourceBucket, ActionRead, false},
+		{"Any subject can grant the permission", []string{"auditor", "uploader"}, ResourceFile, ActionCreate, true},
+		{"Denied role cannot download as a viewer", []string{"viewer", "dropbox"}, ResourceFile, ActionDownload, false},
+		{"Denied role keeps its grants", []string{"viewer", "dropbox"}, ResourceFile, ActionCreate, true},
+		{"Denied role keeps the grants of the group", []string{"viewer", "dropbox"}, ResourceBucket, ActionRead, true},
+		{"Denial overrides inherited grants", []string{"owner", "activity-only"}, ResourceFile, ActionErase, false},
+		{"Denial alone grants nothing", []string{"activity-only"}, ResourceActivity, ActionRead, false},
+		{"Cyclic inheritance terminates", []string{"loop-a"}, ResourceFile, ActionCreate, false},
+		{"No subjects are denied", nil, ResourceBucket, ActionRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, engine.Enforce(tt.subjects, tt.resource, tt.action))
+		})
+	}
+}
+
+// TestPolicyEngineRolesFor tests the resolution of custom roles assigned to users.
+func TestPolicyEngineRolesFor(t *testing.T) {
+	userID := uuid.New()
+	bucketID := uuid.New()
+	otherBucketID := uuid.New()
+
+	engine := NewStaticPolicyEngine([]models.Policy{
+		{Ptype: models.PolicyTypeRole, V0: userID.String(), V1: "auditor", V2: Wildcard},
+		{Ptype: models.PolicyTypeRole, V0: userID.String(), V1: "uploader", V2: bucketID.String()},
+		{Ptype: models.PolicyTypeRole, V0: "uploader", V1: "viewer", V2: Wildcard},
+	})
+
+	assert.ElementsMatch(t, []string{"uploader"}, engine.RolesFor(userID, bucketID.String()))
+	assert.Empty(t, engine.RolesFor(userID, otherBucketID.String()))
+	assert.ElementsMatch(t, []string{"auditor"}, engine.RolesFor(userID, Wildcard))
+	assert.Empty(t, engine.RolesFor(uuid.New(), bucketID.String()))
+}
+
+// TestIsDefaultPolicy tests the detection of read-only built-in rules.
+func TestIsDefaultPolicy(t *testing.T) {
+	assert.True(t, IsDefaultPolicy(models.Policy{Ptype: models.PolicyTypePermission, V0: "admin", V1: "*", V2: "*"}))
+	assert.False(t, IsDefaultPolicy(models.Policy{Ptype: models.PolicyTypePermission, V0: "auditor", V1: "*", V2: "*"}))
+}
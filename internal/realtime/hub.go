@@ -0,0 +1,109 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"api/internal/cache"
+	"api/internal/configuration"
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// bucketEventMessage is the message published on the cache, which keeps the folder used to filter the event.
+type bucketEventMessage struct {
+	Event    models.BucketEvent `json:"event"`
+	FolderID *uuid.UUID         `json:"folder_id,omitempty"`
+}
+
+// BucketHub publishes the bucket events on a channel of the cache, which every instance subscribes to,
+// and delivers them to the local subscribers of the bucket. A subscriber that does not keep up
+// misses events rather than slowing down the others.
+type BucketHub struct {
+	cache       cache.ICache
+	mu          sync.RWMutex
+	subscribers map[uuid.UUID]map[chan models.BucketEvent]struct{}
+}
+
+func NewBucketHub(cache cache.ICache) *BucketHub {
+	return &BucketHub{
+		cache:       cache,
+		subscribers: make(map[uuid.UUID]map[chan models.BucketEvent]struct{}),
+	}
+}
+
+// Start listens to the events published by all the instances, subscribing again when the connection is lost.
+func (h *BucketHub) Start() {
+	for {
+		err := h.cache.Subscribe(context.Background(), configuration.CacheBucketEventsChannel, h.dispatch)
+		zap.L().Warn("Bucket events subscription ended, subscribing again", zap.Error(err))
+		time.Sleep(configuration.BucketEventsResubscribeDelaySeconds * time.Second)
+	}
+}
+
+func (h *BucketHub) Publish(event models.BucketEvent) {
+	message, err := json.Marshal(bucketEventMessage{Event: event, FolderID: event.FolderID})
+	if err != nil {
+		zap.L().Error("Failed to marshal bucket event", zap.Error(err))
+		return
+	}
+
+	if err = h.cache.Publish(configuration.CacheBucketEventsChannel, string(message)); err != nil {
+		zap.L().Error("Failed to publish bucket event",
+			zap.String("bucket_id", event.BucketID.String()),
+			zap.String("type", event.Type),
+			zap.Error(err))
+	}
+}
+
+// Subscribe returns the events of a bucket, and the function ending the subscription.
+func (h *BucketHub) Subscribe(bucketID uuid.UUID) (<-chan models.BucketEvent, func()) {
+	events := make(chan models.BucketEvent, configuration.BucketEventsBufferSize)
+
+	h.mu.Lock()
+	if h.subscribers[bucketID] == nil {
+		h.subscribers[bucketID] = make(map[chan models.BucketEvent]struct{})
+	}
+	h.subscribers[bucketID][events] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers[bucketID], events)
+			if len(h.subscribers[bucketID]) == 0 {
+				delete(h.subscribers, bucketID)
+			}
+			close(events)
+		})
+	}
+
+	return events, unsubscribe
+}
+
+func (h *BucketHub) dispatch(message string) {
+	var decoded bucketEventMessage
+	if err := json.Unmarshal([]byte(message), &decoded); err != nil {
+		zap.L().Error("Failed to unmarshal bucket event", zap.Error(err))
+		return
+	}
+	event := decoded.Event
+	event.FolderID = decoded.FolderID
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for subscriber := range h.subscribers[event.BucketID] {
+		select {
+		case subscriber <- event:
+		default:
+			zap.L().Debug("Bucket events subscriber is full, dropping event",
+				zap.String("bucket_id", event.BucketID.String()))
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package realtime
+
+import (
+	"api/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// IBucketHub broadcasts the changes of the buckets to the users viewing them, on all the API instances.
+type IBucketHub interface {
+	Publish(event models.BucketEvent)
+	Subscribe(bucketID uuid.UUID) (<-chan models.BucketEvent, func())
+}
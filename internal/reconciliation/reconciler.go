@@ -0,0 +1,347 @@
+package reconciliation
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/models"
+	"api/internal/storage"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// expectedObjectStatuses are the statuses of the files whose object must be in the storage.
+var expectedObjectStatuses = []models.FileStatus{
+	models.FileStatusUploaded,
+	models.FileStatusScanning,
+	models.FileStatusQuarantined,
+	models.FileStatusDeleted,
+	models.FileStatusRestoring,
+}
+
+// objectKey identifies an object stored under buckets/{bucket-id}/{file-id}.
+type objectKey struct {
+	bucketID uuid.UUID
+	fileID   uuid.UUID
+}
+
+// trashMarker is a marker stored under trash/{bucket-id}/files|folders/{id}.
+type trashMarker struct {
+	path     string
+	bucketID uuid.UUID
+	id       uuid.UUID
+	isFolder bool
+}
+
+// Reconciler compares the objects of the storage with the files and folders of the database.
+// It reports the objects without a file, the files without an object, and the trash markers
+// of files and folders which are not in the trash. With Repair, the orphaned objects and the stale
+// markers are removed, and the files whose object is confirmed missing are deleted.
+// The keys of the objects are held in memory during the run.
+type Reconciler struct {
+	DB      *gorm.DB
+	Storage storage.IStorage
+	Repair  bool
+}
+
+// Run reconciles the storage with the database. Files and folders changed after the start
+// of the run are skipped, as the listing of the storage may not reflect them.
+func (r Reconciler) Run() (models.ReconciliationReport, error) {
+	startedAt := time.Now()
+	report := models.ReconciliationReport{Issues: []models.ReconciliationIssue{}}
+
+	objects, err := r.listObjects(&report)
+	if err != nil {
+		return report, err
+	}
+
+	markers, err := r.listMarkers(&report)
+	if err != nil {
+		return report, err
+	}
+
+	if err = r.reconcileFiles(&report, objects, startedAt); err != nil {
+		return report, err
+	}
+
+	r.reconcileOrphanedObjects(&report, objects)
+
+	if err = r.reconcileMarkers(&report, markers, startedAt); err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (r Reconciler) listObjects(report *models.ReconciliationReport) (map[objectKey]struct{}, error) {
+	objects := map[objectKey]struct{}{}
+	err := r.Storage.WalkObjects("buckets/", func(key string) error {
+		report.ObjectsScanned++
+
+		parts := strings.Split(strings.TrimPrefix(key, "buckets/"), "/")
+		if len(parts) != 2 {
+			report.UnrecognizedObjects++
+			return nil
+		}
+		bucketID, bucketErr := uuid.Parse(parts[0])
+		fileID, fileErr := uuid.Parse(parts[1])
+		if bucketErr != nil || fileErr != nil {
+			report.UnrecognizedObjects++
+			return nil
+		}
+
+		objects[objectKey{bucketID: bucketID, fileID: fileID}] = struct{}{}
+		return nil
+	})
+	return objects, err
+}
+
+func (r Reconciler) listMarkers(report *models.ReconciliationReport) ([]trashMarker, error) {
+	var markers []trashMarker
+	err := r.Storage.WalkObjects("trash/", func(key string) error {
+		report.MarkersScanned++
+
+		parts := strings.Split(strings.TrimPrefix(key, "trash/"), "/")
+		if len(parts) != 3 || (parts[1] != "files" && parts[1] != "folders") {
+			report.UnrecognizedObjects++
+			return nil
+		}
+		bucketID, bucketErr := uuid.Parse(parts[0])
+		id, idErr := uuid.Parse(parts[2])
+		if bucketErr != nil || idErr != nil {
+			report.UnrecognizedObjects++
+			return nil
+		}
+
+		markers = append(markers, trashMarker{path: key, bucketID: bucketID, id: id, isFolder: parts[1] == "folders"})
+		return nil
+	})
+	return markers, err
+}
+
+// reconcileFiles walks all the files, removing their object from the set of objects to leave the orphaned ones,
+// and reports the files whose object is missing.
+func (r Reconciler) reconcileFiles(
+	report *models.ReconciliationReport,
+	objects map[objectKey]struct{},
+	startedAt time.Time,
+) error {
+	var files []models.File
+	return r.DB.Unscoped().
+		Select("id", "bucket_id", "status", "updated_at").
+		FindInBatches(&files, c.ReconciliationBatchSize, func(_ *gorm.DB, _ int) error {
+			for _, file := range files {
+				report.FilesScanned++
+
+				key := objectKey{bucketID: file.BucketID, fileID: file.ID}
+				if _, ok := objects[key]; ok {
+					delete(objects, key)
+					continue
+				}
+
+				if !isObjectExpected(file) || file.UpdatedAt.After(startedAt) {
+					continue
+				}
+
+				report.MissingObjects++
+				issue := models.ReconciliationIssue{
+					Type:     models.ReconciliationMissingObject,
+					Path:     objectPath(file.BucketID, file.ID),
+					BucketID: file.BucketID,
+					ObjectID: file.ID,
+				}
+				if r.Repair {
+					r.repairMissingObject(&issue, file, startedAt)
+				}
+				addIssue(report, issue)
+			}
+			return nil
+		}).Error
+}
+
+// repairMissingObject deletes a file once the storage confirms its object is missing.
+func (r Reconciler) repairMissingObject(issue *models.ReconciliationIssue, file models.File, startedAt time.Time) {
+	_, err := r.Storage.StatObject(issue.Path)
+	if err == nil {
+		issue.Error = "object found on a second check"
+		return
+	}
+	if !errors.Is(err, storage.ErrObjectNotFound) {
+		issue.Error = err.Error()
+		return
+	}
+
+	result := r.DB.Unscoped().
+		Where("id = ? AND status = ? AND updated_at <= ?", file.ID, file.Status, startedAt).
+		Delete(&models.File{})
+	if result.Error != nil {
+		issue.Error = result.Error.Error()
+		return
+	}
+	if result.RowsAffected == 0 {
+		issue.Error = "file changed during the reconciliation"
+		return
+	}
+
+	zap.L().Info("Deleted a file whose object is missing",
+		zap.String("file_id", file.ID.String()),
+		zap.String("bucket_id", file.BucketID.String()))
+	issue.Repaired = true
+}
+
+// reconcileOrphanedObjects reports the objects left without a file, and removes them in batches.
+func (r Reconciler) reconcileOrphanedObjects(report *models.ReconciliationReport, objects map[objectKey]struct{}) {
+	issues := make([]models.ReconciliationIssue, 0, len(objects))
+	for key := range objects {
+		issues = append(issues, models.ReconciliationIssue{
+			Type:     models.ReconciliationOrphanedObject,
+			Path:     objectPath(key.bucketID, key.fileID),
+			BucketID: key.bucketID,
+			ObjectID: key.fileID,
+		})
+	}
+	report.OrphanedObjects = len(issues)
+
+	if r.Repair {
+		r.removeObjects(issues)
+	}
+	for _, issue := range issues {
+		addIssue(report, issue)
+	}
+}
+
+// reconcileMarkers reports the markers of files and folders which are not in the trash,
+// which would otherwise expire and remove the content of a file or folder in use.
+func (r Reconciler) reconcileMarkers(
+	report *models.ReconciliationReport,
+	markers []trashMarker,
+	startedAt time.Time,
+) error {
+	var stale []models.ReconciliationIssue
+	for start := 0; start < len(markers); start += c.ReconciliationBatchSize {
+		batch := markers[start:min(start+c.ReconciliationBatchSize, len(markers))]
+
+		trashed, err := r.trashedObjects(batch, startedAt)
+		if err != nil {
+			return err
+		}
+
+		for _, marker := range batch {
+			if _, ok := trashed[marker.id]; ok {
+				continue
+			}
+			stale = append(stale, models.ReconciliationIssue{
+				Type:     models.ReconciliationStaleTrashMarker,
+				Path:     marker.path,
+				BucketID: marker.bucketID,
+				ObjectID: marker.id,
+			})
+		}
+	}
+	report.StaleTrashMarkers = len(stale)
+
+	if r.Repair {
+		r.removeObjects(stale)
+	}
+	for _, issue := range stale {
+		addIssue(report, issue)
+	}
+	return nil
+}
+
+// trashedObjects returns the IDs of the markers whose file or folder is in the trash, being restored,
+// or changed since the start of the run.
+func (r Reconciler) trashedObjects(markers []trashMarker, startedAt time.Time) (map[uuid.UUID]struct{}, error) {
+	var fileIDs, folderIDs []uuid.UUID
+	for _, marker := range markers {
+		if marker.isFolder {
+			folderIDs = append(folderIDs, marker.id)
+		} else {
+			fileIDs = append(fileIDs, marker.id)
+		}
+	}
+
+	trashed := map[uuid.UUID]struct{}{}
+	if err := r.pluckTrashed(&models.File{}, fileIDs, startedAt, trashed); err != nil {
+		return nil, err
+	}
+	if err := r.pluckTrashed(&models.Folder{}, folderIDs, startedAt, trashed); err != nil {
+		return nil, err
+	}
+	return trashed, nil
+}
+
+func (r Reconciler) pluckTrashed(
+	model interface{},
+	ids []uuid.UUID,
+	startedAt time.Time,
+	trashed map[uuid.UUID]struct{},
+) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var found []uuid.UUID
+	err := r.DB.Unscoped().Model(model).
+		Where("id IN ?", ids).
+		Where("deleted_at IS NOT NULL OR status = ? OR updated_at > ?", models.FileStatusRestoring, startedAt).
+		Pluck("id", &found).Error
+	if err != nil {
+		return err
+	}
+	for _, id := range found {
+		trashed[id] = struct{}{}
+	}
+	return nil
+}
+
+// removeObjects removes the objects of the issues in batches, and records the outcome on each issue.
+func (r Reconciler) removeObjects(issues []models.ReconciliationIssue) {
+	for start := 0; start < len(issues); start += c.ReconciliationBatchSize {
+		batch := issues[start:min(start+c.ReconciliationBatchSize, len(issues))]
+
+		paths := make([]string, len(batch))
+		for i, issue := range batch {
+			paths[i] = issue.Path
+		}
+
+		err := r.Storage.RemoveObjects(paths)
+		for i := range batch {
+			if err != nil {
+				batch[i].Error = err.Error()
+			} else {
+				batch[i].Repaired = true
+			}
+		}
+	}
+}
+
+func isObjectExpected(file models.File) bool {
+	for _, status := range expectedObjectStatuses {
+		if file.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func objectPath(bucketID uuid.UUID, fileID uuid.UUID) string {
+	return path.Join("buckets", bucketID.String(), fileID.String())
+}
+
+// addIssue counts the repaired issues, and lists the first ones in the report.
+func addIssue(report *models.ReconciliationReport, issue models.ReconciliationIssue) {
+	if issue.Repaired {
+		report.Repaired++
+	}
+	if len(report.Issues) >= c.ReconciliationMaxIssues {
+		report.IssuesTruncated = true
+		return
+	}
+	report.Issues = append(report.Issues, issue)
+}
@@ -0,0 +1,214 @@
+package reconciliation
+
+import (
+	"path"
+	"regexp"
+	"testing"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/models"
+	"api/internal/storage"
+	"api/internal/tests"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+// walkKeys makes the storage list the given keys under a prefix.
+func walkKeys(store *tests.MockStorage, prefix string, keys ...string) {
+	store.On("WalkObjects", prefix, mock.Anything).
+		Run(func(args mock.Arguments) {
+			fn := args.Get(1).(func(key string) error) //nolint:errcheck // test mock type assertion
+			for _, key := range keys {
+				if err := fn(key); err != nil {
+					return
+				}
+			}
+		}).
+		Return(nil)
+}
+
+func TestReconcilerRun(t *testing.T) {
+	bucketID := uuid.New()
+	stored, orphaned := uuid.New(), uuid.New()
+	missing, uploading, changed := uuid.New(), uuid.New(), uuid.New()
+	trashedFile, restoredFolder := uuid.New(), uuid.New()
+	before := time.Now().Add(-time.Hour)
+
+	orphanedPath := path.Join("buckets", bucketID.String(), orphaned.String())
+	missingPath := path.Join("buckets", bucketID.String(), missing.String())
+	stalePath := path.Join("trash", bucketID.String(), "folders", restoredFolder.String())
+
+	for _, repair := range []bool{false, true} {
+		name := "Report"
+		if repair {
+			name = "Repair"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			store := &tests.MockStorage{}
+			walkKeys(store, "buckets/",
+				path.Join("buckets", bucketID.String(), stored.String()),
+				orphanedPath,
+				"buckets/readme.txt",
+			)
+			walkKeys(store, "trash/",
+				path.Join("trash", bucketID.String(), "files", trashedFile.String()),
+				stalePath,
+				path.Join("trash", bucketID.String(), "tags", uuid.NewString()),
+			)
+
+			dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","bucket_id","status","updated_at" FROM "files" ` +
+				`ORDER BY "files"."id" LIMIT $1`)).
+				WithArgs(c.ReconciliationBatchSize).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "bucket_id", "status", "updated_at"}).
+					AddRow(stored, bucketID, models.FileStatusUploaded, before).
+					AddRow(missing, bucketID, models.FileStatusUploaded, before).
+					AddRow(uploading, bucketID, models.FileStatusUploading, before).
+					// Changed since the listing of the storage
+					AddRow(changed, bucketID, models.FileStatusUploaded, time.Now().Add(time.Hour)))
+			if repair {
+				store.On("StatObject", missingPath).Return(storage.ObjectInfo{}, storage.ErrObjectNotFound)
+				dbMock.ExpectBegin()
+				query := `DELETE FROM "files" WHERE id = $1 AND status = $2 AND updated_at <= $3`
+				dbMock.ExpectExec(regexp.QuoteMeta(query)).
+					WithArgs(missing, models.FileStatusUploaded, sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				dbMock.ExpectCommit()
+				store.On("RemoveObjects", []string{orphanedPath}).Return(nil)
+				store.On("RemoveObjects", []string{stalePath}).Return(nil)
+			}
+			dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "files" WHERE id IN ($1) `+
+				`AND (deleted_at IS NOT NULL OR status = $2 OR updated_at > $3)`)).
+				WithArgs(trashedFile, models.FileStatusRestoring, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(trashedFile))
+			dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "folders" WHERE id IN ($1) `+
+				`AND (deleted_at IS NOT NULL OR status = $2 OR updated_at > $3)`)).
+				WithArgs(restoredFolder, models.FileStatusRestoring, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			report, err := Reconciler{DB: db, Storage: store, Repair: repair}.Run()
+			require.NoError(t, err)
+
+			repaired := 0
+			if repair {
+				repaired = 3
+			}
+			assert.Equal(t, models.ReconciliationReport{
+				ObjectsScanned:      3,
+				MarkersScanned:      3,
+				FilesScanned:        4,
+				OrphanedObjects:     1,
+				MissingObjects:      1,
+				StaleTrashMarkers:   1,
+				UnrecognizedObjects: 2,
+				Repaired:            repaired,
+				Issues: []models.ReconciliationIssue{
+					{
+						Type:     models.ReconciliationMissingObject,
+						Path:     missingPath,
+						BucketID: bucketID,
+						ObjectID: missing,
+						Repaired: repair,
+					},
+					{
+						Type:     models.ReconciliationOrphanedObject,
+						Path:     orphanedPath,
+						BucketID: bucketID,
+						ObjectID: orphaned,
+						Repaired: repair,
+					},
+					{
+						Type:     models.ReconciliationStaleTrashMarker,
+						Path:     stalePath,
+						BucketID: bucketID,
+						ObjectID: restoredFolder,
+						Repaired: repair,
+					},
+				},
+			}, report)
+			if !repair {
+				store.AssertNotCalled(t, "RemoveObjects", mock.Anything)
+			}
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRepairMissingObject(t *testing.T) {
+	file := models.File{ID: uuid.New(), BucketID: uuid.New(), Status: models.FileStatusUploaded}
+	objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
+
+	testCases := []struct {
+		name          string
+		statErr       error
+		deletedRows   int64
+		expectedError string
+	}{
+		{
+			name:          "Object found on a second check",
+			expectedError: "object found on a second check",
+		},
+		{
+			name:          "File changed during the reconciliation",
+			statErr:       storage.ErrObjectNotFound,
+			expectedError: "file changed during the reconciliation",
+		},
+		{
+			name:        "Object confirmed missing",
+			statErr:     storage.ErrObjectNotFound,
+			deletedRows: 1,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			store := &tests.MockStorage{}
+			store.On("StatObject", objectPath).Return(storage.ObjectInfo{}, tt.statErr)
+			if tt.statErr != nil {
+				dbMock.ExpectBegin()
+				dbMock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "files"`)).
+					WillReturnResult(sqlmock.NewResult(0, tt.deletedRows))
+				dbMock.ExpectCommit()
+			}
+
+			issue := models.ReconciliationIssue{Path: objectPath}
+			Reconciler{DB: db, Storage: store, Repair: true}.repairMissingObject(&issue, file, time.Now())
+
+			assert.Equal(t, tt.expectedError, issue.Error)
+			assert.Equal(t, tt.expectedError == "", issue.Repaired)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAddIssueTruncatesTheReport(t *testing.T) {
+	report := models.ReconciliationReport{}
+	for range c.ReconciliationMaxIssues + 2 {
+		addIssue(&report, models.ReconciliationIssue{Repaired: true})
+	}
+
+	assert.Len(t, report.Issues, c.ReconciliationMaxIssues)
+	assert.True(t, report.IssuesTruncated)
+	assert.Equal(t, c.ReconciliationMaxIssues+2, report.Repaired)
+}
@@ -0,0 +1,87 @@
+package reconciliation
+
+import (
+	"errors"
+	"time"
+
+	c "api/internal/configuration"
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAlreadyRunning is returned when a reconciliation is already running.
+var ErrAlreadyRunning = errors.New("RECONCILIATION_RUNNING")
+
+// Start records a new run of the reconciliation. A single run is allowed at a time,
+// and the runs interrupted by a restart are marked as failed once they time out.
+func Start(db *gorm.DB, repair bool, startedBy *uuid.UUID) (models.StorageReconciliation, error) {
+	interrupted := "The reconciliation did not complete"
+	err := db.Model(&models.StorageReconciliation{}).
+		Where("status = ? AND created_at < ?",
+			models.ReconciliationStatusRunning,
+			time.Now().Add(-c.ReconciliationTimeoutMinutes*time.Minute)).
+		Updates(map[string]interface{}{
+			"status":       models.ReconciliationStatusFailed,
+			"error":        interrupted,
+			"completed_at": time.Now(),
+		}).Error
+	if err != nil {
+		return models.StorageReconciliation{}, err
+	}
+
+	run := models.StorageReconciliation{
+		Repair:    repair,
+		Status:    models.ReconciliationStatusRunning,
+		StartedBy: startedBy,
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&run)
+	if result.Error != nil {
+		return models.StorageReconciliation{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.StorageReconciliation{}, ErrAlreadyRunning
+	}
+
+	return run, nil
+}
+
+// Execute runs the reconciliation and records its report on the run.
+func (r Reconciler) Execute(run *models.StorageReconciliation) {
+	zap.L().Info("Storage reconciliation started",
+		zap.String("reconciliation_id", run.ID.String()),
+		zap.Bool("repair", r.Repair))
+
+	report, err := r.Run()
+	now := time.Now()
+	run.Report = &report
+	run.CompletedAt = &now
+	run.Status = models.ReconciliationStatusCompleted
+	if err != nil {
+		zap.L().Error("Storage reconciliation failed",
+			zap.String("reconciliation_id", run.ID.String()),
+			zap.Error(err))
+		message := err.Error()
+		run.Status = models.ReconciliationStatusFailed
+		run.Error = &message
+	} else {
+		zap.L().Info("Storage reconciliation completed",
+			zap.String("reconciliation_id", run.ID.String()),
+			zap.Int("orphaned_objects", report.OrphanedObjects),
+			zap.Int("missing_objects", report.MissingObjects),
+			zap.Int("stale_trash_markers", report.StaleTrashMarkers),
+			zap.Int("repaired", report.Repaired))
+	}
+
+	err = r.DB.Model(run).
+		Select("status", "report", "error", "completed_at").
+		Updates(run).Error
+	if err != nil {
+		zap.L().Error("Failed to record the storage reconciliation",
+			zap.String("reconciliation_id", run.ID.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"api/internal/activity"
+	apierrors "api/internal/errors"
+	"api/internal/handlers"
+	m "api/internal/middlewares"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ActivityService struct {
+	DB             *gorm.DB
+	ActivityLogger activity.IActivityLogger
+	PolicyEngine   rbac.IPolicyEngine
+	SigningKey     string
+}
+
+func (s ActivityService) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceAudit, rbac.ActionRead)).
+		With(m.ValidateQuery[models.ActivityQueryParams]).
+		Get("/", handlers.GetOneWithQueryHandler(s.GetAuditActivity))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceActivity, rbac.ActionExport)).
+		With(m.ValidateQuery[models.ActivityExportQueryParams]).
+		Get("/export", handlers.StreamWithQueryHandler(s.ExportActivity))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceAudit, rbac.ActionRead)).
+		Get("/queue", handlers.GetOneHandler(s.GetQueueStats))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceActivity, rbac.ActionVerify)).
+		With(m.ValidateQuery[models.ActivityVerifyQueryParams]).
+		Get("/verify", handlers.GetOneWithQueryHandler(s.VerifyActivity))
+
+	return r
+}
+
+// GetAuditActivity searches the authentication and administration records of the user accounts.
+func (s ActivityService) GetAuditActivity(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+	query models.ActivityQueryParams,
+) (models.Page[map[string]interface{}], error) {
+	return searchActivity(s.DB, s.ActivityLogger, logger, map[string][]string{
+		"object_type": {rbac.ResourceAuth.String(), rbac.ResourceUser.String()},
+	}, query, nil)
+}
+
+// ExportActivity exports the activity of the whole platform, or of a bucket or a user with the query filters.
+func (s ActivityService) ExportActivity(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+	query models.ActivityExportQueryParams,
+) (models.Stream, error) {
+	scope := "platform"
+	switch {
+	case query.BucketID != "":
+		scope = "bucket-" + query.BucketID
+	case query.UserID != "":
+		scope = "user-" + query.UserID
+	}
+	return exportActivity(s.DB, s.ActivityLogger, logger, map[string][]string{}, scope, query, nil)
+}
+
+// GetQueueStats describes the activity waiting to be delivered to the activity backend.
+func (s ActivityService) GetQueueStats(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+) (models.ActivityQueueStats, error) {
+	queue, ok := s.ActivityLogger.(*activity.QueuedActivityLogger)
+	if !ok {
+		return models.ActivityQueueStats{}, errors.New("QUEUE_DISABLED")
+	}
+
+	stats, err := queue.Stats()
+	if err != nil {
+		logger.Error("Failed to read the activity queue", zap.Error(err))
+		return models.ActivityQueueStats{}, errors.New("INTERNAL_SERVER_ERROR")
+	}
+	return stats, nil
+}
+
+// VerifyActivity checks the audit hash chain of the activity recorded over a time range.
+func (s ActivityService) VerifyActivity(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+	query models.ActivityVerifyQueryParams,
+) (models.ActivityChainReport, error) {
+	from, err := time.Parse(time.RFC3339, query.From)
+	if err != nil {
+		return models.ActivityChainReport{}, apierrors.NewAPIError(400, activity.ErrInvalidTimeRange.Error())
+	}
+
+	to := time.Now()
+	if query.To != "" {
+		to, err = time.Parse(time.RFC3339, query.To)
+		if err != nil {
+			return models.ActivityChainReport{}, apierrors.NewAPIError(400, activity.ErrInvalidTimeRange.Error())
+		}
+	}
+
+	if !from.Before(to) {
+		return models.ActivityChainReport{}, apierrors.NewAPIError(400, activity.ErrInvalidTimeRange.Error())
+	}
+
+	report, err := activity.VerifyChain(s.DB, s.ActivityLogger, s.SigningKey, from, to)
+	if errors.Is(err, activity.ErrVerifyRangeTooLarge) {
+		return models.ActivityChainReport{}, apierrors.NewAPIError(400, err.Error())
+	}
+	if err != nil {
+		logger.Error("Failed to verify the activity chain", zap.Error(err))
+		return models.ActivityChainReport{}, apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
+	}
+
+	if !report.Valid {
+		logger.Warn("Activity chain verification failed",
+			zap.Time("from", from),
+			zap.Time("to", to),
+			zap.Int("issues", len(report.Issues)))
+	}
+
+	return report, nil
+}
+
+// searchActivity narrows the allowed activity with the query filters and enriches the resulting page.
+func searchActivity(
+	db *gorm.DB,
+	activityLogger activity.IActivityLogger,
+	logger *zap.Logger,
+	fields map[string][]string,
+	query models.ActivityQueryParams,
+	visible func(map[string]interface{}) bool,
+) (models.Page[map[string]interface{}], error) {
+	criteria, ok, err := activity.NewSearchCriteria(fields, query)
+	if err != nil {
+		return models.Page[map[string]interface{}]{}, apierrors.NewAPIError(400, err.Error())
+	}
+	if !ok {
+		return models.Page[map[string]interface{}]{Data: []map[string]interface{}{}}, nil
+	}
+
+	history, nextCursor, err := activityLogger.Search(criteria)
+	if errors.Is(err, activity.ErrInvalidCursor) {
+		return models.Page[map[string]interface{}]{}, apierrors.NewAPIError(400, err.Error())
+	}
+	if err != nil {
+		logger.Error("Search history failed", zap.Error(err))
+		return models.Page[map[string]interface{}]{}, apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
+	}
+
+	if visible != nil {
+		history = slices.DeleteFunc(history, func(entry map[string]interface{}) bool { return !visible(entry) })
+	}
+
+	return models.Page[map[string]interface{}]{
+		Data:       activity.EnrichActivity(db, history),
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// exportActivity streams all the allowed activity matching the export filters.
+// The time range is checked before the stream starts, so that invalid requests still get an error response.
+func exportActivity(
+	db *gorm.DB,
+	activityLogger activity.IActivityLogger,
+	logger *zap.Logger,
+	fields map[string][]string,
+	scope string,
+	query models.ActivityExportQueryParams,
+	visible func(map[string]interface{}) bool,
+) (models.Stream, error) {
+	criteria, ok, err := activity.NewSearchCriteria(fields, query.SearchParams())
+	if err != nil {
+		return models.Stream{}, apierrors.NewAPIError(400, err.Error())
+	}
+
+	return models.Stream{
+		ContentType: activity.ExportContentType(query.Format),
+		Filename: fmt.Sprintf("activity-%s-%s-%s.%s",
+			scope, criteria.From.UTC().Format("20060102"), criteria.To.UTC().Format("20060102"), query.Format),
+		Write: func(w io.Writer) error {
+			if !ok {
+				return activity.WriteEmptyExport(query.Format, w)
+			}
+			exportErr := activity.ExportActivity(db, activityLogger, criteria, visible, query.Format, w)
+			if exportErr != nil {
+				logger.Error("Failed to export activity", zap.String("scope", scope), zap.Error(exportErr))
+				return exportErr
+			}
+			return nil
+		},
+	}, nil
+}
+
+// newUserActivity builds the record of an authentication or administration action on a user account.
+// The actor is the user performing the action, which is the account itself for authentication events.
+func newUserActivity(
+	message string,
+	resource rbac.Resource,
+	action rbac.Action,
+	actorID uuid.UUID,
+	target models.User,
+	extra map[string]string,
+) models.Activity {
+	fields := map[string]string{
+		"action":      action.String(),
+		"object_type": resource.String(),
+	}
+	if actorID != uuid.Nil {
+		fields["user_id"] = actorID.String()
+	}
+	if target.ID != uuid.Nil && target.ID != actorID {
+		fields["target_user_id"] = target.ID.String()
+	}
+	for key, value := range extra {
+		fields[key] = value
+	}
+
+	return models.Activity{
+		Message: message,
+		Object:  target.ToActivity(),
+		Filter:  activity.NewLogFilter(fields),
+	}
+}
+
+// sendAuthActivity records an authentication event. A delivery failure is logged
+// rather than returned, so that users can still sign in when the activity backend is down.
+func sendAuthActivity(logger *zap.Logger, activityLogger activity.IActivityLogger, action models.Activity) {
+	if err := activityLogger.Send(action); err != nil {
+		logger.Error("Failed to record authentication activity", zap.String("message", action.Message), zap.Error(err))
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	"api/internal/activity"
+	"api/internal/configuration"
+	h "api/internal/helpers"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// activityLoggerStub records the activity sent, and the criteria of the searches answered with the results.
+type activityLoggerStub struct {
+	sent     []models.Activity
+	criteria []activity.SearchCriteria
+	results  []map[string]interface{}
+}
+
+func (s *activityLoggerStub) Send(message models.Activity) error {
+	s.sent = append(s.sent, message)
+	return nil
+}
+
+func (s *activityLoggerStub) Search(criteria activity.SearchCriteria) ([]map[string]interface{}, string, error) {
+	s.criteria = append(s.criteria, criteria)
+	return s.results, "", nil
+}
+
+func TestNewUserActivity(t *testing.T) {
+	actor := models.User{ID: uuid.New(), Email: "admin@example.com"}
+	target := models.User{ID: uuid.New(), Email: "user@example.com"}
+
+	testCases := []struct {
+		name     string
+		actorID  uuid.UUID
+		target   models.User
+		expected map[string]string
+	}{
+		{
+			name:    "Action on another user",
+			actorID: actor.ID,
+			target:  target,
+			expected: map[string]string{
+				"action":         rbac.ActionUpdate.String(),
+				"object_type":    rbac.ResourceUser.String(),
+				"user_id":        actor.ID.String(),
+				"target_user_id": target.ID.String(),
+				"reason":         "test",
+			},
+		},
+		{
+			name:    "Action of the user on itself",
+			actorID: actor.ID,
+			target:  actor,
+			expected: map[string]string{
+				"action":      rbac.ActionUpdate.String(),
+				"object_type": rbac.ResourceUser.String(),
+				"user_id":     actor.ID.String(),
+				"reason":      "test",
+			},
+		},
+		{
+			name:   "Action without a known actor",
+			target: models.User{Email: "unknown@example.com"},
+			expected: map[string]string{
+				"action":      rbac.ActionUpdate.String(),
+				"object_type": rbac.ResourceUser.String(),
+				"reason":      "test",
+			},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			action := newUserActivity(
+				activity.UserRoleUpdated,
+				rbac.ResourceUser,
+				rbac.ActionUpdate,
+				tt.actorID,
+				tt.target,
+				map[string]string{"reason": "test"},
+			)
+
+			assert.Equal(t, activity.UserRoleUpdated, action.Message)
+			assert.Equal(t, tt.expected, action.Filter.Fields)
+			assert.Equal(t, tt.target.ToActivity(), action.Object)
+		})
+	}
+}
+
+func TestGetAuditActivity(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          models.ActivityQueryParams
+		expectedFields map[string][]string
+	}{
+		{
+			name:           "Authentication and user administration records",
+			expectedFields: map[string][]string{"object_type": {"auth", "user"}},
+		},
+		{
+			name:           "Filtered on authentication records",
+			query:          models.ActivityQueryParams{ObjectType: "auth", Action: "login"},
+			expectedFields: map[string][]string{"object_type": {"auth"}, "action": {"login"}},
+		},
+		{
+			name:  "Filtered on other records",
+			query: models.ActivityQueryParams{ObjectType: "bucket"},
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			logger := &activityLoggerStub{results: []map[string]interface{}{{"object_type": "auth"}}}
+
+			service := ActivityService{DB: db, ActivityLogger: logger}
+			page, err := service.GetAuditActivity(zap.NewNop(), models.UserClaims{}, nil, tt.query)
+			require.NoError(t, err)
+
+			if tt.expectedFields == nil {
+				// The other records are never searched
+				assert.Empty(t, logger.criteria)
+				assert.Empty(t, page.Data)
+			} else {
+				require.Len(t, logger.criteria, 1)
+				assert.Equal(t, tt.expectedFields, logger.criteria[0].Fields)
+				assert.Len(t, page.Data, 1)
+			}
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestLoginRecordsFailedAttempts(t *testing.T) {
+	hash, err := h.CreateHash("password")
+	require.NoError(t, err)
+	userID := uuid.New()
+
+	testCases := []struct {
+		name           string
+		rows           *sqlmock.Rows
+		expectedReason string
+	}{
+		{
+			name:           "Unknown user",
+			rows:           sqlmock.NewRows([]string{"id"}),
+			expectedReason: "unknown_user",
+		},
+		{
+			name: "Invalid password",
+			rows: sqlmock.NewRows([]string{"id", "email", "hashed_password"}).
+				AddRow(userID, "user@example.com", hash),
+			expectedReason: "invalid_password",
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users"`)).WillReturnRows(tt.rows)
+			logger := &activityLoggerStub{}
+
+			service := AuthService{
+				DB:             db,
+				Providers:      configuration.Providers{string(models.LocalProviderType): {}},
+				ActivityLogger: logger,
+			}
+			_, err := service.Login(zap.NewNop(), models.UserClaims{}, nil, models.AuthLoginBody{
+				Email:    "user@example.com",
+				Password: "wrong password",
+			})
+			require.Error(t, err)
+
+			require.Len(t, logger.sent, 1)
+			assert.Equal(t, activity.UserLoginFailed, logger.sent[0].Message)
+			assert.Equal(t, tt.expectedReason, logger.sent[0].Filter.Fields["reason"])
+			assert.Equal(t, rbac.ResourceAuth.String(), logger.sent[0].Filter.Fields["object_type"])
+			assert.NotContains(t, logger.sent[0].Filter.Fields, "user_id")
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"api/internal/messaging"
 	m "api/internal/middlewares"
 	"api/internal/models"
+	"api/internal/rbac"
 	"api/internal/sql"
 
 	"github.com/alexedwards/argon2id"
@@ -86,9 +87,15 @@ func (s AuthService) Login(
 	if result.RowsAffected == 1 {
 		match, err := argon2id.ComparePasswordAndHash(body.Password, searchUser.HashedPassword)
 		if err != nil || !match {
+			s.sendLoginFailed(logger, searchUser, string(models.LocalProviderType), "invalid_password")
 			return models.AuthLoginResponse{}, errors.New("invalid email / password combination")
 		}
 
+		if searchUser.IsDisabled() {
+			s.sendLoginFailed(logger, searchUser, string(models.LocalProviderType), "user_disabled")
+			return models.AuthLoginResponse{}, apierrors.NewAPIError(403, "USER_DISABLED")
+		}
+
 		accessToken, err := h.NewAccessToken(
 			s.JWTSecret,
 			&searchUser,
@@ -107,11 +114,30 @@ func (s AuthService) Login(
 			return models.AuthLoginResponse{}, apierrors.ErrGenerateRefreshTokenFailed
 		}
 
+		s.sendLoggedIn(logger, searchUser, string(models.LocalProviderType))
+
 		return models.AuthLoginResponse{AccessToken: accessToken, RefreshToken: refreshToken}, nil
 	}
+	s.sendLoginFailed(logger, models.User{Email: body.Email}, string(models.LocalProviderType), "unknown_user")
 	return models.AuthLoginResponse{}, errors.New("invalid email / password combination")
 }
 
+// sendLoginFailed records a rejected sign-in attempt with the reason it was rejected.
+func (s AuthService) sendLoginFailed(logger *zap.Logger, user models.User, providerKey string, reason string) {
+	sendAuthActivity(logger, s.ActivityLogger, newUserActivity(
+		activity.UserLoginFailed, rbac.ResourceAuth, rbac.ActionLogin, uuid.Nil, user,
+		map[string]string{"provider_key": providerKey, "reason": reason},
+	))
+}
+
+// sendLoggedIn records a successful sign-in.
+func (s AuthService) sendLoggedIn(logger *zap.Logger, user models.User, providerKey string) {
+	sendAuthActivity(logger, s.ActivityLogger, newUserActivity(
+		activity.UserLoggedIn, rbac.ResourceAuth, rbac.ActionLogin, user.ID, user,
+		map[string]string{"provider_key": providerKey},
+	))
+}
+
 func (s AuthService) Verify(
 	_ *zap.Logger,
 	_ models.UserClaims,
@@ -132,11 +158,19 @@ func (s AuthService) Refresh(
 	if err != nil {
 		return models.AuthRefreshResponse{}, err
 	}
-	accessToken, err := h.NewAccessToken(
-		s.JWTSecret,
-		&models.User{ID: refreshToken.UserID, Email: refreshToken.Email, Role: refreshToken.Role},
-		refreshToken.Provider,
-	)
+
+	// The account is reloaded so that disabled users cannot renew their session
+	// and that role changes are reflected in the new access token.
+	var user models.User
+	result := s.DB.Where("id = ?", refreshToken.UserID).Find(&user)
+	if result.RowsAffected == 0 {
+		return models.AuthRefreshResponse{}, apierrors.NewAPIError(403, "FORBIDDEN")
+	}
+	if user.IsDisabled() {
+		return models.AuthRefreshResponse{}, apierrors.NewAPIError(403, "USER_DISABLED")
+	}
+
+	accessToken, err := h.NewAccessToken(s.JWTSecret, &user, refreshToken.Provider)
 	return models.AuthRefreshResponse{AccessToken: accessToken}, err
 }
 
@@ -221,6 +255,16 @@ func (s AuthService) OpenIDCallback(
 		if err != nil {
 			return "", "", apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
 		}
+
+		sendAuthActivity(logger, s.ActivityLogger, newUserActivity(
+			activity.UserSignedUp, rbac.ResourceUser, rbac.ActionCreate, searchUser.ID, searchUser,
+			map[string]string{"provider_key": providerKey},
+		))
+	}
+
+	if searchUser.IsDisabled() {
+		s.sendLoginFailed(logger, searchUser, providerKey, "user_disabled")
+		return "", "", apierrors.NewAPIError(403, "USER_DISABLED")
 	}
 
 	accessToken, err := h.NewAccessToken(s.JWTSecret, &searchUser, providerKey)
@@ -233,6 +277,8 @@ func (s AuthService) OpenIDCallback(
 		return "", "", apierrors.ErrGenerateRefreshTokenFailed
 	}
 
+	s.sendLoggedIn(logger, searchUser, providerKey)
+
 	return accessToken, refreshToken, nil
 }
 
@@ -258,6 +304,10 @@ func (s AuthService) ValidatePasswordReset(
 		return models.AuthLoginResponse{}, apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
 	}
 
+	if challenge.User.IsDisabled() {
+		return models.AuthLoginResponse{}, apierrors.NewAPIError(403, "USER_DISABLED")
+	}
+
 	if challenge.ExpiresAt != nil && time.Now().After(*challenge.ExpiresAt) {
 		s.DB.Delete(&challenge)
 		return models.AuthLoginResponse{}, apierrors.NewAPIError(410, "CHALLENGE_EXPIRED")
@@ -322,6 +372,10 @@ func (s AuthService) ValidatePasswordReset(
 	)
 	successEvent.Trigger()
 
+	sendAuthActivity(logger, s.ActivityLogger, newUserActivity(
+		activity.UserPasswordReset, rbac.ResourceAuth, rbac.ActionUpdate, challenge.User.ID, *challenge.User, nil,
+	))
+
 	accessToken, err := h.NewAccessToken(
 		s.JWTSecret,
 		challenge.User,
@@ -364,7 +418,7 @@ func (s AuthService) RequestPasswordReset(
 	result := s.DB.Where("email = ? AND provider_type = ?", body.Email, models.LocalProviderType).
 		First(&user)
 
-	if result.RowsAffected == 0 {
+	if result.RowsAffected == 0 || user.IsDisabled() {
 		return nil, nil
 	}
 
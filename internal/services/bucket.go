@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"time"
 
@@ -14,12 +15,15 @@ import (
 	m "api/internal/middlewares"
 	"api/internal/models"
 	"api/internal/rbac"
+	"api/internal/realtime"
+	"api/internal/sql"
 	"api/internal/storage"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BucketService struct {
@@ -28,50 +32,97 @@ type BucketService struct {
 	Publisher          messaging.IPublisher
 	Providers          c.Providers
 	ActivityLogger     activity.IActivityLogger
+	PolicyEngine       rbac.IPolicyEngine
+	BucketHub          realtime.IBucketHub
 	WebURL             string
 	TrashRetentionDays int
+	// TrashRetentionMinDays and TrashRetentionMaxDays bound the retention the owners can set on their buckets.
+	TrashRetentionMinDays int
+	TrashRetentionMaxDays int
+	// WebhookAllowedHosts are the hosts the webhooks may reach over http or on an internal address.
+	WebhookAllowedHosts []string
 }
 
 func (s BucketService) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(m.AuthorizeRole(models.RoleGuest)).
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead)).
 		Get("/", handlers.GetListHandler(s.GetBucketList))
 
-	r.With(m.AuthorizeRole(models.RoleUser)).
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceBucket, rbac.ActionCreate)).
 		With(m.Validate[models.BucketCreateUpdateBody]).
 		Post("/", handlers.CreateHandler(s.CreateBucket))
 
-	r.With(m.AuthorizeRole(models.RoleGuest)).
-		Get("/activity", handlers.GetListHandler(s.GetActivity))
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceActivity, rbac.ActionRead)).
+		With(m.ValidateQuery[models.ActivityQueryParams]).
+		Get("/activity", handlers.GetOneWithQueryHandler(s.GetActivity))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceBucket, rbac.ActionTransfer)).
+		Get("/orphaned", handlers.GetListHandler(s.GetOrphanedBuckets))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceBucket, rbac.ActionTransfer)).
+		With(m.Validate[models.BucketTransferBody]).
+		Put("/orphaned/{id0}", handlers.UpdateHandler(s.AssignOrphanedBucket))
 
 	r.Route("/{id0}", func(r chi.Router) {
-		r.With(m.AuthorizeGroup(s.DB, models.GroupViewer, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 			With(m.ValidateQuery[models.BucketQueryParams]).
 			Get("/", handlers.GetOneWithQueryHandler(s.GetBucket))
 
-		r.With(m.AuthorizeGroup(s.DB, models.GroupOwner, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionUpdate, 0)).
 			With(m.Validate[models.BucketCreateUpdateBody]).
 			Patch("/", handlers.UpdateHandler(s.UpdateBucket))
 
-		r.With(m.AuthorizeGroup(s.DB, models.GroupOwner, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionDelete, 0)).
 			Delete("/", handlers.DeleteHandler(s.DeleteBucket))
 
-		r.With(m.AuthorizeGroup(s.DB, models.GroupViewer, 0)).
-			Get("/activity", handlers.GetOneHandler(s.GetBucketActivity))
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionUpdate, 0)).
+			With(m.Validate[models.BucketTrashRetentionBody]).
+			Put("/trash-retention", handlers.UpdateHandler(s.UpdateTrashRetention))
+
+		r.With(m.AuthorizeRoleOrGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionLock, 0)).
+			With(m.Validate[models.RetentionLockBody]).
+			Put("/lock", handlers.UpdateHandler(s.UpdateBucketLock))
+
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
+			Get("/events", handlers.EventStreamHandler(s.StreamBucketEvents))
+
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceActivity, rbac.ActionRead, 0)).
+			With(m.ValidateQuery[models.ActivityQueryParams]).
+			Get("/activity", handlers.GetOneWithQueryHandler(s.GetBucketActivity))
+
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceActivity, rbac.ActionExport, 0)).
+			With(m.ValidateQuery[models.ActivityExportQueryParams]).
+			Get("/activity/export", handlers.StreamWithQueryHandler(s.ExportBucketActivity))
+
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionTransfer, 0)).
+			With(m.Validate[models.BucketTransferBody]).
+			Post("/transfer", handlers.UpdateHandler(s.TransferOwnership))
 
 		r.Mount("/members", BucketMemberService{
 			DB:             s.DB,
 			Providers:      s.Providers,
 			Publisher:      s.Publisher,
 			ActivityLogger: s.ActivityLogger,
+			PolicyEngine:   s.PolicyEngine,
 			WebURL:         s.WebURL,
 		}.Routes())
 
+		r.Mount("/webhooks", BucketWebhookService{
+			DB:                  s.DB,
+			Publisher:           s.Publisher,
+			ActivityLogger:      s.ActivityLogger,
+			PolicyEngine:        s.PolicyEngine,
+			WebhookAllowedHosts: s.WebhookAllowedHosts,
+		}.Routes())
+
 		r.Mount("/", BucketFileService{
 			DB:                 s.DB,
 			Storage:            s.Storage,
+			Publisher:          s.Publisher,
 			ActivityLogger:     s.ActivityLogger,
+			PolicyEngine:       s.PolicyEngine,
+			BucketHub:          s.BucketHub,
 			TrashRetentionDays: s.TrashRetentionDays,
 		}.Routes())
 
@@ -80,6 +131,8 @@ func (s BucketService) Routes() chi.Router {
 			Storage:            s.Storage,
 			Publisher:          s.Publisher,
 			ActivityLogger:     s.ActivityLogger,
+			PolicyEngine:       s.PolicyEngine,
+			BucketHub:          s.BucketHub,
 			TrashRetentionDays: s.TrashRetentionDays,
 		}.Routes())
 	})
@@ -104,7 +157,7 @@ func (s BucketService) CreateBucket(
 			return res.Error
 		}
 
-		err := rbac.CreateMembership(tx, user.UserID, newBucket.ID, models.GroupOwner)
+		err := rbac.CreateMembership(tx, user.UserID, newBucket.ID, models.GroupOwner, nil)
 		if err != nil {
 			logger.Error("Failed to create owner membership", zap.Error(err))
 			return err
@@ -121,7 +174,7 @@ func (s BucketService) CreateBucket(
 			}),
 		}
 
-		err = s.ActivityLogger.Send(action)
+		err = activity.SendTx(s.ActivityLogger, tx, action)
 		if err != nil {
 			logger.Error("Failed to register activity", zap.Error(err))
 			return err
@@ -206,7 +259,7 @@ func (s BucketService) GetBucketList(
 
 func (s BucketService) GetBucket(
 	logger *zap.Logger,
-	_ models.UserClaims,
+	user models.UserClaims,
 	ids uuid.UUIDs,
 	queryParams models.BucketQueryParams,
 ) (models.Bucket, error) {
@@ -322,12 +375,97 @@ func (s BucketService) GetBucket(
 		}
 	}
 
-	bucket.Files = files
-	bucket.Folders = folders
+	access, err := rbac.LoadFolderAccess(s.DB, user.UserID, bucketID)
+	if err != nil {
+		logger.Error("Failed to resolve folder permissions", zap.Error(err))
+		return bucket, apierrors.ErrInternalServer
+	}
+
+	bucket.Files = filterVisibleFiles(access, files)
+	bucket.Folders = filterVisibleFolders(access, folders)
 
 	return bucket, nil
 }
 
+// StreamBucketEvents subscribes to the changes of a bucket, keeping the events of the files and folders
+// the user can view. The folder permissions are loaded again at every heartbeat, and the stream ends
+// when the user is no longer a member of the bucket.
+func (s BucketService) StreamBucketEvents(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+) (<-chan models.BucketEvent, func(), error) {
+	bucketID := ids[0]
+
+	access, err := rbac.LoadFolderAccess(s.DB, user.UserID, bucketID)
+	if err != nil {
+		logger.Error("Failed to resolve folder permissions", zap.Error(err))
+		return nil, nil, apierrors.ErrInternalServer
+	}
+
+	events, unsubscribe := s.BucketHub.Subscribe(bucketID)
+	visible := make(chan models.BucketEvent, c.BucketEventsBufferSize)
+
+	go func() {
+		defer close(visible)
+
+		refresh := time.NewTicker(c.BucketEventsHeartbeatSeconds * time.Second)
+		defer refresh.Stop()
+
+		for {
+			select {
+			case event, open := <-events:
+				if !open {
+					return
+				}
+				if !access.Has(event.FolderID, models.GroupViewer) {
+					continue
+				}
+				select {
+				case visible <- event:
+				default:
+					logger.Debug("Bucket events stream is full, dropping event")
+				}
+			case <-refresh.C:
+				refreshed, err := rbac.LoadFolderAccess(s.DB, user.UserID, bucketID)
+				if err != nil {
+					logger.Warn("Failed to refresh folder permissions", zap.Error(err))
+					continue
+				}
+				if refreshed.BucketGroup == "" {
+					unsubscribe()
+					return
+				}
+				access = refreshed
+			}
+		}
+	}()
+
+	return visible, unsubscribe, nil
+}
+
+// filterVisibleFiles drops the files located in folders the user cannot view.
+func filterVisibleFiles(access *rbac.FolderAccess, files []models.File) []models.File {
+	visible := make([]models.File, 0, len(files))
+	for _, file := range files {
+		if access.Has(file.FolderID, models.GroupViewer) {
+			visible = append(visible, file)
+		}
+	}
+	return visible
+}
+
+// filterVisibleFolders drops the folders the user cannot view.
+func filterVisibleFolders(access *rbac.FolderAccess, folders []models.Folder) []models.Folder {
+	visible := make([]models.Folder, 0, len(folders))
+	for _, folder := range folders {
+		if access.Has(&folder.ID, models.GroupViewer) {
+			visible = append(visible, folder)
+		}
+	}
+	return visible
+}
+
 func (s BucketService) UpdateBucket(
 	_ *zap.Logger,
 	_ models.UserClaims,
@@ -342,19 +480,152 @@ func (s BucketService) UpdateBucket(
 	return nil
 }
 
+// UpdateTrashRetention sets the retention of the trash of a bucket, within the bounds set by the administrators,
+// and configures the storage to expire the trash markers of the new retention. The files and folders already
+// in the trash keep the retention of their marker in the storage, but are expired by the database with the new one.
+func (s BucketService) UpdateTrashRetention(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.BucketTrashRetentionBody,
+) error {
+	if body.RetentionDays != nil &&
+		(*body.RetentionDays < s.TrashRetentionMinDays || *body.RetentionDays > s.TrashRetentionMaxDays) {
+		return apierrors.NewAPIError(400, apierrors.ErrTrashRetentionOutOfBounds)
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		// The lifecycle policy is read and written by a single owner at a time
+		if err := sql.LockTrashRetentions(tx); err != nil {
+			logger.Error("Failed to lock the trash retentions", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		var bucket models.Bucket
+		if result := tx.Where("id = ?", ids[0]).First(&bucket); result.RowsAffected == 0 {
+			return apierrors.NewAPIError(404, "BUCKET_NOT_FOUND")
+		}
+
+		if err := tx.Model(&bucket).Update("trash_retention_days", body.RetentionDays).Error; err != nil {
+			logger.Error("Failed to update the trash retention of the bucket", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		retentions, err := sql.GetTrashRetentions(tx, s.TrashRetentionDays)
+		if err != nil {
+			logger.Error("Failed to list the trash retentions of the buckets", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		// The retention is rolled back when the storage cannot expire the markers kept for it
+		if err = s.Storage.EnsureTrashLifecyclePolicy(retentions); err != nil {
+			logger.Error("Failed to configure the trash lifecycle policy", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		action := models.Activity{
+			Message: activity.BucketRetentionUpdated,
+			Object: models.BucketRetentionActivity{
+				ID:                 bucket.ID,
+				Name:               bucket.Name,
+				TrashRetentionDays: body.RetentionDays,
+			},
+			Filter: activity.NewLogFilter(map[string]string{
+				"action":      rbac.ActionUpdate.String(),
+				"bucket_id":   bucket.ID.String(),
+				"object_type": rbac.ResourceBucket.String(),
+				"user_id":     user.UserID.String(),
+			}),
+		}
+		if err = activity.SendTx(s.ActivityLogger, tx, action); err != nil {
+			logger.Error("Failed to log trash retention activity", zap.Error(err))
+		}
+
+		return nil
+	})
+}
+
+// UpdateBucketLock sets or releases the legal hold of a bucket, and sets or extends its retention date.
+// While locked, the files and folders of the bucket cannot be trashed or purged, nor the bucket deleted.
+// The lock is only enforced by the database, as the files of all the buckets share the same storage bucket.
+func (s BucketService) UpdateBucketLock(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.RetentionLockBody,
+) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var bucket models.Bucket
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ids[0]).First(&bucket)
+		if result.RowsAffected == 0 {
+			return apierrors.NewAPIError(404, "BUCKET_NOT_FOUND")
+		}
+
+		previous := bucket.RetentionLock
+		lock, err := updateRetentionLock(previous, body)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"legal_hold":   lock.LegalHold,
+			"retain_until": lock.RetainUntil,
+		}
+		if err = tx.Model(&bucket).Updates(updates).Error; err != nil {
+			logger.Error("Failed to update the retention lock of the bucket", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		action := models.Activity{
+			Message: activity.BucketLockUpdated,
+			Object: models.RetentionLockActivity{
+				ID:                  bucket.ID,
+				Name:                bucket.Name,
+				LegalHold:           lock.LegalHold,
+				RetainUntil:         lock.RetainUntil,
+				PreviousLegalHold:   previous.LegalHold,
+				PreviousRetainUntil: previous.RetainUntil,
+			},
+			Filter: activity.NewLogFilter(map[string]string{
+				"action":      rbac.ActionLock.String(),
+				"bucket_id":   bucket.ID.String(),
+				"object_type": rbac.ResourceBucket.String(),
+				"user_id":     user.UserID.String(),
+			}),
+		}
+
+		// The change is rolled back when it cannot be audited
+		if err = activity.SendTx(s.ActivityLogger, tx, action); err != nil {
+			logger.Error("Failed to log bucket lock activity", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		return nil
+	})
+}
+
 func (s BucketService) DeleteBucket(
 	logger *zap.Logger,
 	user models.UserClaims,
 	ids uuid.UUIDs,
 ) error {
 	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		// The bucket is locked so that its retention lock cannot be set while it is deleted
 		bucket := models.Bucket{}
-		result := tx.Where("id = ?", ids[0]).First(&bucket)
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ids[0]).First(&bucket)
 
 		if result.RowsAffected == 0 {
 			return apierrors.NewAPIError(404, "BUCKET_NOT_FOUND")
 		}
 
+		locked, err := sql.IsRetentionLocked(tx, bucket.ID, nil)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return apierrors.NewAPIError(409, apierrors.ErrBucketLocked)
+		}
+
 		// Soft delete bucket (memberships will be cascade deleted by foreign key constraint)
 		if _, err := gorm.G[models.Bucket](tx).Where("id = ?", bucket.ID).Delete(context.Background()); err != nil {
 			return err
@@ -376,7 +647,7 @@ func (s BucketService) DeleteBucket(
 			}),
 		}
 
-		if err := s.ActivityLogger.Send(action); err != nil {
+		if err := activity.SendTx(s.ActivityLogger, tx, action); err != nil {
 			return err
 		}
 
@@ -386,6 +657,10 @@ func (s BucketService) DeleteBucket(
 		return nil
 	})
 	if err != nil {
+		var apiErr *apierrors.APIError
+		if errors.As(err, &apiErr) {
+			return err
+		}
 		logger.Error("Failed to delete bucket", zap.Error(err))
 		return apierrors.ErrDeleteFailed
 	}
@@ -397,7 +672,8 @@ func (s BucketService) GetActivity(
 	logger *zap.Logger,
 	user models.UserClaims,
 	ids uuid.UUIDs,
-) []map[string]interface{} {
+	query models.ActivityQueryParams,
+) (models.Page[map[string]interface{}], error) {
 	buckets := s.GetBucketList(logger, user, ids)
 
 	var bucketIDs []string
@@ -405,54 +681,44 @@ func (s BucketService) GetActivity(
 		bucketIDs = append(bucketIDs, bucket.ID.String())
 	}
 
-	if len(bucketIDs) > 0 {
-		searchCriteria := map[string][]string{
-			"object_type": {rbac.ResourceBucket.String(), rbac.ResourceFile.String()},
-			"bucket_id":   bucketIDs,
-		}
-
-		history, err := s.ActivityLogger.Search(searchCriteria)
-		if err != nil {
-			logger.Error("Search history failed", zap.Error(err))
-			return []map[string]interface{}{}
-		}
-
-		if len(history) == 0 {
-			return []map[string]interface{}{}
-		}
-
-		return activity.EnrichActivity(s.DB, history)
+	if len(bucketIDs) == 0 {
+		return models.Page[map[string]interface{}]{Data: []map[string]interface{}{}}, nil
 	}
 
-	return []map[string]interface{}{}
+	return searchActivity(s.DB, s.ActivityLogger, logger, map[string][]string{
+		"object_type": {rbac.ResourceBucket.String(), rbac.ResourceFile.String()},
+		"bucket_id":   bucketIDs,
+	}, query, rbac.VisibleActivity(s.DB, user.UserID))
 }
 
 func (s BucketService) GetBucketActivity(
 	logger *zap.Logger,
 	user models.UserClaims,
 	ids uuid.UUIDs,
+	query models.ActivityQueryParams,
 ) (models.Page[map[string]interface{}], error) {
 	bucket, err := s.GetBucket(logger, user, ids, models.BucketQueryParams{})
 	if err != nil {
 		return models.Page[map[string]interface{}]{}, err
 	}
 
-	searchCriteria := map[string][]string{
+	return searchActivity(s.DB, s.ActivityLogger, logger, map[string][]string{
 		"object_type": {rbac.ResourceBucket.String(), rbac.ResourceFile.String()},
 		"bucket_id":   {bucket.ID.String()},
-	}
-
-	history, err := s.ActivityLogger.Search(searchCriteria)
-	if err != nil {
-		logger.Error("Search history failed", zap.Error(err))
-		return models.Page[map[string]interface{}]{}, err
-	}
-
-	if len(history) == 0 {
-		return models.Page[map[string]interface{}]{}, nil
-	}
-
-	enriched := activity.EnrichActivity(s.DB, history)
+	}, query, rbac.VisibleActivity(s.DB, user.UserID))
+}
 
-	return models.Page[map[string]interface{}]{Data: enriched}, nil
+// ExportBucketActivity exports the activity history of the bucket, its files and its folders
+// which the user can view.
+func (s BucketService) ExportBucketActivity(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	query models.ActivityExportQueryParams,
+) (models.Stream, error) {
+	bucketID := ids[0].String()
+	return exportActivity(s.DB, s.ActivityLogger, logger, map[string][]string{
+		"object_type": {rbac.ResourceBucket.String(), rbac.ResourceFile.String(), rbac.ResourceFolder.String()},
+		"bucket_id":   {bucketID},
+	}, "bucket-"+bucketID, query, rbac.VisibleActivity(s.DB, user.UserID))
 }
@@ -1,6 +1,7 @@
 package services
 
 import (
+	"encoding/base64"
 	"errors"
 	"path"
 	"path/filepath"
@@ -9,9 +10,11 @@ import (
 	apierrors "api/internal/errors"
 	"api/internal/handlers"
 	h "api/internal/helpers"
+	"api/internal/messaging"
 	m "api/internal/middlewares"
 	"api/internal/models"
 	"api/internal/rbac"
+	"api/internal/realtime"
 	"api/internal/sql"
 	"api/internal/storage"
 
@@ -25,32 +28,52 @@ import (
 type BucketFileService struct {
 	DB                 *gorm.DB
 	Storage            storage.IStorage
+	Publisher          messaging.IPublisher
 	ActivityLogger     activity.IActivityLogger
+	PolicyEngine       rbac.IPolicyEngine
+	BucketHub          realtime.IBucketHub
 	TrashRetentionDays int
 }
 
 func (s BucketFileService) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(m.AuthorizeGroup(s.DB, models.GroupContributor, 0)).
+	// Routes only require bucket read access: folder permissions can extend the bucket group,
+	// so the file action is authorized on the target folder by each handler.
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 		With(m.Validate[models.FileTransferBody]).
 		Post("/files", handlers.CreateHandler(s.UploadFile))
 
 	r.Route("/files/{id1}", func(r chi.Router) {
-		r.With(m.AuthorizeGroup(s.DB, models.GroupContributor, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 			With(m.Validate[models.FilePatchBody]).
 			Patch("/", handlers.UpdateHandler(s.PatchFile))
 
-		r.With(m.AuthorizeGroup(s.DB, models.GroupContributor, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 			Delete("/", handlers.DeleteHandler(s.DeleteFile))
 
-		r.With(m.AuthorizeGroup(s.DB, models.GroupViewer, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 			Get("/download", handlers.GetOneHandler(s.DownloadFile))
+
+		r.With(m.AuthorizeRoleOrGroup(s.DB, s.PolicyEngine, rbac.ResourceFile, rbac.ActionLock, 0)).
+			With(m.Validate[models.RetentionLockBody]).
+			Put("/lock", handlers.UpdateHandler(s.UpdateFileLock))
 	})
 
 	return r
 }
 
+// authorizeFolder checks the file action against the user's effective group on the folder.
+func (s BucketFileService) authorizeFolder(
+	logger *zap.Logger,
+	user models.UserClaims,
+	bucketID uuid.UUID,
+	folderID *uuid.UUID,
+	action rbac.Action,
+) error {
+	return authorizeFolder(s.DB, s.PolicyEngine, logger, user, bucketID, folderID, rbac.ResourceFile, action)
+}
+
 func (s BucketFileService) UploadFile(
 	logger *zap.Logger,
 	user models.UserClaims,
@@ -71,6 +94,10 @@ func (s BucketFileService) UploadFile(
 		}
 	}
 
+	if err := s.authorizeFolder(logger, user, bucket.ID, body.FolderID, rbac.ActionCreate); err != nil {
+		return models.FileTransferResponse{}, err
+	}
+
 	var existingFile models.File
 	query := s.DB.Where("bucket_id = ? AND name = ?", bucket.ID, body.Name)
 	if body.FolderID != nil {
@@ -97,6 +124,15 @@ func (s BucketFileService) UploadFile(
 		Size:      body.Size,
 	}
 
+	if body.ChecksumAlgorithm != "" {
+		digest, err := base64.StdEncoding.DecodeString(body.Checksum)
+		if err != nil || len(digest) != body.ChecksumAlgorithm.DigestSize() {
+			return models.FileTransferResponse{}, apierrors.NewAPIError(400, apierrors.ErrInvalidChecksum)
+		}
+		file.ChecksumAlgorithm = &body.ChecksumAlgorithm
+		file.Checksum = &body.Checksum
+	}
+
 	var url string
 	var formData map[string]string
 	var err error
@@ -114,6 +150,7 @@ func (s BucketFileService) UploadFile(
 				"file_id":   file.ID.String(),
 				"user_id":   user.UserID.String(),
 			},
+			file.GetChecksum(),
 		)
 		if err != nil {
 			logger.Error("Generate presigned URL failed", zap.Error(err))
@@ -126,6 +163,8 @@ func (s BucketFileService) UploadFile(
 		return models.FileTransferResponse{}, apierrors.ErrCreateFailed
 	}
 
+	s.BucketHub.Publish(models.NewFileEvent(models.BucketEventFileCreated, *file, &user.UserID))
+
 	return models.FileTransferResponse{
 		ID:   file.ID.String(),
 		URL:  url,
@@ -141,6 +180,21 @@ func (s BucketFileService) PatchFile(
 ) error {
 	bucketID, fileID := ids[0], ids[1]
 
+	var file models.File
+	result := s.DB.Unscoped().Where("id = ? AND bucket_id = ?", fileID, bucketID).Find(&file)
+	if result.RowsAffected == 0 {
+		return apierrors.NewAPIError(404, "FILE_NOT_FOUND")
+	}
+
+	action := rbac.ActionErase
+	if body.Status == string(models.FileStatusUploaded) {
+		action = rbac.ActionRestore
+	}
+
+	if err := s.authorizeFolder(logger, user, bucketID, file.FolderID, action); err != nil {
+		return err
+	}
+
 	switch body.Status {
 	case string(models.FileStatusDeleted):
 		return s.TrashFile(logger, user, bucketID, fileID)
@@ -159,6 +213,16 @@ func (s BucketFileService) DeleteFile(
 ) error {
 	bucketID, fileID := ids[0], ids[1]
 
+	var file models.File
+	result := s.DB.Unscoped().Where("id = ? AND bucket_id = ?", fileID, bucketID).Find(&file)
+	if result.RowsAffected == 0 {
+		return apierrors.NewAPIError(404, "FILE_NOT_FOUND")
+	}
+
+	if err := s.authorizeFolder(logger, user, bucketID, file.FolderID, rbac.ActionPurge); err != nil {
+		return err
+	}
+
 	return s.PurgeFile(logger, user, bucketID, fileID)
 }
 
@@ -181,6 +245,19 @@ func (s BucketFileService) DownloadFile(
 		)
 	}
 
+	if err = s.authorizeFolder(logger, user, bucketID, file.FolderID, rbac.ActionDownload); err != nil {
+		return models.FileTransferResponse{}, err
+	}
+
+	switch file.Status {
+	case models.FileStatusQuarantined:
+		return models.FileTransferResponse{}, apierrors.NewAPIError(403, apierrors.ErrFileQuarantined)
+	case models.FileStatusScanning:
+		return models.FileTransferResponse{}, apierrors.NewAPIError(409, apierrors.ErrFileScanning)
+	case models.FileStatusFailed:
+		return models.FileTransferResponse{}, apierrors.NewAPIError(409, apierrors.ErrFileUploadFailed)
+	}
+
 	url, err := s.Storage.PresignedGetObject(
 		path.Join("buckets", file.BucketID.String(), file.ID.String()),
 	)
@@ -205,12 +282,127 @@ func (s BucketFileService) DownloadFile(
 		return models.FileTransferResponse{}, err
 	}
 
+	dispatchWebhooks(s.Publisher, bucketID, user, models.WebhookEventFileDownloaded, file.ToActivity())
+
 	return models.FileTransferResponse{
 		ID:  file.ID.String(),
 		URL: url,
 	}, nil
 }
 
+// checkRetentionLock refuses to trash or purge a file under a retention lock, or in a locked bucket.
+func (s BucketFileService) checkRetentionLock(tx *gorm.DB, logger *zap.Logger, file models.File) error {
+	if file.IsLocked() {
+		return apierrors.NewAPIError(409, apierrors.ErrFileLocked)
+	}
+
+	locked, err := sql.IsBucketLocked(tx, file.BucketID)
+	if err != nil {
+		logger.Error("Failed to check the retention lock of the bucket", zap.Error(err))
+		return apierrors.NewAPIError(500, "FETCH_FAILED")
+	}
+	if locked {
+		return apierrors.NewAPIError(409, apierrors.ErrBucketLocked)
+	}
+	return nil
+}
+
+// UpdateFileLock sets or releases the legal hold of a file, and sets or extends its retention date.
+// While locked, the file cannot be trashed or purged. The lock is mapped to the object in the storage
+// when its bucket supports it, and is otherwise only enforced by the database.
+func (s BucketFileService) UpdateFileLock(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.RetentionLockBody,
+) error {
+	bucketID, fileID := ids[0], ids[1]
+
+	// The previous lock of the object, once set in the storage
+	var restore *models.RetentionLock
+	var objectPath string
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var file models.File
+		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND bucket_id = ?", fileID, bucketID).
+			First(&file)
+		if result.Error != nil {
+			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+				return apierrors.NewAPIError(404, "FILE_NOT_FOUND")
+			}
+			logger.Error("Failed to fetch file for locking", zap.Error(result.Error))
+			return apierrors.NewAPIError(500, "FETCH_FAILED")
+		}
+
+		// The object of an upload may not exist yet
+		if file.Status == models.FileStatusUploading || file.Status == models.FileStatusFailed {
+			return apierrors.NewAPIError(409, apierrors.ErrFileNotLockable)
+		}
+
+		previous := file.RetentionLock
+		lock, err := updateRetentionLock(previous, body)
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"legal_hold":   lock.LegalHold,
+			"retain_until": lock.RetainUntil,
+		}
+		if err = tx.Model(&file).Updates(updates).Error; err != nil {
+			logger.Error("Failed to update the retention lock of the file", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		action := models.Activity{
+			Message: activity.FileLockUpdated,
+			Object: models.RetentionLockActivity{
+				ID:                  file.ID,
+				Name:                file.Name,
+				LegalHold:           lock.LegalHold,
+				RetainUntil:         lock.RetainUntil,
+				PreviousLegalHold:   previous.LegalHold,
+				PreviousRetainUntil: previous.RetainUntil,
+			},
+			Filter: activity.NewLogFilter(map[string]string{
+				"action":      rbac.ActionLock.String(),
+				"bucket_id":   file.BucketID.String(),
+				"file_id":     file.ID.String(),
+				"object_type": rbac.ResourceFile.String(),
+				"user_id":     user.UserID.String(),
+			}),
+		}
+
+		// The change is rolled back when it cannot be audited
+		if err = activity.SendTx(s.ActivityLogger, tx, action); err != nil {
+			logger.Error("Failed to log file lock activity", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		// The object is locked last, once the change is audited, and is restored if the change is not committed
+		objectPath = path.Join("buckets", file.BucketID.String(), file.ID.String())
+		err = s.Storage.SetObjectRetention(objectPath, lock)
+		if errors.Is(err, storage.ErrRetentionNotSupported) {
+			logger.Debug("Retention lock only enforced by the database", zap.String("path", objectPath))
+			return nil
+		} else if err != nil {
+			logger.Error("Failed to set the retention of the object", zap.Error(err), zap.String("path", objectPath))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+
+		restore = &previous
+		return nil
+	})
+	if err != nil && restore != nil {
+		if restoreErr := s.Storage.SetObjectRetention(objectPath, *restore); restoreErr != nil {
+			logger.Error("Failed to restore the retention of the object",
+				zap.Error(restoreErr),
+				zap.String("path", objectPath))
+		}
+	}
+	return err
+}
+
 // TrashFile moves a file to trash (soft delete) with atomic status transition.
 func (s BucketFileService) TrashFile(
 	logger *zap.Logger,
@@ -218,7 +410,8 @@ func (s BucketFileService) TrashFile(
 	bucketID uuid.UUID,
 	fileID uuid.UUID,
 ) error {
-	return s.DB.Transaction(func(tx *gorm.DB) error {
+	var trashedFile models.File
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
 		var file models.File
 		result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("id = ? AND bucket_id = ?", fileID, bucketID).
@@ -240,6 +433,10 @@ func (s BucketFileService) TrashFile(
 			return apierrors.NewAPIError(409, "INVALID_FILE_STATUS_TRANSITION")
 		}
 
+		if err := s.checkRetentionLock(tx, logger, file); err != nil {
+			return err
+		}
+
 		updates := map[string]interface{}{
 			"status":     models.FileStatusDeleted,
 			"deleted_by": user.UserID,
@@ -254,9 +451,15 @@ func (s BucketFileService) TrashFile(
 			return apierrors.NewAPIError(500, "DELETE_FAILED")
 		}
 
+		retentionDays, err := sql.GetBucketTrashRetention(tx, file.BucketID, s.TrashRetentionDays)
+		if err != nil {
+			logger.Error("Failed to get the trash retention of the bucket", zap.Error(err))
+			return apierrors.NewAPIError(500, "FETCH_FAILED")
+		}
+
 		objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
 
-		if err := s.Storage.MarkAsTrashed(objectPath, file); err != nil {
+		if err = s.Storage.MarkAsTrashed(objectPath, file, retentionDays); err != nil {
 			logger.Error(
 				"Failed to mark file as trashed - rolling back transaction",
 				zap.Error(err),
@@ -278,13 +481,23 @@ func (s BucketFileService) TrashFile(
 			}),
 		}
 
-		if err := s.ActivityLogger.Send(action); err != nil {
+		if err := activity.SendTx(s.ActivityLogger, tx, action); err != nil {
 			logger.Error("Failed to log trash activity", zap.Error(err))
 			return err
 		}
 
+		trashedFile = file
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.BucketHub.Publish(models.NewFileEvent(models.BucketEventFileTrashed, trashedFile, &user.UserID))
+	dispatchWebhooks(s.Publisher, bucketID, user, models.WebhookEventFileTrashed, trashedFile.ToActivity())
+	notifyBucketActivity(s.Publisher, bucketID, user, models.NotificationEventFileDeleted,
+		trashedFile.FolderID, trashedFile.Name)
+	return nil
 }
 
 func (s BucketFileService) restoreParentFolders(
@@ -383,7 +596,7 @@ func (s BucketFileService) RestoreFile(
 				"user_id":     user.UserID.String(),
 			}),
 		}
-		if activityErr := s.ActivityLogger.Send(action); activityErr != nil {
+		if activityErr := activity.SendTx(s.ActivityLogger, tx, action); activityErr != nil {
 			logger.Error("Failed to log restore activity", zap.Error(activityErr))
 			return activityErr
 		}
@@ -407,6 +620,10 @@ func (s BucketFileService) RestoreFile(
 		// Don't return error - the database is already updated
 	}
 
+	restoredFile.Status = models.FileStatusUploaded
+	restoredFile.DeletedAt = gorm.DeletedAt{}
+	s.BucketHub.Publish(models.NewFileEvent(models.BucketEventFileRestored, restoredFile, &user.UserID))
+	dispatchWebhooks(s.Publisher, bucketID, user, models.WebhookEventFileRestored, restoredFile.ToActivity())
 	return nil
 }
 
@@ -416,7 +633,8 @@ func (s BucketFileService) PurgeFile(
 	user models.UserClaims,
 	bucketID, fileID uuid.UUID,
 ) error {
-	return s.DB.Transaction(func(tx *gorm.DB) error {
+	var purgedFile models.File
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
 		// Fetch file inside transaction with row lock (use Unscoped to query soft-deleted files)
 		var file models.File
 		result := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
@@ -431,11 +649,16 @@ func (s BucketFileService) PurgeFile(
 			return apierrors.NewAPIError(500, "FETCH_FAILED")
 		}
 
-		// Only allow purging soft-deleted files (in trash)
-		if !file.DeletedAt.Valid {
+		// Only allow purging soft-deleted files (in trash), and quarantined or failed files which cannot be trashed
+		untrashable := file.Status == models.FileStatusQuarantined || file.Status == models.FileStatusFailed
+		if !file.DeletedAt.Valid && !untrashable {
 			return apierrors.NewAPIError(409, "FILE_NOT_IN_TRASH")
 		}
 
+		if err := s.checkRetentionLock(tx, logger, file); err != nil {
+			return err
+		}
+
 		// Use new path structure: buckets/{bucket_id}/{file_id}
 		objectPath := path.Join("buckets", file.BucketID.String(), file.ID.String())
 
@@ -474,11 +697,21 @@ func (s BucketFileService) PurgeFile(
 			}),
 		}
 
-		if err := s.ActivityLogger.Send(action); err != nil {
+		if err := activity.SendTx(s.ActivityLogger, tx, action); err != nil {
 			logger.Error("Failed to log purge activity", zap.Error(err))
 			return err
 		}
 
+		purgedFile = file
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	s.BucketHub.Publish(models.NewFileEvent(models.BucketEventFilePurged, purgedFile, &user.UserID))
+	dispatchWebhooks(s.Publisher, bucketID, user, models.WebhookEventFilePurged, purgedFile.ToActivity())
+	notifyBucketActivity(s.Publisher, bucketID, user, models.NotificationEventFileDeleted,
+		purgedFile.FolderID, purgedFile.Name)
+	return nil
 }
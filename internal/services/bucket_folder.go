@@ -13,6 +13,8 @@ import (
 	m "api/internal/middlewares"
 	"api/internal/models"
 	"api/internal/rbac"
+	"api/internal/realtime"
+	"api/internal/sql"
 	"api/internal/storage"
 
 	"github.com/go-chi/chi/v5"
@@ -27,35 +29,56 @@ type BucketFolderService struct {
 	Storage            storage.IStorage
 	Publisher          messaging.IPublisher
 	ActivityLogger     activity.IActivityLogger
+	PolicyEngine       rbac.IPolicyEngine
+	BucketHub          realtime.IBucketHub
 	TrashRetentionDays int
 }
 
 func (s BucketFolderService) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(m.AuthorizeGroup(s.DB, models.GroupContributor, 0)).
+	// Routes only require bucket read access: folder permissions can extend the bucket group,
+	// so the folder action is authorized on the target folder by each handler.
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 		With(m.Validate[models.FolderCreateBody]).
 		Post("/", handlers.CreateHandler(s.CreateFolder))
 
 	r.Route("/{id1}", func(r chi.Router) {
 		// PUT for name updates (RESTful full resource update)
-		r.With(m.AuthorizeGroup(s.DB, models.GroupContributor, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 			With(m.Validate[models.FolderUpdateBody]).
 			Put("/", handlers.UpdateHandler(s.UpdateFolder))
 
 		// PATCH for status updates (trash/restore) - consistent with files
-		r.With(m.AuthorizeGroup(s.DB, models.GroupContributor, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 			With(m.Validate[models.FolderPatchBody]).
 			Patch("/", handlers.UpdateHandler(s.PatchFolder))
 
 		// DELETE for permanent deletion
-		r.With(m.AuthorizeGroup(s.DB, models.GroupContributor, 0)).
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceBucket, rbac.ActionRead, 0)).
 			Delete("/", handlers.DeleteHandler(s.DeleteFolder))
+
+		r.Mount("/permissions", BucketFolderPermissionService{
+			DB:             s.DB,
+			ActivityLogger: s.ActivityLogger,
+			PolicyEngine:   s.PolicyEngine,
+		}.Routes())
 	})
 
 	return r
 }
 
+// authorizeFolder checks the folder action against the user's effective group on the folder.
+func (s BucketFolderService) authorizeFolder(
+	logger *zap.Logger,
+	user models.UserClaims,
+	bucketID uuid.UUID,
+	folderID *uuid.UUID,
+	action rbac.Action,
+) error {
+	return authorizeFolder(s.DB, s.PolicyEngine, logger, user, bucketID, folderID, rbac.ResourceFolder, action)
+}
+
 func (s BucketFolderService) CreateFolder(
 	logger *zap.Logger,
 	user models.UserClaims,
@@ -78,6 +101,10 @@ func (s BucketFolderService) CreateFolder(
 		}
 	}
 
+	if err := s.authorizeFolder(logger, user, bucketID, body.FolderID, rbac.ActionCreate); err != nil {
+		return models.Folder{}, err
+	}
+
 	var existingFolder models.Folder
 	query := s.DB.Where("bucket_id = ? AND name = ?", bucketID, body.Name)
 	if body.FolderID != nil {
@@ -117,6 +144,8 @@ func (s BucketFolderService) CreateFolder(
 		logger.Error("Failed to log folder creation activity", zap.Error(err))
 	}
 
+	s.BucketHub.Publish(models.NewFolderEvent(models.BucketEventFolderCreated, folder, &user.UserID))
+
 	return folder, nil
 }
 
@@ -134,6 +163,10 @@ func (s BucketFolderService) UpdateFolder(
 		return apierrors.NewAPIError(404, "FOLDER_NOT_FOUND")
 	}
 
+	if err := s.authorizeFolder(logger, user, bucketID, &folder.ID, rbac.ActionUpdate); err != nil {
+		return err
+	}
+
 	var existingFolder models.Folder
 	query := s.DB.Where("bucket_id = ? AND name = ? AND id != ?", bucketID, body.Name, folderID)
 	if folder.FolderID != nil {
@@ -168,6 +201,8 @@ func (s BucketFolderService) UpdateFolder(
 		logger.Error("Failed to log folder update activity", zap.Error(err))
 	}
 
+	s.BucketHub.Publish(models.NewFolderEvent(models.BucketEventFolderUpdated, folder, &user.UserID))
+
 	return nil
 }
 
@@ -186,6 +221,15 @@ func (s BucketFolderService) PatchFolder(
 		return apierrors.NewAPIError(404, "FOLDER_NOT_FOUND")
 	}
 
+	action := rbac.ActionErase
+	if body.Status == string(models.FileStatusUploaded) {
+		action = rbac.ActionRestore
+	}
+
+	if err := s.authorizeFolder(logger, user, bucketID, &folder.ID, action); err != nil {
+		return err
+	}
+
 	switch body.Status {
 	case string(models.FileStatusDeleted):
 		return s.TrashFolder(logger, user, folder)
@@ -210,9 +254,26 @@ func (s BucketFolderService) DeleteFolder(
 		return apierrors.NewAPIError(404, "FOLDER_NOT_FOUND")
 	}
 
+	if err := s.authorizeFolder(logger, user, bucketID, &folder.ID, rbac.ActionPurge); err != nil {
+		return err
+	}
+
 	return s.PurgeFolder(logger, user, folder)
 }
 
+// checkRetentionLock refuses to trash or purge a folder holding a locked file, or in a locked bucket.
+func (s BucketFolderService) checkRetentionLock(logger *zap.Logger, folder models.Folder) error {
+	locked, err := sql.IsRetentionLocked(s.DB, folder.BucketID, &folder.ID)
+	if err != nil {
+		logger.Error("Failed to check the retention locks of the folder", zap.Error(err))
+		return apierrors.NewAPIError(500, "FETCH_FAILED")
+	}
+	if locked {
+		return apierrors.NewAPIError(409, apierrors.ErrFolderLocked)
+	}
+	return nil
+}
+
 // TrashFolder moves a folder and all its contents to trash (async) with atomic status transition.
 func (s BucketFolderService) TrashFolder(
 	logger *zap.Logger,
@@ -227,6 +288,16 @@ func (s BucketFolderService) TrashFolder(
 		return apierrors.NewAPIError(409, "FOLDER_RESTORE_IN_PROGRESS")
 	}
 
+	if err := s.checkRetentionLock(logger, folder); err != nil {
+		return err
+	}
+
+	retentionDays, err := sql.GetBucketTrashRetention(s.DB, folder.BucketID, s.TrashRetentionDays)
+	if err != nil {
+		logger.Error("Failed to get the trash retention of the bucket", zap.Error(err))
+		return apierrors.NewAPIError(500, "FETCH_FAILED")
+	}
+
 	updates := map[string]interface{}{
 		"status":     models.FileStatusDeleted,
 		"deleted_by": user.UserID,
@@ -242,7 +313,7 @@ func (s BucketFolderService) TrashFolder(
 	}
 
 	objectPath := path.Join("buckets", folder.BucketID.String(), folder.ID.String())
-	if err := s.Storage.MarkAsTrashed(objectPath, folder); err != nil {
+	if err = s.Storage.MarkAsTrashed(objectPath, folder, retentionDays); err != nil {
 		logger.Warn("Failed to create trash marker for folder", zap.Error(err))
 	}
 
@@ -265,6 +336,8 @@ func (s BucketFolderService) TrashFolder(
 		logger.Error("Failed to log trash activity", zap.Error(err))
 	}
 
+	s.BucketHub.Publish(models.NewFolderEvent(models.BucketEventFolderTrashed, folder, &user.UserID))
+
 	logger.Info("Folder trash initiated (async)",
 		zap.String("folder", folder.Name),
 		zap.String("folder_id", folder.ID.String()))
@@ -325,7 +398,11 @@ func (s BucketFolderService) RestoreFolder(
 		}
 
 		// Check if expired (extra safety check)
-		retentionPeriod := time.Duration(s.TrashRetentionDays) * 24 * time.Hour
+		retentionDays, err := sql.GetBucketTrashRetention(tx, lockedFolder.BucketID, s.TrashRetentionDays)
+		if err != nil {
+			return err
+		}
+		retentionPeriod := time.Duration(retentionDays) * 24 * time.Hour
 		if time.Since(lockedFolder.DeletedAt.Time) > retentionPeriod {
 			return apierrors.NewAPIError(410, apierrors.ErrFolderTrashExpired)
 		}
@@ -398,6 +475,8 @@ func (s BucketFolderService) RestoreFolder(
 		logger.Error("Failed to log restore activity", zap.Error(activityErr))
 	}
 
+	s.BucketHub.Publish(models.NewFolderEvent(models.BucketEventFolderRestored, restoredFolder, &user.UserID))
+
 	logger.Info("Folder restore initiated (async)",
 		zap.String("folder", restoredFolder.Name),
 		zap.String("folder_id", restoredFolder.ID.String()))
@@ -416,6 +495,10 @@ func (s BucketFolderService) PurgeFolder(
 		return apierrors.NewAPIError(409, "FOLDER_NOT_IN_TRASH")
 	}
 
+	if err := s.checkRetentionLock(logger, folder); err != nil {
+		return err
+	}
+
 	// Trigger async purge event
 	event := events.NewFolderPurge(s.Publisher, folder.BucketID, folder.ID, user.UserID)
 	event.Trigger()
@@ -436,6 +519,8 @@ func (s BucketFolderService) PurgeFolder(
 		logger.Error("Failed to log purge activity", zap.Error(err))
 	}
 
+	s.BucketHub.Publish(models.NewFolderEvent(models.BucketEventFolderPurged, folder, &user.UserID))
+
 	logger.Info("Folder purge initiated (async)",
 		zap.String("folder", folder.Name),
 		zap.String("folder_id", folder.ID.String()))
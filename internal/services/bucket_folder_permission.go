@@ -0,0 +1,184 @@
+package services
+
+import (
+	"api/internal/activity"
+	apierrors "api/internal/errors"
+	"api/internal/handlers"
+	m "api/internal/middlewares"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BucketFolderPermissionService struct {
+	DB             *gorm.DB
+	ActivityLogger activity.IActivityLogger
+	PolicyEngine   rbac.IPolicyEngine
+}
+
+func (s BucketFolderPermissionService) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceFolder, rbac.ActionGrant, 0)).
+		Get("/", handlers.GetListHandler(s.GetFolderPermissions))
+
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceFolder, rbac.ActionGrant, 0)).
+		With(m.Validate[models.UpdateFolderPermissionsBody]).
+		Put("/", handlers.UpdateHandler(s.UpdateFolderPermissions))
+
+	return r
+}
+
+func (s BucketFolderPermissionService) GetFolderPermissions(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	ids uuid.UUIDs,
+) []models.FolderPermissionResponse {
+	bucketID, folderID := ids[0], ids[1]
+
+	var folder models.Folder
+	result := s.DB.Where("id = ? AND bucket_id = ?", folderID, bucketID).Find(&folder)
+	if result.RowsAffected == 0 {
+		return []models.FolderPermissionResponse{}
+	}
+
+	permissions, err := rbac.GetFolderPermissions(s.DB, folder.ID)
+	if err != nil {
+		logger.Error("Failed to fetch folder permissions", zap.Error(err))
+		return []models.FolderPermissionResponse{}
+	}
+
+	response := []models.FolderPermissionResponse{}
+	for _, permission := range permissions {
+		response = append(response, models.FolderPermissionResponse{
+			UserID:    permission.User.ID,
+			Email:     permission.User.Email,
+			FirstName: permission.User.FirstName,
+			LastName:  permission.User.LastName,
+			Group:     permission.Group,
+		})
+	}
+
+	return response
+}
+
+// UpdateFolderPermissions replaces the ACL entries of a folder.
+// Only existing bucket members can be targeted, folder permissions never grant access to outsiders.
+func (s BucketFolderPermissionService) UpdateFolderPermissions(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.UpdateFolderPermissionsBody,
+) error {
+	bucketID, folderID := ids[0], ids[1]
+
+	var folder models.Folder
+	result := s.DB.Where("id = ? AND bucket_id = ?", folderID, bucketID).Find(&folder)
+	if result.RowsAffected == 0 {
+		return apierrors.NewAPIError(404, "FOLDER_NOT_FOUND")
+	}
+
+	memberships, err := rbac.GetBucketMembers(s.DB, bucketID)
+	if err != nil {
+		logger.Error("Failed to fetch bucket memberships", zap.Error(err))
+		return apierrors.NewAPIError(500, "FETCH_FAILED")
+	}
+
+	membersByEmail := map[string]models.Membership{}
+	for _, membership := range memberships {
+		membersByEmail[membership.User.Email] = membership
+	}
+
+	updated := map[uuid.UUID]models.Group{}
+	for _, permission := range body.Permissions {
+		membership, exists := membersByEmail[permission.Email]
+		if !exists {
+			return apierrors.NewAPIError(400, "NOT_A_BUCKET_MEMBER")
+		}
+		updated[membership.UserID] = permission.Group
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		current, fetchErr := rbac.GetFolderPermissions(tx, folder.ID)
+		if fetchErr != nil {
+			return fetchErr
+		}
+
+		for _, permission := range current {
+			if _, kept := updated[permission.UserID]; !kept {
+				if deleteErr := rbac.DeleteFolderPermission(tx, folder.ID, permission.UserID); deleteErr != nil {
+					return deleteErr
+				}
+			}
+		}
+
+		for userID, group := range updated {
+			permission := models.FolderPermission{
+				FolderID:  folder.ID,
+				BucketID:  bucketID,
+				UserID:    userID,
+				Group:     group,
+				CreatedBy: user.UserID,
+			}
+			if setErr := rbac.SetFolderPermission(tx, permission); setErr != nil {
+				return setErr
+			}
+		}
+
+		action := models.Activity{
+			Message: activity.FolderPermissionsUpdated,
+			Object:  folder.ToActivity(),
+			Filter: activity.NewLogFilter(map[string]string{
+				"action":      rbac.ActionGrant.String(),
+				"bucket_id":   bucketID.String(),
+				"folder_id":   folder.ID.String(),
+				"object_type": rbac.ResourceFolder.String(),
+				"user_id":     user.UserID.String(),
+			}),
+		}
+
+		return activity.SendTx(s.ActivityLogger, tx, action)
+	})
+	if err != nil {
+		logger.Error("Failed to update folder permissions", zap.Error(err))
+		return apierrors.NewAPIError(500, "UPDATE_FAILED")
+	}
+
+	return nil
+}
+
+// authorizeFolder checks an action against the user's effective group on a folder, taking folder permissions
+// into account, and against the custom roles assigned to the user on the bucket.
+// A folder the user has no access to, by lack of membership or by revocation, is denied whatever their roles.
+func authorizeFolder(
+	db *gorm.DB,
+	engine rbac.IPolicyEngine,
+	logger *zap.Logger,
+	user models.UserClaims,
+	bucketID uuid.UUID,
+	folderID *uuid.UUID,
+	resource rbac.Resource,
+	action rbac.Action,
+) error {
+	access, err := rbac.LoadFolderAccess(db, user.UserID, bucketID)
+	if err != nil {
+		logger.Error("Failed to resolve folder permissions", zap.Error(err))
+		return apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
+	}
+
+	group := access.GroupFor(folderID)
+	if group == "" || group == models.GroupNone {
+		return apierrors.NewAPIError(403, "FORBIDDEN")
+	}
+
+	subjects := append([]string{string(group)}, engine.RolesFor(user.UserID, bucketID.String())...)
+
+	if !engine.Enforce(subjects, resource, action) {
+		return apierrors.NewAPIError(403, "FORBIDDEN")
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"strings"
+	"time"
 
 	"api/internal/activity"
 	"api/internal/configuration"
@@ -25,16 +26,17 @@ type BucketMemberService struct {
 	Providers      configuration.Providers
 	Publisher      messaging.IPublisher
 	ActivityLogger activity.IActivityLogger
+	PolicyEngine   rbac.IPolicyEngine
 	WebURL         string
 }
 
 func (s BucketMemberService) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(m.AuthorizeGroup(s.DB, models.GroupViewer, 0)).
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceMember, rbac.ActionRead, 0)).
 		Get("/", handlers.GetListHandler(s.GetBucketMembers))
 
-	r.With(m.AuthorizeGroup(s.DB, models.GroupOwner, 0)).
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceMember, rbac.ActionGrant, 0)).
 		With(m.Validate[models.UpdateMembersBody]).
 		Put("/", handlers.UpdateHandler(s.UpdateBucketMembers))
 
@@ -73,6 +75,7 @@ func (s BucketMemberService) GetBucketMembers(
 			LastName:  membership.User.LastName,
 			Group:     membership.Group,
 			Status:    "active",
+			ExpiresAt: membership.ExpiresAt,
 		})
 	}
 
@@ -87,9 +90,10 @@ func (s BucketMemberService) GetBucketMembers(
 			}
 
 			membersList = append(membersList, models.BucketMember{
-				Email:  invite.Email,
-				Group:  invite.Group,
-				Status: "invited",
+				Email:     invite.Email,
+				Group:     invite.Group,
+				Status:    "invited",
+				ExpiresAt: invite.ExpiresAt,
 			})
 		}
 	}
@@ -123,6 +127,13 @@ func (s BucketMemberService) UpdateBucketMembers(
 		return apierrors.NewAPIError(404, "BUCKET_NOT_FOUND")
 	}
 
+	now := time.Now()
+	for _, member := range body.Members {
+		if member.ExpiresAt != nil && !member.ExpiresAt.After(now) {
+			return apierrors.NewAPIError(400, "EXPIRY_DATE_IN_PAST")
+		}
+	}
+
 	members := s.GetBucketMembers(logger, user, ids)
 	currentMembers := map[string]models.BucketMember{}
 	for _, member := range members {
@@ -176,10 +187,12 @@ func (s BucketMemberService) compareMemberships(
 
 	for email, updatedMember := range updatedMembers {
 		if currentMember, exists := currentMembers[email]; exists {
-			if currentMember.Group != updatedMember.Group {
+			if currentMember.Group != updatedMember.Group ||
+				!sameExpiry(currentMember.ExpiresAt, updatedMember.ExpiresAt) {
 				updated := models.BucketMemberToUpdate{
 					BucketMember: currentMember,
 					NewGroup:     updatedMember.Group,
+					NewExpiresAt: updatedMember.ExpiresAt,
 				}
 				changes.ToUpdate = append(changes.ToUpdate, updated)
 			}
@@ -197,6 +210,14 @@ func (s BucketMemberService) compareMemberships(
 	return changes
 }
 
+// sameExpiry compares two optional expiry dates, nil meaning the grant never expires.
+func sameExpiry(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
 func (s BucketMemberService) addMember(
 	logger *zap.Logger,
 	user models.UserClaims,
@@ -214,6 +235,7 @@ func (s BucketMemberService) addMember(
 				Group:     invite.Group,
 				BucketID:  bucket.ID,
 				CreatedBy: user.UserID,
+				ExpiresAt: invite.ExpiresAt,
 			}
 
 			if err := tx.Create(&inviteRecord).Error; err != nil {
@@ -248,7 +270,7 @@ func (s BucketMemberService) addMember(
 			)
 			bucketSharedEvent.Trigger()
 
-			err := rbac.CreateMembership(tx, invitee.ID, bucket.ID, invite.Group)
+			err := rbac.CreateMembership(tx, invitee.ID, bucket.ID, invite.Group, invite.ExpiresAt)
 			if err != nil {
 				logger.Error("Failed to create membership", zap.Error(err))
 				return err
@@ -267,7 +289,7 @@ func (s BucketMemberService) addMember(
 			}),
 		}
 
-		if err := s.ActivityLogger.Send(action); err != nil {
+		if err := activity.SendTx(s.ActivityLogger, tx, action); err != nil {
 			logger.Error("Failed to log user invitation activity", zap.Error(err))
 			return err
 		}
@@ -276,7 +298,15 @@ func (s BucketMemberService) addMember(
 	})
 	if err != nil {
 		logger.Error("Failed to add member", zap.Error(err))
+		return
 	}
+
+	dispatchWebhooks(s.Publisher, bucket.ID, user, models.WebhookEventMemberCreated, models.WebhookMember{
+		Email:     invite.Email,
+		Group:     invite.Group,
+		ExpiresAt: invite.ExpiresAt,
+	})
+	notifyBucketActivity(s.Publisher, bucket.ID, user, models.NotificationEventMemberCreated, nil, invite.Email)
 }
 
 func (s BucketMemberService) updateMember(
@@ -289,7 +319,10 @@ func (s BucketMemberService) updateMember(
 		if member.Status == "invited" {
 			updateResult := tx.Model(&models.Invite{}).
 				Where("bucket_id = ? AND email = ?", bucket.ID, member.Email).
-				Update("group", member.NewGroup)
+				Updates(map[string]interface{}{
+					"group":      member.NewGroup,
+					"expires_at": member.NewExpiresAt,
+				})
 
 			if updateResult.Error != nil {
 				logger.Error("Failed to update invite role", zap.Error(updateResult.Error))
@@ -300,7 +333,7 @@ func (s BucketMemberService) updateMember(
 				return nil
 			}
 		} else {
-			err := rbac.UpdateMembership(tx, member.UserID, bucket.ID, member.NewGroup)
+			err := rbac.UpdateMembership(tx, member.UserID, bucket.ID, member.NewGroup, member.NewExpiresAt)
 			if err != nil {
 				logger.Error("Failed to update membership", zap.Error(err))
 				return err
@@ -319,7 +352,7 @@ func (s BucketMemberService) updateMember(
 			}),
 		}
 
-		if err := s.ActivityLogger.Send(action); err != nil {
+		if err := activity.SendTx(s.ActivityLogger, tx, action); err != nil {
 			logger.Error("Failed to log user role update activity", zap.Error(err))
 			return err
 		}
@@ -328,7 +361,14 @@ func (s BucketMemberService) updateMember(
 	})
 	if err != nil {
 		logger.Error("Failed to update member", zap.Error(err))
+		return
 	}
+
+	dispatchWebhooks(s.Publisher, bucket.ID, user, models.WebhookEventMemberUpdated, models.WebhookMember{
+		Email:     member.Email,
+		Group:     member.NewGroup,
+		ExpiresAt: member.NewExpiresAt,
+	})
 }
 
 func (s BucketMemberService) deleteMember(
@@ -371,7 +411,7 @@ func (s BucketMemberService) deleteMember(
 			}),
 		}
 
-		if err := s.ActivityLogger.Send(action); err != nil {
+		if err := activity.SendTx(s.ActivityLogger, tx, action); err != nil {
 			logger.Error("Failed to log user removal activity", zap.Error(err))
 			return err
 		}
@@ -380,5 +420,10 @@ func (s BucketMemberService) deleteMember(
 	})
 	if err != nil {
 		logger.Error("Failed to delete member", zap.Error(err))
+		return
 	}
+
+	dispatchWebhooks(s.Publisher, bucket.ID, user, models.WebhookEventMemberDeleted, models.WebhookMember{
+		Email: member.Email,
+	})
 }
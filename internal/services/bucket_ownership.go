@@ -0,0 +1,143 @@
+package services
+
+import (
+	"api/internal/activity"
+	apierrors "api/internal/errors"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// TransferOwnership makes another user the owner of the bucket, the current owner becomes a contributor.
+func (s BucketService) TransferOwnership(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.BucketTransferBody,
+) error {
+	bucketID := ids[0]
+
+	var bucket models.Bucket
+	result := s.DB.Where("id = ?", bucketID).Find(&bucket)
+	if result.RowsAffected == 0 {
+		return apierrors.NewAPIError(404, "BUCKET_NOT_FOUND")
+	}
+
+	target, err := findTransferTarget(s.DB, logger, body)
+	if err != nil {
+		return err
+	}
+
+	if target.ID == user.UserID {
+		return apierrors.NewAPIError(400, "CANNOT_TRANSFER_TO_SELF")
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		return transferBucketOwnership(tx, s.ActivityLogger, bucket, &user.UserID, target, user.UserID)
+	})
+	if err != nil {
+		logger.Error("Failed to transfer bucket ownership", zap.Error(err))
+		return apierrors.NewAPIError(500, "UPDATE_FAILED")
+	}
+
+	return nil
+}
+
+// GetOrphanedBuckets lists the buckets without an active owner, for platform administrators.
+func (s BucketService) GetOrphanedBuckets(logger *zap.Logger, _ models.UserClaims, _ uuid.UUIDs) []models.Bucket {
+	buckets, err := rbac.GetOrphanedBuckets(s.DB)
+	if err != nil {
+		logger.Error("Failed to fetch orphaned buckets", zap.Error(err))
+		return []models.Bucket{}
+	}
+	return buckets
+}
+
+// AssignOrphanedBucket gives a new owner to a bucket without an active owner.
+func (s BucketService) AssignOrphanedBucket(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.BucketTransferBody,
+) error {
+	bucketID := ids[0]
+
+	orphaned, err := rbac.GetOrphanedBuckets(s.DB.Where("id = ?", bucketID))
+	if err != nil {
+		logger.Error("Failed to fetch orphaned buckets", zap.Error(err))
+		return apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
+	}
+	if len(orphaned) == 0 {
+		return apierrors.NewAPIError(409, "BUCKET_HAS_OWNER")
+	}
+
+	target, err := findTransferTarget(s.DB, logger, body)
+	if err != nil {
+		return err
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		return transferBucketOwnership(tx, s.ActivityLogger, orphaned[0], nil, target, user.UserID)
+	})
+	if err != nil {
+		logger.Error("Failed to assign orphaned bucket", zap.Error(err))
+		return apierrors.NewAPIError(500, "UPDATE_FAILED")
+	}
+
+	return nil
+}
+
+// findTransferTarget finds the user receiving the ownership of a bucket by their email, and their provider
+// when the email is registered with several providers, as the ownership must never go to the wrong account.
+func findTransferTarget(db *gorm.DB, logger *zap.Logger, body models.BucketTransferBody) (models.User, error) {
+	query := db.Where("email = ?", body.Email)
+	if body.Provider != "" {
+		query = query.Where("provider_key = ?", body.Provider)
+	}
+
+	var users []models.User
+	if err := query.Limit(2).Find(&users).Error; err != nil {
+		logger.Error("Failed to fetch the new owner", zap.Error(err))
+		return models.User{}, apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
+	}
+
+	switch len(users) {
+	case 0:
+		return models.User{}, apierrors.NewAPIError(404, "USER_NOT_FOUND")
+	case 1:
+		return users[0], nil
+	default:
+		return models.User{}, apierrors.NewAPIError(409, "USER_EMAIL_AMBIGUOUS")
+	}
+}
+
+// transferBucketOwnership transfers the ownership of a bucket and logs the activity on behalf of the actor.
+func transferBucketOwnership(
+	tx *gorm.DB,
+	activityLogger activity.IActivityLogger,
+	bucket models.Bucket,
+	from *uuid.UUID,
+	to models.User,
+	actorID uuid.UUID,
+) error {
+	if err := rbac.TransferOwnership(tx, bucket.ID, from, to.ID); err != nil {
+		return err
+	}
+
+	action := models.Activity{
+		Message: activity.BucketOwnerTransferred,
+		Object:  bucket.ToActivity(),
+		Filter: activity.NewLogFilter(map[string]string{
+			"action":              rbac.ActionTransfer.String(),
+			"object_type":         rbac.ResourceBucket.String(),
+			"bucket_id":           bucket.ID.String(),
+			"user_id":             actorID.String(),
+			"bucket_member_email": to.Email,
+		}),
+	}
+
+	return activityLogger.Send(action)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	apierrors "api/internal/errors"
+	"api/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+func TestFindTransferTarget(t *testing.T) {
+	localID, oidcID := uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name          string
+		body          models.BucketTransferBody
+		rows          *sqlmock.Rows
+		expectedID    uuid.UUID
+		expectedError *apierrors.APIError
+	}{
+		{
+			name:       "Email of a single user",
+			body:       models.BucketTransferBody{Email: "user@example.com"},
+			rows:       sqlmock.NewRows([]string{"id", "email"}).AddRow(localID, "user@example.com"),
+			expectedID: localID,
+		},
+		{
+			name:          "Unknown email",
+			body:          models.BucketTransferBody{Email: "unknown@example.com"},
+			rows:          sqlmock.NewRows([]string{"id"}),
+			expectedError: apierrors.NewAPIError(404, "USER_NOT_FOUND"),
+		},
+		{
+			name: "Email registered with several providers",
+			body: models.BucketTransferBody{Email: "user@example.com"},
+			rows: sqlmock.NewRows([]string{"id", "email"}).
+				AddRow(localID, "user@example.com").
+				AddRow(oidcID, "user@example.com"),
+			expectedError: apierrors.NewAPIError(409, "USER_EMAIL_AMBIGUOUS"),
+		},
+		{
+			name:       "Email and provider",
+			body:       models.BucketTransferBody{Email: "user@example.com", Provider: "oidc"},
+			rows:       sqlmock.NewRows([]string{"id", "email"}).AddRow(oidcID, "user@example.com"),
+			expectedID: oidcID,
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			if tt.body.Provider != "" {
+				dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE email = $1 AND provider_key = $2`)).
+					WithArgs(tt.body.Email, tt.body.Provider, 2).
+					WillReturnRows(tt.rows)
+			} else {
+				dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE email = $1`)).
+					WithArgs(tt.body.Email, 2).
+					WillReturnRows(tt.rows)
+			}
+
+			target, err := findTransferTarget(db, zap.NewNop(), tt.body)
+
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedID, target.ID)
+			}
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTransferOwnershipToAnAmbiguousEmail(t *testing.T) {
+	db, dbMock := newMockDB(t)
+	bucketID := uuid.New()
+
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "buckets" WHERE id = $1`)).
+		WithArgs(bucketID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(bucketID))
+	dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE email = $1`)).
+		WithArgs("user@example.com", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uuid.New()).AddRow(uuid.New()))
+
+	service := BucketService{DB: db}
+	err := service.TransferOwnership(zap.NewNop(), models.UserClaims{UserID: uuid.New()}, uuid.UUIDs{bucketID},
+		models.BucketTransferBody{Email: "user@example.com"})
+
+	assert.Equal(t, apierrors.NewAPIError(409, "USER_EMAIL_AMBIGUOUS"), err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+
+	apierrors "api/internal/errors"
+	"api/internal/models"
+	"api/internal/tests"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestUpdateTrashRetention(t *testing.T) {
+	days := func(days int) *int { return &days }
+
+	testCases := []struct {
+		name          string
+		retentionDays *int
+		inBounds      bool
+	}{
+		{name: "Retention below the minimum", retentionDays: days(2)},
+		{name: "Retention above the maximum", retentionDays: days(91)},
+		{name: "Minimum retention", retentionDays: days(3), inBounds: true},
+		{name: "Maximum retention", retentionDays: days(90), inBounds: true},
+		{name: "Retention of the platform", inBounds: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			bucketID := uuid.New()
+
+			expectedError := apierrors.NewAPIError(400, apierrors.ErrTrashRetentionOutOfBounds)
+			if tt.inBounds {
+				// The lifecycle policy is only read once the lock of the retentions is held
+				dbMock.ExpectBegin()
+				dbMock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock(hashtext($1))")).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "buckets" WHERE id = $1`)).
+					WithArgs(bucketID, 1).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				dbMock.ExpectRollback()
+				expectedError = apierrors.NewAPIError(404, "BUCKET_NOT_FOUND")
+			}
+
+			service := BucketService{
+				DB:                    db,
+				Storage:               &tests.MockStorage{},
+				TrashRetentionDays:    7,
+				TrashRetentionMinDays: 3,
+				TrashRetentionMaxDays: 90,
+			}
+			body := models.BucketTrashRetentionBody{RetentionDays: tt.retentionDays}
+			err := service.UpdateTrashRetention(zap.NewNop(), models.UserClaims{}, uuid.UUIDs{bucketID}, body)
+
+			assert.Equal(t, expectedError, err)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"api/internal/activity"
+	c "api/internal/configuration"
+	apierrors "api/internal/errors"
+	"api/internal/events"
+	"api/internal/handlers"
+	"api/internal/helpers"
+	"api/internal/messaging"
+	m "api/internal/middlewares"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BucketWebhookService struct {
+	DB             *gorm.DB
+	Publisher      messaging.IPublisher
+	ActivityLogger activity.IActivityLogger
+	PolicyEngine   rbac.IPolicyEngine
+	// WebhookAllowedHosts are the hosts the webhooks may reach over http or on an internal address.
+	WebhookAllowedHosts []string
+}
+
+func (s BucketWebhookService) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceWebhook, rbac.ActionRead, 0)).
+		Get("/", handlers.GetListHandler(s.GetWebhookList))
+
+	r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceWebhook, rbac.ActionCreate, 0)).
+		With(m.Validate[models.WebhookCreateBody]).
+		Post("/", handlers.CreateHandler(s.CreateWebhook))
+
+	r.Route("/{id1}", func(r chi.Router) {
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceWebhook, rbac.ActionUpdate, 0)).
+			With(m.Validate[models.WebhookUpdateBody]).
+			Patch("/", handlers.UpdateHandler(s.UpdateWebhook))
+
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceWebhook, rbac.ActionDelete, 0)).
+			Delete("/", handlers.DeleteHandler(s.DeleteWebhook))
+
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceWebhook, rbac.ActionRead, 0)).
+			Get("/deliveries", handlers.GetListHandler(s.GetWebhookDeliveries))
+
+		r.With(m.AuthorizeGroup(s.DB, s.PolicyEngine, rbac.ResourceWebhook, rbac.ActionUpdate, 0)).
+			With(m.Validate[models.WebhookRedeliverBody]).
+			Post("/redeliver", handlers.CreateHandler(s.RedeliverWebhook))
+	})
+
+	return r
+}
+
+func (s BucketWebhookService) GetWebhookList(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	ids uuid.UUIDs,
+) []models.Webhook {
+	var webhooks []models.Webhook
+	if err := s.DB.Where("bucket_id = ?", ids[0]).Order("created_at").Find(&webhooks).Error; err != nil {
+		logger.Error("Failed to fetch webhooks", zap.Error(err))
+		return []models.Webhook{}
+	}
+	return webhooks
+}
+
+// CreateWebhook registers a webhook and returns its signing secret, which is only disclosed once.
+func (s BucketWebhookService) CreateWebhook(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.WebhookCreateBody,
+) (models.WebhookWithSecret, error) {
+	if err := events.CheckWebhookURL(body.URL, s.WebhookAllowedHosts); err != nil {
+		return models.WebhookWithSecret{}, apierrors.NewAPIError(400, apierrors.ErrWebhookURLNotAllowed)
+	}
+
+	secret, err := helpers.RandString(c.WebhookSecretBytes)
+	if err != nil {
+		logger.Error("Failed to generate webhook secret", zap.Error(err))
+		return models.WebhookWithSecret{}, apierrors.ErrCreateFailed
+	}
+
+	webhook := models.Webhook{
+		BucketID:   ids[0],
+		URL:        body.URL,
+		Secret:     secret,
+		EventTypes: body.EventTypes,
+		Enabled:    true,
+		CreatedBy:  user.UserID,
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&webhook).Error; err != nil {
+			logger.Error("Failed to create webhook", zap.Error(err))
+			return err
+		}
+		action := webhookActivity(activity.WebhookCreated, rbac.ActionCreate, user, webhook)
+		return activity.SendTx(s.ActivityLogger, tx, action)
+	})
+	if err != nil {
+		return models.WebhookWithSecret{}, apierrors.ErrCreateFailed
+	}
+
+	return models.WebhookWithSecret{Webhook: webhook, Secret: secret}, nil
+}
+
+func (s BucketWebhookService) UpdateWebhook(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.WebhookUpdateBody,
+) error {
+	if body.URL != "" {
+		if err := events.CheckWebhookURL(body.URL, s.WebhookAllowedHosts); err != nil {
+			return apierrors.NewAPIError(400, apierrors.ErrWebhookURLNotAllowed)
+		}
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		webhook, err := getWebhook(tx, ids[0], ids[1])
+		if err != nil {
+			return err
+		}
+
+		if body.URL != "" {
+			webhook.URL = body.URL
+		}
+		if len(body.EventTypes) > 0 {
+			webhook.EventTypes = body.EventTypes
+		}
+		if body.Enabled != nil {
+			webhook.Enabled = *body.Enabled
+		}
+
+		if err = tx.Save(&webhook).Error; err != nil {
+			logger.Error("Failed to update webhook", zap.Error(err))
+			return apierrors.NewAPIError(500, "UPDATE_FAILED")
+		}
+		action := webhookActivity(activity.WebhookUpdated, rbac.ActionUpdate, user, webhook)
+		return activity.SendTx(s.ActivityLogger, tx, action)
+	})
+}
+
+// DeleteWebhook removes a webhook along with its delivery log.
+func (s BucketWebhookService) DeleteWebhook(logger *zap.Logger, user models.UserClaims, ids uuid.UUIDs) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		webhook, err := getWebhook(tx, ids[0], ids[1])
+		if err != nil {
+			return err
+		}
+
+		if err = tx.Delete(&webhook).Error; err != nil {
+			logger.Error("Failed to delete webhook", zap.Error(err))
+			return apierrors.ErrDeleteFailed
+		}
+		action := webhookActivity(activity.WebhookDeleted, rbac.ActionDelete, user, webhook)
+		return activity.SendTx(s.ActivityLogger, tx, action)
+	})
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, most recent first.
+func (s BucketWebhookService) GetWebhookDeliveries(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	ids uuid.UUIDs,
+) []models.WebhookDelivery {
+	webhook, err := getWebhook(s.DB, ids[0], ids[1])
+	if err != nil {
+		return []models.WebhookDelivery{}
+	}
+
+	var deliveries []models.WebhookDelivery
+	err = s.DB.Where("webhook_id = ?", webhook.ID).
+		Order("created_at DESC").
+		Limit(c.WebhookDeliveryLogLimit).
+		Find(&deliveries).Error
+	if err != nil {
+		logger.Error("Failed to fetch webhook deliveries", zap.Error(err))
+		return []models.WebhookDelivery{}
+	}
+	return deliveries
+}
+
+// RedeliverWebhook delivers the payload of a previous delivery again, as a new delivery.
+// The payload keeps its event identifier, so that receivers can recognize the event.
+func (s BucketWebhookService) RedeliverWebhook(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	ids uuid.UUIDs,
+	body models.WebhookRedeliverBody,
+) (models.WebhookDelivery, error) {
+	webhook, err := getWebhook(s.DB, ids[0], ids[1])
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	if !webhook.Enabled {
+		return models.WebhookDelivery{}, apierrors.NewAPIError(409, "WEBHOOK_DISABLED")
+	}
+
+	var previous models.WebhookDelivery
+	if err = s.DB.Where("id = ? AND webhook_id = ?", body.DeliveryID, webhook.ID).First(&previous).Error; err != nil {
+		return models.WebhookDelivery{}, apierrors.NewAPIError(404, "DELIVERY_NOT_FOUND")
+	}
+
+	delivery := models.WebhookDelivery{
+		WebhookID:     webhook.ID,
+		EventType:     previous.EventType,
+		Payload:       previous.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: time.Now().Add(c.WebhookDeliveryLeaseSeconds * time.Second),
+	}
+	if err = s.DB.Create(&delivery).Error; err != nil {
+		logger.Error("Failed to create webhook delivery", zap.Error(err))
+		return models.WebhookDelivery{}, apierrors.ErrCreateFailed
+	}
+
+	deliveryEvent := events.NewWebhookDelivery(s.Publisher, delivery.ID)
+	deliveryEvent.Trigger()
+
+	return delivery, nil
+}
+
+func getWebhook(db *gorm.DB, bucketID uuid.UUID, webhookID uuid.UUID) (models.Webhook, error) {
+	var webhook models.Webhook
+	err := db.Where("id = ? AND bucket_id = ?", webhookID, bucketID).First(&webhook).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Webhook{}, apierrors.NewAPIError(404, "WEBHOOK_NOT_FOUND")
+		}
+		return models.Webhook{}, apierrors.NewAPIError(500, "FETCH_FAILED")
+	}
+	return webhook, nil
+}
+
+func webhookActivity(
+	message string,
+	action rbac.Action,
+	user models.UserClaims,
+	webhook models.Webhook,
+) models.Activity {
+	return models.Activity{
+		Message: message,
+		Object:  webhook.ToActivity(),
+		Filter: activity.NewLogFilter(map[string]string{
+			"action":      action.String(),
+			"object_type": rbac.ResourceBucket.String(),
+			"bucket_id":   webhook.BucketID.String(),
+			"user_id":     user.UserID.String(),
+			"webhook_id":  webhook.ID.String(),
+		}),
+	}
+}
+
+// dispatchWebhooks notifies the webhooks of a bucket subscribed to an event.
+func dispatchWebhooks(
+	publisher messaging.IPublisher,
+	bucketID uuid.UUID,
+	user models.UserClaims,
+	eventType string,
+	data interface{},
+) {
+	userID := user.UserID
+	dispatchEvent := events.NewWebhookDispatch(publisher, bucketID, &userID, eventType, data)
+	dispatchEvent.Trigger()
+}
@@ -13,6 +13,7 @@ import (
 	"api/internal/messaging"
 	m "api/internal/middlewares"
 	"api/internal/models"
+	"api/internal/rbac"
 	"api/internal/sql"
 	"api/internal/storage"
 
@@ -80,6 +81,10 @@ func (s InviteService) CreateInviteChallenge(
 		return nil, apierrors.NewAPIError(404, "INVITE_NOT_FOUND")
 	}
 
+	if invite.ExpiresAt != nil && time.Now().After(*invite.ExpiresAt) {
+		return nil, apierrors.NewAPIError(410, "INVITE_EXPIRED")
+	}
+
 	s.DB.Where("invite_id = ? AND type = ?", invite.ID, models.ChallengeTypeInvite).
 		Delete(&models.Challenge{})
 
@@ -235,6 +240,11 @@ func (s InviteService) ValidateInviteChallenge(
 	)
 	welcomeEvent.Trigger()
 
+	sendAuthActivity(logger, s.ActivityLogger, newUserActivity(
+		activity.InviteAccepted, rbac.ResourceUser, rbac.ActionCreate, newUser.ID, newUser,
+		map[string]string{"invite_id": inviteID.String(), "invited_by": challenge.Invite.CreatedBy.String()},
+	))
+
 	accessToken, err := h.NewAccessToken(s.JWTSecret, &newUser, string(models.LocalProviderType))
 	if err != nil {
 		logger.Error("Failed to generate access token", zap.Error(err))
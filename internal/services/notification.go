@@ -0,0 +1,193 @@
+package services
+
+import (
+	"html/template"
+	"net/http"
+	"time"
+
+	c "api/internal/configuration"
+	apierrors "api/internal/errors"
+	"api/internal/handlers"
+	h "api/internal/helpers"
+	m "api/internal/middlewares"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationService handles the unsubscribe links of the notification emails, which do not require to sign in,
+// and the mail log of the administrators. Opening an unsubscribe link only asks for a confirmation,
+// the preference is disabled by posting to the link.
+type NotificationService struct {
+	DB           *gorm.DB
+	PolicyEngine rbac.IPolicyEngine
+}
+
+func (s NotificationService) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(m.ValidateQuery[models.NotificationUnsubscribeQueryParams]).
+		Get("/unsubscribe", s.ConfirmUnsubscribe)
+
+	r.With(m.ValidateQuery[models.NotificationUnsubscribeQueryParams]).
+		Post("/unsubscribe", s.Unsubscribe)
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceMail, rbac.ActionRead)).
+		With(m.ValidateQuery[models.MailDeliveryQueryParams]).
+		Get("/mails", handlers.GetOneWithQueryHandler(s.GetMailDeliveries))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceMail, rbac.ActionUpdate)).
+		With(m.Validate[models.MailResendBody]).
+		Post("/mails/resend", handlers.CreateHandler(s.ResendMails))
+
+	return r
+}
+
+// unsubscribePage asks to confirm the unsubscription, as the link of an email may be opened by a mail scanner,
+// then tells it is done. The form posts to the link itself, which holds the token.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>Safebucket notifications</title>
+  <style>
+    body { font-family: "Nunito Sans", Helvetica, Arial, sans-serif; background: #F4F4F7; color: #51545E; }
+    main { max-width: 480px; margin: 80px auto; padding: 35px; background: #FFFFFF; border-radius: 3px; }
+    button { background: #8653e9; color: #FFFFFF; border: 0; border-radius: 3px; padding: 10px 18px; cursor: pointer; }
+  </style>
+</head>
+<body>
+<main>
+{{if .Done}}
+  <p>You will no longer receive the notifications of {{.Scope}}.</p>
+{{else}}
+  <p>Stop receiving the notifications of {{.Scope}}?</p>
+  <form method="post">
+    <input type="hidden" name="List-Unsubscribe" value="One-Click">
+    <button type="submit">Unsubscribe</button>
+  </form>
+{{end}}
+</main>
+</body>
+</html>
+`))
+
+// ConfirmUnsubscribe renders the page confirming the unsubscription from a notification preference.
+// It does not change the preference.
+func (s NotificationService) ConfirmUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	s.handleUnsubscribe(w, r, false)
+}
+
+// Unsubscribe disables the preference the email was sent for. It is posted by the confirmation page,
+// and by the mail clients supporting the one-click unsubscription of RFC 8058.
+func (s NotificationService) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	s.handleUnsubscribe(w, r, true)
+}
+
+func (s NotificationService) handleUnsubscribe(w http.ResponseWriter, r *http.Request, unsubscribe bool) {
+	logger := m.GetLogger(r)
+
+	query, ok := r.Context().Value(models.QueryKey{}).(models.NotificationUnsubscribeQueryParams)
+	if !ok {
+		logger.Error("Failed to extract query params from context")
+		h.RespondWithError(w, http.StatusInternalServerError, []string{"INTERNAL_SERVER_ERROR"})
+		return
+	}
+
+	var preference models.NotificationPreference
+	if err := s.DB.Where("unsubscribe_token = ?", query.Token).First(&preference).Error; err != nil {
+		h.RespondWithError(w, http.StatusNotFound, []string{"PREFERENCE_NOT_FOUND"})
+		return
+	}
+
+	if unsubscribe {
+		if err := s.DB.Model(&preference).Update("enabled", false).Error; err != nil {
+			logger.Error("Failed to unsubscribe from notifications", zap.Error(err))
+			h.RespondWithError(w, http.StatusInternalServerError, []string{"UPDATE_FAILED"})
+			return
+		}
+	}
+
+	scope := "all your buckets"
+	if preference.BucketID != nil {
+		var bucket models.Bucket
+		if err := s.DB.Select("name").Where("id = ?", *preference.BucketID).First(&bucket).Error; err == nil {
+			scope = bucket.Name
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	data := struct {
+		Scope string
+		Done  bool
+	}{Scope: scope, Done: unsubscribe}
+	if err := unsubscribePage.Execute(w, data); err != nil {
+		logger.Error("Failed to render the unsubscribe page", zap.Error(err))
+	}
+}
+
+// GetMailDeliveries returns the latest emails of the mail log, most recent first.
+func (s NotificationService) GetMailDeliveries(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+	query models.MailDeliveryQueryParams,
+) (models.Page[models.MailDelivery], error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = c.MailDeliveryLogDefaultLimit
+	}
+
+	db := s.DB.Order("created_at DESC").Limit(limit)
+	if query.Status != "" {
+		db = db.Where("status = ?", query.Status)
+	}
+	if query.Recipient != "" {
+		db = db.Where("recipient = ?", query.Recipient)
+	}
+
+	var deliveries []models.MailDelivery
+	if err := db.Find(&deliveries).Error; err != nil {
+		logger.Error("Failed to fetch the mail log", zap.Error(err))
+		return models.Page[models.MailDelivery]{}, apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
+	}
+	return models.Page[models.MailDelivery]{Data: deliveries}, nil
+}
+
+// ResendMails queues the failed and bounced emails again, and returns the emails queued.
+// Emails already sent, or still queued, are left untouched.
+func (s NotificationService) ResendMails(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+	body models.MailResendBody,
+) ([]models.MailDelivery, error) {
+	var deliveries []models.MailDelivery
+	err := s.DB.Model(&deliveries).
+		Clauses(clause.Returning{}).
+		Where("id IN ? AND status IN ?", body.IDs, []models.MailDeliveryStatus{
+			models.MailDeliveryFailed,
+			models.MailDeliveryBounced,
+		}).
+		Updates(map[string]interface{}{
+			"status":          models.MailDeliveryPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error
+	if err != nil {
+		logger.Error("Failed to resend emails", zap.Error(err))
+		return nil, apierrors.NewAPIError(500, "UPDATE_FAILED")
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	apierrors "api/internal/errors"
+	"api/internal/handlers"
+	m "api/internal/middlewares"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type PolicyService struct {
+	DB           *gorm.DB
+	PolicyEngine rbac.IPolicyEngine
+}
+
+func (s PolicyService) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourcePolicy, rbac.ActionRead)).
+		Get("/", handlers.GetListHandler(s.GetPolicyList))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourcePolicy, rbac.ActionCreate)).
+		With(m.Validate[models.PolicyCreateBody]).
+		Post("/", handlers.CreateHandler(s.CreatePolicy))
+
+	r.Route("/{id0}", func(r chi.Router) {
+		r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourcePolicy, rbac.ActionDelete)).
+			Delete("/", handlers.DeleteHandler(s.DeletePolicy))
+	})
+
+	return r
+}
+
+func (s PolicyService) GetPolicyList(logger *zap.Logger, _ models.UserClaims, _ uuid.UUIDs) []models.Policy {
+	var policies []models.Policy
+	if err := s.DB.Order("ptype, v0, v1, v2").Find(&policies).Error; err != nil {
+		logger.Error("Failed to list policies", zap.Error(err))
+		return []models.Policy{}
+	}
+	return policies
+}
+
+// CreatePolicy adds a custom rule. Built-in roles and groups cannot be granted new permissions
+// nor be assigned through custom rules, so that the default authorization model stays intact.
+// A rule denying an action to a custom role overrides what the group of its members grants.
+func (s PolicyService) CreatePolicy(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+	body models.PolicyCreateBody,
+) (models.Policy, error) {
+	policy := models.Policy{
+		Ptype: body.Ptype,
+		V0:    body.V0,
+		V1:    body.V1,
+		V2:    body.V2,
+	}
+	if body.V3 == models.PolicyEffectDeny {
+		policy.V3 = models.PolicyEffectDeny
+	}
+
+	switch policy.Ptype {
+	case models.PolicyTypePermission:
+		if rbac.IsBuiltInSubject(policy.V0) {
+			return models.Policy{}, apierrors.NewAPIError(403, "POLICY_READ_ONLY")
+		}
+	case models.PolicyTypeRole:
+		if rbac.IsBuiltInSubject(policy.V0) || rbac.IsBuiltInSubject(policy.V1) {
+			return models.Policy{}, apierrors.NewAPIError(403, "POLICY_READ_ONLY")
+		}
+		if policy.V2 == "" {
+			policy.V2 = rbac.Wildcard
+		}
+	}
+
+	result := s.DB.Where(
+		"ptype = ? AND v0 = ? AND v1 = ? AND v2 = ?",
+		policy.Ptype, policy.V0, policy.V1, policy.V2,
+	).Find(&models.Policy{})
+	if result.RowsAffected > 0 {
+		return models.Policy{}, apierrors.NewAPIError(409, "POLICY_ALREADY_EXISTS")
+	}
+
+	if err := s.DB.Create(&policy).Error; err != nil {
+		logger.Error("Failed to create policy", zap.Error(err))
+		return models.Policy{}, apierrors.ErrCreateFailed
+	}
+
+	if err := s.PolicyEngine.Reload(); err != nil {
+		logger.Error("Failed to reload policies", zap.Error(err))
+	}
+
+	return policy, nil
+}
+
+func (s PolicyService) DeletePolicy(logger *zap.Logger, _ models.UserClaims, ids uuid.UUIDs) error {
+	var policy models.Policy
+	result := s.DB.Where("id = ?", ids[0]).Find(&policy)
+	if result.RowsAffected == 0 {
+		return apierrors.NewAPIError(404, "POLICY_NOT_FOUND")
+	}
+
+	if rbac.IsDefaultPolicy(policy) {
+		return apierrors.NewAPIError(403, "POLICY_READ_ONLY")
+	}
+
+	if err := s.DB.Delete(&policy).Error; err != nil {
+		logger.Error("Failed to delete policy", zap.Error(err))
+		return apierrors.ErrDeleteFailed
+	}
+
+	if err := s.PolicyEngine.Reload(); err != nil {
+		logger.Error("Failed to reload policies", zap.Error(err))
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"time"
+
+	apierrors "api/internal/errors"
+	"api/internal/models"
+)
+
+// updateRetentionLock returns the retention lock requested by the body. The legal hold can be set and released,
+// and is kept when omitted, but a retention date cannot be brought forward or removed before it has passed,
+// nor set in the past.
+func updateRetentionLock(current models.RetentionLock, body models.RetentionLockBody) (models.RetentionLock, error) {
+	now := time.Now()
+
+	if body.RetainUntil != nil && !body.RetainUntil.After(now) {
+		return current, apierrors.NewAPIError(400, apierrors.ErrInvalidRetainUntil)
+	}
+
+	if current.RetainUntil != nil && current.RetainUntil.After(now) &&
+		(body.RetainUntil == nil || body.RetainUntil.Before(*current.RetainUntil)) {
+		return current, apierrors.NewAPIError(409, apierrors.ErrRetentionCannotBeShortened)
+	}
+
+	legalHold := current.LegalHold
+	if body.LegalHold != nil {
+		legalHold = *body.LegalHold
+	}
+
+	return models.RetentionLock{LegalHold: legalHold, RetainUntil: body.RetainUntil}, nil
+}
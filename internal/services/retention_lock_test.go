@@ -0,0 +1,175 @@
+package services
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	apierrors "api/internal/errors"
+	"api/internal/models"
+	"api/internal/tests"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestUpdateRetentionLock(t *testing.T) {
+	hold, release := true, false
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	soon := now.Add(24 * time.Hour)
+	later := now.Add(48 * time.Hour)
+
+	testCases := []struct {
+		name          string
+		current       models.RetentionLock
+		body          models.RetentionLockBody
+		expected      models.RetentionLock
+		expectedError *apierrors.APIError
+	}{
+		{
+			name:     "Legal hold is set",
+			body:     models.RetentionLockBody{LegalHold: &hold},
+			expected: models.RetentionLock{LegalHold: true},
+		},
+		{
+			name:     "Legal hold is kept when omitted",
+			current:  models.RetentionLock{LegalHold: true},
+			body:     models.RetentionLockBody{RetainUntil: &soon},
+			expected: models.RetentionLock{LegalHold: true, RetainUntil: &soon},
+		},
+		{
+			name:     "Legal hold is released explicitly",
+			current:  models.RetentionLock{LegalHold: true},
+			body:     models.RetentionLockBody{LegalHold: &release},
+			expected: models.RetentionLock{},
+		},
+		{
+			name:     "Retention date is extended",
+			current:  models.RetentionLock{RetainUntil: &soon},
+			body:     models.RetentionLockBody{RetainUntil: &later},
+			expected: models.RetentionLock{RetainUntil: &later},
+		},
+		{
+			name:     "Passed retention date is removed",
+			current:  models.RetentionLock{RetainUntil: &past},
+			body:     models.RetentionLockBody{},
+			expected: models.RetentionLock{},
+		},
+		{
+			name:          "Retention date cannot be brought forward",
+			current:       models.RetentionLock{RetainUntil: &later},
+			body:          models.RetentionLockBody{RetainUntil: &soon},
+			expectedError: apierrors.NewAPIError(409, apierrors.ErrRetentionCannotBeShortened),
+		},
+		{
+			name:          "Retention date cannot be removed before it has passed",
+			current:       models.RetentionLock{RetainUntil: &soon},
+			body:          models.RetentionLockBody{LegalHold: &release},
+			expectedError: apierrors.NewAPIError(409, apierrors.ErrRetentionCannotBeShortened),
+		},
+		{
+			name:          "Retention date cannot be in the past",
+			body:          models.RetentionLockBody{RetainUntil: &past},
+			expectedError: apierrors.NewAPIError(400, apierrors.ErrInvalidRetainUntil),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			lock, err := updateRetentionLock(tt.current, tt.body)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+				assert.Equal(t, tt.current, lock)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, lock)
+		})
+	}
+}
+
+func TestRetentionLockPreventsTrashAndPurge(t *testing.T) {
+	later := time.Now().Add(24 * time.Hour)
+	trashedAt := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name          string
+		purge         bool
+		lock          models.RetentionLock
+		bucketLocked  bool
+		expectedError *apierrors.APIError
+	}{
+		{
+			name:          "Trash a file under a legal hold",
+			lock:          models.RetentionLock{LegalHold: true},
+			expectedError: apierrors.NewAPIError(409, apierrors.ErrFileLocked),
+		},
+		{
+			name:          "Trash a file in a locked bucket",
+			bucketLocked:  true,
+			expectedError: apierrors.NewAPIError(409, apierrors.ErrBucketLocked),
+		},
+		{
+			name:          "Purge a file retained until a later date",
+			purge:         true,
+			lock:          models.RetentionLock{RetainUntil: &later},
+			expectedError: apierrors.NewAPIError(409, apierrors.ErrFileLocked),
+		},
+		{
+			name:          "Purge a file in a locked bucket",
+			purge:         true,
+			bucketLocked:  true,
+			expectedError: apierrors.NewAPIError(409, apierrors.ErrBucketLocked),
+		},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+			bucketID, fileID := uuid.New(), uuid.New()
+
+			// Trashed files are fetched unscoped to be purged
+			query := `SELECT * FROM "files" WHERE (id = $1 AND bucket_id = $2)`
+			var deletedAt *time.Time
+			if tt.purge {
+				query = `SELECT * FROM "files" WHERE id = $1 AND bucket_id = $2`
+				deletedAt = &trashedAt
+			}
+
+			dbMock.ExpectBegin()
+			rows := sqlmock.NewRows([]string{
+				"id", "bucket_id", "status", "legal_hold", "retain_until", "deleted_at",
+			}).AddRow(fileID, bucketID, models.FileStatusUploaded, tt.lock.LegalHold, tt.lock.RetainUntil, deletedAt)
+			dbMock.ExpectQuery(regexp.QuoteMeta(query)).
+				WithArgs(fileID, bucketID, 1).
+				WillReturnRows(rows)
+			if !tt.lock.IsLocked() {
+				count := 0
+				if tt.bucketLocked {
+					count = 1
+				}
+				dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "buckets" WHERE id = $1 AND (legal_hold OR`)).
+					WithArgs(bucketID, sqlmock.AnyArg()).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+			}
+			dbMock.ExpectRollback()
+
+			// The object is left untouched
+			service := BucketFileService{DB: db, Storage: &tests.MockStorage{}}
+
+			var err error
+			if tt.purge {
+				err = service.PurgeFile(zap.NewNop(), models.UserClaims{}, bucketID, fileID)
+			} else {
+				err = service.TrashFile(zap.NewNop(), models.UserClaims{}, bucketID, fileID)
+			}
+
+			assert.Equal(t, tt.expectedError, err)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
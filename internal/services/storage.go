@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+
+	"api/internal/activity"
+	c "api/internal/configuration"
+	apierrors "api/internal/errors"
+	"api/internal/handlers"
+	m "api/internal/middlewares"
+	"api/internal/models"
+	"api/internal/rbac"
+	"api/internal/reconciliation"
+	"api/internal/storage"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// StorageService lets the administrators reconcile the storage with the database.
+// A reconciliation runs in the background, its report is fetched once completed.
+type StorageService struct {
+	DB             *gorm.DB
+	Storage        storage.IStorage
+	ActivityLogger activity.IActivityLogger
+	PolicyEngine   rbac.IPolicyEngine
+}
+
+func (s StorageService) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceStorage, rbac.ActionRead)).
+		Get("/reconciliations", handlers.GetListHandler(s.GetReconciliationList))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceStorage, rbac.ActionUpdate)).
+		With(m.Validate[models.StorageReconciliationBody]).
+		Post("/reconciliations", handlers.CreateHandler(s.StartReconciliation))
+
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceStorage, rbac.ActionRead)).
+		Get("/reconciliations/{id0}", handlers.GetOneHandler(s.GetReconciliation))
+
+	return r
+}
+
+// GetReconciliationList lists the latest runs, without their report.
+func (s StorageService) GetReconciliationList(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+) []models.StorageReconciliation {
+	var runs []models.StorageReconciliation
+	err := s.DB.Omit("report").
+		Order("created_at DESC").
+		Limit(c.ReconciliationListLimit).
+		Find(&runs).Error
+	if err != nil {
+		logger.Error("Failed to list the storage reconciliations", zap.Error(err))
+		return []models.StorageReconciliation{}
+	}
+	return runs
+}
+
+func (s StorageService) GetReconciliation(
+	_ *zap.Logger,
+	_ models.UserClaims,
+	ids uuid.UUIDs,
+) (models.StorageReconciliation, error) {
+	var run models.StorageReconciliation
+	result := s.DB.Where("id = ?", ids[0]).Find(&run)
+	if result.RowsAffected == 0 {
+		return models.StorageReconciliation{}, apierrors.NewAPIError(404, "RECONCILIATION_NOT_FOUND")
+	}
+	return run, nil
+}
+
+// StartReconciliation starts a run in the background, which repairs the differences when requested.
+func (s StorageService) StartReconciliation(
+	logger *zap.Logger,
+	user models.UserClaims,
+	_ uuid.UUIDs,
+	body models.StorageReconciliationBody,
+) (models.StorageReconciliation, error) {
+	run, err := reconciliation.Start(s.DB, body.Repair, &user.UserID)
+	if errors.Is(err, reconciliation.ErrAlreadyRunning) {
+		return models.StorageReconciliation{}, apierrors.NewAPIError(409, err.Error())
+	}
+	if err != nil {
+		logger.Error("Failed to start the storage reconciliation", zap.Error(err))
+		return models.StorageReconciliation{}, apierrors.ErrCreateFailed
+	}
+
+	action := models.Activity{
+		Message: activity.StorageReconciled,
+		Object:  models.StorageReconciliationActivity{ID: run.ID, Repair: run.Repair},
+		Filter: activity.NewLogFilter(map[string]string{
+			"action":      rbac.ActionUpdate.String(),
+			"object_type": rbac.ResourceStorage.String(),
+			"user_id":     user.UserID.String(),
+		}),
+	}
+	if err = s.ActivityLogger.Send(action); err != nil {
+		logger.Error("Failed to log storage reconciliation activity", zap.Error(err))
+	}
+
+	// The run is copied, as the response is written while the reconciliation updates it
+	reconciler := reconciliation.Reconciler{DB: s.DB, Storage: s.Storage, Repair: run.Repair}
+	background := run
+	go reconciler.Execute(&background)
+
+	return run, nil
+}
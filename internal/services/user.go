@@ -2,12 +2,18 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
+	"api/internal/activity"
+	"api/internal/configuration"
 	apierrors "api/internal/errors"
 	"api/internal/handlers"
 	h "api/internal/helpers"
 	m "api/internal/middlewares"
 	"api/internal/models"
+	"api/internal/rbac"
 	"api/internal/sql"
 
 	"github.com/alexedwards/argon2id"
@@ -18,16 +24,19 @@ import (
 )
 
 type UserService struct {
-	DB *gorm.DB
+	DB             *gorm.DB
+	ActivityLogger activity.IActivityLogger
+	PolicyEngine   rbac.IPolicyEngine
 }
 
 func (s UserService) Routes() chi.Router {
 	r := chi.NewRouter()
 
-	r.With(m.AuthorizeRole(models.RoleAdmin)).
-		Get("/", handlers.GetListHandler(s.GetUserList))
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceUser, rbac.ActionRead)).
+		With(m.ValidateQuery[models.UserListQueryParams]).
+		Get("/", handlers.GetOneWithQueryHandler(s.GetUserList))
 
-	r.With(m.AuthorizeRole(models.RoleAdmin)).
+	r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceUser, rbac.ActionCreate)).
 		With(m.Validate[models.UserCreateBody]).Post("/", handlers.CreateHandler(s.CreateUser))
 
 	r.Route("/{id0}", func(r chi.Router) {
@@ -37,18 +46,30 @@ func (s UserService) Routes() chi.Router {
 		r.With(m.AuthorizeSelfOrAdmin(0)).
 			With(m.Validate[models.UserUpdateBody]).Patch("/", handlers.UpdateHandler(s.UpdateUser))
 
-		r.With(m.AuthorizeRole(models.RoleAdmin)).
-			Delete("/", handlers.DeleteHandler(s.DeleteUser))
+		r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceUser, rbac.ActionDelete)).
+			With(m.ValidateQuery[models.UserDeleteQueryParams]).
+			Delete("/", handlers.DeleteWithQueryHandler(s.DeleteUser))
 
 		r.With(m.AuthorizeSelfOrAdmin(0)).
 			Get("/stats", handlers.GetOneHandler(s.GetUserStats))
+
+		r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceUser, rbac.ActionUpdate)).
+			With(m.Validate[models.UserRoleUpdateBody]).
+			Put("/role", handlers.UpdateHandler(s.UpdateUserRole))
+
+		r.With(m.AuthorizeRole(s.PolicyEngine, rbac.ResourceUser, rbac.ActionUpdate)).
+			With(m.Validate[models.UserStatusUpdateBody]).
+			Put("/status", handlers.UpdateHandler(s.UpdateUserStatus))
+
+		r.With(m.AuthorizeSelfOrAdmin(0)).
+			Mount("/notifications", UserNotificationService{DB: s.DB}.Routes())
 	})
 	return r
 }
 
 func (s UserService) CreateUser(
 	logger *zap.Logger,
-	_ models.UserClaims,
+	user models.UserClaims,
 	_ uuid.UUIDs,
 	body models.UserCreateBody,
 ) (models.User, error) {
@@ -69,7 +90,14 @@ func (s UserService) CreateUser(
 		}
 		newUser.HashedPassword = hash
 
-		err = sql.CreateUserWithInvites(logger, s.DB, &newUser)
+		err = s.DB.Transaction(func(tx *gorm.DB) error {
+			if err = sql.CreateUserWithInvites(logger, tx, &newUser); err != nil {
+				return err
+			}
+			return activity.SendTx(s.ActivityLogger, tx, newUserActivity(
+				activity.UserCreated, rbac.ResourceUser, rbac.ActionCreate, user.UserID, newUser, nil,
+			))
+		})
 		if err != nil {
 			return models.User{}, apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
 		}
@@ -79,10 +107,112 @@ func (s UserService) CreateUser(
 	return models.User{}, errors.New("user already exists, try to reset your password")
 }
 
-func (s UserService) GetUserList(_ *zap.Logger, _ models.UserClaims, _ uuid.UUIDs) []models.User {
+// userListSortColumns maps the sort options to SQL expressions.
+// Nullable names are coalesced so that the keyset comparison also works on missing values.
+var userListSortColumns = map[string]string{
+	"created_at": "created_at",
+	"email":      "email",
+	"first_name": "COALESCE(first_name, '')",
+	"last_name":  "COALESCE(last_name, '')",
+}
+
+// GetUserList searches the users with keyset pagination: the cursor holds the sort value and the ID
+// of the last user of the previous page, which keeps pages stable while users are being created.
+func (s UserService) GetUserList(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	_ uuid.UUIDs,
+	query models.UserListQueryParams,
+) (models.Page[models.User], error) {
+	sort := query.Sort
+	if sort == "" {
+		sort = "created_at"
+	}
+	order := query.Order
+	if order == "" {
+		order = "asc"
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = configuration.UserListDefaultLimit
+	}
+	sortColumn := userListSortColumns[sort]
+
+	db := s.DB.Model(&models.User{})
+	if query.Search != "" {
+		pattern := "%" + escapeLikePattern(query.Search) + "%"
+		db = db.Where(
+			"email ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ? OR CONCAT_WS(' ', first_name, last_name) ILIKE ?",
+			pattern, pattern, pattern, pattern,
+		)
+	}
+	if query.Provider != "" {
+		db = db.Where("provider_key = ?", query.Provider)
+	}
+	if query.Role != "" {
+		db = db.Where("role = ?", query.Role)
+	}
+	switch query.Status {
+	case "active":
+		db = db.Where("disabled_at IS NULL")
+	case "disabled":
+		db = db.Where("disabled_at IS NOT NULL")
+	}
+
+	if query.Cursor != "" {
+		value, id, err := h.DecodeCursor(query.Cursor)
+		if err != nil {
+			return models.Page[models.User]{}, apierrors.NewAPIError(400, "INVALID_CURSOR")
+		}
+
+		var cursorValue any = value
+		if sort == "created_at" {
+			createdAt, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return models.Page[models.User]{}, apierrors.NewAPIError(400, "INVALID_CURSOR")
+			}
+			cursorValue = createdAt
+		}
+
+		operator := ">"
+		if order == "desc" {
+			operator = "<"
+		}
+		db = db.Where(fmt.Sprintf("(%s, id) %s (?, ?)", sortColumn, operator), cursorValue, id)
+	}
+
 	var users []models.User
-	s.DB.Find(&users)
-	return users
+	err := db.Order(fmt.Sprintf("%s %s, id %s", sortColumn, order, order)).Limit(limit + 1).Find(&users).Error
+	if err != nil {
+		logger.Error("Failed to list users", zap.Error(err))
+		return models.Page[models.User]{}, apierrors.NewAPIError(500, "INTERNAL_SERVER_ERROR")
+	}
+
+	page := models.Page[models.User]{Data: users}
+	if len(users) > limit {
+		page.Data = users[:limit]
+		last := users[limit-1]
+		page.NextCursor = h.EncodeCursor(userSortValue(last, sort), last.ID)
+	}
+	return page, nil
+}
+
+func userSortValue(user models.User, sort string) string {
+	switch sort {
+	case "email":
+		return user.Email
+	case "first_name":
+		return user.FirstName
+	case "last_name":
+		return user.LastName
+	default:
+		return user.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
+
+// escapeLikePattern escapes the wildcards of a LIKE pattern so that the search is literal.
+func escapeLikePattern(search string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search)
 }
 
 func (s UserService) GetUser(
@@ -109,6 +239,7 @@ func (s UserService) UpdateUser(
 	updatedUser := models.User{
 		FirstName: body.FirstName,
 		LastName:  body.LastName,
+		Language:  body.Language,
 	}
 
 	if body.OldPassword != "" && body.NewPassword != "" {
@@ -149,11 +280,163 @@ func (s UserService) UpdateUser(
 	return nil
 }
 
-func (s UserService) DeleteUser(logger *zap.Logger, user models.UserClaims, ids uuid.UUIDs) error {
+// UpdateUserRole changes the platform role of a user.
+// Administrators cannot change their own role, and the last active administrator cannot be demoted.
+func (s UserService) UpdateUserRole(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.UserRoleUpdateBody,
+) error {
+	target, err := s.getManagedUser(user, ids[0])
+	if err != nil {
+		return err
+	}
+
+	if target.Role == body.Role {
+		return nil
+	}
+
+	if target.Role == models.RoleAdmin && !target.IsDisabled() && !s.hasOtherActiveAdmin(target.ID) {
+		return apierrors.NewAPIError(409, "LAST_ADMIN")
+	}
+
+	previousRole := target.Role
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Model(&target).Update("role", body.Role).Error; err != nil {
+			return err
+		}
+		return activity.SendTx(s.ActivityLogger, tx, newUserActivity(
+			activity.UserRoleUpdated, rbac.ResourceUser, rbac.ActionUpdate, user.UserID, target,
+			map[string]string{"previous_role": string(previousRole), "role": string(body.Role)},
+		))
+	})
+	if err != nil {
+		logger.Error("Failed to update user role", zap.Error(err))
+		return apierrors.NewAPIError(500, "UPDATE_FAILED")
+	}
+	return nil
+}
+
+// UpdateUserStatus disables or re-enables a user account.
+// A disabled user keeps their data and memberships but can no longer sign in nor use their tokens.
+func (s UserService) UpdateUserStatus(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	body models.UserStatusUpdateBody,
+) error {
+	target, err := s.getManagedUser(user, ids[0])
+	if err != nil {
+		return err
+	}
+
+	if *body.Disabled == target.IsDisabled() {
+		return nil
+	}
+
+	var disabledAt *time.Time
+	if *body.Disabled {
+		if target.Role == models.RoleAdmin && !s.hasOtherActiveAdmin(target.ID) {
+			return apierrors.NewAPIError(409, "LAST_ADMIN")
+		}
+		now := time.Now()
+		disabledAt = &now
+	}
+
+	message := activity.UserEnabled
+	if *body.Disabled {
+		message = activity.UserDisabled
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err = tx.Model(&target).Update("disabled_at", disabledAt).Error; err != nil {
+			return err
+		}
+		return activity.SendTx(s.ActivityLogger, tx, newUserActivity(
+			message, rbac.ResourceUser, rbac.ActionUpdate, user.UserID, target, nil,
+		))
+	})
+	if err != nil {
+		logger.Error("Failed to update user status", zap.Error(err))
+		return apierrors.NewAPIError(500, "UPDATE_FAILED")
+	}
+	return nil
+}
+
+// getManagedUser loads the user targeted by an administration action, refusing actions on oneself.
+func (s UserService) getManagedUser(user models.UserClaims, userID uuid.UUID) (models.User, error) {
+	if user.UserID == userID {
+		return models.User{}, apierrors.NewAPIError(400, "CANNOT_MODIFY_SELF")
+	}
+
+	var target models.User
+	result := s.DB.Where("id = ?", userID).Find(&target)
+	if result.RowsAffected == 0 {
+		return models.User{}, apierrors.NewAPIError(404, "USER_NOT_FOUND")
+	}
+	return target, nil
+}
+
+func (s UserService) hasOtherActiveAdmin(userID uuid.UUID) bool {
+	var count int64
+	s.DB.Model(&models.User{}).
+		Where("role = ? AND disabled_at IS NULL AND id <> ?", models.RoleAdmin, userID).
+		Count(&count)
+	return count > 0
+}
+
+// DeleteUser soft-deletes a user. When the user is the sole owner of buckets,
+// the deletion is refused unless another user is given to take over their ownership.
+func (s UserService) DeleteUser(
+	logger *zap.Logger,
+	user models.UserClaims,
+	ids uuid.UUIDs,
+	query models.UserDeleteQueryParams,
+) error {
 	userID := ids[0]
 
-	err := s.DB.Transaction(func(tx *gorm.DB) error {
-		result := tx.Where("id = ?", userID).Delete(&models.User{})
+	soleOwned, err := rbac.GetSoleOwnedBuckets(s.DB, userID)
+	if err != nil {
+		logger.Error("Failed to fetch sole owned buckets", zap.Error(err))
+		return apierrors.ErrInternalServer
+	}
+
+	var transferTo models.User
+	if len(soleOwned) > 0 {
+		if query.TransferTo == "" {
+			return apierrors.NewAPIError(409, "USER_IS_SOLE_BUCKET_OWNER")
+		}
+
+		result := s.DB.Where("id = ?", query.TransferTo).Find(&transferTo)
+		if result.RowsAffected == 0 {
+			return apierrors.NewAPIError(404, "TRANSFER_TARGET_NOT_FOUND")
+		}
+		if transferTo.ID == userID {
+			return apierrors.NewAPIError(400, "CANNOT_TRANSFER_TO_SELF")
+		}
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		for _, bucket := range soleOwned {
+			if transferErr := transferBucketOwnership(
+				tx, s.ActivityLogger, bucket, nil, transferTo, user.UserID,
+			); transferErr != nil {
+				logger.Error(
+					"Failed to transfer bucket ownership",
+					zap.Error(transferErr),
+					zap.String("bucket_id", bucket.ID.String()),
+				)
+				return transferErr
+			}
+		}
+
+		var target models.User
+		if result := tx.Where("id = ?", userID).Find(&target); result.RowsAffected == 0 {
+			return errors.New("USER_NOT_FOUND")
+		}
+
+		result := tx.Delete(&target)
 		if result.RowsAffected == 0 {
 			return errors.New("USER_NOT_FOUND")
 		}
@@ -180,7 +463,9 @@ func (s UserService) DeleteUser(logger *zap.Logger, user models.UserClaims, ids
 
 		// Note: User's memberships will be cascade deleted by the foreign key constraint
 
-		return nil
+		return activity.SendTx(s.ActivityLogger, tx, newUserActivity(
+			activity.UserDeleted, rbac.ResourceUser, rbac.ActionDelete, user.UserID, target, nil,
+		))
 	})
 	if err != nil {
 		return apierrors.ErrInternalServer
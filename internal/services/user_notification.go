@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	c "api/internal/configuration"
+	apierrors "api/internal/errors"
+	"api/internal/events"
+	"api/internal/handlers"
+	"api/internal/helpers"
+	"api/internal/messaging"
+	m "api/internal/middlewares"
+	"api/internal/models"
+	"api/internal/rbac"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// UserNotificationService manages the notification preferences of a user.
+type UserNotificationService struct {
+	DB *gorm.DB
+}
+
+func (s UserNotificationService) Routes() chi.Router {
+	r := chi.NewRouter()
+
+	r.Get("/", handlers.GetListHandler(s.GetPreferenceList))
+
+	r.With(m.Validate[models.NotificationPreferenceBody]).
+		Put("/", handlers.CreateHandler(s.SavePreference))
+
+	r.Delete("/{id1}", handlers.DeleteHandler(s.DeletePreference))
+
+	return r
+}
+
+func (s UserNotificationService) GetPreferenceList(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	ids uuid.UUIDs,
+) []models.NotificationPreference {
+	var preferences []models.NotificationPreference
+	err := s.DB.Where("user_id = ?", ids[0]).Order("bucket_id NULLS FIRST, created_at").Find(&preferences).Error
+	if err != nil {
+		logger.Error("Failed to fetch notification preferences", zap.Error(err))
+		return []models.NotificationPreference{}
+	}
+	return preferences
+}
+
+// SavePreference creates or replaces the preference of the user for a bucket, or for all their buckets.
+// The next digest covers the activity from the moment the preference is enabled or its frequency changes.
+func (s UserNotificationService) SavePreference(
+	logger *zap.Logger,
+	_ models.UserClaims,
+	ids uuid.UUIDs,
+	body models.NotificationPreferenceBody,
+) (models.NotificationPreference, error) {
+	userID := ids[0]
+
+	if body.BucketID != nil {
+		membership, err := rbac.GetUserMembership(s.DB, userID, *body.BucketID)
+		if err != nil || membership == nil {
+			return models.NotificationPreference{}, apierrors.NewAPIError(404, "BUCKET_NOT_FOUND")
+		}
+	}
+
+	enabled := body.Enabled == nil || *body.Enabled
+
+	var preference models.NotificationPreference
+	query := s.DB.Where("user_id = ?", userID)
+	if body.BucketID != nil {
+		query = query.Where("bucket_id = ?", *body.BucketID)
+	} else {
+		query = query.Where("bucket_id IS NULL")
+	}
+	err := query.First(&preference).Error
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		token, tokenErr := helpers.RandString(c.NotificationUnsubscribeTokenBytes)
+		if tokenErr != nil {
+			logger.Error("Failed to generate unsubscribe token", zap.Error(tokenErr))
+			return models.NotificationPreference{}, apierrors.ErrCreateFailed
+		}
+		preference = models.NotificationPreference{
+			UserID:           userID,
+			BucketID:         body.BucketID,
+			UnsubscribeToken: token,
+			LastDigestAt:     time.Now(),
+		}
+	case err != nil:
+		logger.Error("Failed to fetch notification preference", zap.Error(err))
+		return models.NotificationPreference{}, apierrors.ErrCreateFailed
+	case (enabled && !preference.Enabled) || body.Frequency != preference.Frequency:
+		preference.LastDigestAt = time.Now()
+	}
+
+	preference.EventTypes = body.EventTypes
+	preference.Frequency = body.Frequency
+	preference.Enabled = enabled
+
+	if err = s.DB.Omit("User").Save(&preference).Error; err != nil {
+		logger.Error("Failed to save notification preference", zap.Error(err))
+		return models.NotificationPreference{}, apierrors.ErrCreateFailed
+	}
+	return preference, nil
+}
+
+func (s UserNotificationService) DeletePreference(logger *zap.Logger, _ models.UserClaims, ids uuid.UUIDs) error {
+	result := s.DB.Where("id = ? AND user_id = ?", ids[1], ids[0]).Delete(&models.NotificationPreference{})
+	if result.Error != nil {
+		logger.Error("Failed to delete notification preference", zap.Error(result.Error))
+		return apierrors.ErrDeleteFailed
+	}
+	if result.RowsAffected == 0 {
+		return apierrors.NewAPIError(404, "PREFERENCE_NOT_FOUND")
+	}
+	return nil
+}
+
+// notifyBucketActivity emails the members of a bucket who opted in to be notified immediately of an activity.
+func notifyBucketActivity(
+	publisher messaging.IPublisher,
+	bucketID uuid.UUID,
+	user models.UserClaims,
+	eventType string,
+	folderID *uuid.UUID,
+	objectName string,
+) {
+	notificationEvent := events.NewBucketActivityNotification(
+		publisher, bucketID, user.UserID, eventType, folderID, objectName,
+	)
+	notificationEvent.Trigger()
+}
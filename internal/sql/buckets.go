@@ -0,0 +1,47 @@
+package sql
+
+import (
+	"sort"
+
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// GetBucketTrashRetention returns the number of days the trashed files and folders of a bucket are kept,
+// which is the retention of the platform unless the bucket sets its own.
+func GetBucketTrashRetention(db *gorm.DB, bucketID uuid.UUID, defaultDays int) (int, error) {
+	var bucket models.Bucket
+	err := db.Unscoped().Select("id", "trash_retention_days").Where("id = ?", bucketID).First(&bucket).Error
+	if err != nil {
+		return 0, err
+	}
+	return bucket.TrashRetention(defaultDays), nil
+}
+
+// trashRetentionsLock is the key of the advisory lock serializing the configurations of the trash lifecycle policy.
+const trashRetentionsLock = "safebucket:trash_retentions"
+
+// LockTrashRetentions takes the advisory lock serializing the configurations of the trash lifecycle policy
+// with the retentions in use, until the end of the transaction. The retentions are then read with GetTrashRetentions,
+// so that a configuration never removes the rule of a retention set concurrently.
+func LockTrashRetentions(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", trashRetentionsLock).Error
+}
+
+// GetTrashRetentions returns the distinct retentions of the trash in use, including the retention of the platform.
+func GetTrashRetentions(db *gorm.DB, defaultDays int) ([]int, error) {
+	var retentions []int
+	err := db.Model(&models.Bucket{}).
+		Where("trash_retention_days IS NOT NULL AND trash_retention_days != ?", defaultDays).
+		Distinct().
+		Pluck("trash_retention_days", &retentions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	retentions = append(retentions, defaultDays)
+	sort.Ints(retentions)
+	return retentions, nil
+}
@@ -0,0 +1,50 @@
+package sql
+
+import (
+	"time"
+
+	"api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// lockedCondition matches the files and buckets under a legal hold or a retention date not yet passed.
+const lockedCondition = "legal_hold OR retain_until > ?"
+
+// IsBucketLocked checks if the bucket is under a legal hold or a retention date, including once deleted.
+func IsBucketLocked(db *gorm.DB, bucketID uuid.UUID) (bool, error) {
+	var count int64
+	err := db.Unscoped().Model(&models.Bucket{}).
+		Where("id = ?", bucketID).
+		Where(lockedCondition, time.Now()).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// IsRetentionLocked checks if the files of a bucket, or of a folder and its subfolders when folderID is set,
+// cannot be trashed or purged: the bucket or one of the files, trashed or not, is under a retention lock.
+func IsRetentionLocked(db *gorm.DB, bucketID uuid.UUID, folderID *uuid.UUID) (bool, error) {
+	locked, err := IsBucketLocked(db, bucketID)
+	if err != nil || locked {
+		return locked, err
+	}
+
+	query := db.Unscoped().Model(&models.File{}).
+		Where("bucket_id = ?", bucketID).
+		Where(lockedCondition, time.Now())
+	if folderID != nil {
+		subtree := db.Raw(`
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM folders WHERE id = ?
+				UNION ALL
+				SELECT folders.id FROM folders JOIN subtree ON folders.folder_id = subtree.id
+			)
+			SELECT id FROM subtree`, folderID)
+		query = query.Where("folder_id IN (?)", subtree)
+	}
+
+	var count int64
+	err = query.Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,82 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+func TestIsRetentionLocked(t *testing.T) {
+	bucketID, folderID := uuid.New(), uuid.New()
+
+	testCases := []struct {
+		name         string
+		folderID     *uuid.UUID
+		bucketLocked bool
+		lockedFiles  int
+		expected     bool
+	}{
+		{name: "Locked bucket", bucketLocked: true, expected: true},
+		{name: "Locked file in the bucket", lockedFiles: 1, expected: true},
+		{name: "Locked file in the folder subtree", folderID: &folderID, lockedFiles: 1, expected: true},
+		{name: "No locked file in the folder subtree", folderID: &folderID},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			db, dbMock := newMockDB(t)
+
+			bucketCount := 0
+			if tt.bucketLocked {
+				bucketCount = 1
+			}
+			dbMock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "buckets" WHERE id = $1 AND (legal_hold OR`)).
+				WithArgs(bucketID, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(bucketCount))
+
+			// The files of the bucket are only checked when the bucket itself is not locked
+			if !tt.bucketLocked {
+				query := `SELECT count(*) FROM "files" WHERE bucket_id = $1 AND (legal_hold OR retain_until > $2)`
+				args := []driver.Value{bucketID, sqlmock.AnyArg()}
+				if tt.folderID != nil {
+					// Files in the folder and all its subfolders, trashed or not
+					query += ` AND folder_id IN (
+			WITH RECURSIVE subtree AS (
+				SELECT id FROM folders WHERE id = $3
+				UNION ALL
+				SELECT folders.id FROM folders JOIN subtree ON folders.folder_id = subtree.id
+			)
+			SELECT id FROM subtree)`
+					args = append(args, *tt.folderID)
+				}
+				dbMock.ExpectQuery(regexp.QuoteMeta(query) + "$").
+					WithArgs(args...).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.lockedFiles))
+			}
+
+			locked, err := IsRetentionLocked(db, bucketID, tt.folderID)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, locked)
+			assert.NoError(t, dbMock.ExpectationsWereMet())
+		})
+	}
+}
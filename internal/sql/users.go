@@ -1,6 +1,8 @@
 package sql
 
 import (
+	"time"
+
 	"api/internal/models"
 	"api/internal/rbac"
 
@@ -10,6 +12,7 @@ import (
 
 // CreateUserWithInvites creates a user and processes any pending invites
 // Converting them to memberships in a single transaction.
+// Expired invites are discarded, time-limited invites keep their expiry on the membership.
 func CreateUserWithInvites(
 	logger *zap.Logger,
 	db *gorm.DB,
@@ -27,8 +30,18 @@ func CreateUserWithInvites(
 			return err
 		}
 
+		now := time.Now()
 		for _, invite := range invites {
-			if err := rbac.CreateMembership(tx, user.ID, invite.BucketID, invite.Group); err != nil {
+			if invite.ExpiresAt != nil && !invite.ExpiresAt.After(now) {
+				if err := tx.Delete(&invite).Error; err != nil {
+					logger.Error("Failed to delete expired invite", zap.Error(err),
+						zap.String("invite_id", invite.ID.String()))
+					return err
+				}
+				continue
+			}
+
+			if err := rbac.CreateMembership(tx, user.ID, invite.BucketID, invite.Group, invite.ExpiresAt); err != nil {
 				logger.Error("Failed to create membership from invite", zap.Error(err),
 					zap.String("group", string(invite.Group)),
 					zap.String("bucket_id", invite.BucketID.String()))
@@ -45,3 +58,17 @@ func CreateUserWithInvites(
 		return nil
 	})
 }
+
+// GetUserLanguage returns the preferred language of the user with the given email,
+// or an empty string when the recipient is not a user or has no preference.
+func GetUserLanguage(db *gorm.DB, email string) string {
+	var languages []string
+	err := db.Model(&models.User{}).
+		Where("email = ? AND language IS NOT NULL", email).
+		Limit(1).
+		Pluck("language", &languages).Error
+	if err != nil || len(languages) == 0 {
+		return ""
+	}
+	return languages[0]
+}
@@ -3,9 +3,12 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"math"
+	"io"
+	"net/url"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"go.uber.org/zap"
 )
 
@@ -72,6 +76,7 @@ func (a AWSStorage) PresignedPostPolicy(
 	path string,
 	size int,
 	metadata map[string]string,
+	checksum *models.FileChecksum,
 ) (string, map[string]string, error) {
 	req := &s3.PutObjectInput{
 		Bucket:        aws.String(a.BucketName),
@@ -93,6 +98,12 @@ func (a AWSStorage) PresignedPostPolicy(
 		})
 	}
 
+	// S3 verifies the SHA-256 and CRC32C checksums on upload, MD5 is only verified once uploaded
+	checksumFields := awsChecksumFields(checksum)
+	for key, value := range checksumFields {
+		conditions = append(conditions, map[string]string{key: value})
+	}
+
 	presignedPost, err := a.presigner.PresignPostObject(
 		context.Background(),
 		req,
@@ -108,12 +119,61 @@ func (a AWSStorage) PresignedPostPolicy(
 		key := "x-amz-meta-" + field
 		presignedPost.Values[key] = metadata[field]
 	}
+	for key, value := range checksumFields {
+		presignedPost.Values[key] = value
+	}
 
 	return presignedPost.URL, presignedPost.Values, nil
 }
 
-func (a AWSStorage) StatObject(path string) (map[string]string, error) {
+// awsChecksumFields returns the form fields declaring the checksum of an upload, when S3 supports the algorithm.
+func awsChecksumFields(checksum *models.FileChecksum) map[string]string {
+	if checksum == nil {
+		return nil
+	}
+
+	switch checksum.Algorithm {
+	case models.ChecksumAlgorithmSHA256:
+		return map[string]string{"x-amz-checksum-algorithm": "SHA256", "x-amz-checksum-sha256": checksum.Value}
+	case models.ChecksumAlgorithmCRC32C:
+		return map[string]string{"x-amz-checksum-algorithm": "CRC32C", "x-amz-checksum-crc32c": checksum.Value}
+	default:
+		return nil
+	}
+}
+
+func (a AWSStorage) StatObject(path string) (ObjectInfo, error) {
 	file, err := a.storage.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket:       aws.String(a.BucketName),
+		Key:          aws.String(path),
+		ChecksumMode: types.ChecksumModeEnabled,
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return ObjectInfo{}, ErrObjectNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	checksums := map[models.ChecksumAlgorithm]string{}
+	if file.ChecksumSHA256 != nil {
+		checksums[models.ChecksumAlgorithmSHA256] = *file.ChecksumSHA256
+	}
+	if file.ChecksumCRC32C != nil {
+		checksums[models.ChecksumAlgorithmCRC32C] = *file.ChecksumCRC32C
+	}
+
+	return ObjectInfo{
+		Size:      aws.ToInt64(file.ContentLength),
+		Metadata:  file.Metadata,
+		Checksums: checksums,
+	}, nil
+}
+
+// GetObject returns the content of an object, which the caller must close.
+func (a AWSStorage) GetObject(path string) (io.ReadCloser, error) {
+	file, err := a.storage.GetObject(context.Background(), &s3.GetObjectInput{
 		Bucket: aws.String(a.BucketName),
 		Key:    aws.String(path),
 	})
@@ -121,7 +181,7 @@ func (a AWSStorage) StatObject(path string) (map[string]string, error) {
 		return nil, err
 	}
 
-	return file.Metadata, err
+	return file.Body, nil
 }
 
 func (a AWSStorage) ListObjects(prefix string, maxKeys int32) ([]string, error) {
@@ -145,6 +205,28 @@ func (a AWSStorage) ListObjects(prefix string, maxKeys int32) ([]string, error)
 	return objects, nil
 }
 
+// WalkObjects calls fn with the key of each object under the prefix, across all the pages of the listing.
+func (a AWSStorage) WalkObjects(prefix string, fn func(key string) error) error {
+	paginator := s3.NewListObjectsV2Paginator(a.storage, &s3.ListObjectsV2Input{
+		Bucket: aws.String(a.BucketName),
+		Prefix: aws.String(prefix),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, obj := range page.Contents {
+			if err = fn(aws.ToString(obj.Key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (a AWSStorage) RemoveObject(path string) error {
 	_, err := a.storage.DeleteObject(context.Background(), &s3.DeleteObjectInput{
 		Bucket: aws.String(a.BucketName),
@@ -261,6 +343,57 @@ func (a AWSStorage) RemoveObjectTags(path string, tagsToRemove []string) error {
 	return err
 }
 
+// SetObjectRetention maps the retention lock of a file to the legal hold and the retention of S3 Object Lock,
+// in governance mode, when the bucket has Object Lock enabled.
+func (a AWSStorage) SetObjectRetention(path string, lock models.RetentionLock) error {
+	ctx := context.Background()
+
+	config, err := a.storage.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(a.BucketName),
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ObjectLockConfigurationNotFoundError" {
+		return ErrRetentionNotSupported
+	}
+	if err != nil {
+		return err
+	}
+	if config.ObjectLockConfiguration == nil ||
+		config.ObjectLockConfiguration.ObjectLockEnabled != types.ObjectLockEnabledEnabled {
+		return ErrRetentionNotSupported
+	}
+
+	status := types.ObjectLockLegalHoldStatusOff
+	if lock.LegalHold {
+		status = types.ObjectLockLegalHoldStatusOn
+	}
+	_, err = a.storage.PutObjectLegalHold(ctx, &s3.PutObjectLegalHoldInput{
+		Bucket:    aws.String(a.BucketName),
+		Key:       aws.String(path),
+		LegalHold: &types.ObjectLockLegalHold{Status: status},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set the legal hold: %w", err)
+	}
+
+	// The retention date is only extended, it expires by itself once passed
+	if lock.RetainUntil != nil {
+		_, err = a.storage.PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+			Bucket: aws.String(a.BucketName),
+			Key:    aws.String(path),
+			Retention: &types.ObjectLockRetention{
+				Mode:            types.ObjectLockRetentionModeGovernance,
+				RetainUntilDate: lock.RetainUntil,
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set the retention: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // IsTrashMarkerPath checks if a deletion event is for a trash marker.
 // Patterns:
 //   - trash/{bucket-id}/files/{file-id} -> buckets/{bucket-id}/{file-id}
@@ -319,7 +452,8 @@ func (a AWSStorage) getTrashMarkerPath(objectPath string, model interface{}) str
 	return path.Join(trashPrefix, bucketID, resourceType, resourceID)
 }
 
-func (a AWSStorage) MarkAsTrashed(objectPath string, object interface{}) error {
+// MarkAsTrashed creates the trash marker of an object, tagged with its retention to be expired by the matching rule.
+func (a AWSStorage) MarkAsTrashed(objectPath string, object interface{}, retentionDays int) error {
 	ctx := context.Background()
 	markerPath := a.getTrashMarkerPath(objectPath, object)
 
@@ -333,12 +467,18 @@ func (a AWSStorage) MarkAsTrashed(objectPath string, object interface{}) error {
 		}
 	}
 
+	tags := url.Values{}
+	for key, value := range trashRetentionTags(retentionDays) {
+		tags.Set(key, value)
+	}
+
 	// Create empty marker object to trigger lifecycle policy deletion
 	reader := bytes.NewReader([]byte{})
 	_, err := a.storage.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(a.BucketName),
-		Key:    aws.String(markerPath),
-		Body:   reader,
+		Bucket:  aws.String(a.BucketName),
+		Key:     aws.String(markerPath),
+		Body:    reader,
+		Tagging: aws.String(tags.Encode()),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create marker: %w", err)
@@ -362,85 +502,80 @@ func (a AWSStorage) UnmarkAsTrashed(objectPath string, object interface{}) error
 	return nil
 }
 
-// isTrashLifecycleRuleUpToDate checks if an existing lifecycle rule matches the desired trash retention configuration.
-func isTrashLifecycleRuleUpToDate(rule types.LifecycleRule, retentionDays int) bool {
-	if rule.Expiration == nil || rule.Expiration.Days == nil {
-		return false
-	}
-	if *rule.Expiration.Days != int32(retentionDays) { //nolint:gosec // validated in EnsureTrashLifecyclePolicy
-		return false
-	}
-	if rule.Filter == nil || rule.Filter.Prefix == nil {
-		return false
-	}
-	if *rule.Filter.Prefix != trashPrefix {
-		return false
-	}
-
-	return true
-}
-
 // processExistingLifecycleRules processes existing lifecycle rules and returns the updated rules list.
+// The rules of the retentions which are not requested are removed, the trash markers still tagged with them
+// being expired by the TrashExpiration job, and so is the rule expiring all the markers after the same period.
 func (a AWSStorage) processExistingLifecycleRules(
 	existingConfig *s3.GetBucketLifecycleConfigurationOutput,
 	err error,
-	trashRuleID, multipartRuleID string,
-	trashRule, multipartRule types.LifecycleRule,
-	retentionDays int,
-) ([]types.LifecycleRule, bool, bool) {
+	multipartRuleID string,
+	trashRules map[string]types.LifecycleRule,
+	multipartRule types.LifecycleRule,
+) []types.LifecycleRule {
 	var rules []types.LifecycleRule
-	trashRuleFound := false
 	multipartRuleFound := false
 
-	if err != nil || existingConfig == nil {
-		// No existing config - return new rules with flags set to true
-		// to prevent duplicate addition in the caller
-		return []types.LifecycleRule{trashRule, multipartRule}, true, true
-	}
-
-	for _, rule := range existingConfig.Rules {
-		if rule.ID == nil {
-			rules = append(rules, rule)
-			continue
-		}
-
-		switch *rule.ID {
-		case trashRuleID:
-			if isTrashLifecycleRuleUpToDate(rule, retentionDays) {
-				zap.L().Debug("Trash lifecycle policy already up-to-date",
-					zap.String("bucket", a.BucketName),
-					zap.Int("retentionDays", retentionDays))
+	if err == nil && existingConfig != nil {
+		for _, rule := range existingConfig.Rules {
+			if rule.ID == nil {
+				rules = append(rules, rule)
+				continue
 			}
-			trashRuleFound = true
-			rules = append(rules, trashRule)
-
-		case multipartRuleID:
-			multipartRuleFound = true
-			if rule.AbortIncompleteMultipartUpload != nil &&
-				rule.AbortIncompleteMultipartUpload.DaysAfterInitiation != nil &&
-				*rule.AbortIncompleteMultipartUpload.DaysAfterInitiation == 1 {
-				zap.L().Debug("Multipart upload cleanup policy already up-to-date",
+
+			switch {
+			case *rule.ID == trashRuleID:
+				zap.L().Info("Removing the trash lifecycle policy shared by all retentions",
 					zap.String("bucket", a.BucketName))
+
+			case isTrashRetentionRule(*rule.ID):
+				if _, ok := trashRules[*rule.ID]; !ok {
+					zap.L().Info("Removing the trash lifecycle policy of an unused retention",
+						zap.String("bucket", a.BucketName),
+						zap.String("rule", *rule.ID))
+				}
+
+			case *rule.ID == multipartRuleID:
+				multipartRuleFound = true
+				if rule.AbortIncompleteMultipartUpload != nil &&
+					rule.AbortIncompleteMultipartUpload.DaysAfterInitiation != nil &&
+					*rule.AbortIncompleteMultipartUpload.DaysAfterInitiation == 1 {
+					zap.L().Debug("Multipart upload cleanup policy already up-to-date",
+						zap.String("bucket", a.BucketName))
+					rules = append(rules, rule)
+				} else {
+					rules = append(rules, multipartRule)
+				}
+
+			default:
 				rules = append(rules, rule)
-			} else {
-				rules = append(rules, multipartRule)
 			}
-
-		default:
-			rules = append(rules, rule)
 		}
 	}
 
-	return rules, trashRuleFound, multipartRuleFound
+	ruleIDs := make([]string, 0, len(trashRules))
+	for ruleID := range trashRules {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Strings(ruleIDs)
+	for _, ruleID := range ruleIDs {
+		rules = append(rules, trashRules[ruleID])
+	}
+
+	if !multipartRuleFound {
+		rules = append(rules, multipartRule)
+	}
+
+	return rules
 }
 
-func (a AWSStorage) EnsureTrashLifecyclePolicy(retentionDays int) error {
-	const trashRuleID = "safebucket-trash-retention"
+// EnsureTrashLifecyclePolicy configures a lifecycle rule for each retention in use, expiring the trash markers
+// tagged with it, merged with the existing rules. Long retentions share the rule of their rounded up retention.
+func (a AWSStorage) EnsureTrashLifecyclePolicy(retentionDays []int) error {
 	const multipartRuleID = "safebucket-abort-incomplete-multipart"
 
 	// Validate retentionDays fits in int32 to prevent overflow
-	if retentionDays < 0 || retentionDays > math.MaxInt32 {
-		return fmt.Errorf("retentionDays %d is out of valid range (0-%d)", retentionDays, math.MaxInt32)
+	if err := validateRetentions(retentionDays); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
@@ -449,15 +584,27 @@ func (a AWSStorage) EnsureTrashLifecyclePolicy(retentionDays int) error {
 		Bucket: aws.String(a.BucketName),
 	})
 
-	trashRule := types.LifecycleRule{
-		ID:     aws.String(trashRuleID),
-		Status: types.ExpirationStatusEnabled,
-		Filter: &types.LifecycleRuleFilter{
-			Prefix: aws.String(trashPrefix),
-		},
-		Expiration: &types.LifecycleExpiration{
-			Days: aws.Int32(int32(retentionDays)),
-		},
+	trashRules := map[string]types.LifecycleRule{}
+	for _, days := range trashRuleRetentions(retentionDays) {
+		var tags []types.Tag
+		for key, value := range trashRetentionTags(days) {
+			tags = append(tags, types.Tag{Key: aws.String(key), Value: aws.String(value)})
+		}
+
+		ruleID := trashRetentionRuleID(days)
+		trashRules[ruleID] = types.LifecycleRule{
+			ID:     aws.String(ruleID),
+			Status: types.ExpirationStatusEnabled,
+			Filter: &types.LifecycleRuleFilter{
+				And: &types.LifecycleRuleAndOperator{
+					Prefix: aws.String(trashPrefix),
+					Tags:   tags,
+				},
+			},
+			Expiration: &types.LifecycleExpiration{
+				Days: aws.Int32(int32(days)), //nolint:gosec // validated above
+			},
+		}
 	}
 
 	multipartRule := types.LifecycleRule{
@@ -471,42 +618,25 @@ func (a AWSStorage) EnsureTrashLifecyclePolicy(retentionDays int) error {
 		},
 	}
 
-	rules, trashRuleFound, multipartRuleFound := a.processExistingLifecycleRules(
-		existingConfig,
-		err,
-		trashRuleID,
-		multipartRuleID,
-		trashRule,
-		multipartRule,
-		retentionDays,
-	)
+	rules := a.processExistingLifecycleRules(existingConfig, err, multipartRuleID, trashRules, multipartRule)
 
-	if !trashRuleFound {
-		rules = append(rules, trashRule)
-	}
-	if !multipartRuleFound {
-		rules = append(rules, multipartRule)
-	}
-
-	{
-		_, err = a.storage.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
-			Bucket: aws.String(a.BucketName),
-			LifecycleConfiguration: &types.BucketLifecycleConfiguration{
-				Rules: rules,
-			},
-		})
-		if err != nil {
-			zap.L().Error("Failed to set lifecycle policies",
-				zap.String("bucket", a.BucketName),
-				zap.Int("trashRetentionDays", retentionDays),
-				zap.Error(err))
-			return err
-		}
-
-		zap.L().Info("Lifecycle policies configured",
+	_, err = a.storage.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket: aws.String(a.BucketName),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{
+			Rules: rules,
+		},
+	})
+	if err != nil {
+		zap.L().Error("Failed to set lifecycle policies",
 			zap.String("bucket", a.BucketName),
-			zap.Int("trashRetentionDays", retentionDays),
-			zap.Int("multipartCleanupDays", 1))
-		return nil
+			zap.Ints("trashRetentionDays", retentionDays),
+			zap.Error(err))
+		return err
 	}
+
+	zap.L().Info("Lifecycle policies configured",
+		zap.String("bucket", a.BucketName),
+		zap.Ints("trashRetentionDays", retentionDays),
+		zap.Int("multipartCleanupDays", 1))
+	return nil
 }
@@ -2,8 +2,11 @@ package storage
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"path"
 	"strings"
@@ -62,7 +65,9 @@ func (g GCPStorage) PresignedPostPolicy(
 	path string,
 	size int,
 	metadata map[string]string,
+	_ *models.FileChecksum,
 ) (string, map[string]string, error) {
+	// The POST policies of GCS cannot declare a checksum, it is verified once uploaded
 	opts := &gcs.PostPolicyV4Options{
 		Expires: time.Now().Add(c.UploadPolicyExpirationInMinutes * time.Minute),
 		Fields: &gcs.PolicyV4Fields{
@@ -86,17 +91,66 @@ func (g GCPStorage) PresignedPostPolicy(
 	return postPolicy.URL, postPolicy.Fields, nil
 }
 
-func (g GCPStorage) StatObject(path string) (map[string]string, error) {
+func (g GCPStorage) StatObject(path string) (ObjectInfo, error) {
 	file, err := g.storage.Bucket(g.BucketName).Object(path).Attrs(context.Background())
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return ObjectInfo{}, ErrObjectNotFound
+	}
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+
+	crc32c := binary.BigEndian.AppendUint32(nil, file.CRC32C)
+	checksums := map[models.ChecksumAlgorithm]string{
+		models.ChecksumAlgorithmCRC32C: base64.StdEncoding.EncodeToString(crc32c),
+	}
+	// Composite objects have no MD5
+	if len(file.MD5) > 0 {
+		checksums[models.ChecksumAlgorithmMD5] = base64.StdEncoding.EncodeToString(file.MD5)
+	}
+
+	return ObjectInfo{
+		Size:      file.Size,
+		Metadata:  file.Metadata,
+		Checksums: checksums,
+	}, nil
+}
+
+// GetObject returns the content of an object, which the caller must close.
+func (g GCPStorage) GetObject(path string) (io.ReadCloser, error) {
+	reader, err := g.storage.Bucket(g.BucketName).Object(path).NewReader(context.Background())
 	if err != nil {
 		return nil, err
 	}
 
-	return file.Metadata, err
+	return reader, nil
+}
+
+// WalkObjects calls fn with the key of each object under the prefix, across all the pages of the listing.
+func (g GCPStorage) WalkObjects(prefix string, fn func(key string) error) error {
+	it := g.storage.Bucket(g.BucketName).Objects(context.Background(), &gcs.Query{Prefix: prefix})
+
+	for {
+		attrs, err := it.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(attrs.Name); err != nil {
+			return err
+		}
+	}
 }
 
+// RemoveObject removes an object. Like on S3, removing an object which does not exist succeeds.
 func (g GCPStorage) RemoveObject(path string) error {
-	return g.storage.Bucket(g.BucketName).Object(path).Delete(context.Background())
+	err := g.storage.Bucket(g.BucketName).Object(path).Delete(context.Background())
+	if errors.Is(err, gcs.ErrObjectNotExist) {
+		return nil
+	}
+	return err
 }
 
 func (g GCPStorage) RemoveObjects(paths []string) error {
@@ -199,6 +253,36 @@ func (g GCPStorage) RemoveObjectTags(path string, tagsToRemove []string) error {
 	return err
 }
 
+// SetObjectRetention maps the legal hold of a file to the temporary hold of the object, and its retention date
+// to the retention of the object, in unlocked mode, when the bucket has object retention enabled.
+func (g GCPStorage) SetObjectRetention(path string, lock models.RetentionLock) error {
+	ctx := context.Background()
+	bucket := g.storage.Bucket(g.BucketName)
+
+	update := gcs.ObjectAttrsToUpdate{TemporaryHold: lock.LegalHold}
+
+	supported := true
+	if lock.RetainUntil != nil {
+		attrs, err := bucket.Attrs(ctx)
+		if err != nil {
+			return err
+		}
+		supported = attrs.ObjectRetentionMode == "Enabled"
+		if supported {
+			update.Retention = &gcs.ObjectRetention{Mode: "Unlocked", RetainUntil: *lock.RetainUntil}
+		}
+	}
+
+	if _, err := bucket.Object(path).Update(ctx, update); err != nil {
+		return fmt.Errorf("failed to set the retention: %w", err)
+	}
+
+	if !supported {
+		return ErrRetentionNotSupported
+	}
+	return nil
+}
+
 // IsTrashMarkerPath checks if a deletion event is for a trash marker.
 // Patterns:
 //   - trash/{bucket-id}/files/{file-id} -> buckets/{bucket-id}/{file-id}
@@ -257,7 +341,9 @@ func (g GCPStorage) getTrashMarkerPath(objectPath string, model interface{}) str
 	return path.Join(trashPrefix, bucketID, resourceType, resourceID)
 }
 
-func (g GCPStorage) MarkAsTrashed(objectPath string, object interface{}) error {
+// MarkAsTrashed creates the trash marker of an object. Lifecycle conditions cannot match tags, so the custom time
+// of the marker is set to a day before its expiration, which the lifecycle rule deletes a day later.
+func (g GCPStorage) MarkAsTrashed(objectPath string, object interface{}, retentionDays int) error {
 	ctx := context.Background()
 	markerPath := g.getTrashMarkerPath(objectPath, object)
 
@@ -271,6 +357,8 @@ func (g GCPStorage) MarkAsTrashed(objectPath string, object interface{}) error {
 	// Create empty marker object to trigger lifecycle policy deletion
 	markerObj := g.storage.Bucket(g.BucketName).Object(markerPath)
 	writer := markerObj.NewWriter(ctx)
+	writer.Metadata = trashRetentionTags(retentionDays)
+	writer.CustomTime = time.Now().AddDate(0, 0, retentionDays-1)
 
 	// Write empty content (0 bytes)
 	if _, err := writer.Write([]byte{}); err != nil {
@@ -296,7 +384,13 @@ func (g GCPStorage) UnmarkAsTrashed(objectPath string, object interface{}) error
 	return nil
 }
 
-func (g GCPStorage) EnsureTrashLifecyclePolicy(retentionDays int) error {
+// EnsureTrashLifecyclePolicy configures the lifecycle rule deleting the trash markers a day after their custom time,
+// which holds the retention of each marker, merged with the existing rules.
+func (g GCPStorage) EnsureTrashLifecyclePolicy(retentionDays []int) error {
+	if err := validateRetentions(retentionDays); err != nil {
+		return err
+	}
+
 	ctx := context.Background()
 	bucket := g.storage.Bucket(g.BucketName)
 
@@ -315,17 +409,16 @@ func (g GCPStorage) EnsureTrashLifecyclePolicy(retentionDays int) error {
 
 	if attrs.Lifecycle.Rules != nil {
 		for i, rule := range attrs.Lifecycle.Rules {
-			// Check for trash expiration rule
+			// Check for trash expiration rule, which expired the markers by age before the retention of the buckets
 			if rule.Action.Type == trashRuleActionType &&
 				rule.Condition.MatchesPrefix != nil &&
 				len(rule.Condition.MatchesPrefix) > 0 &&
 				rule.Condition.MatchesPrefix[0] == trashPrefix {
 				existingTrashRuleIndex = i
 
-				if rule.Condition.AgeInDays == int64(retentionDays) {
+				if rule.Condition.DaysSinceCustomTime == 1 && rule.Condition.AgeInDays == 0 {
 					zap.L().Debug("Trash lifecycle policy already up-to-date",
-						zap.String("bucket", g.BucketName),
-						zap.Int("retentionDays", retentionDays))
+						zap.String("bucket", g.BucketName))
 					// Don't return yet - need to check multipart rule too
 				}
 			}
@@ -345,8 +438,8 @@ func (g GCPStorage) EnsureTrashLifecyclePolicy(retentionDays int) error {
 			Type: trashRuleActionType,
 		},
 		Condition: gcs.LifecycleCondition{
-			AgeInDays:     int64(retentionDays),
-			MatchesPrefix: []string{trashPrefix},
+			DaysSinceCustomTime: 1,
+			MatchesPrefix:       []string{trashPrefix},
 		},
 	}
 
@@ -379,14 +472,14 @@ func (g GCPStorage) EnsureTrashLifecyclePolicy(retentionDays int) error {
 	if _, err = bucket.Update(ctx, updateAttrs); err != nil {
 		zap.L().Error("Failed to update lifecycle policies",
 			zap.String("bucket", g.BucketName),
-			zap.Int("trashRetentionDays", retentionDays),
+			zap.Ints("trashRetentionDays", retentionDays),
 			zap.Error(err))
 		return err
 	}
 
 	zap.L().Info("Lifecycle policies configured",
 		zap.String("bucket", g.BucketName),
-		zap.Int("trashRetentionDays", retentionDays),
+		zap.Ints("trashRetentionDays", retentionDays),
 		zap.Int("multipartCleanupDays", 1))
 
 	return nil
@@ -1,5 +1,12 @@
 package storage
 
+import (
+	"errors"
+	"io"
+
+	"api/internal/models"
+)
+
 const (
 	bucketsPrefix = "buckets/"
 	trashPrefix   = "trash/"
@@ -7,23 +14,53 @@ const (
 	filePath      = "files"
 )
 
+// ErrObjectNotFound is returned by StatObject when the object does not exist.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrRetentionNotSupported is returned by SetObjectRetention when the bucket of the storage is not configured
+// to lock its objects, the retention lock being then only enforced by the database.
+var ErrRetentionNotSupported = errors.New("object retention not supported by the bucket")
+
 type IStorage interface {
 	PresignedGetObject(path string) (string, error)
 	PresignedPostPolicy(
 		path string,
 		size int,
 		metadata map[string]string,
+		checksum *models.FileChecksum,
 	) (string, map[string]string, error)
-	StatObject(path string) (map[string]string, error)
+	StatObject(path string) (ObjectInfo, error)
+	GetObject(path string) (io.ReadCloser, error)
 	ListObjects(prefix string, maxKeys int32) ([]string, error)
+	WalkObjects(prefix string, fn func(key string) error) error
 	RemoveObject(path string) error
 	RemoveObjects(paths []string) error
 	SetObjectTags(path string, tags map[string]string) error
 	GetObjectTags(path string) (map[string]string, error)
 	RemoveObjectTags(path string, tagsToRemove []string) error
-	EnsureTrashLifecyclePolicy(retentionDays int) error
-	MarkAsTrashed(objectPath string, model interface{}) error
+	SetObjectRetention(path string, lock models.RetentionLock) error
+	EnsureTrashLifecyclePolicy(retentionDays []int) error
+	MarkAsTrashed(objectPath string, model interface{}, retentionDays int) error
 	UnmarkAsTrashed(objectPath string, model interface{}) error
 	IsTrashMarkerPath(path string) (isMarker bool, originalPath string)
 	GetBucketName() string
 }
+
+// ObjectInfo describes a stored object. Checksums holds the checksums of the content reported by the provider,
+// encoded in base64, which depend on the provider and on how the object was uploaded.
+type ObjectInfo struct {
+	Size      int64
+	Metadata  map[string]string
+	Checksums map[models.ChecksumAlgorithm]string
+}
+
+// Uploader returns the ID of the user who uploaded the object, from the metadata set by the upload policy,
+// whose keys are formatted differently by each provider.
+func (o ObjectInfo) Uploader() string {
+	for _, key := range []string{"user_id", "User-Id", "user-id"} {
+		if userID, ok := o.Metadata[key]; ok {
+			return userID
+		}
+	}
+	return ""
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/url"
 	"path"
+	"sort"
 	"strings"
 	"time"
 
@@ -144,6 +146,7 @@ func (s S3Storage) PresignedPostPolicy(
 	path string,
 	size int,
 	metadata map[string]string,
+	checksum *models.FileChecksum,
 ) (string, map[string]string, error) {
 	policy := minio.NewPostPolicy()
 	_ = policy.SetBucket(s.BucketName)
@@ -153,6 +156,10 @@ func (s S3Storage) PresignedPostPolicy(
 	_ = policy.SetUserMetadata("Bucket-Id", metadata["bucket_id"])
 	_ = policy.SetUserMetadata("File-Id", metadata["file_id"])
 	_ = policy.SetUserMetadata("User-Id", metadata["user_id"])
+	// The SHA-256 and CRC32C checksums are verified on upload, MD5 is only verified once uploaded
+	if err := policy.SetChecksum(minioChecksum(checksum)); err != nil {
+		return "", map[string]string{}, err
+	}
 
 	presignedURL, metadata, err := s.storage.PresignedPostPolicy(context.Background(), policy)
 	if err != nil {
@@ -164,18 +171,64 @@ func (s S3Storage) PresignedPostPolicy(
 	return urlString, metadata, nil
 }
 
-func (s S3Storage) StatObject(path string) (map[string]string, error) {
+// minioChecksum returns the checksum declared for an upload, which is not set when the algorithm is not supported.
+func minioChecksum(checksum *models.FileChecksum) minio.Checksum {
+	if checksum == nil {
+		return minio.Checksum{}
+	}
+
+	switch checksum.Algorithm {
+	case models.ChecksumAlgorithmSHA256:
+		return minio.NewChecksumString(minio.ChecksumSHA256, checksum.Value)
+	case models.ChecksumAlgorithmCRC32C:
+		return minio.NewChecksumString(minio.ChecksumCRC32C, checksum.Value)
+	default:
+		return minio.Checksum{}
+	}
+}
+
+func (s S3Storage) StatObject(path string) (ObjectInfo, error) {
 	file, err := s.storage.StatObject(
 		context.Background(),
 		s.BucketName,
 		path,
-		minio.StatObjectOptions{},
+		minio.StatObjectOptions{Checksum: true},
 	)
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == minio.NoSuchKey {
+			return ObjectInfo{}, ErrObjectNotFound
+		}
+		return ObjectInfo{}, err
+	}
+
+	checksums := map[models.ChecksumAlgorithm]string{}
+	if file.ChecksumSHA256 != "" {
+		checksums[models.ChecksumAlgorithmSHA256] = file.ChecksumSHA256
+	}
+	if file.ChecksumCRC32C != "" {
+		checksums[models.ChecksumAlgorithmCRC32C] = file.ChecksumCRC32C
+	}
+
+	return ObjectInfo{
+		Size:      file.Size,
+		Metadata:  file.UserMetadata,
+		Checksums: checksums,
+	}, nil
+}
+
+// GetObject returns the content of an object, which the caller must close.
+func (s S3Storage) GetObject(path string) (io.ReadCloser, error) {
+	object, err := s.storage.GetObject(context.Background(), s.BucketName, path, minio.GetObjectOptions{})
 	if err != nil {
 		return nil, err
 	}
 
-	return file.UserMetadata, err
+	// The request is only sent on the first read, check the object exists to report errors early
+	if _, err = object.Stat(); err != nil {
+		_ = object.Close()
+		return nil, err
+	}
+	return object, nil
 }
 
 func (s S3Storage) ListObjects(prefix string, maxKeys int32) ([]string, error) {
@@ -197,6 +250,29 @@ func (s S3Storage) ListObjects(prefix string, maxKeys int32) ([]string, error) {
 	return objects, nil
 }
 
+// WalkObjects calls fn with the key of each object under the prefix, across all the pages of the listing.
+func (s S3Storage) WalkObjects(prefix string, fn func(key string) error) error {
+	// Canceling the context stops the listing when fn fails
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opts := minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: true,
+	}
+
+	for object := range s.storage.ListObjects(ctx, s.BucketName, opts) {
+		if object.Err != nil {
+			return object.Err
+		}
+		if err := fn(object.Key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (s S3Storage) RemoveObject(path string) error {
 	return s.storage.RemoveObject(
 		context.Background(),
@@ -295,6 +371,46 @@ func (s S3Storage) RemoveObjectTags(path string, tagsToRemove []string) error {
 	return err
 }
 
+// SetObjectRetention maps the retention lock of a file to the legal hold and the retention of the object,
+// in governance mode, when the bucket has object locking enabled.
+func (s S3Storage) SetObjectRetention(path string, lock models.RetentionLock) error {
+	ctx := context.Background()
+
+	enabled, _, _, _, err := s.storage.GetObjectLockConfig(ctx, s.BucketName)
+	if minio.ToErrorResponse(err).Code == "ObjectLockConfigurationNotFoundError" {
+		return ErrRetentionNotSupported
+	}
+	if err != nil {
+		return err
+	}
+	if enabled != "Enabled" {
+		return ErrRetentionNotSupported
+	}
+
+	status := minio.LegalHoldDisabled
+	if lock.LegalHold {
+		status = minio.LegalHoldEnabled
+	}
+	err = s.storage.PutObjectLegalHold(ctx, s.BucketName, path, minio.PutObjectLegalHoldOptions{Status: &status})
+	if err != nil {
+		return fmt.Errorf("failed to set the legal hold: %w", err)
+	}
+
+	// The retention date is only extended, it expires by itself once passed
+	if lock.RetainUntil != nil {
+		mode := minio.Governance
+		err = s.storage.PutObjectRetention(ctx, s.BucketName, path, minio.PutObjectRetentionOptions{
+			Mode:            &mode,
+			RetainUntilDate: lock.RetainUntil,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set the retention: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // IsTrashMarkerPath checks if a deletion event is for a trash marker.
 // Patterns:
 //   - trash/{bucket-id}/files/{file-id} -> buckets/{bucket-id}/{file-id}
@@ -353,7 +469,8 @@ func (s S3Storage) getTrashMarkerPath(objectPath string, model interface{}) stri
 	return path.Join(trashPrefix, bucketID, resourceType, resourceID)
 }
 
-func (s S3Storage) MarkAsTrashed(objectPath string, object interface{}) error {
+// MarkAsTrashed creates the trash marker of an object, tagged with its retention to be expired by the matching rule.
+func (s S3Storage) MarkAsTrashed(objectPath string, object interface{}, retentionDays int) error {
 	ctx := context.Background()
 	markerPath := s.getTrashMarkerPath(objectPath, object)
 
@@ -366,7 +483,9 @@ func (s S3Storage) MarkAsTrashed(objectPath string, object interface{}) error {
 
 	// Create empty marker object to trigger lifecycle policy deletion
 	reader := bytes.NewReader([]byte{})
-	_, err := s.storage.PutObject(ctx, s.BucketName, markerPath, reader, 0, minio.PutObjectOptions{})
+	_, err := s.storage.PutObject(ctx, s.BucketName, markerPath, reader, 0, minio.PutObjectOptions{
+		UserTags: trashRetentionTags(retentionDays),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to create marker: %w", err)
 	}
@@ -384,36 +503,34 @@ func (s S3Storage) UnmarkAsTrashed(objectPath string, object interface{}) error
 }
 
 // processExistingLifecycleRules processes existing lifecycle rules and returns the updated configuration.
+// The rules of the retentions which are not requested are removed, the trash markers still tagged with them
+// being expired by the TrashExpiration job, and so is the rule expiring all the markers after the same period.
 func (s S3Storage) processExistingLifecycleRules(
 	existingConfig *lifecycle.Configuration,
 	err error,
-	trashRuleID, multipartRuleID string,
-	retentionDays int,
+	multipartRuleID string,
+	trashRules map[string]lifecycle.Rule,
 ) *lifecycle.Configuration {
-	if err != nil || existingConfig == nil || existingConfig.Empty() {
-		return lifecycle.NewConfiguration()
+	config := lifecycle.NewConfiguration()
+	if err == nil && existingConfig != nil && !existingConfig.Empty() {
+		config = existingConfig
 	}
 
-	config := existingConfig
 	var newRules []lifecycle.Rule
-	trashRuleFound := false
-	multipartRuleFound := false
-
 	for _, rule := range config.Rules {
-		switch rule.ID {
-		case trashRuleID:
-			trashRuleFound = true
-			if !rule.Expiration.IsDaysNull() &&
-				int(rule.Expiration.Days) == retentionDays &&
-				rule.RuleFilter.Prefix == "trash/" {
-				zap.L().Debug("Trash lifecycle policy already up-to-date",
+		switch {
+		case rule.ID == trashRuleID:
+			zap.L().Info("Removing the trash lifecycle policy shared by all retentions",
+				zap.String("bucket", s.BucketName))
+
+		case isTrashRetentionRule(rule.ID):
+			if _, ok := trashRules[rule.ID]; !ok {
+				zap.L().Info("Removing the trash lifecycle policy of an unused retention",
 					zap.String("bucket", s.BucketName),
-					zap.Int("retentionDays", retentionDays))
-				newRules = append(newRules, rule)
+					zap.String("rule", rule.ID))
 			}
 
-		case multipartRuleID:
-			multipartRuleFound = true
+		case rule.ID == multipartRuleID:
 			if rule.AbortIncompleteMultipartUpload.DaysAfterInitiation == 1 {
 				zap.L().Debug("Multipart upload cleanup policy already up-to-date",
 					zap.String("bucket", s.BucketName))
@@ -425,83 +542,79 @@ func (s S3Storage) processExistingLifecycleRules(
 		}
 	}
 
-	if trashRuleFound || multipartRuleFound {
-		config.Rules = newRules
+	ruleIDs := make([]string, 0, len(trashRules))
+	for ruleID := range trashRules {
+		ruleIDs = append(ruleIDs, ruleID)
+	}
+	sort.Strings(ruleIDs)
+	for _, ruleID := range ruleIDs {
+		newRules = append(newRules, trashRules[ruleID])
 	}
 
+	config.Rules = newRules
 	return config
 }
 
 // EnsureTrashLifecyclePolicy configures lifecycle policies for the bucket, merging with existing rules.
-// It adds or updates the trash expiration rule (prefix: trash/) with the specified retention period.
+// It adds or updates a trash expiration rule (prefix: trash/) for each retention period in use,
+// expiring the trash markers tagged with it. Long retentions share the rule of their rounded up retention.
 //
 // NOTE: AbortIncompleteMultipartUpload is not supported by MinIO.
 // MinIO does not fully support the AbortIncompleteMultipartUpload lifecycle action.
 // References:
 // - https://github.com/minio/minio/issues/16120
 // - https://github.com/minio/minio/issues/19115
-func (s S3Storage) EnsureTrashLifecyclePolicy(retentionDays int) error {
-	const trashRuleID = "safebucket-trash-retention"
+func (s S3Storage) EnsureTrashLifecyclePolicy(retentionDays []int) error {
 	const multipartRuleID = "safebucket-abort-incomplete-multipart"
 
 	// Validate retentionDays to prevent overflow and invalid values
-	if retentionDays < 0 {
-		return fmt.Errorf("retentionDays %d cannot be negative", retentionDays)
-	}
-	if retentionDays > 2147483647 { // math.MaxInt32
-		return fmt.Errorf("retentionDays %d exceeds maximum allowed value (2147483647)", retentionDays)
+	if err := validateRetentions(retentionDays); err != nil {
+		return err
 	}
 
 	ctx := context.Background()
 
-	// Fetch existing lifecycle configuration
-	existingConfig, err := s.storage.GetBucketLifecycle(ctx, s.BucketName)
-
-	// Process existing rules to preserve non-SafeBucket policies
-	config := s.processExistingLifecycleRules(
-		existingConfig,
-		err,
-		trashRuleID,
-		multipartRuleID,
-		retentionDays,
-	)
-
-	// Check if trash rule already exists and is up-to-date
-	trashRuleExists := false
-	for _, rule := range config.Rules {
-		if rule.ID == trashRuleID {
-			trashRuleExists = true
-			break
+	trashRules := map[string]lifecycle.Rule{}
+	for _, days := range trashRuleRetentions(retentionDays) {
+		var tags []lifecycle.Tag
+		for key, value := range trashRetentionTags(days) {
+			tags = append(tags, lifecycle.Tag{Key: key, Value: value})
 		}
-	}
 
-	// Add or update trash rule if needed
-	if !trashRuleExists {
-		trashRule := lifecycle.Rule{
-			ID:     trashRuleID,
+		ruleID := trashRetentionRuleID(days)
+		trashRules[ruleID] = lifecycle.Rule{
+			ID:     ruleID,
 			Status: "Enabled",
 			RuleFilter: lifecycle.Filter{
-				Prefix: "trash/",
+				And: lifecycle.And{
+					Prefix: trashPrefix,
+					Tags:   tags,
+				},
 			},
 			Expiration: lifecycle.Expiration{
-				Days: lifecycle.ExpirationDays(retentionDays),
+				Days: lifecycle.ExpirationDays(days),
 			},
 		}
-		config.Rules = append(config.Rules, trashRule)
 	}
 
+	// Fetch existing lifecycle configuration
+	existingConfig, err := s.storage.GetBucketLifecycle(ctx, s.BucketName)
+
+	// Process existing rules to preserve non-SafeBucket policies
+	config := s.processExistingLifecycleRules(existingConfig, err, multipartRuleID, trashRules)
+
 	err = s.storage.SetBucketLifecycle(ctx, s.BucketName, config)
 	if err != nil {
 		zap.L().Error("Failed to set lifecycle policies",
 			zap.String("bucket", s.BucketName),
-			zap.Int("trashRetentionDays", retentionDays),
+			zap.Ints("trashRetentionDays", retentionDays),
 			zap.Error(err))
 		return err
 	}
 
 	zap.L().Info("Lifecycle policies configured",
 		zap.String("bucket", s.BucketName),
-		zap.Int("trashRetentionDays", retentionDays),
+		zap.Ints("trashRetentionDays", retentionDays),
 		zap.Int("multipartCleanupDays", 1))
 	return nil
 }
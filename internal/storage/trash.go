@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// trashRuleID is the ID of the lifecycle rule which expired all the trash markers after the same period,
+	// replaced by a rule for each retention.
+	trashRuleID = "safebucket-trash-retention"
+	// trashRetentionTag is the tag holding the retention of the lifecycle rule expiring a trash marker.
+	trashRetentionTag = "safebucket-retention-days"
+	// maxRetentionDays is the highest retention the configuration allows.
+	maxRetentionDays = 3650
+	// trashRuleExactDays is the retention up to which the trash markers are expired on the exact day.
+	// Longer retentions are rounded up to a number of trashRulePeriodDays, so that the lifecycle rules
+	// stay far below the 1000 rules the providers allow.
+	trashRuleExactDays  = 30
+	trashRulePeriodDays = 30
+)
+
+// trashRuleRetention returns the retention of the lifecycle rule expiring the markers kept for the given number
+// of days. Rounded up retentions are expired on the exact day by the TrashExpiration job.
+func trashRuleRetention(retentionDays int) int {
+	if retentionDays <= trashRuleExactDays {
+		return retentionDays
+	}
+	return (retentionDays + trashRulePeriodDays - 1) / trashRulePeriodDays * trashRulePeriodDays
+}
+
+// trashRuleRetentions returns the distinct retentions of the lifecycle rules of the given retentions, sorted.
+func trashRuleRetentions(retentionDays []int) []int {
+	seen := map[int]bool{}
+	var retentions []int
+	for _, days := range retentionDays {
+		ruleDays := trashRuleRetention(days)
+		if !seen[ruleDays] {
+			seen[ruleDays] = true
+			retentions = append(retentions, ruleDays)
+		}
+	}
+	sort.Ints(retentions)
+	return retentions
+}
+
+// trashRetentionRuleID returns the ID of the lifecycle rule expiring the markers tagged with the given retention.
+func trashRetentionRuleID(ruleDays int) string {
+	return fmt.Sprintf("%s-%dd", trashRuleID, ruleDays)
+}
+
+// isTrashRetentionRule checks if a lifecycle rule expires the trash markers of a retention.
+func isTrashRetentionRule(ruleID string) bool {
+	return strings.HasPrefix(ruleID, trashRuleID+"-")
+}
+
+// trashRetentionTags returns the tags of a trash marker kept for the given number of days,
+// which select the lifecycle rule of its retention.
+func trashRetentionTags(retentionDays int) map[string]string {
+	return map[string]string{trashRetentionTag: strconv.Itoa(trashRuleRetention(retentionDays))}
+}
+
+// validateRetentions checks the retentions are positive and fit in the lifecycle rules of the providers.
+func validateRetentions(retentionDays []int) error {
+	for _, days := range retentionDays {
+		if days < 1 || days > maxRetentionDays {
+			return fmt.Errorf("retentionDays %d is out of valid range (1-%d)", days, maxRetentionDays)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrashRuleRetention(t *testing.T) {
+	testCases := []struct {
+		retentionDays int
+		expected      int
+	}{
+		{1, 1},
+		{7, 7},
+		{30, 30},
+		{31, 60},
+		{60, 60},
+		{365, 390},
+		{3650, 3660},
+	}
+
+	for _, tt := range testCases {
+		assert.Equal(t, tt.expected, trashRuleRetention(tt.retentionDays), "retention of %d days", tt.retentionDays)
+	}
+}
+
+func TestTrashRuleRetentionsFitInLifecycleRules(t *testing.T) {
+	assert.Equal(t, []int{7, 30, 60, 390}, trashRuleRetentions([]int{365, 7, 45, 30, 60, 7, 370}))
+
+	allRetentions := make([]int, maxRetentionDays)
+	for days := 1; days <= maxRetentionDays; days++ {
+		allRetentions[days-1] = days
+	}
+	// S3 allows up to 1000 lifecycle rules on a bucket
+	assert.LessOrEqual(t, len(trashRuleRetentions(allRetentions)), 200)
+}
+
+func TestTrashRetentionTags(t *testing.T) {
+	assert.Equal(t, map[string]string{trashRetentionTag: "7"}, trashRetentionTags(7))
+	assert.Equal(t, map[string]string{trashRetentionTag: "390"}, trashRetentionTags(365))
+}
+
+func TestTrashRetentionRuleID(t *testing.T) {
+	assert.Equal(t, "safebucket-trash-retention-30d", trashRetentionRuleID(30))
+	assert.True(t, isTrashRetentionRule(trashRetentionRuleID(30)))
+	assert.False(t, isTrashRetentionRule(trashRuleID))
+	assert.False(t, isTrashRetentionRule("safebucket-abort-incomplete-multipart"))
+}
+
+func TestValidateRetentions(t *testing.T) {
+	testCases := []struct {
+		name          string
+		retentionDays []int
+		valid         bool
+	}{
+		{"Retentions within the bounds", []int{1, 7, maxRetentionDays}, true},
+		{"No retention", nil, true},
+		{"Retention of zero days", []int{7, 0}, false},
+		{"Negative retention", []int{-1}, false},
+		{"Retention above the maximum", []int{maxRetentionDays + 1}, false},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRetentions(tt.retentionDays)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestAWSProcessExistingLifecycleRulesPrunesUnusedRetentions(t *testing.T) {
+	const multipartRuleID = "safebucket-abort-incomplete-multipart"
+	multipartRule := types.LifecycleRule{
+		ID:                             aws.String(multipartRuleID),
+		AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(1)},
+	}
+	trashRules := map[string]types.LifecycleRule{
+		trashRetentionRuleID(7):  {ID: aws.String(trashRetentionRuleID(7))},
+		trashRetentionRuleID(30): {ID: aws.String(trashRetentionRuleID(30))},
+	}
+	existing := &s3.GetBucketLifecycleConfigurationOutput{
+		Rules: []types.LifecycleRule{
+			{ID: aws.String(trashRuleID)},
+			{ID: aws.String(trashRetentionRuleID(7))},
+			{ID: aws.String(trashRetentionRuleID(14))},
+			{ID: aws.String("custom-rule")},
+			multipartRule,
+		},
+	}
+
+	rules := AWSStorage{BucketName: "safebucket"}.
+		processExistingLifecycleRules(existing, nil, multipartRuleID, trashRules, multipartRule)
+
+	var ruleIDs []string
+	for _, rule := range rules {
+		ruleIDs = append(ruleIDs, *rule.ID)
+	}
+	assert.Equal(t, []string{
+		"custom-rule",
+		multipartRuleID,
+		trashRetentionRuleID(30),
+		trashRetentionRuleID(7),
+	}, ruleIDs)
+}
+
+func TestS3ProcessExistingLifecycleRulesPrunesUnusedRetentions(t *testing.T) {
+	const multipartRuleID = "safebucket-abort-incomplete-multipart"
+	trashRules := map[string]lifecycle.Rule{
+		trashRetentionRuleID(7):  {ID: trashRetentionRuleID(7)},
+		trashRetentionRuleID(30): {ID: trashRetentionRuleID(30)},
+	}
+	existing := lifecycle.NewConfiguration()
+	existing.Rules = []lifecycle.Rule{
+		{ID: trashRuleID},
+		{ID: trashRetentionRuleID(7)},
+		{ID: trashRetentionRuleID(14)},
+		{ID: "custom-rule"},
+	}
+
+	config := S3Storage{BucketName: "safebucket"}.
+		processExistingLifecycleRules(existing, nil, multipartRuleID, trashRules)
+
+	var ruleIDs []string
+	for _, rule := range config.Rules {
+		ruleIDs = append(ruleIDs, rule.ID)
+	}
+	assert.Equal(t, []string{"custom-rule", trashRetentionRuleID(30), trashRetentionRuleID(7)}, ruleIDs)
+}
@@ -0,0 +1,47 @@
+package tests
+
+import (
+	"io"
+
+	"api/internal/models"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockBucketHub struct {
+	mock.Mock
+}
+
+func (m *MockBucketHub) Publish(event models.BucketEvent) {
+	m.Called(event)
+}
+
+func (m *MockBucketHub) Subscribe(bucketID uuid.UUID) (<-chan models.BucketEvent, func()) {
+	args := m.Called(bucketID)
+	events, _ := args.Get(0).(<-chan models.BucketEvent)
+	unsubscribe, _ := args.Get(1).(func())
+	return events, unsubscribe
+}
+
+type MockScanner struct {
+	mock.Mock
+}
+
+func (m *MockScanner) Scan(content io.Reader, size int64) (models.FileScanResult, string, error) {
+	args := m.Called(content, size)
+	return args.Get(0).(models.FileScanResult), args.String(1), args.Error(2) //nolint:errcheck // test mock
+}
+
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(messages ...*message.Message) error {
+	return m.Called(messages).Error(0)
+}
+
+func (m *MockPublisher) Close() error {
+	return m.Called().Error(0)
+}
@@ -66,6 +66,49 @@ func (m *MockGetOneFunc[Out]) GetOne(logger *zap.Logger, claims models.UserClaim
 	return args.Get(0).(Out), args.Error(1) //nolint:errcheck // test mock type assertion expected to succeed
 }
 
+type MockGetOneWithQueryFunc[Q any, Out any] struct {
+	mock.Mock
+}
+
+func (m *MockGetOneWithQueryFunc[Q, Out]) GetOne(
+	logger *zap.Logger,
+	claims models.UserClaims,
+	ids uuid.UUIDs,
+	query Q,
+) (Out, error) {
+	args := m.Called(logger, claims, ids, query)
+	return args.Get(0).(Out), args.Error(1) //nolint:errcheck // test mock type assertion expected to succeed
+}
+
+type MockStreamWithQueryFunc[Q any] struct {
+	mock.Mock
+}
+
+func (m *MockStreamWithQueryFunc[Q]) Stream(
+	logger *zap.Logger,
+	claims models.UserClaims,
+	ids uuid.UUIDs,
+	query Q,
+) (models.Stream, error) {
+	args := m.Called(logger, claims, ids, query)
+	return args.Get(0).(models.Stream), args.Error(1) //nolint:errcheck // test mock type assertion expected to succeed
+}
+
+type MockEventStreamFunc[Out any] struct {
+	mock.Mock
+}
+
+func (m *MockEventStreamFunc[Out]) Subscribe(
+	logger *zap.Logger,
+	claims models.UserClaims,
+	ids uuid.UUIDs,
+) (<-chan Out, func(), error) {
+	args := m.Called(logger, claims, ids)
+	events := args.Get(0).(<-chan Out)  //nolint:errcheck // test mock type assertion expected to succeed
+	unsubscribe := args.Get(1).(func()) //nolint:errcheck // test mock type assertion expected to succeed
+	return events, unsubscribe, args.Error(2)
+}
+
 type MockUpdateFunc[In any] struct {
 	mock.Mock
 }
@@ -83,3 +126,17 @@ func (m *MockDeleteFunc) Delete(logger *zap.Logger, claims models.UserClaims, id
 	args := m.Called(logger, claims, ids)
 	return args.Error(0)
 }
+
+type MockDeleteWithQueryFunc[Q any] struct {
+	mock.Mock
+}
+
+func (m *MockDeleteWithQueryFunc[Q]) Delete(
+	logger *zap.Logger,
+	claims models.UserClaims,
+	ids uuid.UUIDs,
+	query Q,
+) error {
+	args := m.Called(logger, claims, ids, query)
+	return args.Error(0)
+}
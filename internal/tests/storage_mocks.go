@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"io"
+
+	"api/internal/models"
+	"api/internal/storage"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockStorage struct {
+	mock.Mock
+}
+
+func (m *MockStorage) PresignedGetObject(path string) (string, error) {
+	args := m.Called(path)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockStorage) PresignedPostPolicy(
+	path string,
+	size int,
+	metadata map[string]string,
+	checksum *models.FileChecksum,
+) (string, map[string]string, error) {
+	args := m.Called(path, size, metadata, checksum)
+	return args.String(0), args.Get(1).(map[string]string), args.Error(2) //nolint:errcheck // test mock
+}
+
+func (m *MockStorage) StatObject(path string) (storage.ObjectInfo, error) {
+	args := m.Called(path)
+	return args.Get(0).(storage.ObjectInfo), args.Error(1) //nolint:errcheck // test mock type assertion
+}
+
+func (m *MockStorage) GetObject(path string) (io.ReadCloser, error) {
+	args := m.Called(path)
+	content, _ := args.Get(0).(io.ReadCloser)
+	return content, args.Error(1)
+}
+
+func (m *MockStorage) ListObjects(prefix string, maxKeys int32) ([]string, error) {
+	args := m.Called(prefix, maxKeys)
+	keys, _ := args.Get(0).([]string)
+	return keys, args.Error(1)
+}
+
+func (m *MockStorage) WalkObjects(prefix string, fn func(key string) error) error {
+	args := m.Called(prefix, fn)
+	return args.Error(0)
+}
+
+func (m *MockStorage) RemoveObject(path string) error {
+	return m.Called(path).Error(0)
+}
+
+func (m *MockStorage) RemoveObjects(paths []string) error {
+	return m.Called(paths).Error(0)
+}
+
+func (m *MockStorage) SetObjectTags(path string, tags map[string]string) error {
+	return m.Called(path, tags).Error(0)
+}
+
+func (m *MockStorage) GetObjectTags(path string) (map[string]string, error) {
+	args := m.Called(path)
+	tags, _ := args.Get(0).(map[string]string)
+	return tags, args.Error(1)
+}
+
+func (m *MockStorage) RemoveObjectTags(path string, tagsToRemove []string) error {
+	return m.Called(path, tagsToRemove).Error(0)
+}
+
+func (m *MockStorage) SetObjectRetention(path string, lock models.RetentionLock) error {
+	return m.Called(path, lock).Error(0)
+}
+
+func (m *MockStorage) EnsureTrashLifecyclePolicy(retentionDays []int) error {
+	return m.Called(retentionDays).Error(0)
+}
+
+func (m *MockStorage) MarkAsTrashed(objectPath string, model interface{}, retentionDays int) error {
+	return m.Called(objectPath, model, retentionDays).Error(0)
+}
+
+func (m *MockStorage) UnmarkAsTrashed(objectPath string, model interface{}) error {
+	return m.Called(objectPath, model).Error(0)
+}
+
+func (m *MockStorage) IsTrashMarkerPath(path string) (bool, string) {
+	args := m.Called(path)
+	return args.Bool(0), args.String(1)
+}
+
+func (m *MockStorage) GetBucketName() string {
+	return m.Called().String(0)
+}
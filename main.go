@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"api/internal/configuration"
@@ -11,8 +12,11 @@ import (
 	"api/internal/database"
 	"api/internal/events"
 	h "api/internal/helpers"
+	"api/internal/jobs"
 	m "api/internal/middlewares"
 	"api/internal/models"
+	"api/internal/rbac"
+	"api/internal/realtime"
 	"api/internal/services"
 
 	"github.com/go-chi/chi/v5"
@@ -26,13 +30,19 @@ import (
 func main() {
 	zap.ReplaceGlobals(zap.Must(zap.NewProduction()))
 
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		reconcile(os.Args[2:])
+		return
+	}
+
 	config := configuration.Read()
 	core.NewLogger(config.App.LogLevel)
 	db := database.InitDB(config.Database)
 	cache := core.NewCache(config.Cache)
-	storage := core.NewStorage(config.Storage, config.App.TrashRetentionDays)
-	notifier := core.NewNotifier(config.Notifier)
-	activity := core.NewActivityLogger(config.Activity)
+	storage := core.NewStorage(config.Storage, db, config.App.TrashRetentionDays)
+	notifier := core.NewNotifier(config.Notifier, db, cache)
+	activity := core.NewActivityLogger(config.Activity, db)
+	scanner := core.NewScanner(config.Antivirus)
 
 	adminUser := models.User{
 		FirstName:    "admin",
@@ -53,18 +63,34 @@ func main() {
 		DoUpdates: clause.AssignmentColumns([]string{"hashed_password"}),
 	}).Create(&adminUser)
 
+	if err := rbac.SeedDefaultPolicies(db); err != nil {
+		zap.L().Fatal("Failed to seed default policies", zap.Error(err))
+	}
+
+	policyEngine, err := rbac.NewPolicyEngine(db)
+	if err != nil {
+		zap.L().Fatal("Failed to load policies", zap.Error(err))
+	}
+
 	appIdentity := uuid.New().String()
 
+	bucketHub := realtime.NewBucketHub(cache)
+	go bucketHub.Start()
+
 	eventsManager := core.NewEventsManager(config.Events, storage)
 	eventRouter := core.NewEventRouter(eventsManager)
 
 	eventParams := &events.EventParams{
 		WebURL:             config.App.WebURL,
+		APIURL:             config.App.APIURL,
 		Notifier:           notifier,
 		Publisher:          eventRouter,
 		DB:                 db,
 		Storage:            storage,
 		ActivityLogger:     activity,
+		Scanner:            scanner,
+		BucketHub:          bucketHub,
+		WebhookClient:      events.NewWebhookClient(config.App.WebhookAllowedHosts),
 		TrashRetentionDays: config.App.TrashRetentionDays,
 	}
 
@@ -79,18 +105,70 @@ func main() {
 
 	go events.HandleBucketEvents(
 		bucketEventsSubscriber,
+		eventRouter,
+		bucketHub,
 		db,
 		activity,
 		storage,
+		scanner,
 		config.App.TrashRetentionDays,
 		bucketEvents,
 	)
 
 	go cache.StartIdentityTicker(appIdentity)
+	go policyEngine.StartReloadTicker(time.Minute)
+
+	membershipExpiration := jobs.MembershipExpiration{
+		DB:             db,
+		Publisher:      eventRouter,
+		ActivityLogger: activity,
+		WarningPeriod:  time.Duration(config.App.MembershipExpiryWarningDays) * 24 * time.Hour,
+	}
+	go membershipExpiration.Start(configuration.MembershipExpirationIntervalMinutes * time.Minute)
+
+	activitySigningKey := config.Activity.SigningKey
+	if activitySigningKey == "" {
+		activitySigningKey = config.App.JWTSecret
+	}
+
+	activityChainCheckpoint := jobs.ActivityChainCheckpoint{
+		DB:         db,
+		SigningKey: activitySigningKey,
+	}
+	go activityChainCheckpoint.Start(configuration.ActivityChainCheckpointIntervalMinutes * time.Minute)
+
+	core.StartActivityDelivery(config.Activity, activity)
+	core.StartMailDelivery(notifier)
+
+	webhookRetry := jobs.WebhookRetry{
+		DB:        db,
+		Publisher: eventRouter,
+	}
+	go webhookRetry.Start(configuration.WebhookRetryIntervalSeconds * time.Second)
+
+	notificationDigest := jobs.NotificationDigest{
+		DB:        db,
+		Publisher: eventRouter,
+	}
+	go notificationDigest.Start(configuration.NotificationDigestIntervalMinutes * time.Minute)
+
+	uploadReaper := jobs.UploadReaper{
+		Params:   eventParams,
+		Cache:    cache,
+		Identity: appIdentity,
+	}
+	go uploadReaper.Start(configuration.UploadReaperIntervalMinutes * time.Minute)
+
+	trashExpiration := jobs.TrashExpiration{
+		Params:   eventParams,
+		Cache:    cache,
+		Identity: appIdentity,
+	}
+	go trashExpiration.Start(configuration.TrashExpirationIntervalMinutes * time.Minute)
 
 	r := chi.NewRouter()
 
-	r.Use(middleware.Timeout(5 * time.Second))
+	r.Use(m.Timeout(5*time.Second, r, "/api/v1/buckets/{id0}/events"))
 	r.Use(m.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -111,21 +189,40 @@ func main() {
 
 	// API routes with auth middleware
 	r.Route("/api", func(apiRouter chi.Router) {
-		apiRouter.Use(m.Authenticate(config.App.JWTSecret))
+		apiRouter.Use(m.Authenticate(config.App.JWTSecret, db))
 		apiRouter.Use(m.RateLimit(cache, config.App.TrustedProxies))
 
 		apiRouter.Mount("/v1/users", services.UserService{
-			DB: db,
+			DB:             db,
+			ActivityLogger: activity,
+			PolicyEngine:   policyEngine,
 		}.Routes())
 
 		apiRouter.Mount("/v1/buckets", services.BucketService{
-			DB:                 db,
-			Storage:            storage,
-			Publisher:          eventRouter,
-			ActivityLogger:     activity,
-			Providers:          providers,
-			WebURL:             config.App.WebURL,
-			TrashRetentionDays: config.App.TrashRetentionDays,
+			DB:                    db,
+			Storage:               storage,
+			Publisher:             eventRouter,
+			ActivityLogger:        activity,
+			Providers:             providers,
+			PolicyEngine:          policyEngine,
+			BucketHub:             bucketHub,
+			WebURL:                config.App.WebURL,
+			TrashRetentionDays:    config.App.TrashRetentionDays,
+			TrashRetentionMinDays: config.App.TrashRetentionMinDays,
+			TrashRetentionMaxDays: config.App.TrashRetentionMaxDays,
+			WebhookAllowedHosts:   config.App.WebhookAllowedHosts,
+		}.Routes())
+
+		apiRouter.Mount("/v1/activity", services.ActivityService{
+			DB:             db,
+			ActivityLogger: activity,
+			PolicyEngine:   policyEngine,
+			SigningKey:     activitySigningKey,
+		}.Routes())
+
+		apiRouter.Mount("/v1/policies", services.PolicyService{
+			DB:           db,
+			PolicyEngine: policyEngine,
 		}.Routes())
 
 		apiRouter.Mount("/v1/auth", services.AuthService{
@@ -137,6 +234,18 @@ func main() {
 			ActivityLogger: activity,
 		}.Routes())
 
+		apiRouter.Mount("/v1/notifications", services.NotificationService{
+			DB:           db,
+			PolicyEngine: policyEngine,
+		}.Routes())
+
+		apiRouter.Mount("/v1/storage", services.StorageService{
+			DB:             db,
+			Storage:        storage,
+			ActivityLogger: activity,
+			PolicyEngine:   policyEngine,
+		}.Routes())
+
 		apiRouter.Mount("/v1/invites", services.InviteService{
 			DB:             db,
 			JWTSecret:      config.App.JWTSecret,
@@ -175,7 +284,7 @@ func main() {
 		IdleTimeout:  5 * time.Second,
 	}
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if err != nil {
 		zap.L().Error("Failed to start the app")
 	}
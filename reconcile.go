@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"api/internal/configuration"
+	"api/internal/core"
+	"api/internal/database"
+	"api/internal/models"
+	"api/internal/reconciliation"
+
+	"go.uber.org/zap"
+)
+
+// reconcile runs the reconciliation of the storage with the database, and prints its report.
+// Usage: safebucket reconcile [-repair].
+func reconcile(args []string) {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	repair := flags.Bool(
+		"repair",
+		false,
+		"remove the orphaned objects, the stale trash markers and the files without object",
+	)
+	_ = flags.Parse(args)
+
+	config := configuration.Read()
+	core.NewLogger(config.App.LogLevel)
+	db := database.InitDB(config.Database)
+	storage := core.NewStorage(config.Storage, db, 0)
+
+	run, err := reconciliation.Start(db, *repair, nil)
+	if err != nil {
+		zap.L().Fatal("Failed to start the storage reconciliation", zap.Error(err))
+	}
+
+	reconciler := reconciliation.Reconciler{DB: db, Storage: storage, Repair: *repair}
+	reconciler.Execute(&run)
+
+	output, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		zap.L().Fatal("Failed to encode the reconciliation report", zap.Error(err))
+	}
+	fmt.Println(string(output))
+
+	if run.Status != models.ReconciliationStatusCompleted {
+		os.Exit(1)
+	}
+}